@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+
+	"audio-loss-checker/internal/analyzer"
+	"audio-loss-checker/internal/cache"
+
+	"github.com/spf13/cobra"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "管理分析结果缓存",
+	Long: `分析结果按文件路径、大小和修改时间（可选内容哈希）缓存，
+文件没有变化且分析器版本和分析参数相同时，再次扫描直接使用缓存的结果。`,
+}
+
+var cachePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "删除过期的缓存条目",
+	Long:  `删除分析器版本不同、对应文件已删除或已修改的缓存条目，以及损坏的条目。`,
+	Args:  cobra.NoArgs,
+	RunE:  runCachePrune,
+}
+
+func init() {
+	cacheCmd.AddCommand(cachePruneCmd)
+	rootCmd.AddCommand(cacheCmd)
+}
+
+func runCachePrune(cmd *cobra.Command, args []string) error {
+	dir, err := resolveCacheDir()
+	if err != nil {
+		return err
+	}
+	c, err := cache.Open(dir)
+	if err != nil {
+		return err
+	}
+	stats, err := c.Prune(analyzer.AnalyzerVersion)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("缓存目录: %s\n已删除 %d 条过期缓存，保留 %d 条\n", dir, stats.Removed, stats.Kept)
+	return nil
+}
+
+// resolveCacheDir 返回 --cache-dir 指定的目录，未指定时使用默认目录
+func resolveCacheDir() (string, error) {
+	if cacheDir != "" {
+		return cacheDir, nil
+	}
+	return cache.DefaultDir()
+}
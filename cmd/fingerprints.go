@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"os"
+
+	"audio-loss-checker/internal/analyzer"
+
+	"github.com/spf13/cobra"
+)
+
+var fingerprintsCmd = &cobra.Command{
+	Use:   "fingerprints",
+	Short: "输出内置的编码器低通指纹表",
+	Long: `以JSON格式输出内置的有损编码器低通指纹表（LAME CBR/V0-V9、AAC-LC、HE-AAC、Vorbis、Opus）。
+
+每项包括编码器 (codec)、设置 (profile)、码率 (bitrate)、低通频率 (lowpass, Hz)、
+过渡带宽度 (transition, Hz) 和截断形状 (shelf: brick 陡峭截断 / soft 较缓的滚降)。
+可以把输出保存为文件修改后用 --fingerprints 指定，代替内置表。`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_, err := os.Stdout.Write(analyzer.BuiltinFingerprints())
+		return err
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(fingerprintsCmd)
+}
@@ -3,12 +3,11 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"path/filepath"
 	"runtime"
-	"strings"
 
 	"audio-loss-checker/internal/analyzer"
 	"audio-loss-checker/internal/types"
+	"audio-loss-checker/pkg/checker"
 
 	"github.com/spf13/cobra"
 )
@@ -17,8 +16,20 @@ var (
 	quiet       bool
 	onlyFake    bool
 	jsonOutput  bool
+	htmlReport  string
+	segments    float64
+	verify      bool
+	accurateRip string
+	albumBy     string
 	cutoffFreq  float64
 	concurrency int
+	windows     int
+	overlap     float64
+	noCache     bool
+	rebuild     bool
+	cacheHash   bool
+	cacheDir    string
+	fingerprint string
 	version     = "1.1.0"
 )
 
@@ -26,7 +37,7 @@ var rootCmd = &cobra.Command{
 	Use:   "audio-loss-checker [path]",
 	Short: "检测无损音频文件是否真的是无损格式",
 	Long: `Audio Loss Checker 是一个CLI工具，用于检测无损音频文件是否真的是无损格式。
-当前支持 WAV, FLAC 格式。ALAC 和 APE 格式支持开发中。
+当前支持 WAV (含RF64/Wave64), FLAC, ALAC, APE, WavPack, AIFF, DSD (DSF/DFF) 格式，整轨镜像可配合CUE表按音轨分析。
 
 通过频谱分析检测音频文件是否存在高频截断，从而判断是否为从有损格式转换而来的"假无损"文件。`,
 	Args: cobra.ExactArgs(1),
@@ -44,8 +55,21 @@ func init() {
 	rootCmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "静默模式，仅输出假无损文件路径")
 	rootCmd.Flags().BoolVar(&onlyFake, "only-fake", false, "只显示假无损文件的分析报告")
 	rootCmd.Flags().BoolVar(&jsonOutput, "json", false, "以JSON格式输出结果")
-	rootCmd.Flags().Float64Var(&cutoffFreq, "cutoff", 18000, "频率截断阈值 (Hz)")
-	rootCmd.Flags().IntVarP(&concurrency, "concurrency", "j", runtime.NumCPU(), "并发处理文件数量")
+	rootCmd.Flags().StringVar(&htmlReport, "html", "", "生成离线HTML报告（含频谱图）到指定文件")
+	rootCmd.Flags().BoolVar(&verify, "verify", false, "校验解码后PCM数据的MD5（FLAC），损坏或被截断的文件报告为 CORRUPT/TRUNCATED")
+	rootCmd.Flags().StringVar(&accurateRip, "accuraterip", "", "与本地AccurateRip数据核对CD规格音轨的校验和，指定 dBAR-*.bin 文件或包含这些文件的目录")
+	rootCmd.Flags().StringVar(&albumBy, "album-by", "auto", "专辑汇总的分组方式: auto（CUE表、专辑+艺术家标签、目录依次优先）、directory（按目录）、none（不汇总）")
+	rootCmd.Flags().Float64Var(&segments, "segments", 0, "分段分析，每段的时长（秒），报告各段的最高有效频率并标出与其余部分不同的分段；0表示不分段")
+	// 分析参数对子命令（如 spectrogram）同样有效
+	rootCmd.PersistentFlags().Float64Var(&cutoffFreq, "cutoff", analyzer.DefaultCutoffFreq, "频率截断阈值 (Hz)")
+	rootCmd.PersistentFlags().IntVarP(&concurrency, "concurrency", "j", runtime.NumCPU(), "并发处理文件数量")
+	rootCmd.PersistentFlags().IntVar(&windows, "windows", analyzer.DefaultWelchWindows, "频谱平均的最大窗口数，0表示使用全部窗口")
+	rootCmd.PersistentFlags().Float64Var(&overlap, "overlap", analyzer.DefaultWelchOverlap, "相邻分析窗口的重叠比例 (0-1)")
+	rootCmd.PersistentFlags().StringVar(&fingerprint, "fingerprints", "", "从JSON文件读取编码器低通指纹表，代替内置表")
+	rootCmd.PersistentFlags().StringVar(&cacheDir, "cache-dir", "", "结果缓存目录，默认为用户缓存目录下的 audio-loss-checker")
+	rootCmd.Flags().BoolVar(&noCache, "no-cache", false, "不读取也不写入结果缓存")
+	rootCmd.Flags().BoolVar(&rebuild, "rebuild-cache", false, "忽略已有缓存，重新分析所有文件并更新缓存")
+	rootCmd.Flags().BoolVar(&cacheHash, "cache-hash", false, "命中缓存前还校验文件内容的SHA-256（需要读取整个文件）")
 	rootCmd.Flags().BoolP("version", "v", false, "显示版本信息")
 
 	// 添加版本命令
@@ -61,20 +85,26 @@ func runAnalysis(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("路径不存在: %s", targetPath)
 	}
 
-	// 创建分析器配置
-	config := &types.AnalyzerConfig{
-		CutoffFreq:  cutoffFreq,
-		Concurrency: concurrency,
-		Quiet:       quiet,
-		OnlyFake:    onlyFake,
-		JSONOutput:  jsonOutput,
+	// 创建分析器
+	opts := append(analysisOptions(),
+		checker.WithSegments(segments),
+		checker.WithVerify(verify),
+		checker.WithSpectrograms(htmlReport != ""),
+		checker.WithAlbumBy(albumBy),
+	)
+	if accurateRip != "" {
+		opts = append(opts, checker.WithAccurateRip(accurateRip))
+	}
+	if !noCache {
+		opts = append(opts, checker.WithCache(cacheDir), checker.WithCacheRebuild(rebuild), checker.WithCacheHash(cacheHash))
+	}
+	audioChecker, err := checker.New(opts...)
+	if err != nil {
+		return err
 	}
-
-	// 创建分析器实例
-	audioAnalyzer := analyzer.NewAnalyzer(config)
 
 	// 收集音频文件
-	files, err := collectAudioFiles(targetPath)
+	files, err := checker.CollectFiles(targetPath)
 	if err != nil {
 		return fmt.Errorf("收集音频文件失败: %w", err)
 	}
@@ -84,37 +114,26 @@ func runAnalysis(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	// 开始分析
-	return audioAnalyzer.AnalyzeFiles(files)
+	// 开始分析，每个文件完成后立即输出
+	output := analyzer.NewOutput(&types.OutputConfig{
+		Quiet:      quiet,
+		OnlyFake:   onlyFake,
+		JSONOutput: jsonOutput,
+		HTMLReport: htmlReport,
+	}, len(files))
+	return output.Finish(audioChecker.AnalyzeFiles(files, output.FileDone))
 }
 
-func collectAudioFiles(path string) ([]string, error) {
-	var files []string
-	supportedExts := map[string]bool{
-		".wav":  true,
-		".flac": true,
-		// TODO: 待实现的格式
-		// ".alac": true,
-		// ".ape":  true,
-		// ".m4a":  true, // ALAC files often use .m4a extension
+// analysisOptions 根据各命令共用的分析参数创建选项
+func analysisOptions() []checker.Option {
+	opts := []checker.Option{
+		checker.WithCutoff(cutoffFreq),
+		checker.WithConcurrency(concurrency),
+		checker.WithWindows(windows),
+		checker.WithOverlap(overlap),
 	}
-
-	err := filepath.Walk(path, func(filePath string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		if info.IsDir() {
-			return nil
-		}
-
-		ext := filepath.Ext(strings.ToLower(filePath))
-		if supportedExts[ext] {
-			files = append(files, filePath)
-		}
-
-		return nil
-	})
-
-	return files, err
+	if fingerprint != "" {
+		opts = append(opts, checker.WithFingerprintFile(fingerprint))
+	}
+	return opts
 }
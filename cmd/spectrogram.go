@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"audio-loss-checker/pkg/checker"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	spectrogramDir    string
+	spectrogramWidth  int
+	spectrogramHeight int
+)
+
+var spectrogramCmd = &cobra.Command{
+	Use:   "spectrogram [path]",
+	Short: "为音频文件生成频谱图PNG",
+	Long: `为指定文件或目录中的每个音频文件生成时间-频率频谱图（PNG）。
+
+频谱图使用dB色标，带有频率网格，并以红色实线标出分析得到的最高有效频率、
+黄色虚线标出检测到的截断频率，便于在删除假无损文件之前人工确认。
+CUE表引用的整轨镜像按整个文件生成一张图。`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSpectrogram,
+}
+
+func init() {
+	spectrogramCmd.Flags().StringVarP(&spectrogramDir, "output", "o", "", "频谱图输出目录，默认写到音频文件旁边")
+	spectrogramCmd.Flags().IntVar(&spectrogramWidth, "width", checker.DefaultSpectrogramWidth, "频谱图宽度（时间方向的列数）")
+	spectrogramCmd.Flags().IntVar(&spectrogramHeight, "height", checker.DefaultSpectrogramHeight, "频谱图高度（频率方向的行数）")
+	rootCmd.AddCommand(spectrogramCmd)
+}
+
+func runSpectrogram(cmd *cobra.Command, args []string) error {
+	targetPath := args[0]
+
+	info, err := os.Stat(targetPath)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("路径不存在: %s", targetPath)
+	}
+	// 输出目录中保留文件相对于输入目录的子目录
+	root := targetPath
+	if err == nil && !info.IsDir() {
+		root = filepath.Dir(targetPath)
+	}
+
+	// 共用的分析参数用于在频谱图上标出截断频率
+	audioChecker, err := checker.New(analysisOptions()...)
+	if err != nil {
+		return err
+	}
+
+	files, err := checker.CollectFiles(targetPath)
+	if err != nil {
+		return fmt.Errorf("收集音频文件失败: %w", err)
+	}
+	if len(files) == 0 {
+		fmt.Println("未找到支持的音频文件")
+		return nil
+	}
+
+	// 部分文件失败时只报告失败数，不再打印用法
+	cmd.SilenceUsage = true
+	return audioChecker.RenderSpectrograms(files, checker.SpectrogramOptions{
+		OutputDir: spectrogramDir,
+		Root:      root,
+		Width:     spectrogramWidth,
+		Height:    spectrogramHeight,
+	})
+}
@@ -3,8 +3,6 @@ module audio-loss-checker
 go 1.24.2
 
 require (
-	github.com/go-audio/audio v1.0.0
-	github.com/go-audio/wav v1.1.0
 	github.com/mewkiz/flac v1.0.7
 	github.com/mjibson/go-dsp v0.0.0-20180508042940-11479a337f12
 	github.com/schollz/progressbar/v3 v3.14.1
@@ -12,7 +10,6 @@ require (
 )
 
 require (
-	github.com/go-audio/riff v1.0.0 // indirect
 	github.com/icza/bitio v1.0.0 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/mewkiz/pkg v0.0.0-20190919212034-518ade7978e2 // indirect
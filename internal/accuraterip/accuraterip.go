@@ -0,0 +1,162 @@
+package accuraterip
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DiscID AccurateRip的光盘标识，由各音轨的起始扇区和导出区位置计算
+type DiscID struct {
+	TrackCount int
+	ID1        uint32 // 各音轨起始扇区与导出区之和
+	ID2        uint32 // 各音轨起始扇区（至少为1）乘以音轨序号之和，加上导出区乘以音轨数加1
+	CDDB       uint32 // freedb光盘ID
+}
+
+// ComputeDiscID 按各音轨的起始扇区（相对第一轨所在光盘的LBA）和导出区起始扇区计算光盘标识
+func ComputeDiscID(offsets []int, leadOut int) DiscID {
+	id := DiscID{TrackCount: len(offsets)}
+	digits := 0
+	for i, offset := range offsets {
+		id.ID1 += uint32(offset)
+		id.ID2 += uint32(max(offset, 1)) * uint32(i+1)
+		// freedb的时间包含2秒（150扇区）的引导区
+		for seconds := (offset + 150) / 75; seconds > 0; seconds /= 10 {
+			digits += seconds % 10
+		}
+	}
+	id.ID1 += uint32(leadOut)
+	id.ID2 += uint32(leadOut) * uint32(len(offsets)+1)
+	if len(offsets) > 0 {
+		length := (leadOut+150)/75 - (offsets[0]+150)/75
+		id.CDDB = uint32(digits%0xff)<<24 | uint32(length)<<8 | uint32(len(offsets))
+	}
+	return id
+}
+
+// String 与AccurateRip数据文件名相同的写法，如 "012-0012a5b1-00a1c2d3-9e0b1c0c"
+func (id DiscID) String() string {
+	return fmt.Sprintf("%03d-%08x-%08x-%08x", id.TrackCount, id.ID1, id.ID2, id.CDDB)
+}
+
+// Entry 数据中一张光盘的一条音轨记录，同一张光盘的不同压盘和v1/v2结果各为一组记录
+type Entry struct {
+	Source     string // 所在的数据文件
+	Disc       DiscID
+	Track      int // 音轨序号，从1开始
+	Confidence int // 提交了相同结果的次数
+	CRC        uint32
+}
+
+// Database 从本地文件读取的AccurateRip数据
+type Database struct {
+	Entries []Entry
+	Digest  string // 所有数据文件内容的SHA-256，数据变化时分析结果的缓存失效
+}
+
+// Load 读取本地的AccurateRip数据：单个 .bin 文件（AccurateRip服务器返回的 dBAR-*.bin），
+// 或包含这些文件的目录（递归查找）
+func Load(path string) (*Database, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取AccurateRip数据失败: %w", err)
+	}
+
+	files := []string{path}
+	if info.IsDir() {
+		files = nil
+		err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && strings.EqualFold(filepath.Ext(p), ".bin") {
+				files = append(files, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("读取AccurateRip数据失败: %w", err)
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("%s 中没有AccurateRip数据文件（dBAR-*.bin）", path)
+		}
+		sort.Strings(files)
+	}
+
+	db := &Database{}
+	digest := sha256.New()
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("读取AccurateRip数据失败: %w", err)
+		}
+		entries, err := parse(file, data)
+		if err != nil {
+			return nil, err
+		}
+		db.Entries = append(db.Entries, entries...)
+		digest.Write([]byte(filepath.Base(file)))
+		digest.Write(data)
+	}
+	db.Digest = hex.EncodeToString(digest.Sum(nil))
+	return db, nil
+}
+
+// parse 解析一个数据文件：由若干组记录组成，每组13字节的头部（音轨数、ID1、ID2、freedb ID，小端序）
+// 之后每条音轨9字节（置信度、校验和、第450帧的校验和）
+func parse(path string, data []byte) ([]Entry, error) {
+	var entries []Entry
+	for len(data) > 0 {
+		if len(data) < 13 || data[0] == 0 || len(data) < 13+9*int(data[0]) {
+			return nil, fmt.Errorf("AccurateRip数据文件 %s 格式错误", filepath.Base(path))
+		}
+		disc := DiscID{
+			TrackCount: int(data[0]),
+			ID1:        binary.LittleEndian.Uint32(data[1:5]),
+			ID2:        binary.LittleEndian.Uint32(data[5:9]),
+			CDDB:       binary.LittleEndian.Uint32(data[9:13]),
+		}
+		data = data[13:]
+		for track := 1; track <= disc.TrackCount; track++ {
+			entries = append(entries, Entry{
+				Source:     path,
+				Disc:       disc,
+				Track:      track,
+				Confidence: int(data[0]),
+				CRC:        binary.LittleEndian.Uint32(data[1:5]),
+			})
+			data = data[9:]
+		}
+	}
+	return entries, nil
+}
+
+// Candidates 返回可能与一条音轨对应的记录，以及数据中是否有这张光盘
+// disc 非空且数据中有这张光盘时只返回这张光盘的记录；track、trackCount 为0表示未知，不作限制
+func (db *Database) Candidates(disc *DiscID, track, trackCount int) ([]Entry, bool) {
+	known := false
+	if disc != nil {
+		for _, e := range db.Entries {
+			if e.Disc == *disc {
+				known = true
+				break
+			}
+		}
+	}
+
+	var candidates []Entry
+	for _, e := range db.Entries {
+		if (known && e.Disc != *disc) || (track > 0 && e.Track != track) || (trackCount > 0 && e.Disc.TrackCount != trackCount) {
+			continue
+		}
+		candidates = append(candidates, e)
+	}
+	return candidates, known
+}
@@ -0,0 +1,82 @@
+package accuraterip
+
+// 音轨在光盘中的位置，第一轨和最后一轨计算校验和时要跳过开头或结尾的一部分
+const (
+	PositionFirst  = "first"  // 第一轨
+	PositionMiddle = "middle" // 中间的音轨
+	PositionLast   = "last"   // 最后一轨
+	PositionOnly   = "only"   // 只有一条音轨的光盘，同时是第一轨和最后一轨
+)
+
+// Positions 所有的音轨位置，无法确定位置时按这个顺序逐一尝试
+var Positions = []string{PositionMiddle, PositionFirst, PositionLast, PositionOnly}
+
+const (
+	// SectorFrames CD每个扇区的采样帧数（2352字节，16位双声道）
+	SectorFrames = 588
+	// skipFirst 第一轨跳过开头5个扇区少1帧，容纳不同光驱的读取偏移
+	skipFirst = 5*SectorFrames - 1
+	// skipLast 最后一轨跳过结尾5个扇区
+	skipLast = 5 * SectorFrames
+)
+
+// Checksum 逐帧累加一条音轨的AccurateRip v1/v2校验和
+// 每帧按16位双声道拼成32位整数（左声道在低16位），乘以从1开始的帧位置：
+// v1 为乘积低32位之和，v2 另外加上乘积的高32位
+// 音轨位置可能要到读完后才能确定，因此另外记录开头 skipFirst 帧和最后 skipLast 帧的部分，按位置扣除
+type Checksum struct {
+	frames         int64
+	v1, v2         uint32
+	headV1, headV2 uint32
+	tailV1, tailV2 [skipLast]uint32 // 环形缓冲区，最近 skipLast 帧各自的贡献
+}
+
+// Add 累加一帧
+func (c *Checksum) Add(left, right int16) {
+	c.frames++
+	sample := uint64(uint16(left)) | uint64(uint16(right))<<16
+	product := sample * uint64(uint32(c.frames))
+	lo, hi := uint32(product), uint32(product>>32)
+	c.v1 += lo
+	c.v2 += lo + hi
+	if c.frames <= skipFirst {
+		c.headV1 += lo
+		c.headV2 += lo + hi
+	}
+	i := c.frames % skipLast
+	c.tailV1[i], c.tailV2[i] = lo, lo+hi
+}
+
+// Frames 已累加的帧数
+func (c *Checksum) Frames() int64 {
+	return c.frames
+}
+
+// Sums 返回音轨位于 position 时的v1和v2校验和；音轨短于要跳过的部分时返回false
+func (c *Checksum) Sums(position string) (v1, v2 uint32, ok bool) {
+	first := position == PositionFirst || position == PositionOnly
+	last := position == PositionLast || position == PositionOnly
+	skip := int64(0)
+	if first {
+		skip += skipFirst
+	}
+	if last {
+		skip += skipLast
+	}
+	if c.frames <= skip {
+		return 0, 0, false
+	}
+
+	v1, v2 = c.v1, c.v2
+	if first {
+		v1 -= c.headV1
+		v2 -= c.headV2
+	}
+	if last {
+		for i := range c.tailV1 {
+			v1 -= c.tailV1[i]
+			v2 -= c.tailV2[i]
+		}
+	}
+	return v1, v2, true
+}
@@ -0,0 +1,52 @@
+package accuraterip
+
+import "testing"
+
+// syntheticFrame 测试音轨第 i 帧（从0开始）的采样，覆盖整个16位取值范围
+func syntheticFrame(i int) (left, right int16) {
+	return int16(i*7919+13) ^ -0x8000, int16(i*104729+7) ^ -0x8000
+}
+
+// 期望值由一个独立的 Python 实现逐帧计算：帧位置 mult 从1开始，
+// 第一轨只累加 mult > 5*588-1 的帧，最后一轨只累加 mult <= N-5*588 的帧，
+// v1 累加 (sample*mult) 的低32位，v2 再加上高32位
+func TestChecksum(t *testing.T) {
+	const frames = 10000
+	var c Checksum
+	for i := 0; i < frames; i++ {
+		c.Add(syntheticFrame(i))
+	}
+	if c.Frames() != frames {
+		t.Fatalf("累加了 %d 帧", c.Frames())
+	}
+
+	tests := []struct {
+		position string
+		v1, v2   uint32
+	}{
+		{PositionMiddle, 0xA6136718, 0xA79154A7},
+		{PositionFirst, 0x61907DAE, 0x62ED4EF2},
+		{PositionLast, 0xC0440546, 0xC101C9AE},
+		{PositionOnly, 0x7BC11BDC, 0x7C5DC3F9},
+	}
+	for _, tt := range tests {
+		v1, v2, ok := c.Sums(tt.position)
+		if !ok || v1 != tt.v1 || v2 != tt.v2 {
+			t.Errorf("%s: v1=%08X v2=%08X ok=%v，应为 %08X %08X", tt.position, v1, v2, ok, tt.v1, tt.v2)
+		}
+	}
+}
+
+// 音轨不长于要跳过的部分时无法计算
+func TestChecksumShortTrack(t *testing.T) {
+	var c Checksum
+	for i := 0; i < skipFirst+skipLast; i++ {
+		c.Add(syntheticFrame(i))
+	}
+	for _, position := range Positions {
+		_, _, ok := c.Sums(position)
+		if want := position != PositionOnly; ok != want {
+			t.Errorf("%s: ok=%v，应为 %v", position, ok, want)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package analyzer
+
+import (
+	"fmt"
+
+	"audio-loss-checker/internal/accuraterip"
+	"audio-loss-checker/internal/cue"
+	"audio-loss-checker/internal/riplog"
+	"audio-loss-checker/internal/types"
+)
+
+// accurateRipSink 累加CD规格音轨的AccurateRip校验和
+type accurateRipSink struct {
+	sum accuraterip.Checksum
+}
+
+// newAccurateRipSink 音频为CD规格且完整解码时返回校验和计算步骤，否则返回nil
+func newAccurateRipSink(audioFile types.AudioFile) *accurateRipSink {
+	if !isCDAudio(audioFile) || partialReason(audioFile) != "" {
+		return nil
+	}
+	return &accurateRipSink{}
+}
+
+func (s *accurateRipSink) consume(block [][]float64) {
+	for i := range block[0] {
+		s.sum.Add(cdSample(block[0][i]), cdSample(block[1][i]))
+	}
+}
+
+// albumPosition 音轨在光盘中的位置，决定AccurateRip校验和跳过的范围
+type albumPosition struct {
+	track, count int                 // 音轨号和光盘的音轨数，未知时为0
+	position     string              // accuraterip.PositionFirst 等，无法确定时为空
+	disc         *accuraterip.DiscID // 光盘标识，无法计算时为nil
+}
+
+// filePosition 单独的音轨文件：音轨号取自抓轨日志或标签，音轨数取自日志中的光盘目录或标签
+func filePosition(ripLog *ripLogMatch, metadata types.AudioMetadata) albumPosition {
+	pos := albumPosition{track: metadata.TrackNumber, count: metadata.TrackTotal}
+	if ripLog != nil {
+		if ripLog.entry != nil && !ripLog.isRange() {
+			pos.track = ripLog.entry.Number
+		}
+		if pos.disc = tocDiscID(ripLog.log); pos.disc != nil {
+			pos.count = pos.disc.TrackCount
+		}
+	}
+	pos.position = trackPosition(pos.track, pos.count)
+	return pos
+}
+
+// cuePosition 整轨镜像中的音轨：按CUE中全部音轨的顺序确定第一轨和最后一轨
+func cuePosition(sheet *cue.Sheet, number int, disc *accuraterip.DiscID) albumPosition {
+	var numbers []int
+	for _, file := range sheet.Files {
+		for _, track := range file.Tracks {
+			numbers = append(numbers, track.Number)
+		}
+	}
+	pos := albumPosition{track: number, count: len(numbers), disc: disc}
+	switch {
+	case len(numbers) == 1:
+		pos.position = accuraterip.PositionOnly
+	case number == numbers[0]:
+		pos.position = accuraterip.PositionFirst
+	case number == numbers[len(numbers)-1]:
+		pos.position = accuraterip.PositionLast
+	default:
+		pos.position = accuraterip.PositionMiddle
+	}
+	return pos
+}
+
+// cueDiscID 整轨镜像的光盘标识：抓轨日志中有光盘目录时以日志为准，
+// 否则CUE只引用这一个镜像时按各音轨的 INDEX 01 和镜像长度计算；无法计算时返回nil
+func cueDiscID(sheet *cue.Sheet, file cue.File, ripLog *ripLogMatch, totalFrames int64) *accuraterip.DiscID {
+	if ripLog != nil {
+		if disc := tocDiscID(ripLog.log); disc != nil {
+			return disc
+		}
+	}
+	if len(sheet.Files) != 1 || len(file.Tracks) == 0 {
+		return nil
+	}
+	offsets := make([]int, len(file.Tracks))
+	for i, track := range file.Tracks {
+		offsets[i] = track.Index01
+	}
+	disc := accuraterip.ComputeDiscID(offsets, int(totalFrames/accuraterip.SectorFrames))
+	return &disc
+}
+
+// tocDiscID 按抓轨日志中的光盘目录计算光盘标识，日志中没有目录时返回nil
+func tocDiscID(log *riplog.Log) *accuraterip.DiscID {
+	if len(log.TOC) == 0 {
+		return nil
+	}
+	offsets := make([]int, len(log.TOC))
+	for i, entry := range log.TOC {
+		offsets[i] = entry.Start
+	}
+	disc := accuraterip.ComputeDiscID(offsets, log.TOC[len(log.TOC)-1].End+1)
+	return &disc
+}
+
+// trackPosition 按音轨号和音轨数确定位置，任一未知或不合理时返回空字符串
+func trackPosition(track, count int) string {
+	switch {
+	case track <= 0 || count <= 0 || track > count:
+		return ""
+	case count == 1:
+		return accuraterip.PositionOnly
+	case track == 1:
+		return accuraterip.PositionFirst
+	case track == count:
+		return accuraterip.PositionLast
+	}
+	return accuraterip.PositionMiddle
+}
+
+// checkAccurateRip 计算音轨的AccurateRip校验和，提供了本地数据时与其核对
+// 位置未知时依次按各种位置计算并核对，吻合时以吻合的位置为准；音轨太短时返回nil
+func (a *Analyzer) checkAccurateRip(sink *accurateRipSink, pos albumPosition) *types.AccurateRip {
+	positions := []string{pos.position}
+	if pos.position == "" {
+		positions = accuraterip.Positions
+	}
+	v1, v2, ok := sink.sum.Sums(positions[0])
+	if !ok {
+		return nil
+	}
+	info := &types.AccurateRip{
+		Track:      pos.track,
+		TrackCount: pos.count,
+		Position:   pos.position,
+		V1:         fmt.Sprintf("%08X", v1),
+		V2:         fmt.Sprintf("%08X", v2),
+	}
+	if pos.position == "" {
+		info.Position = "unknown"
+	}
+	if pos.disc != nil {
+		info.DiscID = pos.disc.String()
+	}
+
+	db := a.config.AccurateRip
+	if db == nil {
+		return info
+	}
+	// 静音音轨的校验和为0，任何来源都一样，不能说明音频与CD一致
+	if v1 == 0 && v2 == 0 {
+		info.Details = "音轨为全静音，AccurateRip校验和为0，无法据此核对"
+		return info
+	}
+
+	candidates, discKnown := db.Candidates(pos.disc, pos.track, pos.count)
+	var best *accuraterip.Entry
+	for _, position := range positions {
+		v1, v2, ok := sink.sum.Sums(position)
+		if !ok {
+			continue
+		}
+		for i := range candidates {
+			e := &candidates[i]
+			version := 0
+			switch e.CRC {
+			case v2:
+				version = 2
+			case v1:
+				version = 1
+			}
+			if version == 0 || (best != nil && e.Confidence <= best.Confidence) {
+				continue
+			}
+			best = e
+			info.Match, info.Version, info.Confidence, info.Source = "match", version, e.Confidence, e.Source
+			info.Position, info.V1, info.V2 = position, fmt.Sprintf("%08X", v1), fmt.Sprintf("%08X", v2)
+		}
+	}
+
+	switch {
+	case best != nil:
+		info.Track, info.TrackCount = best.Track, best.Disc.TrackCount
+		info.Details = fmt.Sprintf("AccurateRip v%d 校验和 %08X 与光盘 %s 第 %d 轨的记录一致（置信度 %d），音频与原版CD逐位相同",
+			info.Version, best.CRC, best.Disc, best.Track, best.Confidence)
+	case discKnown && pos.track > 0 && len(candidates) > 0:
+		info.Match = "mismatch"
+		info.Details = fmt.Sprintf("AccurateRip数据中有光盘 %s 第 %d 轨的 %d 条记录，但校验和都不一致：可能是其他压盘、抓取时的读取偏移不同，或音频被修改过",
+			pos.disc, pos.track, len(candidates))
+	default:
+		info.Match = "notFound"
+		info.Details = "AccurateRip数据中没有吻合的记录，可能数据中没有这张光盘"
+	}
+	return info
+}
@@ -0,0 +1,242 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"audio-loss-checker/internal/types"
+)
+
+const (
+	// albumConsistencyHz 音轨的最高有效频率与专辑中位数相差不超过此值视为一致
+	albumConsistencyHz = 500.0
+	// albumConsistent 一致且截断陡峭的音轨比例达到此值，且中位数低于完整频谱时，视为整张专辑有共同的截断：
+	// 有损编码的低通对每条音轨都一样，真实录音的高频随曲目内容变化，即使频率相近也是平缓衰减
+	albumConsistent = 0.8
+	// albumFakeShare 判定为假无损的音轨比例达到此值时整张专辑为 FAKE；有共同截断时，假无损和疑似合计达到此值即可
+	albumFakeShare = 0.5
+	// albumSuspectShare 判定为假无损或疑似的音轨比例达到此值（且至少2条）时整张专辑为 SUSPECT
+	albumSuspectShare = 0.25
+)
+
+// albumGroup 同一张专辑的分析结果
+type albumGroup struct {
+	album   *types.AlbumResult
+	results []*types.AnalysisResult
+}
+
+// aggregateAlbums 把分析结果按专辑分组并给出专辑的判定，只汇总至少有2条音轨的专辑
+// mode 为 "auto" 时CUE分轨的结果按CUE表分组，其余有专辑标签的按专辑+艺术家分组（多碟专辑分在不同目录也能合并），
+// 没有标签的按目录分组；为 "directory" 时一律按目录分组，为 "none" 时不汇总
+func aggregateAlbums(results []*types.AnalysisResult, mode string) []*types.AlbumResult {
+	if mode == "none" {
+		return nil
+	}
+
+	sorted := append([]*types.AnalysisResult(nil), results...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].FilePath != sorted[j].FilePath {
+			return sorted[i].FilePath < sorted[j].FilePath
+		}
+		return trackNumber(sorted[i]) < trackNumber(sorted[j])
+	})
+
+	groups := make(map[string]*albumGroup)
+	var keys []string
+	for _, result := range sorted {
+		if result.Status == "ERROR" {
+			continue
+		}
+		key, album := albumKey(result, mode)
+		group, ok := groups[key]
+		if !ok {
+			group = &albumGroup{album: album}
+			groups[key] = group
+			keys = append(keys, key)
+		}
+		group.results = append(group.results, result)
+	}
+
+	var albums []*types.AlbumResult
+	for _, key := range keys {
+		group := groups[key]
+		if len(group.results) < 2 {
+			continue
+		}
+		group.judge()
+		albums = append(albums, group.album)
+	}
+	return albums
+}
+
+// albumKey 返回结果所属专辑的分组键，以及填好专辑信息的汇总结果
+func albumKey(result *types.AnalysisResult, mode string) (string, *types.AlbumResult) {
+	dir := filepath.Dir(result.FilePath)
+	album := &types.AlbumResult{
+		Album:     result.Metadata.Album,
+		Artist:    result.Metadata.Artist,
+		Directory: dir,
+		GroupBy:   "directory",
+	}
+	if mode != "directory" {
+		switch {
+		case result.Track != nil:
+			album.GroupBy, album.CueSheet = "cue", result.Track.CueSheet
+			return "cue\x00" + result.Track.CueSheet, album
+		case result.Metadata.Album != "":
+			album.GroupBy = "tags"
+			return "tags\x00" + strings.ToLower(result.Metadata.Album) + "\x00" + strings.ToLower(result.Metadata.Artist), album
+		}
+	}
+	return "dir\x00" + dir, album
+}
+
+// judge 统计各音轨的判定和最高有效频率，给出专辑的判定
+// 有损编码的低通对整张专辑的每条音轨都一样，而真实录音的高频随曲目内容变化：
+// 多数音轨在同一频率附近陡峭截断时，个别勉强通过单独判定的音轨也一并视为可疑
+func (g *albumGroup) judge() {
+	album := g.album
+	album.Tracks = len(g.results)
+
+	var frequencies []float64
+	var steep []bool
+	nyquist := 0.0
+	for _, result := range g.results {
+		switch {
+		case result.Analysis.Confidence >= fakeConfidence:
+			album.Fake++
+		case result.Analysis.Confidence >= suspectConfidence:
+			album.Suspect++
+		}
+		// DSD的高频是噪声整形的结果，不参与截断统计
+		if result.Analysis.SampleRate > 0 && result.Analysis.DSDRate == 0 && result.Analysis.MaxFrequency > 0 {
+			frequencies = append(frequencies, result.Analysis.MaxFrequency)
+			steep = append(steep, steepCutoff(result))
+			if rate := float64(result.Analysis.SampleRate) / 2; nyquist == 0 || rate < nyquist {
+				nyquist = rate
+			}
+		}
+	}
+	flagged := album.Fake + album.Suspect
+	album.FlaggedShare = roundShare(float64(flagged) / float64(album.Tracks))
+	fakeShare := float64(album.Fake) / float64(album.Tracks)
+
+	shared := false
+	if len(frequencies) > 0 {
+		album.MedianCutoff = median(frequencies)
+		deviations := make([]float64, len(frequencies))
+		consistent, consistentSteep := 0, 0
+		for i, f := range frequencies {
+			deviations[i] = math.Abs(f - album.MedianCutoff)
+			if deviations[i] <= albumConsistencyHz {
+				consistent++
+				if steep[i] {
+					consistentSteep++
+				}
+			}
+		}
+		album.CutoffSpread = median(deviations)
+		album.Consistency = roundShare(float64(consistent) / float64(len(frequencies)))
+		shared = len(frequencies) >= 2 && float64(consistentSteep)/float64(len(frequencies)) >= albumConsistent &&
+			album.MedianCutoff < fullBandRatio*nyquist
+	}
+
+	details := []string{fmt.Sprintf("%d 条音轨中 %d 条判定为假无损、%d 条疑似", album.Tracks, album.Fake, album.Suspect)}
+	if len(frequencies) > 0 {
+		details = append(details, fmt.Sprintf("最高有效频率中位数 %.0f Hz，%.0f%% 的音轨与其相差不超过 %.0f Hz",
+			album.MedianCutoff, album.Consistency*100, albumConsistencyHz))
+	}
+	switch {
+	case shared && flagged > 0 && float64(flagged)/float64(album.Tracks) >= albumFakeShare:
+		album.Status = "FAKE"
+		details = append(details, "各音轨在同一频率附近陡峭截断，整张专辑像是来自同一有损来源")
+	case fakeShare >= albumFakeShare:
+		album.Status = "FAKE"
+		details = append(details, "多数音轨判定为假无损，整张专辑可能来自有损来源")
+	case shared:
+		album.Status = "SUSPECT"
+		details = append(details, "各音轨在同一频率附近陡峭截断，通过单独判定的音轨也可能来自同一有损来源，建议整张专辑人工确认")
+	case flagged >= 2 && float64(flagged)/float64(album.Tracks) >= albumSuspectShare:
+		album.Status = "SUSPECT"
+		details = append(details, "相当一部分音轨可疑，建议整张专辑人工确认")
+	default:
+		album.Status = "OK"
+		details = append(details, "整张专辑看起来是真实的无损音频")
+	}
+	album.Details = strings.Join(details, "；")
+}
+
+// steepCutoff 音轨的截断是否陡峭，符合编码器低通滤波的特征
+func steepCutoff(result *types.AnalysisResult) bool {
+	for _, e := range result.Analysis.Evidence {
+		if e.Detector == "shelf" && e.Weight > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// median 返回中位数，values 会被排序
+func median(values []float64) float64 {
+	sort.Float64s(values)
+	n := len(values)
+	if n%2 == 1 {
+		return values[n/2]
+	}
+	return (values[n/2-1] + values[n/2]) / 2
+}
+
+// roundShare 比例保留3位小数
+func roundShare(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
+
+// albumName 专辑的显示名称：专辑标签，没有时为CUE文件名或目录名
+func albumName(album *types.AlbumResult) string {
+	switch {
+	case album.Album != "" && album.Artist != "":
+		return album.Artist + " - " + album.Album
+	case album.Album != "":
+		return album.Album
+	case album.CueSheet != "":
+		return filepath.Base(album.CueSheet)
+	}
+	return filepath.Base(album.Directory)
+}
+
+// printAlbums 打印专辑汇总：判定为 SUSPECT 或 FAKE 的专辑逐一列出，正常的只计数
+func printAlbums(albums []*types.AlbumResult) {
+	if len(albums) == 0 {
+		return
+	}
+	var suspect, fake int
+	for _, album := range albums {
+		switch album.Status {
+		case "SUSPECT":
+			suspect++
+		case "FAKE":
+			fake++
+		}
+	}
+
+	fmt.Printf("\n=== 专辑统计 ===\n")
+	fmt.Printf("专辑数: %d（正常 %d，疑似 %d，假无损 %d）\n", len(albums), len(albums)-suspect-fake, suspect, fake)
+	for _, album := range albums {
+		if album.Status == "OK" {
+			continue
+		}
+		fmt.Printf("\n[%s] %s\n", album.Status, albumName(album))
+		if album.CueSheet != "" {
+			fmt.Printf("  CUE: %s\n", album.CueSheet)
+		} else {
+			fmt.Printf("  目录: %s\n", album.Directory)
+		}
+		fmt.Printf("  %s\n", album.Details)
+	}
+	if fake > 0 || suspect > 0 {
+		fmt.Printf("\n⚠️  %d 张专辑整体判定为假无损、%d 张疑似，其中单独通过判定的音轨也建议一并检查\n", fake, suspect)
+	}
+}
@@ -0,0 +1,163 @@
+package analyzer
+
+import (
+	"path/filepath"
+	"testing"
+
+	"audio-loss-checker/internal/types"
+)
+
+// albumTrack 生成一条音轨的分析结果：steep 为true时带有陡峭截断的证据
+func albumTrack(confidence, maxFreq float64, steep bool) *types.AnalysisResult {
+	result := &types.AnalysisResult{Status: "OK"}
+	result.Analysis.Confidence = confidence
+	result.Analysis.SampleRate = 44100
+	result.Analysis.MaxFrequency = maxFreq
+	if steep {
+		result.Analysis.Evidence = []types.Evidence{{Detector: "shelf", Weight: weightSteepShelf}}
+	} else {
+		result.Analysis.Evidence = []types.Evidence{{Detector: "shelf", Weight: weightGentleShelf}}
+	}
+	return result
+}
+
+// taggedTrack 生成位于 path、带专辑标签的音轨结果
+func taggedTrack(path, album, artist string) *types.AnalysisResult {
+	result := albumTrack(0.1, 21000, false)
+	result.FilePath = filepath.FromSlash(path)
+	result.Metadata.Album, result.Metadata.Artist = album, artist
+	return result
+}
+
+func TestAggregateAlbums(t *testing.T) {
+	var results []*types.AnalysisResult
+	// CUE分轨：即使有专辑标签也按CUE表分组
+	for n := 1; n <= 3; n++ {
+		r := taggedTrack("/music/A/image.flac", "Live", "Band")
+		r.Track = &types.TrackInfo{CueSheet: filepath.FromSlash("/music/A/image.cue"), Number: n}
+		results = append(results, r)
+	}
+	// 分在两个目录中的双碟专辑，标签大小写不同
+	results = append(results,
+		taggedTrack("/music/B/CD2/01.flac", "double album", "the artist"),
+		taggedTrack("/music/B/CD1/01.flac", "Double Album", "The Artist"),
+		taggedTrack("/music/B/CD1/02.flac", "Double Album", "The Artist"),
+		// 同名专辑、不同艺术家，只有一条音轨，不汇总
+		taggedTrack("/music/B/CD2/02.flac", "Double Album", "Someone Else"),
+		// 没有标签，按目录分组
+		taggedTrack("/music/C/01.wav", "", ""),
+		taggedTrack("/music/C/02.wav", "", ""),
+		// 单独一条音轨的目录不汇总
+		taggedTrack("/music/D/single.flac", "", ""),
+	)
+	// 出错的文件不参与汇总
+	broken := taggedTrack("/music/C/03.wav", "", "")
+	broken.Status = "ERROR"
+	results = append(results, broken)
+
+	type want struct {
+		groupBy string
+		tracks  int
+		key     string // CUE文件或目录
+	}
+	tests := []struct {
+		mode string
+		want []want
+	}{
+		{"auto", []want{
+			{"cue", 3, "/music/A/image.cue"},
+			{"tags", 3, "/music/B/CD1"},
+			{"directory", 2, "/music/C"},
+		}},
+		{"directory", []want{
+			{"directory", 3, "/music/A"},
+			{"directory", 2, "/music/B/CD1"},
+			{"directory", 2, "/music/B/CD2"},
+			{"directory", 2, "/music/C"},
+		}},
+		{"none", nil},
+	}
+
+	for _, tt := range tests {
+		albums := aggregateAlbums(results, tt.mode)
+		if len(albums) != len(tt.want) {
+			t.Errorf("%s: 得到 %d 张专辑", tt.mode, len(albums))
+			continue
+		}
+		for i, w := range tt.want {
+			album := albums[i]
+			key := album.Directory
+			if album.GroupBy == "cue" {
+				key = album.CueSheet
+			}
+			if album.GroupBy != w.groupBy || album.Tracks != w.tracks || key != filepath.FromSlash(w.key) || album.Status != "OK" {
+				t.Errorf("%s: 专辑 %d 为 %s %d 条音轨（%s，%s），应为 %s %d 条音轨（%s）", tt.mode, i+1,
+					album.GroupBy, album.Tracks, key, album.Status, w.groupBy, w.tracks, w.key)
+			}
+		}
+	}
+}
+
+func TestAlbumJudge(t *testing.T) {
+	repeat := func(n int, confidence, maxFreq float64, steep bool) []*types.AnalysisResult {
+		var out []*types.AnalysisResult
+		for range n {
+			out = append(out, albumTrack(confidence, maxFreq, steep))
+		}
+		return out
+	}
+	concat := func(groups ...[]*types.AnalysisResult) []*types.AnalysisResult {
+		var out []*types.AnalysisResult
+		for _, g := range groups {
+			out = append(out, g...)
+		}
+		return out
+	}
+	dsd := func(n int) []*types.AnalysisResult {
+		out := repeat(n, 0.1, 40000, true)
+		for _, r := range out {
+			r.Analysis.SampleRate, r.Analysis.DSDRate = 88200, 2822400
+		}
+		return out
+	}
+	varied := func(confidences ...float64) []*types.AnalysisResult {
+		var out []*types.AnalysisResult
+		for i, c := range confidences {
+			out = append(out, albumTrack(c, 15000+float64(i)*1500, false))
+		}
+		return out
+	}
+
+	tests := []struct {
+		name   string
+		tracks []*types.AnalysisResult
+		status string
+		median float64
+	}{
+		// 每条音轨单独都通过了判定，但都在16kHz陡峭截断
+		{"共同的陡峭截断", repeat(5, 0.3, 16000, true), "SUSPECT", 16000},
+		// 有共同截断时，假无损和疑似合计达到一半即为 FAKE
+		{"共同截断且半数可疑", concat(repeat(2, 0.6, 16000, true), repeat(2, 0.3, 16100, true)), "FAKE", 16050},
+		// 截断频率各不相同，但多数音轨单独判定为假无损
+		{"多数假无损", varied(0.9, 0.9, 0.9, 0.1), "FAKE", 17250},
+		// 频率一致但平缓衰减，像录音本身的特点
+		{"一致的平缓衰减", repeat(5, 0.3, 16000, false), "OK", 16000},
+		// 完整频谱不算共同截断
+		{"完整频谱", repeat(5, 0.1, 21500, true), "OK", 21500},
+		{"四分之一可疑", concat(repeat(2, 0.6, 15000, false), varied(0.1, 0.1, 0.1, 0.1, 0.1, 0.1)), "SUSPECT", 17250},
+		{"只有一条可疑", concat(repeat(1, 0.9, 15000, false), varied(0.1, 0.1, 0.1)), "OK", 15750},
+		// DSD不参与截断统计
+		{"DSD", dsd(4), "OK", 0},
+		{"PCM与DSD混合", concat(repeat(2, 0.3, 16000, true), dsd(2)), "SUSPECT", 16000},
+	}
+
+	for _, tt := range tests {
+		group := &albumGroup{album: &types.AlbumResult{}, results: tt.tracks}
+		group.judge()
+		album := group.album
+		if album.Status != tt.status || album.MedianCutoff != tt.median || album.Tracks != len(tt.tracks) {
+			t.Errorf("%s: %s，中位数 %.0f Hz（%s），应为 %s、%.0f Hz", tt.name, album.Status, album.MedianCutoff,
+				album.Details, tt.status, tt.median)
+		}
+	}
+}
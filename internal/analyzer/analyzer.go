@@ -1,48 +1,71 @@
 package analyzer
 
 import (
-	"encoding/json"
 	"fmt"
-	"os"
+	"io"
 	"path/filepath"
+	"strings"
 	"sync"
 
 	"audio-loss-checker/internal/decoder"
 	"audio-loss-checker/internal/types"
-
-	"github.com/schollz/progressbar/v3"
 )
 
 // Analyzer 音频分析器
 type Analyzer struct {
 	config          *types.AnalyzerConfig
 	decoderRegistry *decoder.DecoderRegistry
+	cacheWarning    sync.Once // 写入缓存失败只提示一次
 }
 
-// NewAnalyzer 创建新的分析器
-func NewAnalyzer(config *types.AnalyzerConfig) *Analyzer {
+// NewAnalyzer 创建新的分析器，配置无效时返回错误
+func NewAnalyzer(config *types.AnalyzerConfig) (*Analyzer, error) {
+	if err := validateConfig(config); err != nil {
+		return nil, err
+	}
 	return &Analyzer{
 		config:          config,
 		decoderRegistry: decoder.NewDecoderRegistry(),
+	}, nil
+}
+
+// validateConfig 检查分析参数的取值范围
+func validateConfig(config *types.AnalyzerConfig) error {
+	switch {
+	case config.Concurrency < 1:
+		return fmt.Errorf("并发数至少为1: %d", config.Concurrency)
+	case config.CutoffFreq < 0:
+		return fmt.Errorf("频率截断阈值不能为负数: %g", config.CutoffFreq)
+	case config.Windows < 0:
+		return fmt.Errorf("窗口数不能为负数: %d", config.Windows)
+	case config.Overlap < 0 || config.Overlap >= 1:
+		return fmt.Errorf("重叠比例必须在0到1之间（不含1）: %g", config.Overlap)
+	case config.Segments < 0 || (config.Segments > 0 && config.Segments < MinSegmentSeconds):
+		return fmt.Errorf("分段时长不能小于 %g 秒: %g", MinSegmentSeconds, config.Segments)
 	}
+	switch config.AlbumBy {
+	case "", "auto", "directory", "none":
+	default:
+		return fmt.Errorf("不支持的专辑分组方式: %s（可选 auto、directory、none）", config.AlbumBy)
+	}
+	return nil
 }
 
-// AnalyzeFiles 分析多个音频文件
-func (a *Analyzer) AnalyzeFiles(filePaths []string) error {
-	// 创建进度条
-	var bar *progressbar.ProgressBar
-	if !a.config.Quiet && !a.config.JSONOutput {
-		bar = progressbar.NewOptions(len(filePaths),
-			progressbar.OptionSetDescription("分析音频文件"),
-			progressbar.OptionShowCount(),
-			progressbar.OptionSetWidth(50),
-			progressbar.OptionShowIts(),
-		)
+// Analyze 并发分析多个文件（CUE表按音轨分析），并按专辑汇总
+// 每个文件分析完成后以其结果调用 done（可为nil），done 在调用 Analyze 的goroutine中依次调用
+func (a *Analyzer) Analyze(filePaths []string, done func(path string, results []*types.AnalysisResult)) *types.AnalysisReport {
+	resultCache := a.openCache()
+	report := &types.AnalysisReport{Paths: len(filePaths), Cached: resultCache != nil}
+
+	type pathResults struct {
+		path    string
+		results []*types.AnalysisResult
+		hit     bool
 	}
 
 	// 创建工作通道
 	jobs := make(chan string, len(filePaths))
-	results := make(chan *types.AnalysisResult, len(filePaths))
+	finished := make(chan pathResults, len(filePaths))
 
 	// 启动工作协程
 	var wg sync.WaitGroup
@@ -51,11 +74,13 @@ func (a *Analyzer) AnalyzeFiles(filePaths []string) error {
 		go func() {
 			defer wg.Done()
 			for filePath := range jobs {
-				result := a.analyzeFile(filePath)
-				results <- result
-				if bar != nil {
-					bar.Add(1)
+				item := pathResults{path: filePath}
+				if resultCache != nil {
+					item.results, item.hit = a.analyzeCached(resultCache, filePath)
+				} else {
+					item.results = a.analyzePath(filePath)
 				}
+				finished <- item
 			}
 		}()
 	}
@@ -71,27 +96,57 @@ func (a *Analyzer) AnalyzeFiles(filePaths []string) error {
 	// 等待所有任务完成
 	go func() {
 		wg.Wait()
-		close(results)
+		close(finished)
 	}()
 
-	// 收集并输出结果
-	var allResults []*types.AnalysisResult
-	for result := range results {
-		allResults = append(allResults, result)
-		a.outputResult(result)
+	// 收集结果
+	for item := range finished {
+		report.Results = append(report.Results, item.results...)
+		if item.hit {
+			report.CacheHits++
+		}
+		if done != nil {
+			done(item.path, item.results)
+		}
 	}
 
-	if bar != nil {
-		bar.Finish()
-		fmt.Println() // 换行
+	report.Albums = aggregateAlbums(report.Results, a.config.AlbumBy)
+	return report
+}
+
+// AnalyzePath 分析单个文件，CUE表按音轨输出多个结果；配置了缓存时使用并更新缓存
+func (a *Analyzer) AnalyzePath(path string) []*types.AnalysisResult {
+	if resultCache := a.openCache(); resultCache != nil {
+		results, _ := a.analyzeCached(resultCache, path)
+		return results
 	}
+	return a.analyzePath(path)
+}
 
-	// 输出统计信息
-	if !a.config.Quiet && !a.config.JSONOutput {
-		a.printSummary(allResults)
+// AnalyzeReader 分析调用方提供的音频数据，name 为文件名，用于识别扩展名和结果中的路径
+// 数据不在文件系统中，不查找抓轨日志和WavPack校正文件，也不使用缓存；分析期间调用方不能读取或定位 r
+func (a *Analyzer) AnalyzeReader(r io.ReadSeeker, name string) *types.AnalysisResult {
+	result := &types.AnalysisResult{
+		FilePath: name,
+		Status:   "ERROR",
 	}
 
-	return nil
+	audioFile := a.openAudioFile(result, r)
+	if audioFile == nil {
+		return result
+	}
+	defer audioFile.Close()
+
+	a.analyzeAudio(result, audioFile, nil)
+	return result
+}
+
+// analyzePath 分析任务路径：CUE表按音轨输出多个结果，其余文件输出一个结果
+func (a *Analyzer) analyzePath(path string) []*types.AnalysisResult {
+	if strings.EqualFold(filepath.Ext(path), ".cue") {
+		return a.analyzeCueSheet(path)
+	}
+	return []*types.AnalysisResult{a.analyzeFile(path)}
 }
 
 // analyzeFile 分析单个音频文件
@@ -101,169 +156,351 @@ func (a *Analyzer) analyzeFile(filePath string) *types.AnalysisResult {
 		Status:   "ERROR",
 	}
 
-	// 解码音频文件
-	audioFile, err := a.decoderRegistry.DecodeFile(filePath)
-	if err != nil {
-		result.Error = fmt.Sprintf("解码失败: %v", err)
+	audioFile := a.openAudioFile(result, nil)
+	if audioFile == nil {
 		return result
 	}
 	defer audioFile.Close()
 
-	// 填充基本信息
-	result.Format = audioFile.GetFormat()
-	result.Metadata = audioFile.GetMetadata()
+	a.analyzeAudio(result, audioFile, findRipLog(filePath))
+	return result
+}
 
-	// 获取音频采样数据
-	samples, err := audioFile.GetSamples()
+// analyzeAudio 流式读取已解码的音频完成分析，ripLog 为对应的抓轨日志，没有时为nil
+func (a *Analyzer) analyzeAudio(result *types.AnalysisResult, audioFile types.AudioFile, ripLog *ripLogMatch) {
+	// 流式读取音频采样，内存占用与文件长度无关
+	reader, err := audioFile.NewSampleReader()
 	if err != nil {
 		result.Error = fmt.Sprintf("读取音频数据失败: %v", err)
-		return result
+		return
+	}
+	stream := a.newStreamAnalysis(audioFile, expectedFrames(audioFile))
+	stream.bitDepth = observeBitDepth(reader, audioFile)
+	checker := checkIntegrity(reader, a.config.Verify)
+	stream.crc = ripLog.newCRCSink(audioFile)
+	// 整张光盘抓成一个文件而没有CUE时无法分出音轨，不计算AccurateRip校验和
+	if !ripLog.isRange() {
+		stream.accurateRip = newAccurateRipSink(audioFile)
+	}
+	if _, err := runStream(reader, audioFile.GetChannels(), stream.sinks()); err != nil {
+		result.Error = fmt.Sprintf("读取音频数据失败: %v", err)
+		return
 	}
+	stream.album = filePosition(ripLog, audioFile.GetMetadata())
 
-	// 创建频谱分析器
-	spectrumAnalyzer := NewSpectrumAnalyzer(audioFile.GetSampleRate())
+	a.finishAnalysis(result, audioFile, stream)
+	if checker != nil {
+		applyIntegrity(result, checker.Integrity(), a.config.Verify)
+	}
+	if ripLog != nil {
+		result.RipLog = ripLog.report(0, audioFile, stream.crc)
+	}
+	a.attachSpectrogram(result, stream)
+}
 
-	// 进行频谱分析
-	spectrumResult, err := spectrumAnalyzer.AnalyzeSpectrum(samples)
+// openAudioFile 识别格式并解码，填充格式和元数据：r 为nil时读取文件 result.FilePath，否则读取 r（result.FilePath 为其名称）
+// 出错或无需解码即可得出结论（内容为有损编码）时返回nil，此时 result 已是最终结果
+func (a *Analyzer) openAudioFile(result *types.AnalysisResult, r io.ReadSeeker) types.AudioFile {
+	var src *decoder.Source
+	var err error
+	if r != nil {
+		src, err = decoder.NewSource(r, result.FilePath)
+	} else {
+		src, err = decoder.OpenSource(result.FilePath)
+	}
 	if err != nil {
-		result.Error = fmt.Sprintf("频谱分析失败: %v", err)
-		return result
+		result.Error = fmt.Sprintf("识别格式失败: %v", err)
+		return nil
 	}
 
-	// 填充分析结果
-	result.Analysis = types.AnalysisDetails{
-		IsFake:       spectrumResult.IsFake,
-		CutoffHz:     spectrumResult.CutoffFrequency,
-		Details:      spectrumResult.Details,
-		SampleRate:   audioFile.GetSampleRate(),
-		BitDepth:     audioFile.GetBitDepth(),
-		Channels:     audioFile.GetChannels(),
-		Duration:     audioFile.GetDuration().Seconds(),
-		MaxFrequency: spectrumResult.MaxFrequency,
-	}
-
-	// 根据自定义截断频率判断
-	if spectrumResult.MaxFrequency < a.config.CutoffFreq {
-		result.Analysis.IsFake = true
-		if !spectrumResult.IsFake {
-			result.Analysis.Details = fmt.Sprintf("最高频率 %.0f Hz 低于设定阈值 %.0f Hz",
-				spectrumResult.MaxFrequency, a.config.CutoffFreq)
+	// 按文件内容识别格式，解码时沿用识别结果
+	detection, err := a.decoderRegistry.DetectSource(src)
+	if err != nil {
+		src.Close()
+		result.Error = fmt.Sprintf("识别格式失败: %v", err)
+		return nil
+	}
+	if detection.Mismatch {
+		result.FormatMismatch = &types.FormatMismatch{
+			Extension: detection.Extension,
+			Content:   detection.Name,
 		}
 	}
 
-	// 设置状态
-	if result.Analysis.IsFake {
-		result.Status = "FAKE"
-	} else {
-		result.Status = "OK"
+	// 内容本身就是有损编码（如改了扩展名的MP3、装着AAC的M4A），无需频谱分析
+	if detection.Lossy {
+		src.Close()
+		result.Format = detection.Name
+		conclude(result, []types.Evidence{
+			containerEvidence(fmt.Sprintf("文件内容为%s有损编码，扩展名为 .%s", detection.Name, detection.Extension)),
+		}, "")
+		return nil
 	}
 
-	return result
+	// 解码音频文件
+	audioFile, err := a.decoderRegistry.DecodeSource(src, detection)
+	if err != nil {
+		result.Error = fmt.Sprintf("解码失败: %v", err)
+		return nil
+	}
+
+	// 填充基本信息
+	result.Format = audioFile.GetFormat()
+	result.Metadata = audioFile.GetMetadata()
+
+	return audioFile
 }
 
-// outputResult 输出单个分析结果
-func (a *Analyzer) outputResult(result *types.AnalysisResult) {
-	// 如果只显示假无损文件，跳过正常文件
-	if a.config.OnlyFake && result.Status != "FAKE" {
-		return
+// streamAnalysis 一个文件（或一条音轨）在流式读取中需要的分析步骤
+type streamAnalysis struct {
+	spectrum    *SpectrumAnalyzer
+	welch       []*welchSink     // 每个声道一个
+	brickWall   *brickWallSink   // 仅用于DSD和采样率高于48kHz的PCM
+	stereo      *stereoSink      // 仅用于双声道PCM
+	spectrogram *spectrogramSink // 仅在需要频谱图（HTML报告）时使用
+	bitDepth    *bitUsage        // 采样流能提供整数采样时使用，CUE分轨时各音轨共用整个镜像的统计
+	segments    *segmentSink     // 仅在启用分段分析时用于PCM
+	crc         *crcSink         // 仅在有对应的抓轨日志且为CD规格时使用
+	accurateRip *accurateRipSink // 仅用于CD规格的音轨
+	album       albumPosition    // 音轨在光盘中的位置，读完采样后确定
+}
+
+// newStreamAnalysis 为音频文件（或其中 frames 帧长的一段）准备分析步骤
+func (a *Analyzer) newStreamAnalysis(audioFile types.AudioFile, frames int64) *streamAnalysis {
+	spectrumAnalyzer := NewSpectrumAnalyzer(audioFile.GetSampleRate())
+	spectrumAnalyzer.SetWelchOptions(a.config.Windows, a.config.Overlap)
+	if a.config.CutoffFreq > 0 {
+		spectrumAnalyzer.SetCutoffThreshold(a.config.CutoffFreq)
+	}
+	if len(a.config.Fingerprints) > 0 {
+		spectrumAnalyzer.SetFingerprints(a.config.Fingerprints)
+	}
+	stream := &streamAnalysis{spectrum: spectrumAnalyzer}
+	for ch := 0; ch < audioFile.GetChannels(); ch++ {
+		stream.welch = append(stream.welch, spectrumAnalyzer.newWelchSink(ch, frames))
 	}
+	_, dsd := audioFile.(types.DSDSource)
+	if dsd || audioFile.GetSampleRate() > hiresMinSampleRate {
+		stream.brickWall = spectrumAnalyzer.newBrickWallSink(audioFile.GetChannels(), frames)
+	}
+	if !dsd && audioFile.GetChannels() == 2 {
+		stream.stereo = spectrumAnalyzer.newStereoSink(frames)
+	}
+	if !dsd && a.config.Segments > 0 {
+		stream.segments = spectrumAnalyzer.newSegmentSink(audioFile.GetChannels(), a.config.Segments)
+	}
+	if a.config.Spectrograms {
+		stream.spectrogram = spectrumAnalyzer.newSpectrogramSink(frames, reportSpectrogramWidth, reportSpectrogramHeight)
+	}
+	return stream
+}
 
-	// 静默模式，只输出假无损文件路径
-	if a.config.Quiet {
-		if result.Status == "FAKE" {
-			fmt.Println(result.FilePath)
-		}
-		return
+// sinks 返回需要接收采样的分析步骤
+func (sa *streamAnalysis) sinks() []sampleSink {
+	var sinks []sampleSink
+	for _, sink := range sa.welch {
+		sinks = append(sinks, sink)
 	}
+	if sa.brickWall != nil {
+		sinks = append(sinks, sa.brickWall)
+	}
+	if sa.stereo != nil {
+		sinks = append(sinks, sa.stereo)
+	}
+	if sa.spectrogram != nil {
+		sinks = append(sinks, sa.spectrogram)
+	}
+	if sa.segments != nil {
+		sinks = append(sinks, sa.segments)
+	}
+	if sa.crc != nil {
+		sinks = append(sinks, sa.crc)
+	}
+	if sa.accurateRip != nil {
+		sinks = append(sinks, sa.accurateRip)
+	}
+	return sinks
+}
 
-	// JSON输出格式
-	if a.config.JSONOutput {
-		jsonData, err := json.Marshal(result)
+// finishAnalysis 在采样流读完后进行频谱分析并给出判定
+func (a *Analyzer) finishAnalysis(result *types.AnalysisResult, audioFile types.AudioFile, stream *streamAnalysis) {
+	// 分别分析每个声道的频谱
+	spectra := make([]*SpectrumResult, len(stream.welch))
+	for ch, sink := range stream.welch {
+		spectrumResult, err := sink.result()
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "JSON序列化失败: %v\n", err)
+			result.Error = fmt.Sprintf("频谱分析失败: %v", err)
 			return
 		}
-		fmt.Println(string(jsonData))
-		return
+		spectra[ch] = spectrumResult
 	}
 
-	// 普通格式输出
-	a.printDetailedResult(result)
-}
-
-// printDetailedResult 打印详细结果
-func (a *Analyzer) printDetailedResult(result *types.AnalysisResult) {
-	fmt.Printf("\n=== %s ===\n", filepath.Base(result.FilePath))
-	fmt.Printf("路径: %s\n", result.FilePath)
-	fmt.Printf("格式: %s\n", result.Format)
-	fmt.Printf("状态: %s\n", result.Status)
+	// 有损编码对所有声道使用同样的低通滤波，以最高有效频率最高的声道作为整个文件的判定依据，
+	// 避免空声道或单独偏暗的声道造成误判
+	primary := primaryChannel(spectra)
+	spectrumResult := spectra[primary]
 
-	if result.Error != "" {
-		fmt.Printf("错误: %s\n", result.Error)
-		return
+	// 填充分析结果
+	result.Analysis = types.AnalysisDetails{
+		CutoffHz:             spectrumResult.CutoffFrequency,
+		SampleRate:           audioFile.GetSampleRate(),
+		BitDepth:             audioFile.GetBitDepth(),
+		Channels:             audioFile.GetChannels(),
+		Duration:             audioFile.GetDuration().Seconds(),
+		MaxFrequency:         spectrumResult.MaxFrequency,
+		Windows:              spectrumResult.Windows,
+		CutoffOccurrence:     spectrumResult.CutoffOccurrence(spectrumResult.MaxFrequency),
+		WindowMaxFrequencies: spectrumResult.WindowMaxFrequencies,
+		PerChannel:           channelAnalysis(spectra),
+		EncoderMatch:         spectrumResult.EncoderMatch,
+	}
+	evidence := append([]types.Evidence(nil), spectrumResult.Evidence...)
+
+	// DSD的噪声整形会掩盖常规的截断检测，改用专门的来源判定代替频谱证据
+	if dsd, ok := audioFile.(types.DSDSource); ok {
+		origin := analyzeDSDOrigin(stream.brickWall)
+		result.Analysis.DSDRate = dsd.DSDSampleRate()
+		result.Analysis.DSDOrigin = origin.Origin
+		result.Analysis.CutoffHz = 0
+		result.Analysis.EncoderMatch = nil
+		if origin.Wall != nil {
+			result.Analysis.CutoffHz = origin.Wall.Frequency
+		}
+		evidence = []types.Evidence{dsdEvidence(origin)}
 	}
 
-	// 基本信息
-	fmt.Printf("采样率: %d Hz\n", result.Analysis.SampleRate)
-	fmt.Printf("位深度: %d bit\n", result.Analysis.BitDepth)
-	fmt.Printf("声道数: %d\n", result.Analysis.Channels)
-	fmt.Printf("时长: %.2f 秒\n", result.Analysis.Duration)
+	// 联合立体声的侧声道塌缩是独立的发现，置信度足够高时单独即可判定为有损
+	if stream.stereo != nil {
+		if collapse := stream.stereo.detect(); collapse != nil {
+			result.Analysis.StereoCollapse = &types.StereoCollapse{
+				Detected:   collapse.Detected,
+				Frequency:  collapse.Frequency,
+				DropDB:     collapse.DropDB,
+				Confidence: collapse.Confidence,
+				Details:    collapse.Details,
+			}
+			evidence = append(evidence, *stereoEvidence(collapse))
+		}
+	}
 
-	// 元数据
-	if result.Metadata.Title != "" {
-		fmt.Printf("标题: %s\n", result.Metadata.Title)
+	// 分段分析：与其余部分明显不同的分段单独标记，陡峭的低截断分段作为拼接了有损片段的证据
+	if stream.segments != nil {
+		result.Analysis.Segments = stream.segments.result()
+		if e := stream.spectrum.segmentEvidence(result.Analysis.Segments); e != nil {
+			evidence = append(evidence, *e)
+		}
 	}
-	if result.Metadata.Artist != "" {
-		fmt.Printf("艺术家: %s\n", result.Metadata.Artist)
+
+	// 与AccurateRip数据吻合说明音频与原版CD逐位相同
+	if stream.accurateRip != nil {
+		if result.AccurateRip = a.checkAccurateRip(stream.accurateRip, stream.album); result.AccurateRip != nil {
+			if e := accurateRipEvidence(result.AccurateRip); e != nil {
+				evidence = append(evidence, *e)
+			}
+		}
 	}
-	if result.Metadata.Album != "" {
-		fmt.Printf("专辑: %s\n", result.Metadata.Album)
+
+	// 容器层面已能确定为有损编码
+	if lossy, ok := audioFile.(types.LossyContainer); ok {
+		if reason := lossy.LossyReason(); reason != "" {
+			evidence = append(evidence, containerEvidence(reason))
+		}
 	}
 
-	// 频谱分析结果
-	fmt.Printf("最高有效频率: %.0f Hz\n", result.Analysis.MaxFrequency)
-	if result.Analysis.CutoffHz > 0 {
-		fmt.Printf("截断频率: %.0f Hz\n", result.Analysis.CutoffHz)
+	// 某个声道的截断明显低于其他声道时在说明中给出提示；只解码了部分数据时说明结论的依据
+	note := channelMismatchNote(spectra, primary)
+	if reason := partialReason(audioFile); reason != "" {
+		result.Analysis.PartialDecode = reason
+		note = strings.TrimPrefix(note+"；"+reason, "；")
+	}
+	conclude(result, evidence, note)
+
+	// 升频和位深度填充不代表有损来源，不参与证据评分；没有判定为有损时单独标记为 FAKE_HIRES
+	var hires []string
+	if _, ok := audioFile.(types.DSDSource); !ok {
+		if upsampling := detectUpsampling(stream.brickWall, audioFile.GetSampleRate()); upsampling != nil {
+			result.Analysis.Upsampling = &types.Upsampling{
+				OriginalSampleRate: upsampling.OriginalRate,
+				Frequency:          upsampling.Wall.Frequency,
+				DropDB:             upsampling.Wall.DropDB,
+				Details:            upsampling.Details,
+			}
+			hires = append(hires, upsampling.Details)
+		}
+	}
+	if bitDepth := stream.bitDepth.estimate(); bitDepth != nil {
+		result.Analysis.EffectiveBitDepth = bitDepth
+		// 只有把CD规格（16位及以下）的内容标成高位深度时才算假高解析度，如24位中只有20位有效时仅作提示
+		if bitDepth.Mismatch && bitDepth.Bits <= 16 && result.Analysis.BitDepth > 16 {
+			hires = append(hires, bitDepth.Details)
+		}
+	}
+	if len(hires) > 0 && result.Status != "FAKE" {
+		markFakeHiRes(result, hires)
 	}
-	fmt.Printf("分析结果: %s\n", result.Analysis.Details)
+}
 
-	// 如果是假无损，用红色标记
-	if result.Analysis.IsFake {
-		fmt.Printf("⚠️  警告: 这可能是一个假无损文件！\n")
-	} else {
-		fmt.Printf("✅ 文件看起来是真实的无损音频\n")
+// markFakeHiRes 把没有判定为有损来源的文件标记为 FAKE_HIRES，说明改为升频或位深度检测的结果
+// 原先为 SUSPECT 时保留提示，有损来源的置信度不变
+func markFakeHiRes(result *types.AnalysisResult, reasons []string) {
+	details := strings.Join(reasons, "；")
+	if result.Status == "SUSPECT" {
+		details += fmt.Sprintf("；另有部分证据指向有损来源（置信度 %.0f%%），建议人工确认", result.Analysis.Confidence*100)
 	}
+	result.Status = "FAKE_HIRES"
+	result.VerdictSource = "hires"
+	result.Analysis.Details = details
 }
 
-// printSummary 打印统计摘要
-func (a *Analyzer) printSummary(results []*types.AnalysisResult) {
-	total := len(results)
-	fake := 0
-	ok := 0
-	errors := 0
-
-	for _, result := range results {
-		switch result.Status {
-		case "FAKE":
-			fake++
-		case "OK":
-			ok++
-		case "ERROR":
-			errors++
+// primaryChannel 返回最高有效频率最高的非静音声道，所有声道都是静音时返回0
+func primaryChannel(spectra []*SpectrumResult) int {
+	primary := 0
+	for ch, r := range spectra {
+		if r.Silent {
+			continue
 		}
+		if spectra[primary].Silent || r.MaxFrequency > spectra[primary].MaxFrequency {
+			primary = ch
+		}
+	}
+	return primary
+}
+
+// partialReason 返回音频只解码了部分数据的原因，完整解码时返回空字符串
+// 此时解码结果与原始PCM不是逐位相同的，CRC、AccurateRip和位深度检测都没有意义
+func partialReason(audioFile types.AudioFile) string {
+	if partial, ok := audioFile.(types.PartialSource); ok {
+		return partial.PartialReason()
 	}
+	return ""
+}
 
-	fmt.Printf("\n=== 分析统计 ===\n")
-	fmt.Printf("总文件数: %d\n", total)
-	fmt.Printf("正常文件: %d\n", ok)
-	fmt.Printf("假无损文件: %d\n", fake)
-	if errors > 0 {
-		fmt.Printf("错误文件: %d\n", errors)
+// channelAnalysis 整理各声道的频谱分析结果
+func channelAnalysis(spectra []*SpectrumResult) []types.ChannelAnalysis {
+	channels := make([]types.ChannelAnalysis, len(spectra))
+	for ch, r := range spectra {
+		channels[ch] = types.ChannelAnalysis{
+			Channel:          ch + 1,
+			MaxFrequency:     r.MaxFrequency,
+			CutoffHz:         r.CutoffFrequency,
+			CutoffOccurrence: r.CutoffOccurrence(r.MaxFrequency),
+			Silent:           r.Silent,
+		}
 	}
+	return channels
+}
 
-	if fake > 0 {
-		fmt.Printf("\n⚠️  发现 %d 个可疑的假无损文件，建议进一步检查！\n", fake)
-	} else {
-		fmt.Printf("\n✅ 所有文件都看起来是真实的无损音频\n")
+// channelMismatchNote 列出最高有效频率明显低于判定声道的非静音声道
+func channelMismatchNote(spectra []*SpectrumResult, primary int) string {
+	var notes []string
+	for ch, r := range spectra {
+		if ch == primary || r.Silent {
+			continue
+		}
+		if spectra[primary].MaxFrequency-r.MaxFrequency > channelMismatchHz {
+			notes = append(notes, fmt.Sprintf("声道%d的最高有效频率 (%.0f Hz) 明显低于声道%d (%.0f Hz)",
+				ch+1, r.MaxFrequency, primary+1, spectra[primary].MaxFrequency))
+		}
 	}
+	return strings.Join(notes, "；")
 }
@@ -0,0 +1,72 @@
+package analyzer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"audio-loss-checker/internal/types"
+)
+
+// testWAV 生成PCM格式的WAV文件，samples 为按声道分开、右对齐到 bits 位的整数采样
+func testWAV(sampleRate, bits int, samples [][]int32) []byte {
+	le := binary.LittleEndian
+	channels, width := len(samples), bits/8
+	var pcm []byte
+	for i := range samples[0] {
+		for ch := range samples {
+			v := uint32(samples[ch][i])
+			for b := 0; b < width; b++ {
+				pcm = append(pcm, byte(v>>uint(8*b)))
+			}
+		}
+	}
+
+	wav := append([]byte("RIFF"), le.AppendUint32(nil, uint32(36+len(pcm)))...)
+	wav = append(wav, "WAVEfmt "...)
+	wav = le.AppendUint32(wav, 16)
+	wav = le.AppendUint16(wav, 1) // PCM
+	wav = le.AppendUint16(wav, uint16(channels))
+	wav = le.AppendUint32(wav, uint32(sampleRate))
+	wav = le.AppendUint32(wav, uint32(sampleRate*channels*width))
+	wav = le.AppendUint16(wav, uint16(channels*width))
+	wav = le.AppendUint16(wav, uint16(bits))
+	wav = append(wav, "data"...)
+	wav = le.AppendUint32(wav, uint32(len(pcm)))
+	return append(wav, pcm...)
+}
+
+// 内容为有损编码、扩展名为无损格式的文件不经频谱分析直接判定为 FAKE
+func TestContainerLossy(t *testing.T) {
+	// 两个MPEG-1 Layer III帧（128kbps、44.1kHz，每帧417字节）
+	frame := make([]byte, 417)
+	copy(frame, []byte{0xff, 0xfb, 0x90, 0x00})
+	mp3 := append(bytes.Clone(frame), frame...)
+
+	a, err := NewAnalyzer(&types.AnalyzerConfig{Concurrency: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		mismatch bool
+	}{
+		{"fake.flac", true},
+		{"fake.wav", true},
+		{"song.mp3", false},
+	}
+	for _, tt := range tests {
+		result := a.AnalyzeReader(bytes.NewReader(mp3), tt.name)
+		if result.Status != "FAKE" || result.VerdictSource != "container" || result.Format != "MP3" {
+			t.Errorf("%s: 结果为 %s（依据 %s，格式 %s）", tt.name, result.Status, result.VerdictSource, result.Format)
+		}
+		if !strings.Contains(result.Analysis.Details, "文件内容为MP3有损编码") {
+			t.Errorf("%s: 说明为 %q", tt.name, result.Analysis.Details)
+		}
+		if (result.FormatMismatch != nil) != tt.mismatch {
+			t.Errorf("%s: 扩展名不符为 %+v", tt.name, result.FormatMismatch)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package analyzer
+
+import (
+	"fmt"
+	"math/bits"
+
+	"audio-loss-checker/internal/types"
+)
+
+const (
+	// minBitDepthSamples 参与统计的采样数少于此值时不做判断
+	minBitDepthSamples = 4096
+	// ditherResidualRatio 采样相对较低位深度网格的平均残差低于均匀分布期望值的此比例时，
+	// 认为低位只有微弱的抖动噪声，真实的高位深度内容在低位上接近均匀分布
+	ditherResidualRatio = 0.25
+)
+
+// candidateBitDepths 可能的真实位深度，从低到高
+var candidateBitDepths = []int{8, 16, 20, 24}
+
+// bitUsage 在归一化为浮点数之前统计整数采样的位使用情况
+// 整数采样右对齐到声明的位深度
+type bitUsage struct {
+	declared int
+	samples  int64
+	orMask   uint32 // 所有采样的按位或
+	andMask  uint32 // 所有采样的按位与，与 orMask 比较可得到从未变化过的位
+
+	// 每个低于声明位深度的候选：网格间隔、采样相对网格的残差绝对值之和，以及参与统计的采样数
+	depths   []int
+	steps    []int64
+	residual []int64
+	counted  []int64
+}
+
+// observeBitDepth 采样流能够提供整数采样时注册位深度统计，否则返回nil
+// 只解码了部分数据时整数采样不是原始数据，同样不做统计
+func observeBitDepth(reader types.SampleReader, audioFile types.AudioFile) *bitUsage {
+	declared := audioFile.GetBitDepth()
+	source, ok := reader.(types.IntSampleSource)
+	if !ok || declared <= 0 || declared > 32 || partialReason(audioFile) != "" {
+		return nil
+	}
+	usage := &bitUsage{declared: declared, andMask: ^uint32(0)}
+	for _, depth := range candidateBitDepths {
+		if depth < declared {
+			usage.depths = append(usage.depths, depth)
+			usage.steps = append(usage.steps, int64(1)<<uint(declared-depth))
+		}
+	}
+	usage.residual = make([]int64, len(usage.depths))
+	usage.counted = make([]int64, len(usage.depths))
+	if !source.ObserveIntSamples(usage) {
+		return nil
+	}
+	return usage
+}
+
+// ObserveInt 实现 types.IntSampleObserver
+func (b *bitUsage) ObserveInt(samples []int32) {
+	for _, v := range samples {
+		b.orMask |= uint32(v)
+		b.andMask &= uint32(v)
+
+		magnitude := int64(v)
+		if magnitude < 0 {
+			magnitude = -magnitude
+		}
+		for i, step := range b.steps {
+			// 只统计远大于网格间隔的采样，安静段落的小数值本身就集中在0附近
+			if magnitude < 2*step {
+				continue
+			}
+			// 到最近网格点的距离
+			r := int64(v) & (step - 1)
+			if r > step/2 {
+				r = step - r
+			}
+			b.residual[i] += r
+			b.counted[i]++
+		}
+	}
+	b.samples += int64(len(samples))
+}
+
+// estimate 估计有效位深度，采样不足或全部相同时返回nil
+// 先按从未变化过的低位（补零或固定值）确定上限，再检查低位是否只有微弱的抖动噪声
+func (b *bitUsage) estimate() *types.EffectiveBitDepth {
+	if b == nil || b.samples < minBitDepthSamples {
+		return nil
+	}
+	varying := b.orMask &^ b.andMask
+	if b.declared < 32 {
+		varying &= 1<<uint(b.declared) - 1
+	}
+	if varying == 0 {
+		return nil
+	}
+
+	result := &types.EffectiveBitDepth{Bits: b.declared - bits.TrailingZeros32(varying), Method: "lsb"}
+	for i, depth := range b.depths {
+		if depth >= result.Bits || b.counted[i] < minBitDepthSamples {
+			continue
+		}
+		// 均匀分布时残差绝对值的期望为网格间隔的1/4
+		mean := float64(b.residual[i]) / float64(b.counted[i])
+		if mean < float64(b.steps[i])/4*ditherResidualRatio {
+			result.Bits, result.Method = depth, "dither"
+			break
+		}
+	}
+
+	result.Mismatch = result.Bits < b.declared
+	switch {
+	case !result.Mismatch:
+		result.Details = fmt.Sprintf("全部 %d 位都有实际使用", b.declared)
+	case result.Method == "lsb":
+		result.Details = fmt.Sprintf("声明为 %d 位，但最低 %d 位始终不变，实际为 %d 位", b.declared, b.declared-result.Bits, result.Bits)
+	default:
+		result.Details = fmt.Sprintf("声明为 %d 位，但采样都集中在 %d 位的网格附近，低位只有微弱的抖动噪声，实际约为 %d 位",
+			b.declared, result.Bits, result.Bits)
+	}
+	return result
+}
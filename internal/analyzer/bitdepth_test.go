@@ -0,0 +1,107 @@
+package analyzer
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"testing"
+
+	"audio-loss-checker/internal/types"
+)
+
+// musicSignal 生成 frames 帧的测试音乐：两个正弦波加噪声，幅度为满幅的一半，按 bits 位加TPDF抖动后量化
+// lsbNoise 为量化后再叠加在最低位上的抖动幅度（LSB），用于模拟低位深度内容在高位深度容器中重新加抖动
+func musicSignal(rng *rand.Rand, frames, bits int, shift uint, lsbNoise int) [][]int32 {
+	samples := [][]int32{make([]int32, frames), make([]int32, frames)}
+	full := math.Ldexp(1, bits-1)
+	for ch := range samples {
+		for i := range samples[ch] {
+			x := 0.3*math.Sin(2*math.Pi*440*float64(i)/44100+float64(ch)) +
+				0.15*math.Sin(2*math.Pi*3150*float64(i)/44100) + 0.05*rng.NormFloat64()
+			dither := rng.Float64() - rng.Float64()
+			v := int32(math.Round(x*full + dither))
+			v <<= shift
+			if lsbNoise > 0 {
+				v += int32(rng.Intn(2*lsbNoise+1) - lsbNoise)
+			}
+			samples[ch][i] = v
+		}
+	}
+	return samples
+}
+
+func TestEffectiveBitDepth(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	const frames = 44100
+
+	quiet := make([][]int32, 2)
+	for ch := range quiet {
+		quiet[ch] = make([]int32, frames)
+		for i := range quiet[ch] {
+			// 约-90dBFS的24位内容，大部分采样都在16位网格的2个间隔以内
+			quiet[ch][i] = int32(math.Round(rng.NormFloat64() * 300))
+		}
+	}
+
+	tests := []struct {
+		name     string
+		bits     int
+		samples  [][]int32
+		want     *types.EffectiveBitDepth // 为nil表示不应给出估计
+		mismatch bool
+	}{
+		{
+			name: "16位补零到24位", bits: 24, samples: musicSignal(rng, frames, 16, 8, 0),
+			want: &types.EffectiveBitDepth{Bits: 16, Method: "lsb"}, mismatch: true,
+		},
+		{
+			name: "20位补零到24位", bits: 24, samples: musicSignal(rng, frames, 20, 4, 0),
+			want: &types.EffectiveBitDepth{Bits: 20, Method: "lsb"}, mismatch: true,
+		},
+		{
+			// 低位不是0，但只有 ±1 LSB 的噪声，采样集中在16位网格附近
+			name: "16位内容在24位中加抖动", bits: 24, samples: musicSignal(rng, frames, 16, 8, 1),
+			want: &types.EffectiveBitDepth{Bits: 16, Method: "dither"}, mismatch: true,
+		},
+		{
+			name: "真24位加抖动", bits: 24, samples: musicSignal(rng, frames, 24, 0, 0),
+			want: &types.EffectiveBitDepth{Bits: 24, Method: "lsb"},
+		},
+		{
+			name: "真16位", bits: 16, samples: musicSignal(rng, frames, 16, 0, 0),
+			want: &types.EffectiveBitDepth{Bits: 16, Method: "lsb"},
+		},
+		{
+			name: "极安静的24位内容", bits: 24, samples: quiet,
+			want: &types.EffectiveBitDepth{Bits: 24, Method: "lsb"},
+		},
+		{
+			// 数字静音没有变化的位，无法判断
+			name: "数字静音", bits: 24, samples: [][]int32{make([]int32, frames), make([]int32, frames)},
+		},
+		{
+			name: "采样太少", bits: 24, samples: musicSignal(rng, minBitDepthSamples/4, 16, 8, 0),
+		},
+	}
+
+	a, err := NewAnalyzer(&types.AnalyzerConfig{Concurrency: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		result := a.AnalyzeReader(bytes.NewReader(testWAV(44100, tt.bits, tt.samples)), "test.wav")
+		if result.Error != "" {
+			t.Fatalf("%s: %s", tt.name, result.Error)
+		}
+		got := result.Analysis.EffectiveBitDepth
+		if tt.want == nil {
+			if got != nil {
+				t.Errorf("%s: 判定为 %+v", tt.name, got)
+			}
+			continue
+		}
+		if got == nil || got.Bits != tt.want.Bits || got.Method != tt.want.Method || got.Mismatch != tt.mismatch {
+			t.Errorf("%s: 有效位深度为 %+v，应为 %d 位（%s）", tt.name, got, tt.want.Bits, tt.want.Method)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package analyzer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"audio-loss-checker/internal/cache"
+	"audio-loss-checker/internal/cue"
+	"audio-loss-checker/internal/decoder"
+	"audio-loss-checker/internal/riplog"
+	"audio-loss-checker/internal/types"
+)
+
+// AnalyzerVersion 分析算法版本，算法或结果结构变化时递增，使已有缓存失效
+const AnalyzerVersion = 12
+
+// openCache 按配置打开结果缓存，未启用或无法打开时返回nil（仅警告，不影响分析）
+func (a *Analyzer) openCache() *cache.Cache {
+	if a.config.CacheDir == "" {
+		return nil
+	}
+	c, err := cache.Open(a.config.CacheDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "结果缓存不可用: %v\n", err)
+		return nil
+	}
+	return c
+}
+
+// cacheSettings 返回影响分析结果的配置
+func (a *Analyzer) cacheSettings() cache.Settings {
+	return cache.Settings{
+		AnalyzerVersion: AnalyzerVersion,
+		CutoffFreq:      a.config.CutoffFreq,
+		Windows:         a.config.Windows,
+		Overlap:         a.config.Overlap,
+		Segments:        a.config.Segments,
+		Verify:          a.config.Verify,
+		AccurateRip:     a.accurateRipDigest(),
+		Fingerprints:    a.fingerprintHash(),
+	}
+}
+
+// accurateRipDigest 返回本地AccurateRip数据的摘要，没有提供数据时为空
+func (a *Analyzer) accurateRipDigest() string {
+	if a.config.AccurateRip == nil {
+		return ""
+	}
+	return a.config.AccurateRip.Digest
+}
+
+// fingerprintHash 返回当前使用的指纹表的SHA-256，指纹表变化时缓存失效
+func (a *Analyzer) fingerprintHash() string {
+	fingerprints := a.config.Fingerprints
+	if len(fingerprints) == 0 {
+		fingerprints = builtinFingerprints
+	}
+	data, _ := json.Marshal(fingerprints)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// analyzeCached 文件及其依赖都没有变化时直接使用缓存的结果，否则重新分析并写入缓存；hit 表示使用了缓存的结果
+// 文件状态在分析之前记录，分析期间文件被修改时下次扫描会重新分析
+func (a *Analyzer) analyzeCached(c *cache.Cache, path string) (results []*types.AnalysisResult, hit bool) {
+	stamps, err := cache.StatFiles(cacheDependencies(path), a.config.CacheHash)
+	if err != nil {
+		return a.analyzePath(path), false
+	}
+
+	settings := a.cacheSettings()
+	if !a.config.RebuildCache {
+		entry := c.Lookup(path, settings, stamps)
+		// 需要频谱图时缓存中还必须有频谱图
+		if entry != nil && (!a.config.Spectrograms || len(entry.Spectrograms) == len(entry.Results)) {
+			if a.config.Spectrograms {
+				for i, result := range entry.Results {
+					result.Spectrogram = entry.Spectrograms[i]
+				}
+			}
+			return entry.Results, true
+		}
+	}
+
+	results = a.analyzePath(path)
+
+	// 出错可能是暂时的（如读取失败），不缓存
+	entry := &cache.Entry{
+		Requested: path,
+		Settings:  settings,
+		Files:     stamps,
+		Results:   results,
+	}
+	for _, result := range results {
+		if result.Status == "ERROR" {
+			return results, false
+		}
+		if a.config.Spectrograms {
+			entry.Spectrograms = append(entry.Spectrograms, result.Spectrogram)
+		}
+	}
+	if err := c.Store(entry); err != nil {
+		a.cacheWarning.Do(func() {
+			fmt.Fprintf(os.Stderr, "写入结果缓存失败: %v\n", err)
+		})
+	}
+	return results, false
+}
+
+// cacheDependencies 返回分析 path 时读取的文件：CUE表及其引用的镜像，WavPack的 .wvc 校正文件，同目录下的抓轨日志
+// 校正文件不存在时也记录，出现校正文件后结果会改变
+func cacheDependencies(path string) []string {
+	paths := []string{path}
+	if strings.EqualFold(filepath.Ext(path), ".cue") {
+		if sheet, err := cue.Parse(path); err == nil {
+			for _, file := range sheet.Files {
+				paths = append(paths, file.Path)
+			}
+		}
+	}
+
+	var deps []string
+	dirs := make(map[string]bool)
+	for _, p := range paths {
+		deps = append(deps, p)
+		if dir := filepath.Dir(p); !dirs[dir] {
+			dirs[dir] = true
+			deps = append(deps, riplog.Find(dir)...)
+		}
+		if strings.EqualFold(filepath.Ext(p), ".wv") {
+			correction := decoder.FindCorrectionFile(p)
+			if correction == "" {
+				correction = strings.TrimSuffix(p, filepath.Ext(p)) + ".wvc"
+			}
+			deps = append(deps, correction)
+		}
+	}
+	return deps
+}
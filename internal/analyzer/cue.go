@@ -0,0 +1,151 @@
+package analyzer
+
+import (
+	"fmt"
+	"time"
+
+	"audio-loss-checker/internal/cue"
+	"audio-loss-checker/internal/types"
+)
+
+// analyzeCueSheet 按CUE表把整轨镜像拆分为音轨，每条音轨单独分析
+func (a *Analyzer) analyzeCueSheet(cuePath string) []*types.AnalysisResult {
+	sheet, err := cue.Parse(cuePath)
+	if err != nil {
+		return []*types.AnalysisResult{{
+			FilePath: cuePath,
+			Status:   "ERROR",
+			Error:    fmt.Sprintf("解析CUE失败: %v", err),
+		}}
+	}
+
+	var results []*types.AnalysisResult
+	for _, file := range sheet.Files {
+		results = append(results, a.analyzeCueFile(sheet, file)...)
+	}
+	return results
+}
+
+// analyzeCueFile 分析CUE表中的一个音频文件，每条音轨输出一个结果
+// 音轨范围为本轨 INDEX 01 到下一轨 INDEX 01（最后一轨到文件结尾），音轨间的间隙归入上一轨
+func (a *Analyzer) analyzeCueFile(sheet *cue.Sheet, file cue.File) []*types.AnalysisResult {
+	base := &types.AnalysisResult{
+		FilePath: file.Path,
+		Status:   "ERROR",
+	}
+
+	// 镜像本身无法分析时，每条音轨都给出同样的结果
+	audioFile := a.openAudioFile(base, nil)
+	if audioFile == nil {
+		return trackResults(sheet, file, base)
+	}
+	defer audioFile.Close()
+
+	sampleRate := int64(audioFile.GetSampleRate())
+	channels := audioFile.GetChannels()
+	estimated := expectedFrames(audioFile)
+
+	// 各音轨在同一次顺序读取中分别截取自己范围内的采样
+	results := trackResults(sheet, file, base)
+	starts := make([]int64, len(file.Tracks))
+	ends := make([]int64, len(file.Tracks))
+	streams := make([]*streamAnalysis, len(file.Tracks))
+	var sinks []sampleSink
+	for i, track := range file.Tracks {
+		starts[i] = int64(track.Index01) * sampleRate / cue.FramesPerSecond
+		ends[i] = -1
+		frames := estimated - starts[i]
+		if i+1 < len(file.Tracks) {
+			ends[i] = int64(file.Tracks[i+1].Index01) * sampleRate / cue.FramesPerSecond
+			frames = ends[i] - starts[i]
+		}
+		streams[i] = a.newStreamAnalysis(audioFile, frames)
+		streams[i].accurateRip = newAccurateRipSink(audioFile)
+		sinks = append(sinks, newRangeSink(starts[i], ends[i], channels, streams[i].sinks()))
+	}
+
+	reader, err := audioFile.NewSampleReader()
+	if err != nil {
+		base.Error = fmt.Sprintf("读取音频数据失败: %v", err)
+		return trackResults(sheet, file, base)
+	}
+	bitDepth := observeBitDepth(reader, audioFile)
+	for _, stream := range streams {
+		stream.bitDepth = bitDepth
+	}
+	checker := checkIntegrity(reader, a.config.Verify)
+	// 整轨抓取的日志只有整个镜像的CRC，在范围过滤之外对整个镜像计算
+	ripLog := findRipLog(file.Path)
+	imageCRC := ripLog.newCRCSink(audioFile)
+	if imageCRC != nil {
+		sinks = append(sinks, imageCRC)
+	}
+	totalFrames, err := runStream(reader, channels, sinks)
+	if err != nil {
+		base.Error = fmt.Sprintf("读取音频数据失败: %v", err)
+		return trackResults(sheet, file, base)
+	}
+	var integrity *types.Integrity
+	if checker != nil {
+		integrity = checker.Integrity()
+	}
+	disc := cueDiscID(sheet, file, ripLog, totalFrames)
+
+	for i, track := range file.Tracks {
+		result := results[i]
+		start, end := starts[i], ends[i]
+		if end < 0 || end > totalFrames {
+			end = totalFrames
+		}
+		if start >= end {
+			result.Error = fmt.Sprintf("音轨 %02d 的范围超出音频长度", track.Number)
+			applyIntegrity(result, trackIntegrity(integrity, starts[i], ends[i]), a.config.Verify)
+			continue
+		}
+
+		streams[i].album = cuePosition(sheet, track.Number, disc)
+		a.finishAnalysis(result, audioFile, streams[i])
+		applyIntegrity(result, trackIntegrity(integrity, starts[i], ends[i]), a.config.Verify)
+		if ripLog != nil {
+			result.RipLog = ripLog.report(track.Number, audioFile, imageCRC)
+		}
+		duration := time.Duration(end-start) * time.Second / time.Duration(sampleRate)
+		result.Analysis.Duration = duration.Seconds()
+		result.Metadata.Duration = duration.String()
+		result.Track.End = float64(end) / float64(sampleRate)
+		a.attachSpectrogram(result, streams[i])
+	}
+
+	return results
+}
+
+// trackResults 以 base 为模板为每条音轨生成结果，并填入CUE中的音轨信息
+func trackResults(sheet *cue.Sheet, file cue.File, base *types.AnalysisResult) []*types.AnalysisResult {
+	results := make([]*types.AnalysisResult, len(file.Tracks))
+	for i, track := range file.Tracks {
+		result := *base
+		result.Track = &types.TrackInfo{
+			CueSheet:  sheet.Path,
+			Number:    track.Number,
+			Title:     track.Title,
+			Performer: track.Performer,
+			Start:     float64(track.Index01) / cue.FramesPerSecond,
+		}
+
+		// CUE中的信息优先于镜像自带的标签
+		if track.Title != "" {
+			result.Metadata.Title = track.Title
+		}
+		if track.Performer != "" {
+			result.Metadata.Artist = track.Performer
+		} else if sheet.Performer != "" {
+			result.Metadata.Artist = sheet.Performer
+		}
+		if sheet.Title != "" {
+			result.Metadata.Album = sheet.Title
+		}
+
+		results[i] = &result
+	}
+	return results
+}
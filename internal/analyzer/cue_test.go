@@ -0,0 +1,187 @@
+package analyzer
+
+import (
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"audio-loss-checker/internal/types"
+)
+
+// recordSink 记录收到的第一声道采样
+type recordSink struct {
+	samples []float64
+}
+
+func (r *recordSink) consume(block [][]float64) {
+	r.samples = append(r.samples, block[0]...)
+}
+
+// 按音轨范围截取采样：范围边界落在块中间，最后一轨的 end 为-1，直到流结束
+func TestRangeSink(t *testing.T) {
+	const total, blockSize = 10000, 1000
+	ranges := []struct{ start, end int64 }{{0, 2500}, {2500, 7350}, {7350, -1}, {12000, -1}}
+
+	records := make([]*recordSink, len(ranges))
+	sinks := make([]sampleSink, len(ranges))
+	for i, r := range ranges {
+		records[i] = &recordSink{}
+		sinks[i] = newRangeSink(r.start, r.end, 2, []sampleSink{records[i]})
+	}
+	for pos := 0; pos < total; pos += blockSize {
+		block := [][]float64{make([]float64, blockSize), make([]float64, blockSize)}
+		for i := range block[0] {
+			block[0][i] = float64(pos + i)
+		}
+		for _, sink := range sinks {
+			sink.consume(block)
+		}
+	}
+
+	for i, r := range ranges {
+		end := r.end
+		if end < 0 {
+			end = total
+		}
+		got := records[i].samples
+		if want := max(0, end-r.start); int64(len(got)) != want {
+			t.Errorf("[%d, %d): 收到 %d 帧，应为 %d", r.start, r.end, len(got), want)
+			continue
+		}
+		for j, v := range got {
+			if v != float64(r.start+int64(j)) {
+				t.Errorf("[%d, %d): 第 %d 帧为第 %g 帧的采样", r.start, r.end, j, v)
+				break
+			}
+		}
+	}
+}
+
+// 整轨镜像的帧错误按位置归入音轨，截断只影响延伸到解码结束位置之后的音轨
+func TestTrackIntegrity(t *testing.T) {
+	image := &types.Integrity{
+		ExpectedSamples: 10000,
+		DecodedSamples:  6000,
+		FrameErrors:     2,
+		Errors:          []types.DecodeError{{Position: 1000, Message: "CRC"}, {Position: 5000, Message: "CRC"}},
+		Truncated:       true,
+		Details:         "镜像",
+	}
+	tests := []struct {
+		start, end          int64
+		expected, decoded   int64
+		errors              int
+		truncated           bool
+		unrecordedFrameErrs int // 超过记录上限、无法定位的错误数
+	}{
+		{start: 0, end: 2500, expected: 2500, decoded: 2500, errors: 1},
+		{start: 2500, end: 7350, expected: 4850, decoded: 3500, errors: 1, truncated: true},
+		// 最后一轨的 end 为-1，按文件头记录的长度计算
+		{start: 7350, end: -1, expected: 2650, decoded: 0, truncated: true},
+		{start: 0, end: 2500, expected: 2500, decoded: 2500, errors: 1, unrecordedFrameErrs: 3},
+		{start: 7350, end: -1, expected: 2650, decoded: 0, truncated: true, unrecordedFrameErrs: 3},
+	}
+	for _, tt := range tests {
+		img := *image
+		img.FrameErrors += tt.unrecordedFrameErrs
+		track := trackIntegrity(&img, tt.start, tt.end)
+		if track.ExpectedSamples != tt.expected || track.DecodedSamples != tt.decoded ||
+			len(track.Errors) != tt.errors || track.FrameErrors != tt.errors+tt.unrecordedFrameErrs ||
+			track.Truncated != tt.truncated {
+			t.Errorf("[%d, %d): %+v", tt.start, tt.end, track)
+		}
+		if status := integrityStatus(track); (status == "") != (track.FrameErrors == 0 && !tt.truncated) {
+			t.Errorf("[%d, %d): 状态为 %q", tt.start, tt.end, status)
+		}
+	}
+
+	// 中途停止与截断相同；有帧错误时MD5不符不再归入各音轨
+	stopped := &types.Integrity{ExpectedSamples: 10000, DecodedSamples: 6000, FrameErrors: 1,
+		Errors: []types.DecodeError{{Position: 5990}}, Stopped: true, MD5: "mismatch"}
+	if track := trackIntegrity(stopped, 0, 2500); track.Stopped || track.MD5 != "" || track.FrameErrors != 0 {
+		t.Errorf("第一轨: %+v", track)
+	}
+	if track := trackIntegrity(stopped, 2500, -1); !track.Stopped || track.FrameErrors != 1 {
+		t.Errorf("最后一轨: %+v", track)
+	}
+	// 没有帧错误时MD5不符无法定位，归入所有音轨
+	mismatch := &types.Integrity{ExpectedSamples: 10000, DecodedSamples: 10000, MD5: "mismatch"}
+	if track := trackIntegrity(mismatch, 0, 2500); track.MD5 != "mismatch" || integrityStatus(track) != "CORRUPT" {
+		t.Errorf("MD5不符: %+v", track)
+	}
+}
+
+// 从CUE表到各音轨的分析结果：INDEX 01 按每帧588个采样换算为44.1kHz下的位置
+func TestAnalyzeCueSheet(t *testing.T) {
+	const sampleRate, frames = 44100, 3 * 44100
+	dir := t.TempDir()
+
+	rng := rand.New(rand.NewSource(1))
+	samples := [][]int32{make([]int32, frames), make([]int32, frames)}
+	for ch := range samples {
+		for i := range samples[ch] {
+			samples[ch][i] = int32(rng.NormFloat64() * 3000)
+		}
+	}
+	wav := testWAV(sampleRate, 16, samples)
+	if err := os.WriteFile(filepath.Join(dir, "Live Image.wav"), wav, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	sheet := strings.Join([]string{
+		`PERFORMER "Artist"`,
+		`TITLE "Album"`,
+		`FILE "Live Image.wav" WAVE`,
+		`  TRACK 01 AUDIO`,
+		`    TITLE "One"`,
+		`    INDEX 01 00:00:00`,
+		`  TRACK 02 AUDIO`,
+		`    TITLE "Two"`,
+		`    INDEX 00 00:00:50`,
+		`    INDEX 01 00:01:00`,
+		`  TRACK 03 AUDIO`,
+		`    TITLE "Three"`,
+		`    INDEX 01 00:01:40`,
+	}, "\n")
+	cuePath := filepath.Join(dir, "album.cue")
+	if err := os.WriteFile(cuePath, []byte(sheet), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := NewAnalyzer(&types.AnalyzerConfig{Concurrency: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := a.AnalyzePath(cuePath)
+	if len(results) != 3 {
+		t.Fatalf("得到 %d 个结果", len(results))
+	}
+
+	// 第三轨从第115帧（67620个采样）开始，最后一轨到镜像结尾
+	tests := []struct {
+		title      string
+		start, end float64
+	}{
+		{"One", 0, 1},
+		{"Two", 1, 67620.0 / sampleRate},
+		{"Three", 115.0 / 75, 3},
+	}
+	for i, tt := range tests {
+		result := results[i]
+		if result.Error != "" || result.Track == nil {
+			t.Fatalf("音轨 %d: %+v", i+1, result)
+		}
+		if result.Track.Number != i+1 || result.Metadata.Title != tt.title || result.Metadata.Artist != "Artist" ||
+			result.Metadata.Album != "Album" {
+			t.Errorf("音轨 %d: %+v %+v", i+1, result.Track, result.Metadata)
+		}
+		if math.Abs(result.Track.Start-tt.start) > 1e-9 || math.Abs(result.Track.End-tt.end) > 1e-9 ||
+			math.Abs(result.Analysis.Duration-(tt.end-tt.start)) > 1e-6 {
+			t.Errorf("音轨 %d: %.6f-%.6f 秒，时长 %.6f 秒，应为 %.6f-%.6f", i+1, result.Track.Start, result.Track.End,
+				result.Analysis.Duration, tt.start, tt.end)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package analyzer
+
+import "fmt"
+
+const (
+	// dsdBrickWallDropDB 截断两侧电平差超过该值时视为砖墙滤波
+	// 原生DSD录音在20kHz以上平滑过渡到噪声整形区域，不会出现这样陡峭的下降
+	dsdBrickWallDropDB = 20.0
+
+	// 搜索砖墙截断的频率范围，覆盖有损编码（15-19kHz）和44.1/48kHz PCM（20-24kHz）
+	dsdWallSearchMin = 15000.0
+	dsdWallSearchMax = 24500.0
+)
+
+// DSDOriginResult DSD来源判定结果
+type DSDOriginResult struct {
+	Origin  string     // "native", "pcm44.1k", "pcm48k", "lossy"
+	Wall    *BrickWall // 检测到的砖墙截断，原生DSD为nil
+	IsFake  bool
+	Details string
+}
+
+// analyzeDSDOrigin 根据流式累积的平均功率谱判断DSD是否由PCM或有损音频转换而来
+// 由PCM上变换得到的DSD在原PCM奈奎斯特频率处有明显的砖墙截断，其上方只剩调制器噪声
+func analyzeDSDOrigin(sink *brickWallSink) *DSDOriginResult {
+	wall := sink.detect(dsdWallSearchMin, dsdWallSearchMax)
+	if wall == nil || wall.DropDB < dsdBrickWallDropDB {
+		return &DSDOriginResult{
+			Origin:  "native",
+			Details: "DSD频谱在15-24.5 kHz范围内没有砖墙截断，符合原生DSD录音特征",
+		}
+	}
+
+	result := &DSDOriginResult{Wall: wall, IsFake: true}
+	switch {
+	case wall.Frequency < 19500:
+		result.Origin = "lossy"
+		result.Details = fmt.Sprintf("DSD在 %.0f Hz 处存在砖墙截断（下降 %.0f dB），疑似由有损音频转换而来",
+			wall.Frequency, wall.DropDB)
+	case wall.Frequency <= 22100:
+		result.Origin = "pcm44.1k"
+		result.Details = fmt.Sprintf("DSD在 %.0f Hz 处存在砖墙截断（下降 %.0f dB），疑似由44.1kHz PCM转换而来",
+			wall.Frequency, wall.DropDB)
+	default:
+		result.Origin = "pcm48k"
+		result.Details = fmt.Sprintf("DSD在 %.0f Hz 处存在砖墙截断（下降 %.0f dB），疑似由48kHz PCM转换而来",
+			wall.Frequency, wall.DropDB)
+	}
+
+	return result
+}
@@ -0,0 +1,206 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+
+	"audio-loss-checker/internal/types"
+)
+
+// 证据权重以对数几率 (log-odds) 为单位：正值支持有损来源，负值支持真无损
+// 置信度 = logistic(先验 + 各条证据权重之和)
+const (
+	// evidencePrior 没有任何证据时的对数几率，偏向真无损（约27%）
+	evidencePrior = -1.0
+	// fakeConfidence 置信度达到此值判定为 FAKE
+	fakeConfidence = 0.8
+	// suspectConfidence 置信度达到此值但未达到 fakeConfidence 时判定为 SUSPECT
+	suspectConfidence = 0.5
+
+	weightLowCutoff    = 3.0   // 最高有效频率低于阈值，且截断稳定存在于多数窗口
+	weightUnstable     = -1.5  // 平均频谱偏低，但多数窗口超过该频率（安静段落）
+	weightNarrowNormal = -1.0  // 最高有效频率高于阈值
+	weightFullBand     = -2.0  // 最高有效频率接近奈奎斯特频率
+	weightEncoderMatch = 1.5   // 与已知有损编码器的低通指纹吻合，按匹配度缩放
+	weightSteepShelf   = 1.5   // 截断处下降陡峭，像编码器的低通滤波
+	weightGentleShelf  = -1.0  // 截断处平缓滚降，像录音本身的高频衰减
+	weightBandLimit    = 1.0   // 高频段整体远低于峰值功率
+	weightStereoMax    = 6.0   // 侧声道塌缩，按塌缩检测的置信度缩放
+	weightStereoIntact = -0.5  // 高频侧声道完整
+	weightLossySegment = 3.0   // 有分段的截断陡峭且明显低于其余部分，像拼接进来的有损片段
+	weightDSDConverted = 4.0   // DSD在PCM或有损音频的奈奎斯特频率处有砖墙截断
+	weightDSDNative    = -2.0  // DSD没有砖墙截断
+	weightContainer    = 10.0  // 容器或编码本身就是有损的，单独即可定论
+	weightAccurateRip  = -10.0 // 与AccurateRip数据吻合，音频与原版CD逐位相同，单独即可定论
+	shelfSteepDB       = 30.0  // 截断两侧1kHz频带的电平差达到此值视为陡峭
+	shelfGentleDB      = 12.0  // 低于此值视为平缓滚降
+	shelfBandOffset    = 500.0
+	shelfBandWidth     = 1000.0
+	fullBandRatio      = 0.95 // 最高有效频率达到奈奎斯特频率的此比例视为完整频谱（44.1kHz时约21kHz，高于MP3 320kbps的低通）
+)
+
+// spectrumEvidence 根据平均功率谱收集证据：最高有效频率、截断的稳定性、已知编码器截断频率、截断处的陡峭程度和高频段整体电平
+// 各条规则相互独立，全部参与评分，不会在第一条命中的规则处停止
+func (s *SpectrumAnalyzer) spectrumEvidence(result *SpectrumResult) []types.Evidence {
+	maxFreq := result.MaxFrequency
+	nyquist := float64(s.sampleRate) / 2
+	occurrence := result.CutoffOccurrence(maxFreq)
+	stable := occurrence >= minCutoffOccurrence
+
+	var evidence []types.Evidence
+	switch {
+	case maxFreq < s.cutoffThreshold && stable:
+		details := fmt.Sprintf("最高有效频率过低 (%.0f Hz)，可能从有损格式转换而来", maxFreq)
+		if s.cutoffThreshold != DefaultCutoffFreq {
+			details = fmt.Sprintf("最高频率 %.0f Hz 低于设定阈值 %.0f Hz", maxFreq, s.cutoffThreshold)
+		}
+		evidence = append(evidence, types.Evidence{Detector: "cutoff", Weight: weightLowCutoff, Details: details})
+	case maxFreq < s.cutoffThreshold:
+		// 有损编码的低通滤波对每个窗口都生效；多数窗口都超过该频率时，更可能是安静段落拉低了平均频谱
+		evidence = append(evidence, types.Evidence{Detector: "cutoff", Weight: weightUnstable,
+			Details: fmt.Sprintf("平均频谱最高有效频率 %.0f Hz，但只有 %.0f%% 的窗口在此截止，不像有损编码的低通滤波",
+				maxFreq, occurrence*100)})
+	case maxFreq >= nyquist*fullBandRatio:
+		evidence = append(evidence, types.Evidence{Detector: "cutoff", Weight: weightFullBand,
+			Details: fmt.Sprintf("频谱正常，最高有效频率 %.0f Hz 接近奈奎斯特频率", maxFreq)})
+	default:
+		evidence = append(evidence, types.Evidence{Detector: "cutoff", Weight: weightNarrowNormal,
+			Details: fmt.Sprintf("频谱正常，最高有效频率 %.0f Hz", maxFreq)})
+	}
+
+	// 截断处的陡峭程度：比较截断下方和上方各1kHz频带的平均电平
+	drop, measured := s.shelfDrop(result.PowerSpectrum, maxFreq)
+
+	// 编码器的低通滤波在所有窗口中位置一致，截断不稳定时不做匹配
+	if stable {
+		if match := matchFingerprint(s.fingerprints, maxFreq, drop, measured); match != nil {
+			result.EncoderMatch = match
+			evidence = append(evidence, types.Evidence{Detector: "encoder", Weight: weightEncoderMatch * match.Score,
+				Details: fmt.Sprintf("截断特征最接近 %s（低通 %.0f Hz，匹配度 %.0f%%）",
+					describeEncoder(match), match.Lowpass, match.Score*100)})
+		}
+	}
+
+	if measured && stable {
+		switch {
+		case drop >= shelfSteepDB:
+			evidence = append(evidence, types.Evidence{Detector: "shelf", Weight: weightSteepShelf,
+				Details: fmt.Sprintf("%.0f Hz 处的截断非常陡峭（两侧相差 %.0f dB），符合编码器低通滤波特征", maxFreq, drop)})
+		case drop < shelfGentleDB:
+			evidence = append(evidence, types.Evidence{Detector: "shelf", Weight: weightGentleShelf,
+				Details: fmt.Sprintf("%.0f Hz 附近为平缓滚降（两侧相差 %.0f dB），更像录音本身的高频衰减", maxFreq, drop)})
+		}
+	}
+
+	// 高频段整体远低于峰值功率
+	if result.CutoffFrequency < nyquist*0.9 { // 截断频率低于奈奎斯特频率的90%
+		evidence = append(evidence, types.Evidence{Detector: "bandlimit", Weight: weightBandLimit,
+			Details: fmt.Sprintf("在 %.0f Hz 附近检测到明显的频率截断", result.CutoffFrequency)})
+	}
+
+	return evidence
+}
+
+// shelfDrop 返回 freq 下方与上方相邻频带平均电平之差 (dB)，频带超出有效范围时返回false
+func (s *SpectrumAnalyzer) shelfDrop(power []float64, freq float64) (float64, bool) {
+	nyquist := float64(s.sampleRate) / 2
+	lo := freq - shelfBandOffset - shelfBandWidth
+	hi := freq + shelfBandOffset + shelfBandWidth
+	if len(power) == 0 || lo <= 0 || hi >= nyquist {
+		return 0, false
+	}
+	freqResolution := nyquist / float64(len(power))
+	below := bandMeanDB(power, freqResolution, lo, freq-shelfBandOffset)
+	above := bandMeanDB(power, freqResolution, freq+shelfBandOffset, hi)
+	return below - above, true
+}
+
+// stereoEvidence 把侧声道塌缩检测结果转换为证据，无法判断时返回nil
+func stereoEvidence(collapse *StereoCollapseResult) *types.Evidence {
+	if collapse == nil {
+		return nil
+	}
+	if !collapse.Detected {
+		return &types.Evidence{Detector: "stereo", Weight: weightStereoIntact, Details: collapse.Details}
+	}
+	return &types.Evidence{Detector: "stereo", Weight: weightStereoMax * collapse.Confidence, Details: collapse.Details}
+}
+
+// dsdEvidence 把DSD来源判定转换为证据
+func dsdEvidence(origin *DSDOriginResult) types.Evidence {
+	if origin.IsFake {
+		return types.Evidence{Detector: "dsd", Weight: weightDSDConverted, Details: origin.Details}
+	}
+	return types.Evidence{Detector: "dsd", Weight: weightDSDNative, Details: origin.Details}
+}
+
+// containerEvidence 容器或编码层面已能确定为有损
+func containerEvidence(reason string) types.Evidence {
+	return types.Evidence{Detector: "container", Weight: weightContainer, Details: reason}
+}
+
+// accurateRipEvidence 与AccurateRip数据吻合时返回证据，否则返回nil
+func accurateRipEvidence(info *types.AccurateRip) *types.Evidence {
+	if info.Match != "match" {
+		return nil
+	}
+	return &types.Evidence{Detector: "accuraterip", Weight: weightAccurateRip, Details: info.Details}
+}
+
+// evidenceConfidence 把各条证据合成为有损来源的置信度 (0-1)
+func evidenceConfidence(evidence []types.Evidence) float64 {
+	logOdds := evidencePrior
+	for _, e := range evidence {
+		logOdds += e.Weight
+	}
+	return 1 / (1 + math.Exp(-logOdds))
+}
+
+// confidenceStatus 按置信度给出 OK、SUSPECT 或 FAKE
+func confidenceStatus(confidence float64) string {
+	switch {
+	case confidence >= fakeConfidence:
+		return "FAKE"
+	case confidence >= suspectConfidence:
+		return "SUSPECT"
+	default:
+		return "OK"
+	}
+}
+
+// leadingEvidence 返回与结论方向一致、权重绝对值最大的证据，用作结果说明和判定依据
+func leadingEvidence(evidence []types.Evidence, lossy bool) (types.Evidence, bool) {
+	var lead types.Evidence
+	found := false
+	for _, e := range evidence {
+		if (e.Weight > 0) != lossy || e.Weight == 0 {
+			continue
+		}
+		if !found || math.Abs(e.Weight) > math.Abs(lead.Weight) {
+			lead, found = e, true
+		}
+	}
+	return lead, found
+}
+
+// conclude 根据证据设置结果的置信度、状态、说明和判定依据
+// 说明和判定依据取自支持结论的最强证据，note 非空时附加在说明之后
+func conclude(result *types.AnalysisResult, evidence []types.Evidence, note string) {
+	confidence := evidenceConfidence(evidence)
+	result.Status = confidenceStatus(confidence)
+	result.Analysis.IsFake = result.Status == "FAKE"
+	result.Analysis.Confidence = math.Round(confidence*1000) / 1000
+	result.Analysis.Evidence = evidence
+
+	result.VerdictSource = "spectrum"
+	if lead, ok := leadingEvidence(evidence, result.Status != "OK"); ok {
+		result.Analysis.Details = lead.Details
+		switch lead.Detector {
+		case "container", "stereo", "accuraterip":
+			result.VerdictSource = lead.Detector
+		}
+	}
+	if note != "" {
+		result.Analysis.Details += "；" + note
+	}
+}
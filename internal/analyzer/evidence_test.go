@@ -0,0 +1,116 @@
+package analyzer
+
+import (
+	"math"
+	"testing"
+
+	"audio-loss-checker/internal/types"
+)
+
+// 证据权重之和（加上先验）等于边界置信度的对数几率时判定为较高的一档
+func TestConfidenceStatus(t *testing.T) {
+	logit := func(p float64) float64 { return math.Log(p / (1 - p)) }
+	const eps = 1e-9
+
+	tests := []struct {
+		weight float64
+		want   string
+	}{
+		{weight: 0, want: "OK"},
+		{weight: logit(suspectConfidence) - evidencePrior - eps, want: "OK"},
+		{weight: logit(suspectConfidence) - evidencePrior, want: "SUSPECT"},
+		{weight: logit(fakeConfidence) - evidencePrior - eps, want: "SUSPECT"},
+		{weight: logit(fakeConfidence) - evidencePrior + eps, want: "FAKE"},
+		{weight: weightContainer, want: "FAKE"},
+		{weight: weightAccurateRip, want: "OK"},
+	}
+	for _, tt := range tests {
+		confidence := evidenceConfidence([]types.Evidence{{Detector: "cutoff", Weight: tt.weight}})
+		if got := confidenceStatus(confidence); got != tt.want {
+			t.Errorf("权重 %.9f: 置信度 %.9f 判定为 %s，应为 %s", tt.weight, confidence, got, tt.want)
+		}
+	}
+
+	for confidence, want := range map[float64]string{
+		0: "OK", 0.4999: "OK", suspectConfidence: "SUSPECT", 0.7999: "SUSPECT", fakeConfidence: "FAKE", 1: "FAKE",
+	} {
+		if got := confidenceStatus(confidence); got != want {
+			t.Errorf("置信度 %g 判定为 %s，应为 %s", confidence, got, want)
+		}
+	}
+}
+
+// 典型的证据组合：判定结果、说明取自哪条证据、判定依据
+func TestConclude(t *testing.T) {
+	cutoff := types.Evidence{Detector: "cutoff", Weight: weightLowCutoff, Details: "截断"}
+	encoder := types.Evidence{Detector: "encoder", Weight: weightEncoderMatch * 0.9, Details: "编码器"}
+	steep := types.Evidence{Detector: "shelf", Weight: weightSteepShelf, Details: "陡峭"}
+	gentle := types.Evidence{Detector: "shelf", Weight: weightGentleShelf, Details: "平缓"}
+	fullBand := types.Evidence{Detector: "cutoff", Weight: weightFullBand, Details: "完整频谱"}
+	normal := types.Evidence{Detector: "cutoff", Weight: weightNarrowNormal, Details: "频谱正常"}
+	intact := types.Evidence{Detector: "stereo", Weight: weightStereoIntact, Details: "侧声道完整"}
+	collapse := types.Evidence{Detector: "stereo", Weight: weightStereoMax * 0.8, Details: "侧声道塌缩"}
+	weakCollapse := types.Evidence{Detector: "stereo", Weight: weightStereoMax * 0.5, Details: "侧声道部分塌缩"}
+	container := containerEvidence("MP3")
+	accurateRip := types.Evidence{Detector: "accuraterip", Weight: weightAccurateRip, Details: "AccurateRip"}
+
+	tests := []struct {
+		name     string
+		evidence []types.Evidence
+		status   string
+		details  string
+		source   string
+	}{
+		{name: "没有证据", status: "OK", source: "spectrum"},
+		{name: "完整频谱", evidence: []types.Evidence{fullBand, intact}, status: "OK", details: "完整频谱", source: "spectrum"},
+		{name: "只有低截断", evidence: []types.Evidence{cutoff}, status: "FAKE", details: "截断", source: "spectrum"},
+		// 平缓滚降把低截断拉回到 SUSPECT
+		{name: "低截断但平缓滚降", evidence: []types.Evidence{cutoff, gentle}, status: "SUSPECT", details: "截断", source: "spectrum"},
+		{name: "低截断、编码器吻合、陡峭", evidence: []types.Evidence{encoder, steep, cutoff}, status: "FAKE", details: "截断", source: "spectrum"},
+		// 频谱正常时侧声道塌缩是最强的证据
+		{name: "侧声道塌缩", evidence: []types.Evidence{normal, collapse}, status: "FAKE", details: "侧声道塌缩", source: "stereo"},
+		{name: "完整频谱但侧声道部分塌缩", evidence: []types.Evidence{fullBand, weakCollapse}, status: "SUSPECT", details: "侧声道部分塌缩", source: "stereo"},
+		{name: "容器", evidence: []types.Evidence{container, fullBand}, status: "FAKE", details: "MP3", source: "container"},
+		// 与原版CD逐位相同，频谱证据全部被推翻
+		{name: "AccurateRip", evidence: []types.Evidence{cutoff, encoder, steep, accurateRip}, status: "OK", details: "AccurateRip", source: "accuraterip"},
+		// 权重相同时取第一条
+		{name: "权重相同", evidence: []types.Evidence{steep, {Detector: "bandlimit", Weight: weightSteepShelf, Details: "高频段"}}, status: "FAKE", details: "陡峭", source: "spectrum"},
+	}
+
+	for _, tt := range tests {
+		var result types.AnalysisResult
+		conclude(&result, tt.evidence, "")
+		if result.Status != tt.status || result.Analysis.Details != tt.details || result.VerdictSource != tt.source {
+			t.Errorf("%s: %s（%s，依据 %s），应为 %s（%s，依据 %s）", tt.name, result.Status, result.Analysis.Details,
+				result.VerdictSource, tt.status, tt.details, tt.source)
+		}
+		if result.Analysis.IsFake != (tt.status == "FAKE") || len(result.Analysis.Evidence) != len(tt.evidence) {
+			t.Errorf("%s: %+v", tt.name, result.Analysis)
+		}
+	}
+
+	var result types.AnalysisResult
+	conclude(&result, []types.Evidence{cutoff}, "附注")
+	if result.Analysis.Details != "截断；附注" {
+		t.Errorf("附加说明后为 %q", result.Analysis.Details)
+	}
+}
+
+func TestLeadingEvidence(t *testing.T) {
+	evidence := []types.Evidence{
+		{Detector: "cutoff", Weight: 3},
+		{Detector: "stereo", Weight: -0.5},
+		{Detector: "shelf", Weight: -1},
+		{Detector: "dsd", Weight: 0},
+	}
+	if lead, ok := leadingEvidence(evidence, true); !ok || lead.Detector != "cutoff" {
+		t.Errorf("有损方向: %+v", lead)
+	}
+	if lead, ok := leadingEvidence(evidence, false); !ok || lead.Detector != "shelf" {
+		t.Errorf("无损方向: %+v", lead)
+	}
+	// 权重为0的证据不支持任何方向
+	if _, ok := leadingEvidence(evidence[3:], false); ok {
+		t.Error("权重为0的证据被用作依据")
+	}
+}
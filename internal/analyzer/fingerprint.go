@@ -0,0 +1,142 @@
+package analyzer
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+
+	"audio-loss-checker/internal/types"
+)
+
+const (
+	// fingerprintFreqSigma 最高有效频率偏离过渡带时匹配度下降的尺度 (Hz)
+	fingerprintFreqSigma = 400.0
+	// minFingerprintScore 匹配度低于此值时不认为吻合
+	minFingerprintScore = 0.5
+)
+
+// fingerprintData 内置的编码器低通指纹表
+//
+//go:embed fingerprints.json
+var fingerprintData []byte
+
+// builtinFingerprints 解析后的内置指纹表
+var builtinFingerprints = mustParseFingerprints(fingerprintData)
+
+// BuiltinFingerprints 返回内置指纹表的JSON，可作为自定义指纹表的起点
+func BuiltinFingerprints() []byte {
+	return fingerprintData
+}
+
+// LoadFingerprints 从JSON文件读取指纹表，替换内置表
+func LoadFingerprints(path string) ([]types.EncoderFingerprint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取指纹表失败: %w", err)
+	}
+	fingerprints, err := parseFingerprints(data)
+	if err != nil {
+		return nil, fmt.Errorf("解析指纹表 %s 失败: %w", path, err)
+	}
+	return fingerprints, nil
+}
+
+// parseFingerprints 解析并校验指纹表
+func parseFingerprints(data []byte) ([]types.EncoderFingerprint, error) {
+	var fingerprints []types.EncoderFingerprint
+	if err := json.Unmarshal(data, &fingerprints); err != nil {
+		return nil, err
+	}
+	if len(fingerprints) == 0 {
+		return nil, fmt.Errorf("指纹表为空")
+	}
+	for i, fp := range fingerprints {
+		switch {
+		case fp.Codec == "":
+			return nil, fmt.Errorf("第%d项缺少 codec", i+1)
+		case fp.Lowpass <= 0:
+			return nil, fmt.Errorf("第%d项 (%s) 的 lowpass 必须大于0", i+1, fp.Codec)
+		case fp.Transition < 0:
+			return nil, fmt.Errorf("第%d项 (%s) 的 transition 不能为负数", i+1, fp.Codec)
+		case fp.Shelf != "brick" && fp.Shelf != "soft":
+			return nil, fmt.Errorf("第%d项 (%s) 的 shelf 必须为 brick 或 soft: %q", i+1, fp.Codec, fp.Shelf)
+		}
+	}
+	return fingerprints, nil
+}
+
+// mustParseFingerprints 解析内置指纹表，内置表有误属于编程错误
+func mustParseFingerprints(data []byte) []types.EncoderFingerprint {
+	fingerprints, err := parseFingerprints(data)
+	if err != nil {
+		panic(fmt.Sprintf("内置指纹表无效: %v", err))
+	}
+	return fingerprints
+}
+
+// matchFingerprint 按最高有效频率和截断处的陡峭程度为每个指纹打分，返回得分最高的一个
+// 最高有效频率落在 [lowpass, lowpass+transition] 内时频率得分为1，偏离越远越低；
+// 截断两侧电平差 (shelfDrop) 与指纹的截断形状相符时加分，无法测量时 measured 为false
+func matchFingerprint(fingerprints []types.EncoderFingerprint, maxFreq, shelfDrop float64, measured bool) *types.EncoderMatch {
+	var best *types.EncoderMatch
+	var bestScore, bestCenter float64
+	for _, fp := range fingerprints {
+		distance := 0.0
+		if maxFreq < fp.Lowpass {
+			distance = fp.Lowpass - maxFreq
+		} else if maxFreq > fp.Lowpass+fp.Transition {
+			distance = maxFreq - fp.Lowpass - fp.Transition
+		}
+		freqScore := math.Exp(-0.5 * math.Pow(distance/fingerprintFreqSigma, 2))
+
+		shelfScore := 0.5
+		if measured {
+			shelfScore = shelfShapeScore(fp.Shelf, shelfDrop)
+		}
+		score := freqScore * (0.4 + 0.6*shelfScore)
+
+		// 过渡带相互重叠时，取过渡带中心离最高有效频率最近的一个
+		center := math.Abs(maxFreq - fp.Lowpass - fp.Transition/2)
+		if score >= minFingerprintScore && (best == nil || score > bestScore || score == bestScore && center < bestCenter) {
+			bestScore, bestCenter = score, center
+			best = &types.EncoderMatch{
+				Codec:   fp.Codec,
+				Profile: fp.Profile,
+				Bitrate: fp.Bitrate,
+				Lowpass: fp.Lowpass,
+				Score:   math.Round(score*100) / 100,
+			}
+		}
+	}
+	return best
+}
+
+// shelfShapeScore 截断两侧电平差与截断形状的相符程度 (0-1)
+func shelfShapeScore(shape string, drop float64) float64 {
+	if shape == "soft" {
+		switch {
+		case drop < shelfGentleDB:
+			return 0
+		case drop <= 45:
+			return 1
+		default:
+			return 0.6
+		}
+	}
+	// brick: 达到陡峭标准得满分，平缓滚降得0分
+	return math.Max(0, math.Min(1, (drop-shelfGentleDB)/(shelfSteepDB-shelfGentleDB)))
+}
+
+// describeEncoder 编码器的可读名称，如 "MP3 (LAME) V0 ~245 kbps"
+func describeEncoder(match *types.EncoderMatch) string {
+	name := match.Codec
+	if match.Profile != "" {
+		name += " " + match.Profile
+	}
+	if match.Bitrate != "" {
+		name += " " + match.Bitrate
+	}
+	return name
+}
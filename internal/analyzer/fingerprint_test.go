@@ -0,0 +1,121 @@
+package analyzer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMatchFingerprint(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxFreq  float64
+		drop     float64
+		measured bool
+		codec    string // 为空表示不应匹配
+		bitrate  string
+		profile  string
+	}{
+		// 过渡带相互重叠时取中心最近的一个
+		{name: "LAME V0", maxFreq: 19750, drop: 60, measured: true, codec: "MP3 (LAME)", profile: "V0"},
+		{name: "LAME V0 偏高", maxFreq: 19800, drop: 45, measured: true, codec: "MP3 (LAME)", profile: "V0"},
+		// 较缓的滚降只与soft形状的指纹相符
+		{name: "AAC 256", maxFreq: 20000, drop: 20, measured: true, codec: "AAC-LC", bitrate: "256 kbps"},
+		{name: "AAC 256 偏高", maxFreq: 20300, drop: 25, measured: true, codec: "AAC-LC", bitrate: "256 kbps"},
+		{name: "AAC 128", maxFreq: 16400, drop: 25, measured: true, codec: "AAC-LC", bitrate: "128 kbps"},
+		{name: "Opus", maxFreq: 20100, drop: 60, measured: true, codec: "Opus"},
+		{name: "Opus 偏高", maxFreq: 20150, drop: 50, measured: true, codec: "Opus"},
+		{name: "LAME CBR 128", maxFreq: 17200, drop: 50, measured: true, codec: "MP3 (LAME)", bitrate: "128 kbps"},
+		// 截断两侧无法测量时只按频率打分
+		{name: "无法测量陡峭程度", maxFreq: 19750, codec: "MP3 (LAME)", profile: "V0"},
+		// 完整频谱，高于所有编码器的低通
+		{name: "完整频谱", maxFreq: 21500, drop: 0, measured: true},
+		{name: "完整频谱（无法测量）", maxFreq: 21800},
+		// 录音本身的平缓衰减不像任何编码器
+		{name: "平缓滚降", maxFreq: 16500, drop: 5, measured: true},
+	}
+
+	for _, tt := range tests {
+		match := matchFingerprint(builtinFingerprints, tt.maxFreq, tt.drop, tt.measured)
+		if tt.codec == "" {
+			if match != nil {
+				t.Errorf("%s: 匹配到 %s（%.2f）", tt.name, describeEncoder(match), match.Score)
+			}
+			continue
+		}
+		if match == nil {
+			t.Errorf("%s: 没有匹配", tt.name)
+			continue
+		}
+		if match.Codec != tt.codec || (tt.bitrate != "" && match.Bitrate != tt.bitrate) ||
+			(tt.profile != "" && match.Profile != tt.profile) || match.Score < minFingerprintScore {
+			t.Errorf("%s: 匹配到 %s（%.2f）", tt.name, describeEncoder(match), match.Score)
+		}
+	}
+}
+
+func TestShelfShapeScore(t *testing.T) {
+	tests := []struct {
+		shape string
+		drop  float64
+		want  float64
+	}{
+		{"brick", shelfGentleDB, 0},
+		{"brick", (shelfGentleDB + shelfSteepDB) / 2, 0.5},
+		{"brick", shelfSteepDB, 1},
+		{"brick", 80, 1},
+		{"soft", shelfGentleDB - 1, 0},
+		{"soft", 30, 1},
+		{"soft", 60, 0.6},
+	}
+	for _, tt := range tests {
+		if got := shelfShapeScore(tt.shape, tt.drop); got != tt.want {
+			t.Errorf("%s %.0f dB: %g，应为 %g", tt.shape, tt.drop, got, tt.want)
+		}
+	}
+}
+
+func TestLoadFingerprints(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string // 为空表示应读取成功
+	}{
+		{"有效", `[{"codec": "Test", "lowpass": 16000, "transition": 500, "shelf": "brick"}]`, ""},
+		{"JSON格式错误", `[{"codec": "Test", "lowpass": 16000,]`, "解析指纹表"},
+		{"字段类型错误", `[{"codec": "Test", "lowpass": "16k", "shelf": "brick"}]`, "解析指纹表"},
+		{"空表", `[]`, "指纹表为空"},
+		{"缺少codec", `[{"lowpass": 16000, "shelf": "brick"}]`, "第1项缺少 codec"},
+		{"lowpass为0", `[{"codec": "A", "lowpass": 16000, "shelf": "soft"}, {"codec": "B", "shelf": "brick"}]`, "第2项 (B) 的 lowpass"},
+		{"transition为负数", `[{"codec": "A", "lowpass": 16000, "transition": -1, "shelf": "brick"}]`, "transition 不能为负数"},
+		{"shelf无效", `[{"codec": "A", "lowpass": 16000, "shelf": "steep"}]`, "shelf 必须为 brick 或 soft"},
+	}
+
+	for i, tt := range tests {
+		path := filepath.Join(dir, fmt.Sprintf("%02d.json", i))
+		if err := os.WriteFile(path, []byte(tt.data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		fingerprints, err := LoadFingerprints(path)
+		if tt.wantErr == "" {
+			if err != nil || len(fingerprints) != 1 || fingerprints[0].Codec != "Test" {
+				t.Errorf("%s: %v %+v", tt.name, err, fingerprints)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: 错误为 %v，应包含 %q", tt.name, err, tt.wantErr)
+		}
+	}
+
+	if _, err := LoadFingerprints(filepath.Join(dir, "missing.json")); err == nil || !strings.Contains(err.Error(), "读取指纹表失败") {
+		t.Errorf("文件不存在时错误为 %v", err)
+	}
+	// 内置表可以原样作为自定义指纹表使用
+	if fingerprints, err := parseFingerprints(BuiltinFingerprints()); err != nil || len(fingerprints) != len(builtinFingerprints) {
+		t.Errorf("内置指纹表: %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package analyzer
+
+import "fmt"
+
+const (
+	// hiresMinSampleRate 采样率高于此值时检查是否由较低采样率升频而来
+	hiresMinSampleRate = 48000
+	// upsamplingDropDB 截断两侧电平差超过该值时视为重采样滤波器留下的砖墙
+	upsamplingDropDB = 20.0
+	// upsamplingSearchLow 砖墙搜索范围的下限，为原采样率奈奎斯特频率的比例
+	// 重采样和原始ADC的抗混叠滤波通常在奈奎斯特频率的85%-100%之间截止
+	upsamplingSearchLow = 0.85
+	// upsamplingSearchHigh 砖墙搜索范围的上限，为原采样率奈奎斯特频率的比例
+	upsamplingSearchHigh = 1.01
+)
+
+// standardSampleRates 升频前可能的原始采样率，从低到高
+var standardSampleRates = []int{44100, 48000, 88200, 96000}
+
+// UpsamplingResult 升频检测结果
+type UpsamplingResult struct {
+	OriginalRate int        // 推断的原始采样率
+	Wall         *BrickWall // 原采样率奈奎斯特频率附近的砖墙截断
+	Details      string
+}
+
+// detectUpsampling 在高采样率音频中寻找终止于较低标准采样率奈奎斯特频率的砖墙截断
+// 例如由44.1kHz CD母带升频得到的24/96文件，在22.05kHz附近存在陡峭截断，其上方只剩噪声
+// 没有发现砖墙或采样率不高于48kHz时返回nil
+func detectUpsampling(sink *brickWallSink, sampleRate int) *UpsamplingResult {
+	if sink == nil || sampleRate <= hiresMinSampleRate {
+		return nil
+	}
+	nyquist := float64(sampleRate) / 2
+
+	// 候选原始采样率的奈奎斯特频率必须明显低于当前奈奎斯特频率
+	var candidates []int
+	for _, rate := range standardSampleRates {
+		if float64(rate)/2*upsamplingSearchHigh < nyquist*0.95 {
+			candidates = append(candidates, rate)
+		}
+	}
+
+	// 从低到高，每个候选只在自己奈奎斯特频率附近的窗口内寻找砖墙，避免远低于它的截断被归到它名下
+	for _, rate := range candidates {
+		wall := sink.detect(float64(rate)/2*upsamplingSearchLow, float64(rate)/2*upsamplingSearchHigh)
+		if wall == nil || wall.DropDB < upsamplingDropDB {
+			continue
+		}
+		return &UpsamplingResult{
+			OriginalRate: rate,
+			Wall:         wall,
+			Details: fmt.Sprintf("在 %.0f Hz 处存在砖墙截断（下降 %.0f dB），其上方没有内容，疑似由 %.1f kHz 升频至 %.1f kHz",
+				wall.Frequency, wall.DropDB, float64(rate)/1000, float64(sampleRate)/1000),
+		}
+	}
+	return nil
+}
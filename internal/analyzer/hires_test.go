@@ -0,0 +1,48 @@
+package analyzer
+
+import "testing"
+
+// wallSink 返回一个已累积好功率谱的砖墙检测器：wall 以下为0dB，以上为-80dB
+func wallSink(sampleRate int, wall float64) *brickWallSink {
+	s := NewSpectrumAnalyzer(sampleRate)
+	sink := s.newBrickWallSink(1, int64(s.windowSize))
+	freqResolution := float64(sampleRate) / float64(s.windowSize)
+	for k := range sink.avgPower {
+		if float64(k)*freqResolution < wall {
+			sink.avgPower[k] = 1
+		} else {
+			sink.avgPower[k] = 1e-8
+		}
+	}
+	sink.captured = 1
+	return sink
+}
+
+func TestDetectUpsampling(t *testing.T) {
+	tests := []struct {
+		sampleRate int
+		wall       float64
+		want       int // 0 表示不应判定为升频
+	}{
+		{96000, 22050, 44100},
+		{96000, 24000, 48000},
+		{192000, 44100, 88200},
+		{192000, 48000, 96000},
+		// 截断落在两个候选的窗口之间，不属于任何标准采样率
+		{96000, 30000, 0},
+		{192000, 33000, 0},
+		// 有损编码常见的低通，远低于任何候选
+		{96000, 16000, 0},
+	}
+
+	for _, tt := range tests {
+		result := detectUpsampling(wallSink(tt.sampleRate, tt.wall), tt.sampleRate)
+		got := 0
+		if result != nil {
+			got = result.OriginalRate
+		}
+		if got != tt.want {
+			t.Errorf("%d Hz 采样率、%.0f Hz 截断: 原始采样率为 %d，应为 %d", tt.sampleRate, tt.wall, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+
+	"audio-loss-checker/internal/types"
+)
+
+// checkIntegrity 采样流能够检查解码完整性时返回检查器，verify 为true时同时启用MD5校验
+func checkIntegrity(reader types.SampleReader, verify bool) types.IntegrityChecker {
+	checker, ok := reader.(types.IntegrityChecker)
+	if !ok {
+		return nil
+	}
+	if verify {
+		checker.EnableMD5()
+	}
+	return checker
+}
+
+// integrityStatus 按完整性检查结果给出状态，没有发现问题时返回空字符串
+// 截断优先：缺少的数据无法通过分析弥补，需要重新获取文件
+func integrityStatus(integrity *types.Integrity) string {
+	switch {
+	case integrity.Truncated:
+		return "TRUNCATED"
+	case integrity.FrameErrors > 0 || integrity.Stopped || integrity.MD5 == "mismatch":
+		return "CORRUPT"
+	}
+	return ""
+}
+
+// applyIntegrity 记录完整性检查结果；发现问题时状态改为 CORRUPT 或 TRUNCATED，
+// 说明中保留原先的判定，有损来源的置信度和证据不变
+// 没有启用校验且没有发现问题时不记录，避免每个结果都带上同样的内容
+func applyIntegrity(result *types.AnalysisResult, integrity *types.Integrity, verify bool) {
+	if integrity == nil {
+		return
+	}
+	status := integrityStatus(integrity)
+	if status == "" && !verify {
+		return
+	}
+	result.Integrity = integrity
+	if status == "" {
+		return
+	}
+	// 已解码的部分无法分析（如截断后剩下的采样太少）时，在错误中说明原因
+	if result.Error != "" {
+		result.Error = fmt.Sprintf("%s（%s）", result.Error, integrity.Details)
+		return
+	}
+	result.Analysis.Details = fmt.Sprintf("%s；已解码部分的分析结果为 %s: %s", integrity.Details, result.Status, result.Analysis.Details)
+	result.Status = status
+	result.VerdictSource = "integrity"
+}
+
+// trackIntegrity 从整轨镜像的检查结果中截取 [start, end) 范围内的音轨部分，end 小于0表示直到镜像结尾
+// 帧错误按位置归入音轨；截断或中途停止只影响延伸到解码结束位置之后的音轨；MD5针对整个镜像，不符时无法定位，归入所有音轨
+func trackIntegrity(image *types.Integrity, start, end int64) *types.Integrity {
+	if image == nil {
+		return nil
+	}
+	if end < 0 {
+		end = image.ExpectedSamples
+	}
+	track := *image
+	track.ExpectedSamples, track.DecodedSamples = 0, max(0, image.DecodedSamples-start)
+	if end > 0 {
+		track.ExpectedSamples = end - start
+		track.DecodedSamples = min(track.DecodedSamples, track.ExpectedSamples)
+	}
+
+	track.Errors, track.FrameErrors = nil, 0
+	for _, e := range image.Errors {
+		if e.Position >= start && (end <= 0 || e.Position < end) {
+			track.Errors = append(track.Errors, e)
+			track.FrameErrors++
+		}
+	}
+	// 超过记录上限的错误无法定位，保守地计入所有音轨
+	track.FrameErrors += image.FrameErrors - len(image.Errors)
+	// 镜像中有帧错误时MD5不符已有解释，不再归入没有错误的音轨
+	if image.MD5 == "mismatch" && image.FrameErrors > 0 {
+		track.MD5, track.ExpectedMD5, track.ActualMD5 = "", "", ""
+	}
+
+	// 音轨延伸到解码结束位置之后（未知长度的最后一轨也算）时才受截断或中途停止的影响
+	incomplete := end <= 0 || end > image.DecodedSamples
+	track.Stopped = image.Stopped && incomplete
+	track.Truncated = image.Truncated && incomplete
+
+	var details []string
+	if track.FrameErrors > 0 {
+		details = append(details, fmt.Sprintf("本音轨范围内 %d 个帧校验失败或无法解析", track.FrameErrors))
+	}
+	if track.Stopped {
+		details = append(details, "解码在本音轨结束之前停止")
+	}
+	if track.Truncated {
+		details = append(details, "镜像在本音轨结束之前被截断")
+	}
+	if len(details) == 0 {
+		details = append(details, "本音轨范围内没有发现帧错误")
+	}
+	track.Details = fmt.Sprintf("%s（整个镜像: %s）", strings.Join(details, "；"), image.Details)
+	return &track
+}
@@ -0,0 +1,450 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"audio-loss-checker/internal/types"
+
+	"github.com/schollz/progressbar/v3"
+)
+
+// Output 命令行输出：逐个输出分析结果，全部完成后输出专辑汇总、统计和HTML报告
+type Output struct {
+	config *types.OutputConfig
+	bar    *progressbar.ProgressBar
+}
+
+// NewOutput 创建命令行输出，total 为要分析的文件数，用于显示进度
+func NewOutput(config *types.OutputConfig, total int) *Output {
+	o := &Output{config: config}
+	if !config.Quiet && !config.JSONOutput {
+		o.bar = progressbar.NewOptions(total,
+			progressbar.OptionSetDescription("分析音频文件"),
+			progressbar.OptionShowCount(),
+			progressbar.OptionSetWidth(50),
+			progressbar.OptionShowIts(),
+		)
+	}
+	return o
+}
+
+// FileDone 输出一个文件的分析结果并更新进度，用作 Analyzer.Analyze 的回调
+func (o *Output) FileDone(path string, results []*types.AnalysisResult) {
+	for _, result := range results {
+		o.outputResult(result)
+	}
+	if o.bar != nil {
+		o.bar.Add(1)
+	}
+}
+
+// Finish 结束进度显示，输出专辑汇总和统计信息，并生成HTML报告
+func (o *Output) Finish(report *types.AnalysisReport) error {
+	if o.bar != nil {
+		o.bar.Finish()
+		fmt.Println() // 换行
+	}
+
+	// JSON输出时每张专辑一行，排在所有文件结果之后
+	if o.config.JSONOutput && !o.config.Quiet {
+		o.outputAlbums(report.Albums)
+	}
+
+	// 输出统计信息
+	if !o.config.Quiet && !o.config.JSONOutput {
+		printSummary(report.Results)
+		printAlbums(report.Albums)
+		if report.Cached {
+			fmt.Printf("缓存命中: %d / %d 个文件\n", report.CacheHits, report.Paths)
+		}
+	}
+
+	// 生成HTML报告
+	if o.config.HTMLReport != "" {
+		if err := writeHTMLReport(o.config.HTMLReport, report.Results, report.Albums); err != nil {
+			return fmt.Errorf("生成HTML报告失败: %w", err)
+		}
+		if !o.config.Quiet && !o.config.JSONOutput {
+			fmt.Printf("\nHTML报告已写入: %s\n", o.config.HTMLReport)
+		}
+	}
+
+	return nil
+}
+
+// outputResult 输出单个分析结果
+func (o *Output) outputResult(result *types.AnalysisResult) {
+	// 如果只显示假无损文件，跳过正常文件
+	if o.config.OnlyFake && !isFakeStatus(result.Status) {
+		return
+	}
+
+	// 静默模式，只输出假无损（含假高解析度）文件路径
+	if o.config.Quiet {
+		if isFakeStatus(result.Status) {
+			if result.Track != nil {
+				fmt.Printf("%s (音轨 %02d)\n", result.FilePath, result.Track.Number)
+			} else {
+				fmt.Println(result.FilePath)
+			}
+		}
+		return
+	}
+
+	// JSON输出格式
+	if o.config.JSONOutput {
+		jsonData, err := json.Marshal(result)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "JSON序列化失败: %v\n", err)
+			return
+		}
+		fmt.Println(string(jsonData))
+		return
+	}
+
+	// 普通格式输出
+	printDetailedResult(result)
+}
+
+// outputAlbums 以JSON输出专辑汇总，每行一个 {"album": {...}} 对象，与文件结果区分
+func (o *Output) outputAlbums(albums []*types.AlbumResult) {
+	for _, album := range albums {
+		if o.config.OnlyFake && album.Status == "OK" {
+			continue
+		}
+		jsonData, err := json.Marshal(struct {
+			Album *types.AlbumResult `json:"album"`
+		}{album})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "JSON序列化失败: %v\n", err)
+			continue
+		}
+		fmt.Println(string(jsonData))
+	}
+}
+
+// isFakeStatus 判断状态是否为假无损或假高解析度
+func isFakeStatus(status string) bool {
+	return status == "FAKE" || status == "FAKE_HIRES"
+}
+
+// printDetailedResult 打印详细结果
+func printDetailedResult(result *types.AnalysisResult) {
+	if t := result.Track; t != nil {
+		fmt.Printf("\n=== %s [音轨 %02d] ===\n", filepath.Base(result.FilePath), t.Number)
+		fmt.Printf("路径: %s\n", result.FilePath)
+		fmt.Printf("CUE: %s\n", t.CueSheet)
+		fmt.Printf("音轨区间: %s - %s\n", formatTimestamp(t.Start), formatTimestamp(t.End))
+	} else {
+		fmt.Printf("\n=== %s ===\n", filepath.Base(result.FilePath))
+		fmt.Printf("路径: %s\n", result.FilePath)
+	}
+	fmt.Printf("格式: %s\n", result.Format)
+	fmt.Printf("状态: %s\n", result.Status)
+	if m := result.FormatMismatch; m != nil {
+		fmt.Printf("⚠️  扩展名不符: 扩展名为 .%s，实际内容为 %s\n", m.Extension, m.Content)
+	}
+
+	if result.Error != "" {
+		fmt.Printf("错误: %s\n", result.Error)
+		return
+	}
+
+	// 基本信息（内容为有损编码时未解码，没有这些信息）
+	decoded := result.Analysis.SampleRate > 0
+	if decoded {
+		fmt.Printf("采样率: %d Hz\n", result.Analysis.SampleRate)
+		fmt.Printf("位深度: %d bit\n", result.Analysis.BitDepth)
+		fmt.Printf("声道数: %d\n", result.Analysis.Channels)
+		fmt.Printf("时长: %.2f 秒\n", result.Analysis.Duration)
+	}
+	if rate := result.Analysis.DSDRate; rate > 0 {
+		// DSD64/128/256 为44.1kHz的倍数，48kHz系列按48kHz计算
+		multiple := rate / 44100
+		if rate%44100 != 0 {
+			multiple = rate / 48000
+		}
+		fmt.Printf("DSD采样率: %.4f MHz (DSD%d)\n", float64(rate)/1e6, multiple)
+	}
+
+	// 元数据
+	if result.Metadata.Title != "" {
+		fmt.Printf("标题: %s\n", result.Metadata.Title)
+	}
+	if result.Metadata.Artist != "" {
+		fmt.Printf("艺术家: %s\n", result.Metadata.Artist)
+	}
+	if result.Metadata.Album != "" {
+		fmt.Printf("专辑: %s\n", result.Metadata.Album)
+	}
+
+	// 频谱分析结果
+	if decoded {
+		fmt.Printf("最高有效频率: %.0f Hz\n", result.Analysis.MaxFrequency)
+	}
+	if channels := result.Analysis.PerChannel; len(channels) > 1 {
+		for _, c := range channels {
+			if c.Silent {
+				fmt.Printf("  声道 %d: 静音\n", c.Channel)
+				continue
+			}
+			fmt.Printf("  声道 %d: 最高有效频率 %.0f Hz，截断频率 %.0f Hz\n", c.Channel, c.MaxFrequency, c.CutoffHz)
+		}
+	}
+	if result.Analysis.Windows > 0 {
+		fmt.Printf("分析窗口: %d 个，%.0f%% 的窗口最高频率不超过 %.0f Hz\n",
+			result.Analysis.Windows, result.Analysis.CutoffOccurrence*100, result.Analysis.MaxFrequency)
+	}
+	if result.Analysis.CutoffHz > 0 {
+		fmt.Printf("截断频率: %.0f Hz\n", result.Analysis.CutoffHz)
+	}
+	if m := result.Analysis.EncoderMatch; m != nil {
+		fmt.Printf("疑似来源编码: %s（匹配度 %.0f%%）\n", describeEncoder(m), m.Score*100)
+	}
+	if b := result.Analysis.EffectiveBitDepth; b != nil && b.Mismatch {
+		fmt.Printf("有效位深度: %d bit（%s）\n", b.Bits, b.Details)
+	}
+	if u := result.Analysis.Upsampling; u != nil {
+		fmt.Printf("升频检测: 疑似由 %.1f kHz 升频（%.0f Hz 处截断，下降 %.0f dB）\n",
+			float64(u.OriginalSampleRate)/1000, u.Frequency, u.DropDB)
+	}
+	if c := result.Analysis.StereoCollapse; c != nil {
+		if c.Detected {
+			fmt.Printf("侧声道塌缩: %.0f Hz 以上下降 %.0f dB（置信度 %.0f%%）\n", c.Frequency, c.DropDB, c.Confidence*100)
+		} else {
+			fmt.Printf("侧声道塌缩: 未检测到\n")
+		}
+	}
+	if len(result.Analysis.Segments) > 0 {
+		printSegmentTimeline(result.Analysis.Segments)
+	}
+	if in := result.Integrity; in != nil {
+		printIntegrity(in, result.Analysis.SampleRate)
+	}
+	if l := result.RipLog; l != nil {
+		printRipLog(l)
+	}
+	if r := result.AccurateRip; r != nil {
+		printAccurateRip(r)
+	}
+	fmt.Printf("分析结果: %s\n", result.Analysis.Details)
+	fmt.Printf("有损来源置信度: %.0f%%\n", result.Analysis.Confidence*100)
+	if len(result.Analysis.Evidence) > 0 {
+		fmt.Printf("判定证据:\n")
+		for _, e := range result.Analysis.Evidence {
+			fmt.Printf("  [%+.1f] %s: %s\n", e.Weight, e.Detector, e.Details)
+		}
+	}
+	switch result.VerdictSource {
+	case "container":
+		fmt.Printf("判定依据: 容器格式\n")
+	case "stereo":
+		fmt.Printf("判定依据: 侧声道塌缩\n")
+	case "hires":
+		fmt.Printf("判定依据: 高解析度检测（采样率或位深度）\n")
+	case "integrity":
+		fmt.Printf("判定依据: 解码完整性检查\n")
+	case "accuraterip":
+		fmt.Printf("判定依据: AccurateRip校验和\n")
+	}
+
+	switch result.Status {
+	case "FAKE":
+		fmt.Printf("⚠️  警告: 这可能是一个假无损文件！\n")
+	case "FAKE_HIRES":
+		fmt.Printf("⚠️  警告: 这可能是由CD规格升频或填充位深度而来的假高解析度文件！\n")
+	case "CORRUPT":
+		fmt.Printf("⚠️  警告: 文件数据已损坏，解码结果与原始音频不一致！\n")
+	case "TRUNCATED":
+		fmt.Printf("⚠️  警告: 文件不完整，音频数据提前结束！\n")
+	case "SUSPECT":
+		fmt.Printf("❓ 证据不足以定论，建议结合频谱图人工确认\n")
+	default:
+		fmt.Printf("✅ 文件看起来是真实的无损音频\n")
+	}
+}
+
+// printIntegrity 打印解码完整性检查的结果和最先遇到的几个帧错误
+// 错误位置按采样率换算为时间，CUE分轨时为在整个镜像中的位置
+func printIntegrity(in *types.Integrity, sampleRate int) {
+	fmt.Printf("完整性检查: %s\n", in.Details)
+	if in.ExpectedSamples > 0 {
+		fmt.Printf("  采样数: %d / %d\n", in.DecodedSamples, in.ExpectedSamples)
+	}
+	if in.MD5 == "mismatch" {
+		fmt.Printf("  MD5: 文件记录 %s，实际 %s\n", in.ExpectedMD5, in.ActualMD5)
+	}
+	for _, e := range in.Errors {
+		if sampleRate > 0 {
+			fmt.Printf("  %s 处的帧: %s\n", formatTimestamp(float64(e.Position)/float64(sampleRate)), e.Message)
+		} else {
+			fmt.Printf("  第 %d 个采样处的帧: %s\n", e.Position, e.Message)
+		}
+	}
+}
+
+// printRipLog 打印抓轨日志中的记录和CRC核对结果
+func printRipLog(l *types.RipLog) {
+	var setup []string
+	if l.Drive != "" {
+		setup = append(setup, "驱动器: "+l.Drive)
+	}
+	if l.ReadMode != "" {
+		setup = append(setup, "读取模式: "+l.ReadMode)
+	}
+	fmt.Printf("抓轨日志: %s %s", filepath.Base(l.Path), l.Ripper)
+	if len(setup) > 0 {
+		fmt.Printf("（%s）", strings.Join(setup, "，"))
+	}
+	fmt.Println()
+	if l.CopyCRC != "" {
+		scope := ""
+		if l.Range {
+			scope = "整个镜像，"
+		}
+		fmt.Printf("  日志CRC: %s测试 %s，复制 %s\n", scope, orDash(l.TestCRC), l.CopyCRC)
+	}
+	if l.AccurateRipDetails != "" {
+		fmt.Printf("  AccurateRip（日志）: %s\n", l.AccurateRipDetails)
+	}
+	if l.CRCMatch == "mismatch" {
+		fmt.Printf("  ⚠️  %s\n", l.Details)
+	} else if l.Details != "" {
+		fmt.Printf("  %s\n", l.Details)
+	}
+}
+
+// printAccurateRip 打印AccurateRip校验和及与本地数据的核对结果
+func printAccurateRip(r *types.AccurateRip) {
+	fmt.Printf("AccurateRip: v1 %s，v2 %s", r.V1, r.V2)
+	switch {
+	case r.Track > 0 && r.TrackCount > 0:
+		fmt.Printf("（第 %d/%d 轨）", r.Track, r.TrackCount)
+	case r.Position == "unknown":
+		fmt.Printf("（无法确定音轨位置，按中间音轨计算）")
+	}
+	fmt.Println()
+	if r.DiscID != "" {
+		fmt.Printf("  光盘标识: %s\n", r.DiscID)
+	}
+	if r.Match == "match" {
+		fmt.Printf("  ✅ %s\n", r.Details)
+	} else if r.Details != "" {
+		fmt.Printf("  %s\n", r.Details)
+	}
+}
+
+// orDash 空字符串显示为 "-"
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// printSegmentTimeline 打印分段时间线：每段的最高有效频率 (kHz)，与其余部分明显不同的分段用方括号标出，静音分段显示为 --
+func printSegmentTimeline(segments []types.Segment) {
+	fmt.Printf("分段时间线（每段 %.0f 秒，最高有效频率 kHz，[ ] 为与其余部分明显不同的分段）:\n", segments[0].End-segments[0].Start)
+	for i := 0; i < len(segments); i += segmentsPerRow {
+		row := segments[i:min(i+segmentsPerRow, len(segments))]
+		var cells []string
+		for _, s := range row {
+			switch {
+			case s.Silent:
+				cells = append(cells, "  --  ")
+			case s.Deviant:
+				cells = append(cells, fmt.Sprintf("[%4.1f]", s.MaxFrequency/1000))
+			default:
+				cells = append(cells, fmt.Sprintf(" %4.1f ", s.MaxFrequency/1000))
+			}
+		}
+		fmt.Printf("  %s %s\n", formatTimestamp(row[0].Start), strings.TrimRight(strings.Join(cells, ""), " "))
+	}
+}
+
+// formatTimestamp 把秒数格式化为 分:秒.百分秒
+func formatTimestamp(seconds float64) string {
+	minutes := int(seconds) / 60
+	return fmt.Sprintf("%02d:%05.2f", minutes, seconds-float64(minutes*60))
+}
+
+// summary 分析结果统计
+type summary struct {
+	Total     int
+	OK        int
+	Suspect   int
+	Fake      int
+	FakeHiRes int
+	Corrupt   int
+	Truncated int
+	Errors    int
+}
+
+// summarize 按状态统计分析结果
+func summarize(results []*types.AnalysisResult) summary {
+	stats := summary{Total: len(results)}
+	for _, result := range results {
+		switch result.Status {
+		case "FAKE":
+			stats.Fake++
+		case "OK":
+			stats.OK++
+		case "SUSPECT":
+			stats.Suspect++
+		case "FAKE_HIRES":
+			stats.FakeHiRes++
+		case "CORRUPT":
+			stats.Corrupt++
+		case "TRUNCATED":
+			stats.Truncated++
+		case "ERROR":
+			stats.Errors++
+		}
+	}
+	return stats
+}
+
+// printSummary 打印统计摘要
+func printSummary(results []*types.AnalysisResult) {
+	stats := summarize(results)
+
+	fmt.Printf("\n=== 分析统计 ===\n")
+	fmt.Printf("总文件数: %d\n", stats.Total)
+	fmt.Printf("正常文件: %d\n", stats.OK)
+	if stats.Suspect > 0 {
+		fmt.Printf("疑似假无损文件: %d\n", stats.Suspect)
+	}
+	fmt.Printf("假无损文件: %d\n", stats.Fake)
+	if stats.FakeHiRes > 0 {
+		fmt.Printf("假高解析度文件: %d\n", stats.FakeHiRes)
+	}
+	if stats.Corrupt > 0 {
+		fmt.Printf("损坏文件: %d\n", stats.Corrupt)
+	}
+	if stats.Truncated > 0 {
+		fmt.Printf("截断文件: %d\n", stats.Truncated)
+	}
+	if stats.Errors > 0 {
+		fmt.Printf("错误文件: %d\n", stats.Errors)
+	}
+	if stats.Corrupt > 0 || stats.Truncated > 0 {
+		fmt.Printf("\n⚠️  发现 %d 个数据损坏或被截断的文件，建议重新获取\n", stats.Corrupt+stats.Truncated)
+	}
+
+	if stats.Fake > 0 || stats.FakeHiRes > 0 {
+		if stats.Fake > 0 {
+			fmt.Printf("\n⚠️  发现 %d 个可疑的假无损文件，建议进一步检查！\n", stats.Fake)
+		}
+		if stats.FakeHiRes > 0 {
+			fmt.Printf("\n⚠️  发现 %d 个由较低采样率升频或填充位深度而来的假高解析度文件\n", stats.FakeHiRes)
+		}
+	} else if stats.Suspect > 0 {
+		fmt.Printf("\n❓ 没有确定的假无损文件，%d 个文件证据不足，建议人工确认\n", stats.Suspect)
+	} else if stats.Corrupt == 0 && stats.Truncated == 0 {
+		fmt.Printf("\n✅ 所有文件都看起来是真实的无损音频\n")
+	}
+}
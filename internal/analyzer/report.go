@@ -0,0 +1,345 @@
+package analyzer
+
+import (
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"audio-loss-checker/internal/types"
+)
+
+// reportRow HTML报告表格中的一行
+type reportRow struct {
+	FilePath      string
+	Name          string
+	Track         string
+	TrackNumber   int
+	Format        string
+	Status        string
+	SampleRate    int
+	BitDepth      int
+	Duration      float64
+	DurationText  string
+	MaxFrequency  float64
+	CutoffHz      float64
+	VerdictSource string
+	Confidence    float64
+	Details       string
+	Error         string
+	Mismatch      string
+	RipLog        string // 与抓轨日志的CRC不符时的提示
+	Spectrogram   template.URL
+}
+
+// albumRow HTML报告专辑表格中的一行
+type albumRow struct {
+	Name         string
+	Path         string // CUE文件或目录
+	Status       string
+	Tracks       int
+	Fake         int
+	Suspect      int
+	MedianCutoff float64
+	Consistency  float64
+	Details      string
+}
+
+// reportData HTML报告模板的数据
+type reportData struct {
+	Generated string
+	Summary   summary
+	Albums    []albumRow
+	Rows      []reportRow
+}
+
+// writeHTMLReport 生成单个离线HTML文件：统计摘要、专辑汇总、可排序和筛选的结果表格，频谱图以base64内嵌
+func writeHTMLReport(path string, results []*types.AnalysisResult, albums []*types.AlbumResult) error {
+	sorted := append([]*types.AnalysisResult(nil), results...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].FilePath != sorted[j].FilePath {
+			return sorted[i].FilePath < sorted[j].FilePath
+		}
+		return trackNumber(sorted[i]) < trackNumber(sorted[j])
+	})
+
+	data := reportData{
+		Generated: time.Now().Format("2006-01-02 15:04:05"),
+		Summary:   summarize(results),
+	}
+	for _, album := range albums {
+		row := albumRow{
+			Name:         albumName(album),
+			Path:         album.Directory,
+			Status:       album.Status,
+			Tracks:       album.Tracks,
+			Fake:         album.Fake,
+			Suspect:      album.Suspect,
+			MedianCutoff: album.MedianCutoff,
+			Consistency:  album.Consistency,
+			Details:      album.Details,
+		}
+		if album.CueSheet != "" {
+			row.Path = album.CueSheet
+		}
+		data.Albums = append(data.Albums, row)
+	}
+	for _, result := range sorted {
+		row := reportRow{
+			FilePath:      result.FilePath,
+			Name:          filepath.Base(result.FilePath),
+			TrackNumber:   trackNumber(result),
+			Format:        result.Format,
+			Status:        result.Status,
+			SampleRate:    result.Analysis.SampleRate,
+			BitDepth:      result.Analysis.BitDepth,
+			Duration:      result.Analysis.Duration,
+			MaxFrequency:  result.Analysis.MaxFrequency,
+			CutoffHz:      result.Analysis.CutoffHz,
+			VerdictSource: result.VerdictSource,
+			Confidence:    result.Analysis.Confidence,
+			Details:       result.Analysis.Details,
+			Error:         result.Error,
+		}
+		if result.Track != nil {
+			row.Track = fmt.Sprintf("%02d", result.Track.Number)
+			if result.Track.Title != "" {
+				row.Track += " " + result.Track.Title
+			}
+		}
+		if row.Duration > 0 {
+			row.DurationText = formatTimestamp(row.Duration)
+		}
+		if m := result.FormatMismatch; m != nil {
+			row.Mismatch = fmt.Sprintf("扩展名为 .%s，实际内容为 %s", m.Extension, m.Content)
+		}
+		if l := result.RipLog; l != nil && l.CRCMatch == "mismatch" {
+			row.RipLog = l.Details
+		}
+		if len(result.Spectrogram) > 0 {
+			row.Spectrogram = template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(result.Spectrogram))
+		}
+		data.Rows = append(data.Rows, row)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("创建报告文件失败: %w", err)
+	}
+	if err := reportTemplate.Execute(file, data); err != nil {
+		file.Close()
+		return fmt.Errorf("写入报告失败: %w", err)
+	}
+	return file.Close()
+}
+
+// trackNumber 返回CUE音轨号，普通文件为0
+func trackNumber(result *types.AnalysisResult) int {
+	if result.Track == nil {
+		return 0
+	}
+	return result.Track.Number
+}
+
+// reportTemplate 报告模板，样式和脚本全部内联，不依赖网络
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"percent": func(f float64) string {
+		return fmt.Sprintf("%.0f%%", f*100)
+	},
+	"hz": func(f float64) string {
+		if f <= 0 {
+			return ""
+		}
+		return fmt.Sprintf("%.0f", f)
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<title>Audio Loss Checker 分析报告</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", "Microsoft YaHei", sans-serif; margin: 24px; color: #222; background: #fafafa; }
+h1 { font-size: 22px; margin: 0 0 4px; }
+.generated { color: #777; font-size: 13px; margin-bottom: 16px; }
+.summary { display: flex; gap: 12px; margin-bottom: 16px; }
+.card { background: #fff; border: 1px solid #ddd; border-radius: 6px; padding: 10px 18px; min-width: 90px; }
+.card .num { font-size: 24px; font-weight: bold; }
+.card.fake .num { color: #c62828; }
+.card.suspect .num { color: #f9a825; }
+.card.hires .num { color: #8e24aa; }
+.card.ok .num { color: #2e7d32; }
+.card.error .num { color: #ef6c00; }
+.card.damaged .num { color: #6d4c41; }
+.controls { margin-bottom: 12px; display: flex; gap: 16px; align-items: center; flex-wrap: wrap; }
+.controls input[type=search] { padding: 4px 8px; width: 280px; }
+table { border-collapse: collapse; width: 100%; background: #fff; font-size: 13px; }
+th, td { border: 1px solid #ddd; padding: 6px 8px; text-align: left; vertical-align: top; }
+th { background: #f0f0f0; cursor: pointer; user-select: none; white-space: nowrap; }
+th.asc::after { content: " ▲"; }
+th.desc::after { content: " ▼"; }
+td.num { text-align: right; white-space: nowrap; }
+.status { font-weight: bold; }
+.status-FAKE { color: #c62828; }
+.status-SUSPECT { color: #f9a825; }
+.status-FAKE_HIRES { color: #8e24aa; }
+.status-OK { color: #2e7d32; }
+.status-ERROR { color: #ef6c00; }
+.status-CORRUPT, .status-TRUNCATED { color: #6d4c41; }
+.path { color: #777; font-size: 12px; word-break: break-all; }
+h2 { font-size: 17px; margin: 20px 0 8px; }
+#albums { margin-bottom: 20px; }
+.warn { color: #ef6c00; font-size: 12px; }
+img.spec { width: 240px; cursor: zoom-in; display: block; }
+img.spec.large { width: auto; max-width: 90vw; cursor: zoom-out; }
+</style>
+</head>
+<body>
+<h1>Audio Loss Checker 分析报告</h1>
+<div class="generated">生成时间: {{.Generated}}</div>
+
+<div class="summary">
+  <div class="card"><div>总文件数</div><div class="num">{{.Summary.Total}}</div></div>
+  <div class="card ok"><div>正常文件</div><div class="num">{{.Summary.OK}}</div></div>
+  <div class="card suspect"><div>疑似假无损</div><div class="num">{{.Summary.Suspect}}</div></div>
+  <div class="card fake"><div>假无损文件</div><div class="num">{{.Summary.Fake}}</div></div>
+  <div class="card hires"><div>假高解析度</div><div class="num">{{.Summary.FakeHiRes}}</div></div>
+  <div class="card damaged"><div>损坏文件</div><div class="num">{{.Summary.Corrupt}}</div></div>
+  <div class="card damaged"><div>截断文件</div><div class="num">{{.Summary.Truncated}}</div></div>
+  <div class="card error"><div>错误文件</div><div class="num">{{.Summary.Errors}}</div></div>
+</div>
+
+{{if .Albums}}<h2>专辑汇总</h2>
+<table id="albums">
+<thead>
+<tr>
+  <th>专辑</th>
+  <th>状态</th>
+  <th>音轨数</th>
+  <th>假无损 / 疑似</th>
+  <th>最高有效频率中位数 (Hz)</th>
+  <th>一致性</th>
+  <th>说明</th>
+</tr>
+</thead>
+<tbody>
+{{range .Albums}}<tr>
+  <td>{{.Name}}<div class="path">{{.Path}}</div></td>
+  <td class="status status-{{.Status}}">{{.Status}}</td>
+  <td class="num">{{.Tracks}}</td>
+  <td class="num">{{.Fake}} / {{.Suspect}}</td>
+  <td class="num">{{hz .MedianCutoff}}</td>
+  <td class="num">{{percent .Consistency}}</td>
+  <td>{{.Details}}</td>
+</tr>
+{{end}}</tbody>
+</table>
+<h2>文件</h2>
+{{end}}
+<div class="controls">
+  <label><input type="checkbox" class="status-filter" value="OK" checked> 正常</label>
+  <label><input type="checkbox" class="status-filter" value="SUSPECT" checked> 疑似</label>
+  <label><input type="checkbox" class="status-filter" value="FAKE" checked> 假无损</label>
+  <label><input type="checkbox" class="status-filter" value="FAKE_HIRES" checked> 假高解析度</label>
+  <label><input type="checkbox" class="status-filter" value="CORRUPT" checked> 损坏</label>
+  <label><input type="checkbox" class="status-filter" value="TRUNCATED" checked> 截断</label>
+  <label><input type="checkbox" class="status-filter" value="ERROR" checked> 错误</label>
+  <input type="search" id="search" placeholder="按文件名、格式或说明筛选">
+  <span id="count"></span>
+</div>
+
+<table id="results">
+<thead>
+<tr>
+  <th data-type="text">文件</th>
+  <th data-type="num">音轨</th>
+  <th data-type="text">格式</th>
+  <th data-type="text">状态</th>
+  <th data-type="num">置信度</th>
+  <th data-type="num">采样率</th>
+  <th data-type="num">位深度</th>
+  <th data-type="num">时长</th>
+  <th data-type="num">最高有效频率 (Hz)</th>
+  <th data-type="num">截断频率 (Hz)</th>
+  <th data-type="text">判定依据</th>
+  <th data-type="text">分析结果</th>
+  <th>频谱图</th>
+</tr>
+</thead>
+<tbody>
+{{range .Rows}}<tr data-status="{{.Status}}">
+  <td data-value="{{.FilePath}}">{{.Name}}<div class="path">{{.FilePath}}</div>{{if .Mismatch}}<div class="warn">⚠️ {{.Mismatch}}</div>{{end}}{{if .RipLog}}<div class="warn">⚠️ {{.RipLog}}</div>{{end}}</td>
+  <td class="num" data-value="{{.TrackNumber}}">{{.Track}}</td>
+  <td>{{.Format}}</td>
+  <td class="status status-{{.Status}}">{{.Status}}</td>
+  <td class="num" data-value="{{.Confidence}}">{{if ne .Status "ERROR"}}{{percent .Confidence}}{{end}}</td>
+  <td class="num" data-value="{{.SampleRate}}">{{if .SampleRate}}{{.SampleRate}}{{end}}</td>
+  <td class="num" data-value="{{.BitDepth}}">{{if .BitDepth}}{{.BitDepth}}{{end}}</td>
+  <td class="num" data-value="{{.Duration}}">{{.DurationText}}</td>
+  <td class="num" data-value="{{.MaxFrequency}}">{{hz .MaxFrequency}}</td>
+  <td class="num" data-value="{{.CutoffHz}}">{{hz .CutoffHz}}</td>
+  <td>{{.VerdictSource}}</td>
+  <td>{{if .Error}}错误: {{.Error}}{{else}}{{.Details}}{{end}}</td>
+  <td>{{if .Spectrogram}}<img class="spec" src="{{.Spectrogram}}" alt="频谱图">{{end}}</td>
+</tr>
+{{end}}</tbody>
+</table>
+
+<script>
+(function () {
+  var table = document.getElementById('results');
+  var tbody = table.tBodies[0];
+  var rows = Array.prototype.slice.call(tbody.rows);
+  var search = document.getElementById('search');
+  var filters = document.querySelectorAll('.status-filter');
+
+  function applyFilters() {
+    var allowed = {};
+    filters.forEach(function (f) { allowed[f.value] = f.checked; });
+    var keyword = search.value.trim().toLowerCase();
+    var shown = 0;
+    rows.forEach(function (row) {
+      var visible = allowed[row.dataset.status] !== false &&
+        (keyword === '' || row.textContent.toLowerCase().indexOf(keyword) >= 0);
+      row.style.display = visible ? '' : 'none';
+      if (visible) shown++;
+    });
+    document.getElementById('count').textContent = '显示 ' + shown + ' / ' + rows.length;
+  }
+
+  function cellValue(row, index, type) {
+    var cell = row.cells[index];
+    var value = cell.dataset.value !== undefined ? cell.dataset.value : cell.textContent.trim();
+    return type === 'num' ? (parseFloat(value) || 0) : value.toLowerCase();
+  }
+
+  table.tHead.querySelectorAll('th[data-type]').forEach(function (th) {
+    th.addEventListener('click', function () {
+      var index = th.cellIndex;
+      var type = th.dataset.type;
+      var asc = !th.classList.contains('asc');
+      table.tHead.querySelectorAll('th').forEach(function (h) { h.classList.remove('asc', 'desc'); });
+      th.classList.add(asc ? 'asc' : 'desc');
+      rows.sort(function (a, b) {
+        var x = cellValue(a, index, type), y = cellValue(b, index, type);
+        if (x < y) return asc ? -1 : 1;
+        if (x > y) return asc ? 1 : -1;
+        return 0;
+      });
+      rows.forEach(function (row) { tbody.appendChild(row); });
+    });
+  });
+
+  tbody.addEventListener('click', function (e) {
+    if (e.target.classList.contains('spec')) e.target.classList.toggle('large');
+  });
+  search.addEventListener('input', applyFilters);
+  filters.forEach(function (f) { f.addEventListener('change', applyFilters); });
+  applyFilters();
+})();
+</script>
+</body>
+</html>
+`))
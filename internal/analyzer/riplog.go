@@ -0,0 +1,177 @@
+package analyzer
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"math"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"audio-loss-checker/internal/riplog"
+	"audio-loss-checker/internal/types"
+)
+
+// leadingTrackNumber 文件名开头的音轨号，如 "01 - Title.flac"、"1. Title.flac"
+var leadingTrackNumber = regexp.MustCompile(`^(\d{1,2})\D`)
+
+// ripLogMatch 与一个音频文件（或整轨镜像）对应的抓轨日志
+type ripLogMatch struct {
+	log   *riplog.Log
+	entry *riplog.Track // 文件对应的记录：逐轨抓取时为音轨，整轨范围抓取时为整个镜像
+}
+
+// findRipLog 在音频文件所在目录中查找对应的EAC/XLD抓轨日志，没有时返回nil
+// 优先按日志记录的输出文件名匹配，整轨范围优先于音轨（XLD整轨抓取时各音轨也记录镜像的文件名）；
+// 目录中只有一份日志时，再按文件名开头的音轨号匹配
+func findRipLog(audioPath string) *ripLogMatch {
+	var logs []*riplog.Log
+	for _, path := range riplog.Find(filepath.Dir(audioPath)) {
+		log, err := riplog.ParseFile(path)
+		if err != nil {
+			continue
+		}
+		if entry := log.RangeForFile(audioPath); entry != nil && entry.Filename != "" {
+			return &ripLogMatch{log: log, entry: entry}
+		}
+		if entry := log.TrackByFile(audioPath); entry != nil {
+			return &ripLogMatch{log: log, entry: entry}
+		}
+		logs = append(logs, log)
+	}
+	if len(logs) != 1 {
+		return nil
+	}
+
+	// 日志没有记录文件名，或抓取后改过文件名
+	log := logs[0]
+	if entry := log.RangeForFile(audioPath); entry != nil {
+		return &ripLogMatch{log: log, entry: entry}
+	}
+	if m := leadingTrackNumber.FindStringSubmatch(filepath.Base(audioPath)); m != nil {
+		number, _ := strconv.Atoi(m[1])
+		if entry := log.TrackByNumber(number); entry != nil {
+			return &ripLogMatch{log: log, entry: entry}
+		}
+	}
+	return &ripLogMatch{log: log}
+}
+
+// newCRCSink 日志中有CRC且音频为CD规格时返回CRC计算步骤，否则返回nil
+func (m *ripLogMatch) newCRCSink(audioFile types.AudioFile) *crcSink {
+	if m == nil || m.entry == nil || m.entry.CopyCRC == "" || !isCDAudio(audioFile) || partialReason(audioFile) != "" {
+		return nil
+	}
+	return newCRCSink()
+}
+
+// isCDAudio 判断音频是否为CD规格（16位/44.1kHz/双声道），只有CD规格才能与抓轨日志的CRC比较
+func isCDAudio(audioFile types.AudioFile) bool {
+	_, dsd := audioFile.(types.DSDSource)
+	return !dsd && audioFile.GetSampleRate() == 44100 && audioFile.GetBitDepth() == 16 && audioFile.GetChannels() == 2
+}
+
+// cdSample 把CD规格音频归一化的采样还原为16位整数：归一化时除以32768，乘回去是精确的整数
+func cdSample(v float64) int16 {
+	return int16(math.Max(math.MinInt16, math.Min(math.MaxInt16, math.Round(v*32768))))
+}
+
+// isRange 文件是整轨范围抓取的镜像
+func (m *ripLogMatch) isRange() bool {
+	return m != nil && m.entry != nil && m.entry == m.log.Range
+}
+
+// report 生成结果中的日志信息，number 为音轨号（CUE分轨时），用于查找AccurateRip结果；sink 为nil表示无法计算CRC
+func (m *ripLogMatch) report(number int, audioFile types.AudioFile, sink *crcSink) *types.RipLog {
+	info := &types.RipLog{
+		Path:     m.log.Path,
+		Ripper:   m.log.Name(),
+		Drive:    m.log.Drive,
+		ReadMode: m.log.ReadMode,
+	}
+	if m.entry != nil {
+		info.Range = m.entry == m.log.Range
+		info.TestCRC, info.CopyCRC = m.entry.TestCRC, m.entry.CopyCRC
+		if !info.Range {
+			number = m.entry.Number
+		}
+	}
+	info.Track = number
+	if track := m.log.TrackByNumber(number); track != nil {
+		info.AccurateRip, info.AccurateRipDetails = track.AccurateRip, track.AccurateLine
+	}
+
+	var details []string
+	switch {
+	case m.entry == nil:
+		details = append(details, "日志中没有找到与本文件对应的音轨")
+	case info.CopyCRC == "":
+		details = append(details, "日志中没有记录CRC")
+	case sink == nil && !isCDAudio(audioFile):
+		details = append(details, "不是CD规格（16位/44.1kHz/双声道），无法重新计算CRC")
+	case sink == nil && partialReason(audioFile) != "":
+		details = append(details, "音频只解码了部分数据，无法重新计算CRC")
+	case sink != nil:
+		full, noNull := sink.sums()
+		switch {
+		case full == info.CopyCRC:
+			info.AudioCRC, info.CRCMatch = full, "match"
+		case noNull == info.CopyCRC || noNull == m.entry.CopyCRCNoNull:
+			// EAC可以设置不把值为0的采样计入CRC，XLD另外给出 skip zero 的CRC
+			info.AudioCRC, info.CRCMatch = noNull, "match"
+		default:
+			info.AudioCRC, info.CRCMatch = full, "mismatch"
+		}
+		scope := "解码后的PCM"
+		if info.Range {
+			scope = "整个镜像解码后的PCM"
+		}
+		if info.CRCMatch == "match" {
+			details = append(details, scope+"与日志记录的CRC32一致")
+		} else {
+			details = append(details, fmt.Sprintf("%s的CRC32为 %s，与日志记录的 %s 不符，音频在抓取之后被修改过", scope, info.AudioCRC, info.CopyCRC))
+		}
+	}
+	if info.TestCRC != "" && info.CopyCRC != "" && info.TestCRC != info.CopyCRC {
+		details = append(details, "日志中测试与复制的CRC不一致，抓取本身可能有误")
+	}
+	info.Details = strings.Join(details, "；")
+	return info
+}
+
+// crcSink 按EAC/XLD的方式计算PCM数据的CRC32：16位小端序采样按声道交错
+// 同时计算跳过值为0的采样的版本（EAC关闭 "Use null samples in CRC calculations" 时、XLD的 skip zero）
+type crcSink struct {
+	full   hash.Hash32
+	noNull hash.Hash32
+	buf    []byte
+	nz     []byte
+}
+
+// newCRCSink 创建CRC计算步骤，只用于CD规格的双声道音频
+func newCRCSink() *crcSink {
+	return &crcSink{full: crc32.NewIEEE(), noNull: crc32.NewIEEE()}
+}
+
+func (c *crcSink) consume(block [][]float64) {
+	c.buf, c.nz = c.buf[:0], c.nz[:0]
+	for i := range block[0] {
+		for ch := 0; ch < 2; ch++ {
+			v := cdSample(block[ch][i])
+			c.buf = binary.LittleEndian.AppendUint16(c.buf, uint16(v))
+			if v != 0 {
+				c.nz = binary.LittleEndian.AppendUint16(c.nz, uint16(v))
+			}
+		}
+	}
+	c.full.Write(c.buf)
+	c.noNull.Write(c.nz)
+}
+
+// sums 返回两种CRC32，8位大写十六进制
+func (c *crcSink) sums() (full, noNull string) {
+	return fmt.Sprintf("%08X", c.full.Sum32()), fmt.Sprintf("%08X", c.noNull.Sum32())
+}
@@ -0,0 +1,163 @@
+package analyzer
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"audio-loss-checker/internal/riplog"
+	"audio-loss-checker/internal/types"
+)
+
+// crcBlock 把16位整数采样归一化为采样流提供的浮点数
+func crcBlock(left, right []int16) [][]float64 {
+	block := [][]float64{make([]float64, len(left)), make([]float64, len(right))}
+	for i := range left {
+		block[0][i] = float64(left[i]) / 32768
+		block[1][i] = float64(right[i]) / 32768
+	}
+	return block
+}
+
+// 期望值用 Python 的 zlib.crc32 独立计算：
+// 完整CRC对 struct.pack('<hh', l, r) 逐帧拼接，跳过0的CRC只拼接非0的16位采样
+func TestCRCSink(t *testing.T) {
+	left := []int16{0, 1, -1, 32767, -32768, 0, 1234, -4321, 0, 0}
+	right := []int16{0, 0, 5, -5, 0, 100, 0, -100, 32767, 0}
+	silence := make([]int16, 588)
+
+	tests := []struct {
+		name         string
+		blocks       [][][]float64
+		full, noNull string
+	}{
+		{
+			name:   "一次写入",
+			blocks: [][][]float64{crcBlock(left, right)},
+			full:   "87944085", noNull: "3B7624D9",
+		},
+		{
+			// 分块方式不影响结果
+			name:   "分两块写入",
+			blocks: [][][]float64{crcBlock(left[:3], right[:3]), crcBlock(left[3:], right[3:])},
+			full:   "87944085", noNull: "3B7624D9",
+		},
+		{
+			// 一个扇区的数字静音；跳过0时没有数据，CRC为0
+			name:   "静音扇区",
+			blocks: [][][]float64{crcBlock(silence, silence)},
+			full:   "BE97CE3F", noNull: "00000000",
+		},
+	}
+
+	for _, tt := range tests {
+		sink := newCRCSink()
+		for _, block := range tt.blocks {
+			sink.consume(block)
+		}
+		if full, noNull := sink.sums(); full != tt.full || noNull != tt.noNull {
+			t.Errorf("%s: CRC为 %s / %s，应为 %s / %s", tt.name, full, noNull, tt.full, tt.noNull)
+		}
+	}
+}
+
+// 与日志中的测试/复制CRC比较
+func TestRipLogCRCMatch(t *testing.T) {
+	left := []int16{0, 1, -1, 32767, -32768, 0, 1234, -4321, 0, 0}
+	right := []int16{0, 0, 5, -5, 0, 100, 0, -100, 32767, 0}
+
+	tests := []struct {
+		name    string
+		track   riplog.Track
+		match   string
+		crc     string
+		details string
+	}{
+		{name: "EAC", track: riplog.Track{Number: 1, TestCRC: "87944085", CopyCRC: "87944085"}, match: "match", crc: "87944085"},
+		// EAC关闭 "Use null samples in CRC calculations"
+		{name: "EAC跳过0", track: riplog.Track{Number: 1, TestCRC: "3B7624D9", CopyCRC: "3B7624D9"}, match: "match", crc: "3B7624D9"},
+		// XLD另外记录 skip zero 的CRC
+		{name: "XLD", track: riplog.Track{Number: 1, CopyCRC: "12345678", CopyCRCNoNull: "3B7624D9"}, match: "match", crc: "3B7624D9"},
+		{name: "不符", track: riplog.Track{Number: 1, CopyCRC: "87944086"}, match: "mismatch", crc: "87944085", details: "不符"},
+		{name: "测试与复制不一致", track: riplog.Track{Number: 1, TestCRC: "00000000", CopyCRC: "87944085"}, match: "match", crc: "87944085", details: "测试与复制的CRC不一致"},
+	}
+
+	for _, tt := range tests {
+		log := &riplog.Log{Ripper: "EAC", Tracks: []riplog.Track{tt.track}}
+		m := &ripLogMatch{log: log, entry: &log.Tracks[0]}
+		sink := newCRCSink()
+		sink.consume(crcBlock(left, right))
+
+		info := m.report(0, nil, sink)
+		if info.CRCMatch != tt.match || info.AudioCRC != tt.crc || info.Track != 1 {
+			t.Errorf("%s: 结果为 %s %s（音轨 %d）", tt.name, info.CRCMatch, info.AudioCRC, info.Track)
+		}
+		if tt.details != "" && !strings.Contains(info.Details, tt.details) {
+			t.Errorf("%s: 说明为 %q", tt.name, info.Details)
+		}
+	}
+}
+
+// 从EAC日志原文（UTF-16LE带BOM）到分析结果：WAV为测试采样重复1000次，
+// 日志中的测试/复制CRC同样用 zlib.crc32 独立计算
+func TestRipLogEAC(t *testing.T) {
+	left := []int16{0, 1, -1, 32767, -32768, 0, 1234, -4321, 0, 0}
+	right := []int16{0, 0, 5, -5, 0, 100, 0, -100, 32767, 0}
+	dir := t.TempDir()
+
+	samples := [][]int32{nil, nil}
+	for n := 0; n < 1000; n++ {
+		for i := range left {
+			samples[0] = append(samples[0], int32(left[i]))
+			samples[1] = append(samples[1], int32(right[i]))
+		}
+	}
+	wav := testWAV(44100, 16, samples)
+	audioPath := filepath.Join(dir, "01 - Test.wav")
+	if err := os.WriteFile(audioPath, wav, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	log := strings.Join([]string{
+		"Exact Audio Copy V1.6 from 23. October 2020",
+		"",
+		"EAC extraction logfile from 17. October 2026, 12:00",
+		"",
+		"Used drive  : PLEXTOR DVDR   PX-716A   Adapter: 1  ID: 0",
+		"Read mode               : Secure",
+		"",
+		"Track  1",
+		"",
+		`     Filename C:\Rips\01 - Test.wav`,
+		"",
+		"     Peak level 100.0 %",
+		"     Test CRC 1A98BDDC",
+		"     Copy CRC 1A98BDDC",
+		"     Accurately ripped (confidence 5)  [12345678]  (AR v2)",
+		"     Copy OK",
+	}, "\r\n")
+	encoded := []byte{0xff, 0xfe}
+	for _, u := range utf16.Encode([]rune(log)) {
+		encoded = binary.LittleEndian.AppendUint16(encoded, u)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "rip.log"), encoded, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := NewAnalyzer(&types.AnalyzerConfig{Concurrency: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := a.AnalyzePath(audioPath)
+	if len(results) != 1 || results[0].RipLog == nil {
+		t.Fatalf("没有找到抓轨日志: %+v", results)
+	}
+	info := results[0].RipLog
+	if info.Ripper != "EAC V1.6" || info.TestCRC != "1A98BDDC" || info.CopyCRC != "1A98BDDC" ||
+		info.AudioCRC != "1A98BDDC" || info.CRCMatch != "match" {
+		t.Errorf("日志信息为 %+v", info)
+	}
+}
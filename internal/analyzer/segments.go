@@ -0,0 +1,181 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"audio-loss-checker/internal/types"
+
+	"github.com/mjibson/go-dsp/fft"
+)
+
+const (
+	// segmentWindows 每段最多平均的窗口数
+	segmentWindows = 16
+	// segmentDeviationHz 分段的最高有效频率与其余部分的中位数相差超过此值时视为异常
+	segmentDeviationHz = 2000.0
+	// segmentsPerRow 文本输出的时间线每行显示的分段数
+	segmentsPerRow = 10
+	// MinSegmentSeconds 分段分析每段的最短时长，过短时每段的窗口太少，频谱不稳定
+	MinSegmentSeconds = 1.0
+)
+
+// segmentSink 按固定时长分段，每段单独累积平均功率谱，得到各段的最高有效频率
+// 采样先混合为单声道；每段最多截取 segmentWindows 个均匀分布的窗口，只保留各段的结果
+type segmentSink struct {
+	s        *SpectrumAnalyzer
+	channels int
+	length   int64 // 每段的帧数
+	step     int64 // 段内相邻窗口起点的间隔（帧）
+	windows  int   // 每段计划截取的窗口数
+	ring     []float64
+	pos      int64 // 当前段内已读取的帧数
+	start    int64 // 当前段的起始帧
+	captured int
+	audible  int // 当前段中非静音的窗口数
+	sumPower []float64
+	segments []types.Segment
+}
+
+// newSegmentSink 创建分段分析步骤，seconds 为每段的时长
+func (s *SpectrumAnalyzer) newSegmentSink(channels int, seconds float64) *segmentSink {
+	windowSize := int64(s.windowSize)
+	sink := &segmentSink{
+		s:        s,
+		channels: channels,
+		length:   int64(seconds * float64(s.sampleRate)),
+		ring:     make([]float64, windowSize),
+		sumPower: make([]float64, windowSize/2),
+	}
+	sink.windows, sink.step = spreadWindows(sink.length, windowSize, segmentWindows)
+	return sink
+}
+
+func (g *segmentSink) consume(block [][]float64) {
+	windowSize := int64(len(g.ring))
+	for i := range block[0] {
+		// 混合为单声道
+		sum := 0.0
+		for ch := 0; ch < g.channels; ch++ {
+			sum += block[ch][i]
+		}
+		g.ring[g.pos%windowSize] = sum / float64(g.channels)
+		g.pos++
+
+		// 到达段内下一个窗口的结束位置时计算功率谱
+		if g.captured < g.windows && g.pos == int64(g.captured)*g.step+windowSize {
+			g.addWindow()
+		}
+		if g.pos == g.length {
+			g.finishSegment()
+		}
+	}
+}
+
+// addWindow 计算环形缓冲中最近一个窗口的功率谱并计入当前段
+func (g *segmentSink) addWindow() {
+	windowSize := int64(len(g.ring))
+	start := g.pos % windowSize
+	mono := append(append(make([]float64, 0, windowSize), g.ring[start:]...), g.ring[:start]...)
+	power := g.s.calculatePowerSpectrum(fft.FFTReal(g.s.applyHammingWindow(mono)))
+	for k, p := range power {
+		g.sumPower[k] += p
+	}
+	g.captured++
+
+	energy := 0.0
+	for _, v := range mono {
+		energy += v * v
+	}
+	if energy/float64(len(mono)) >= silentPower {
+		g.audible++
+	}
+}
+
+// finishSegment 结束当前段：计算最高有效频率和截断两侧的电平差，没有截取到窗口的尾段不输出
+func (g *segmentSink) finishSegment() {
+	if g.captured > 0 {
+		rate := float64(g.s.sampleRate)
+		segment := types.Segment{
+			Start:  float64(g.start) / rate,
+			End:    float64(g.start+g.pos) / rate,
+			Silent: g.audible == 0,
+		}
+		if !segment.Silent {
+			avgPower := make([]float64, len(g.sumPower))
+			for k, p := range g.sumPower {
+				avgPower[k] = p / float64(g.captured)
+			}
+			segment.MaxFrequency = g.s.findMaxEffectiveFrequency(avgPower, rate/float64(len(g.ring)))
+			if drop, ok := g.s.shelfDrop(avgPower, segment.MaxFrequency); ok {
+				segment.DropDB = drop
+			}
+		}
+		g.segments = append(g.segments, segment)
+	}
+
+	g.start += g.pos
+	g.pos, g.captured, g.audible = 0, 0, 0
+	for k := range g.sumPower {
+		g.sumPower[k] = 0
+	}
+}
+
+// result 结束最后一段（可能不足一段的时长），标记与其余部分明显不同的分段
+func (g *segmentSink) result() []types.Segment {
+	if g.pos > 0 {
+		g.finishSegment()
+	}
+	flagDeviantSegments(g.segments)
+	return g.segments
+}
+
+// flagDeviantSegments 以非静音分段最高有效频率的中位数作为参照，标记相差超过 segmentDeviationHz 的分段
+// 少于两个非静音分段时无从比较，不做标记
+func flagDeviantSegments(segments []types.Segment) {
+	reference, ok := segmentReference(segments)
+	if !ok {
+		return
+	}
+	for i := range segments {
+		s := &segments[i]
+		s.Deviant = !s.Silent && (s.MaxFrequency < reference-segmentDeviationHz || s.MaxFrequency > reference+segmentDeviationHz)
+	}
+}
+
+// segmentReference 返回非静音分段最高有效频率的中位数
+func segmentReference(segments []types.Segment) (float64, bool) {
+	var freqs []float64
+	for _, s := range segments {
+		if !s.Silent {
+			freqs = append(freqs, s.MaxFrequency)
+		}
+	}
+	if len(freqs) < 2 {
+		return 0, false
+	}
+	sort.Float64s(freqs)
+	return freqs[len(freqs)/2], true
+}
+
+// segmentEvidence 异常分段的截断低于阈值且陡峭时，整个文件可能拼接了有损片段
+// 只看低于其余部分的分段：安静段落也会拉低最高有效频率，但不会出现陡峭的截断
+func (s *SpectrumAnalyzer) segmentEvidence(segments []types.Segment) *types.Evidence {
+	reference, ok := segmentReference(segments)
+	if !ok {
+		return nil
+	}
+	var lossy []string
+	for _, seg := range segments {
+		if seg.Deviant && seg.MaxFrequency < reference && seg.MaxFrequency < s.cutoffThreshold && seg.DropDB >= shelfSteepDB {
+			lossy = append(lossy, fmt.Sprintf("%s-%s (%.0f Hz)", formatTimestamp(seg.Start), formatTimestamp(seg.End), seg.MaxFrequency))
+		}
+	}
+	if len(lossy) == 0 {
+		return nil
+	}
+	return &types.Evidence{Detector: "segments", Weight: weightLossySegment,
+		Details: fmt.Sprintf("%d 个分段的截断陡峭且明显低于其余部分的 %.0f Hz，疑似拼接了有损片段: %s",
+			len(lossy), reference, strings.Join(lossy, ", "))}
+}
@@ -0,0 +1,103 @@
+package analyzer
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"audio-loss-checker/internal/types"
+)
+
+// 8秒的全频带内容中，第3到5秒拼接了在16kHz低通的有损片段：
+// 分段分析必须标出这两秒，并作为拼接证据报告其时间范围
+func TestSplicedLossySegment(t *testing.T) {
+	const sampleRate = 44100
+	rng := rand.New(rand.NewSource(1))
+	samples := [][]int32{nil, nil}
+	for second := 0; second < 8; second++ {
+		cutoff := 19500.0
+		if second == 3 || second == 4 {
+			cutoff = 16000
+		}
+		for ch := range samples {
+			for _, v := range bandNoise(rng, 1<<16, sampleRate, cutoff)[:sampleRate] {
+				samples[ch] = append(samples[ch], int32(math.Round(v*32767)))
+			}
+		}
+	}
+
+	a, err := NewAnalyzer(&types.AnalyzerConfig{Concurrency: 1, Segments: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := a.AnalyzeReader(bytes.NewReader(testWAV(sampleRate, 16, samples)), "spliced.wav")
+	if result.Error != "" {
+		t.Fatal(result.Error)
+	}
+
+	segments := result.Analysis.Segments
+	if len(segments) != 8 {
+		t.Fatalf("得到 %d 个分段", len(segments))
+	}
+	for i, seg := range segments {
+		lossy := i == 3 || i == 4
+		if seg.Start != float64(i) || seg.End != float64(i+1) || seg.Silent || seg.Deviant != lossy {
+			t.Errorf("分段 %d: %+v", i, seg)
+		}
+		want := 19500.0
+		if lossy {
+			want = 16000
+			if seg.DropDB < shelfSteepDB {
+				t.Errorf("分段 %d 的截断只有 %.0f dB", i, seg.DropDB)
+			}
+		}
+		if math.Abs(seg.MaxFrequency-want) > cutoffTolerance {
+			t.Errorf("分段 %d 的最高有效频率为 %.0f Hz，应约为 %.0f Hz", i, seg.MaxFrequency, want)
+		}
+	}
+
+	var evidence *types.Evidence
+	for i, e := range result.Analysis.Evidence {
+		if e.Detector == "segments" {
+			evidence = &result.Analysis.Evidence[i]
+		}
+	}
+	if evidence == nil {
+		t.Fatalf("没有拼接证据: %+v", result.Analysis.Evidence)
+	}
+	for _, span := range []string{"00:03.00-00:04.00", "00:04.00-00:05.00"} {
+		if !strings.Contains(evidence.Details, span) {
+			t.Errorf("证据中没有 %s: %s", span, evidence.Details)
+		}
+	}
+	if !strings.HasPrefix(evidence.Details, "2 个分段") {
+		t.Errorf("证据为 %s", evidence.Details)
+	}
+}
+
+// 全频带但有一段安静的内容：最高有效频率偏低但没有陡峭截断，不算拼接
+func TestQuietSegmentNotSpliced(t *testing.T) {
+	s := NewSpectrumAnalyzer(44100)
+	segments := []types.Segment{
+		{Start: 0, End: 1, MaxFrequency: 19500},
+		{Start: 1, End: 2, MaxFrequency: 15000, DropDB: 10},
+		{Start: 2, End: 3, MaxFrequency: 19600},
+		{Start: 3, End: 4, Silent: true},
+	}
+	flagDeviantSegments(segments)
+	if !segments[1].Deviant || segments[0].Deviant || segments[3].Deviant {
+		t.Errorf("分段标记为 %+v", segments)
+	}
+	if e := s.segmentEvidence(segments); e != nil {
+		t.Errorf("安静段落被当作拼接: %+v", e)
+	}
+
+	// 只有一个非静音分段时无从比较
+	single := []types.Segment{{MaxFrequency: 15000, DropDB: 40}, {Silent: true}}
+	flagDeviantSegments(single)
+	if single[0].Deviant || s.segmentEvidence(single) != nil {
+		t.Errorf("单个分段: %+v", single)
+	}
+}
@@ -0,0 +1,280 @@
+package analyzer
+
+import (
+	"bytes"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/draw"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"audio-loss-checker/internal/types"
+
+	"github.com/mjibson/go-dsp/fft"
+)
+
+// 频谱图默认尺寸（绘图区域，不含坐标轴和色标）
+const (
+	DefaultSpectrogramWidth  = 1200
+	DefaultSpectrogramHeight = 600
+
+	// HTML报告中的频谱图较小，以控制报告体积
+	reportSpectrogramWidth  = 480
+	reportSpectrogramHeight = 200
+)
+
+// spectrogramFloorDB 频谱图色标的下限，相对满幅正弦波
+const spectrogramFloorDB = -120.0
+
+// SpectrogramOptions 频谱图渲染选项
+type SpectrogramOptions struct {
+	OutputDir string // 输出目录，为空时写到音频文件旁边
+	Root      string // 输入的根目录，指定输出目录时在其下保留文件相对于它的子目录
+	Width     int    // 时间方向的列数
+	Height    int    // 频率方向的行数
+}
+
+// spectrogramSink 流式计算频谱图的每一列
+// 各声道混合为单声道，列在整个文件范围内均匀分布，每列的功率谱按行取最大值后转换为dB
+type spectrogramSink struct {
+	s       *SpectrumAnalyzer
+	columns int
+	rows    int
+	first   int64 // 第一列窗口的结束位置
+	span    int64 // 第一列到最后一列窗口结束位置的距离
+	ring    []float64
+	pos     int64
+	refDB   float64 // 满幅正弦波经过窗函数后的峰值功率 (dB)
+	data    [][]float32
+}
+
+// newSpectrogramSink 按预计总帧数安排 columns 列，文件短于一个窗口时不截取
+func (s *SpectrumAnalyzer) newSpectrogramSink(expectedFrames int64, columns, rows int) *spectrogramSink {
+	windowSize := int64(s.windowSize)
+
+	// 窗函数的相干增益，用于把功率换算为相对满幅的dB
+	ones := make([]float64, windowSize)
+	for i := range ones {
+		ones[i] = 1
+	}
+	gain := 0.0
+	for _, w := range s.applyBlackmanHarrisWindow(ones) {
+		gain += w
+	}
+
+	sink := &spectrogramSink{
+		s:       s,
+		columns: columns,
+		rows:    rows,
+		ring:    make([]float64, windowSize),
+		refDB:   20 * math.Log10(gain/2),
+	}
+	if expectedFrames >= windowSize {
+		sink.first = windowSize
+		sink.span = expectedFrames - windowSize
+	}
+	return sink
+}
+
+// target 返回第 i 列窗口的结束位置
+func (g *spectrogramSink) target(i int) int64 {
+	if g.columns <= 1 {
+		return g.first
+	}
+	return g.first + g.span*int64(i)/int64(g.columns-1)
+}
+
+func (g *spectrogramSink) consume(block [][]float64) {
+	if g.first == 0 {
+		return
+	}
+	windowSize := int64(len(g.ring))
+	for i := range block[0] {
+		if len(g.data) >= g.columns {
+			return
+		}
+
+		sum := 0.0
+		for ch := range block {
+			sum += block[ch][i]
+		}
+		g.ring[g.pos%windowSize] = sum / float64(len(block))
+		g.pos++
+
+		// 文件很短时相邻列可能落在同一位置，复用同一个窗口
+		var column []float32
+		for len(g.data) < g.columns && g.pos == g.target(len(g.data)) {
+			if column == nil {
+				column = g.column()
+			}
+			g.data = append(g.data, column)
+		}
+	}
+}
+
+// column 计算环形缓冲中当前窗口的一列
+func (g *spectrogramSink) column() []float32 {
+	start := g.pos % int64(len(g.ring))
+	window := append(append(make([]float64, 0, len(g.ring)), g.ring[start:]...), g.ring[:start]...)
+	power := g.s.calculatePowerSpectrum(fft.FFTReal(g.s.applyBlackmanHarrisWindow(window)))
+
+	column := make([]float32, g.rows)
+	bins := len(power)
+	for r := range column {
+		lo := r * bins / g.rows
+		hi := (r + 1) * bins / g.rows
+		if hi <= lo {
+			hi = lo + 1
+		}
+		peak := 0.0
+		for _, p := range power[lo:hi] {
+			peak = math.Max(peak, p)
+		}
+		column[r] = float32(10*math.Log10(peak+1e-30) - g.refDB)
+	}
+	return column
+}
+
+// RenderSpectrograms 为每个文件生成频谱图PNG，并在图上标出分析得到的截断频率
+func (a *Analyzer) RenderSpectrograms(filePaths []string, opts SpectrogramOptions) error {
+	if opts.OutputDir != "" {
+		if err := os.MkdirAll(opts.OutputDir, 0755); err != nil {
+			return fmt.Errorf("创建输出目录失败: %w", err)
+		}
+	}
+
+	jobs := make(chan string, len(filePaths))
+	for _, filePath := range filePaths {
+		jobs <- filePath
+	}
+	close(jobs)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	failed := 0
+	for i := 0; i < a.config.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for filePath := range jobs {
+				outPath, result, err := a.renderSpectrogram(filePath, opts)
+				mu.Lock()
+				if err != nil {
+					failed++
+					fmt.Fprintf(os.Stderr, "生成频谱图失败 %s: %v\n", filePath, err)
+				} else {
+					fmt.Printf("%s -> %s (%s)\n", filePath, outPath, result.Status)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	fmt.Printf("\n已生成 %d 个频谱图", len(filePaths)-failed)
+	if failed > 0 {
+		fmt.Printf("，%d 个失败", failed)
+	}
+	fmt.Println()
+	if failed > 0 {
+		return fmt.Errorf("%d 个文件生成频谱图失败", failed)
+	}
+	return nil
+}
+
+// renderSpectrogram 在一次顺序读取中同时完成分析和频谱图计算，返回PNG路径和分析结果
+func (a *Analyzer) renderSpectrogram(filePath string, opts SpectrogramOptions) (string, *types.AnalysisResult, error) {
+	result := &types.AnalysisResult{
+		FilePath: filePath,
+		Status:   "ERROR",
+	}
+
+	audioFile := a.openAudioFile(result, nil)
+	if audioFile == nil {
+		if result.Error != "" {
+			return "", nil, fmt.Errorf("%s", result.Error)
+		}
+		return "", nil, fmt.Errorf("无法解码: %s", result.Analysis.Details)
+	}
+	defer audioFile.Close()
+
+	reader, err := audioFile.NewSampleReader()
+	if err != nil {
+		return "", nil, fmt.Errorf("读取音频数据失败: %w", err)
+	}
+	frames := expectedFrames(audioFile)
+	stream := a.newStreamAnalysis(audioFile, frames)
+	stream.bitDepth = observeBitDepth(reader, audioFile)
+	spectrogram := stream.spectrum.newSpectrogramSink(frames, opts.Width, opts.Height)
+	totalFrames, err := runStream(reader, audioFile.GetChannels(), append(stream.sinks(), spectrogram))
+	if err != nil {
+		return "", nil, fmt.Errorf("读取音频数据失败: %w", err)
+	}
+	if len(spectrogram.data) == 0 {
+		return "", nil, fmt.Errorf("音频太短，无法生成频谱图")
+	}
+
+	a.finishAnalysis(result, audioFile, stream)
+	if result.Error != "" {
+		return "", nil, fmt.Errorf("%s", result.Error)
+	}
+
+	img := spectrogram.render(result, float64(totalFrames)/float64(audioFile.GetSampleRate()))
+
+	outPath := spectrogramPath(filePath, opts)
+	if err := os.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
+		return "", nil, fmt.Errorf("创建输出目录失败: %w", err)
+	}
+	out, err := os.Create(outPath)
+	if err != nil {
+		return "", nil, fmt.Errorf("创建PNG文件失败: %w", err)
+	}
+	defer out.Close()
+	if err := png.Encode(out, img); err != nil {
+		return "", nil, fmt.Errorf("写入PNG文件失败: %w", err)
+	}
+
+	return outPath, result, nil
+}
+
+// spectrogramPath 返回频谱图PNG的路径
+// 指定输出目录时在其下保留文件相对于 opts.Root 的子目录，不同目录中的同名文件不会互相覆盖；
+// 文件不在 opts.Root 下（如CUE表引用了其他目录中的镜像）时在文件名后加上完整路径的短哈希
+func spectrogramPath(filePath string, opts SpectrogramOptions) string {
+	if opts.OutputDir == "" {
+		return filepath.Join(filepath.Dir(filePath), filepath.Base(filePath)+".png")
+	}
+	abs, err := filepath.Abs(filePath)
+	if err != nil {
+		abs = filepath.Clean(filePath)
+	}
+	if opts.Root != "" {
+		if root, err := filepath.Abs(opts.Root); err == nil {
+			if rel, err := filepath.Rel(root, abs); err == nil && filepath.IsLocal(rel) {
+				return filepath.Join(opts.OutputDir, rel+".png")
+			}
+		}
+	}
+	return filepath.Join(opts.OutputDir, fmt.Sprintf("%s.%08x.png", filepath.Base(filePath), crc32.ChecksumIEEE([]byte(abs))))
+}
+
+// attachSpectrogram 把HTML报告需要的频谱图编码为PNG放入结果，分析失败或音频太短时不生成
+func (a *Analyzer) attachSpectrogram(result *types.AnalysisResult, stream *streamAnalysis) {
+	if stream.spectrogram == nil || result.Error != "" || len(stream.spectrogram.data) == 0 {
+		return
+	}
+	// 转换为调色板图像，PNG体积约为真彩色的三分之一
+	img := stream.spectrogram.render(result, result.Analysis.Duration)
+	paletted := image.NewPaletted(img.Bounds(), spectrogramColors())
+	draw.Draw(paletted, img.Bounds(), img, img.Bounds().Min, draw.Src)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, paletted); err != nil {
+		return
+	}
+	result.Spectrogram = buf.Bytes()
+}
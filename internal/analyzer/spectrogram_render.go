@@ -0,0 +1,223 @@
+package analyzer
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"audio-loss-checker/internal/types"
+)
+
+// 频谱图版面（像素）
+const (
+	plotLeft   = 56 // 左侧频率刻度
+	plotRight  = 72 // 右侧色标
+	plotTop    = 16
+	plotBottom = 28 // 下方时间刻度
+	glyphScale = 2  // 3x5点阵字体的放大倍数
+)
+
+var (
+	backgroundColor = color.RGBA{24, 24, 24, 255}
+	axisTextColor   = color.RGBA{200, 200, 200, 255}
+	gridColor       = color.RGBA{255, 255, 255, 255}
+	maxFreqColor    = color.RGBA{255, 48, 48, 255}
+	cutoffColor     = color.RGBA{255, 220, 0, 255}
+)
+
+// spectrogramPalette 色标从下限到0dB的颜色节点
+var spectrogramPalette = []color.RGBA{
+	{0, 0, 0, 255},
+	{0, 0, 120, 255},
+	{120, 0, 160, 255},
+	{220, 30, 60, 255},
+	{255, 160, 0, 255},
+	{255, 255, 200, 255},
+}
+
+// render 绘制频谱图，叠加频率网格、时间网格、色标以及分析得到的最高有效频率和截断频率
+func (g *spectrogramSink) render(result *types.AnalysisResult, duration float64) *image.RGBA {
+	width, height := len(g.data), g.rows
+	img := image.NewRGBA(image.Rect(0, 0, plotLeft+width+plotRight, plotTop+height+plotBottom))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = backgroundColor.R, backgroundColor.G, backgroundColor.B, 255
+	}
+
+	// 频谱（第0行为最低频率，画在最下面）
+	for x, column := range g.data {
+		for r, db := range column {
+			img.SetRGBA(plotLeft+x, plotTop+height-1-r, dbColor(float64(db)))
+		}
+	}
+
+	// 频率网格
+	nyquist := float64(g.s.sampleRate) / 2
+	freqStep := niceStep(nyquist, 12, []float64{1000, 2000, 5000, 10000, 20000})
+	for f := freqStep; f < nyquist; f += freqStep {
+		y := plotTop + height - 1 - int(f/nyquist*float64(height))
+		for x := 0; x < width; x++ {
+			blend(img, plotLeft+x, y, gridColor, 0.35)
+		}
+		label := fmt.Sprintf("%gk", f/1000)
+		drawText(img, plotLeft-6-textWidth(label), y-glyphHeight()/2, label, axisTextColor)
+	}
+
+	// 时间网格
+	if duration > 0 {
+		timeStep := niceStep(duration, 10, []float64{1, 2, 5, 10, 15, 30, 60, 120, 300, 600, 1200, 1800, 3600})
+		for t := 0.0; t <= duration; t += timeStep {
+			x := plotLeft + int(t/duration*float64(width-1))
+			if t > 0 {
+				for y := 0; y < height; y++ {
+					blend(img, x, plotTop+y, gridColor, 0.25)
+				}
+			}
+			label := fmt.Sprintf("%d:%02d", int(t)/60, int(t)%60)
+			drawText(img, x-textWidth(label)/2, plotTop+height+8, label, axisTextColor)
+		}
+	}
+
+	// 分析结果：最高有效频率（红色实线），截断频率（黄色虚线，接近奈奎斯特频率时不画）
+	analysis := result.Analysis
+	if f := analysis.MaxFrequency; f > 0 && f < nyquist {
+		g.drawFrequencyLine(img, f, maxFreqColor, false)
+	}
+	if f := analysis.CutoffHz; f > 0 && f < nyquist*0.99 && math.Abs(f-analysis.MaxFrequency) > freqStep/4 {
+		g.drawFrequencyLine(img, f, cutoffColor, true)
+	}
+
+	// 色标
+	barX := plotLeft + width + 12
+	for y := 0; y < height; y++ {
+		db := spectrogramFloorDB * float64(y) / float64(height-1)
+		for x := 0; x < 14; x++ {
+			img.SetRGBA(barX+x, plotTop+y, dbColor(db))
+		}
+	}
+	for db := 0.0; db >= spectrogramFloorDB; db -= 20 {
+		y := plotTop + int(db/spectrogramFloorDB*float64(height-1))
+		drawText(img, barX+20, y-glyphHeight()/2, fmt.Sprintf("%.0f", db), axisTextColor)
+	}
+	drawText(img, barX, plotTop-glyphHeight()-3, "dB", axisTextColor)
+
+	return img
+}
+
+// drawFrequencyLine 在 f 处画一条贯穿的水平线，并在左上方标出频率
+func (g *spectrogramSink) drawFrequencyLine(img *image.RGBA, f float64, c color.RGBA, dashed bool) {
+	width, height := len(g.data), g.rows
+	nyquist := float64(g.s.sampleRate) / 2
+	y := plotTop + height - 1 - int(f/nyquist*float64(height))
+	for x := 0; x < width; x++ {
+		if dashed && x%12 >= 8 {
+			continue
+		}
+		img.SetRGBA(plotLeft+x, y, c)
+		img.SetRGBA(plotLeft+x, y-1, c)
+	}
+	label := fmt.Sprintf("%.1fk", f/1000)
+	drawText(img, plotLeft+6, y-glyphHeight()-4, label, c)
+}
+
+// spectrogramColors 返回频谱图用到的颜色：色标上均匀取的颜色加上版面颜色，用于生成调色板图像
+func spectrogramColors() color.Palette {
+	palette := color.Palette{backgroundColor, axisTextColor, gridColor, maxFreqColor, cutoffColor}
+	for len(palette) < 256 {
+		db := spectrogramFloorDB * float64(256-len(palette)-1) / float64(256-6)
+		palette = append(palette, dbColor(db))
+	}
+	return palette
+}
+
+// dbColor 把相对满幅的dB值映射为色标颜色
+func dbColor(db float64) color.RGBA {
+	t := 1 - db/spectrogramFloorDB
+	t = math.Max(0, math.Min(1, t))
+	pos := t * float64(len(spectrogramPalette)-1)
+	i := int(pos)
+	if i >= len(spectrogramPalette)-1 {
+		return spectrogramPalette[len(spectrogramPalette)-1]
+	}
+	frac := pos - float64(i)
+	a, b := spectrogramPalette[i], spectrogramPalette[i+1]
+	lerp := func(x, y uint8) uint8 { return uint8(float64(x) + (float64(y)-float64(x))*frac) }
+	return color.RGBA{lerp(a.R, b.R), lerp(a.G, b.G), lerp(a.B, b.B), 255}
+}
+
+// niceStep 从候选间隔中选出使刻度数不超过 maxTicks 的最小间隔
+func niceStep(span float64, maxTicks int, candidates []float64) float64 {
+	for _, step := range candidates {
+		if span/step <= float64(maxTicks) {
+			return step
+		}
+	}
+	return candidates[len(candidates)-1] * math.Ceil(span/candidates[len(candidates)-1]/float64(maxTicks))
+}
+
+// blend 以 alpha 的不透明度把颜色 c 叠加到像素上
+func blend(img *image.RGBA, x, y int, c color.RGBA, alpha float64) {
+	if !(image.Point{x, y}.In(img.Rect)) {
+		return
+	}
+	old := img.RGBAAt(x, y)
+	mix := func(o, n uint8) uint8 { return uint8(float64(o)*(1-alpha) + float64(n)*alpha) }
+	img.SetRGBA(x, y, color.RGBA{mix(old.R, c.R), mix(old.G, c.G), mix(old.B, c.B), 255})
+}
+
+// glyphs 3x5点阵字体，每行的低3位从左到右表示像素，只包含刻度需要的字符
+var glyphs = map[rune][5]uint8{
+	'0': {7, 5, 5, 5, 7},
+	'1': {2, 6, 2, 2, 7},
+	'2': {7, 1, 7, 4, 7},
+	'3': {7, 1, 7, 1, 7},
+	'4': {5, 5, 7, 1, 1},
+	'5': {7, 4, 7, 1, 7},
+	'6': {7, 4, 7, 5, 7},
+	'7': {7, 1, 1, 1, 1},
+	'8': {7, 5, 7, 5, 7},
+	'9': {7, 5, 7, 1, 7},
+	'k': {4, 5, 6, 5, 5},
+	':': {0, 2, 0, 2, 0},
+	'-': {0, 0, 7, 0, 0},
+	'.': {0, 0, 0, 0, 2},
+	'd': {1, 1, 7, 5, 7},
+	'B': {6, 5, 6, 5, 6},
+}
+
+// glyphHeight 返回文字高度（像素）
+func glyphHeight() int {
+	return 5 * glyphScale
+}
+
+// textWidth 返回文字宽度（像素），字符间隔一个点
+func textWidth(text string) int {
+	n := len([]rune(text))
+	if n == 0 {
+		return 0
+	}
+	return (n*4 - 1) * glyphScale
+}
+
+// drawText 以 (x, y) 为左上角绘制文字，不支持的字符留空
+func drawText(img *image.RGBA, x, y int, text string, c color.RGBA) {
+	for _, r := range text {
+		glyph := glyphs[r]
+		for row, bits := range glyph {
+			for col := 0; col < 3; col++ {
+				if bits&(4>>col) == 0 {
+					continue
+				}
+				for dy := 0; dy < glyphScale; dy++ {
+					for dx := 0; dx < glyphScale; dx++ {
+						px, py := x+col*glyphScale+dx, y+row*glyphScale+dy
+						if (image.Point{px, py}).In(img.Rect) {
+							img.SetRGBA(px, py, c)
+						}
+					}
+				}
+			}
+		}
+		x += 4 * glyphScale
+	}
+}
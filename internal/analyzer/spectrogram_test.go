@@ -0,0 +1,33 @@
+package analyzer
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// 输出目录中保留相对子目录，不同目录中的同名文件不会互相覆盖
+func TestSpectrogramPath(t *testing.T) {
+	root := t.TempDir()
+	out := filepath.Join(root, "out")
+	opts := SpectrogramOptions{OutputDir: out, Root: filepath.Join(root, "music")}
+
+	a := spectrogramPath(filepath.Join(root, "music", "A", "01.flac"), opts)
+	b := spectrogramPath(filepath.Join(root, "music", "B", "01.flac"), opts)
+	if a != filepath.Join(out, "A", "01.flac.png") || b != filepath.Join(out, "B", "01.flac.png") {
+		t.Errorf("输出路径为 %s 和 %s", a, b)
+	}
+
+	// 不在根目录下的文件（如CUE表引用了其他目录中的镜像）按完整路径加短哈希
+	c := spectrogramPath(filepath.Join(root, "other", "01.flac"), opts)
+	d := spectrogramPath(filepath.Join(root, "another", "01.flac"), opts)
+	if c == d || filepath.Dir(c) != out || !strings.HasPrefix(filepath.Base(c), "01.flac.") {
+		t.Errorf("根目录之外的输出路径为 %s 和 %s", c, d)
+	}
+
+	// 未指定输出目录时写到音频文件旁边
+	e := filepath.Join(root, "music", "A", "01.flac")
+	if got := spectrogramPath(e, SpectrogramOptions{Root: opts.Root}); got != e+".png" {
+		t.Errorf("默认输出路径为 %s", got)
+	}
+}
@@ -5,13 +5,39 @@ import (
 	"math"
 	"math/cmplx"
 
+	"audio-loss-checker/internal/types"
+
 	"github.com/mjibson/go-dsp/fft"
 )
 
+// Welch平均的默认参数
+const (
+	DefaultWelchWindows = 256 // 默认最多平均的窗口数
+	DefaultWelchOverlap = 0.5 // 默认相邻窗口重叠50%
+
+	// DefaultCutoffFreq 默认的频率截断阈值 (Hz)，最高有效频率低于此值是有损来源的证据
+	DefaultCutoffFreq = 18000.0
+)
+
+const (
+	// cutoffTolerance 判断窗口是否在某个频率截止时的容差 (Hz)，单个窗口的最高有效频率受频谱泄漏影响有一定波动
+	cutoffTolerance = 1000.0
+	// minCutoffOccurrence 截断至少要出现在多数窗口中才判定为有损截断
+	minCutoffOccurrence = 0.5
+	// silentPower 窗口均方值低于此值（约-100dBFS）视为静音，不计入逐窗口的最高频率
+	silentPower = 1e-10
+	// channelMismatchHz 声道之间最高有效频率相差超过此值时给出提示
+	channelMismatchHz = 2000.0
+)
+
 // SpectrumAnalyzer 频谱分析器
 type SpectrumAnalyzer struct {
-	sampleRate int
-	windowSize int
+	sampleRate      int
+	windowSize      int
+	windows         int     // Welch平均的最大窗口数，0表示使用全部窗口
+	overlap         float64 // 相邻窗口的重叠比例
+	cutoffThreshold float64 // 频率截断阈值 (Hz)
+	fingerprints    []types.EncoderFingerprint
 }
 
 // NewSpectrumAnalyzer 创建频谱分析器
@@ -19,48 +45,194 @@ func NewSpectrumAnalyzer(sampleRate int) *SpectrumAnalyzer {
 	// 使用合适的窗口大小进行FFT分析
 	windowSize := 8192 // 8K窗口，提供良好的频率分辨率
 	return &SpectrumAnalyzer{
-		sampleRate: sampleRate,
-		windowSize: windowSize,
+		sampleRate:      sampleRate,
+		windowSize:      windowSize,
+		windows:         DefaultWelchWindows,
+		overlap:         DefaultWelchOverlap,
+		cutoffThreshold: DefaultCutoffFreq,
+		fingerprints:    builtinFingerprints,
 	}
 }
 
-// AnalyzeSpectrum 分析音频频谱
+// SetWelchOptions 设置Welch平均的最大窗口数（0表示使用全部窗口）和相邻窗口的重叠比例
+func (s *SpectrumAnalyzer) SetWelchOptions(windows int, overlap float64) {
+	s.windows = windows
+	s.overlap = overlap
+}
+
+// SetFingerprints 设置用于识别来源编码器的低通指纹表
+func (s *SpectrumAnalyzer) SetFingerprints(fingerprints []types.EncoderFingerprint) {
+	s.fingerprints = fingerprints
+}
+
+// SetCutoffThreshold 设置频率截断阈值，最高有效频率低于此值时作为有损来源的证据
+func (s *SpectrumAnalyzer) SetCutoffThreshold(freq float64) {
+	s.cutoffThreshold = freq
+}
+
+// AnalyzeSpectrum 分析单个声道的音频频谱
+// 在整段采样上按Welch方法平均多个相互重叠的窗口，避免单个窗口落在安静段落造成误判
 func (s *SpectrumAnalyzer) AnalyzeSpectrum(samples []float64) (*SpectrumResult, error) {
 	if len(samples) == 0 {
 		return nil, fmt.Errorf("音频采样数据为空")
 	}
 
-	// 如果样本数量太少，使用所有样本
-	if len(samples) < s.windowSize {
-		s.windowSize = nearestPowerOf2(len(samples))
+	sink := s.newWelchSink(0, int64(len(samples)))
+	sink.consume([][]float64{samples})
+	return sink.result()
+}
+
+// AnalyzeStream 从采样流中分别分析每个声道的频谱，内存占用与文件长度无关
+// expectedFrames 为预计的总帧数，用于预先安排窗口位置
+func (s *SpectrumAnalyzer) AnalyzeStream(reader types.SampleReader, channels int, expectedFrames int64) ([]*SpectrumResult, error) {
+	sinks := make([]sampleSink, channels)
+	welch := make([]*welchSink, channels)
+	for ch := range welch {
+		welch[ch] = s.newWelchSink(ch, expectedFrames)
+		sinks[ch] = welch[ch]
+	}
+	if _, err := runStream(reader, channels, sinks); err != nil {
+		return nil, fmt.Errorf("读取音频数据失败: %w", err)
+	}
+
+	results := make([]*SpectrumResult, channels)
+	for ch, sink := range welch {
+		result, err := sink.result()
+		if err != nil {
+			return nil, err
+		}
+		results[ch] = result
+	}
+	return results, nil
+}
+
+// welchSink 流式计算一个声道的Welch平均功率谱，同时记录每个窗口的最高有效频率
+// 窗口总数不超过上限时按重叠比例连续排列，超过上限时在整个文件范围内均匀分布；
+// 长度未知时按重叠比例连续截取，每截取一轮上限数量的窗口后间隔加倍，平均时按窗口代表的时长加权
+type welchSink struct {
+	s           *SpectrumAnalyzer
+	channel     int
+	size        int64 // 窗口长度（帧）
+	next        int64 // 下一个窗口的结束位置
+	step        int64 // 相邻窗口起点的间隔
+	planned     int   // 计划截取的窗口数
+	streaming   bool  // 长度未知，一直截取到流结束
+	weight      float64
+	totalWeight float64
+	captured    int
+	ring        []float64
+	seen        int64
+	sumPower    []float64
+	windowMax   []float64
+}
+
+// newWelchSink 为第 channel 个声道创建分析步骤，根据预计总帧数安排窗口位置
+// 预计总帧数短于一个窗口（长度未知）时从头开始按重叠比例截取，读完后仍没有完整窗口时使用全部采样
+func (s *SpectrumAnalyzer) newWelchSink(channel int, expectedFrames int64) *welchSink {
+	size := int64(s.windowSize)
+	hop := int64(math.Round(float64(size) * (1 - s.overlap)))
+	if hop < 1 {
+		hop = 1
+	}
+	sink := &welchSink{
+		s:        s,
+		channel:  channel,
+		size:     size,
+		next:     size,
+		step:     hop,
+		weight:   1,
+		ring:     make([]float64, size),
+		sumPower: make([]float64, size/2),
+	}
+
+	total := expectedFrames
+	if total < size {
+		sink.streaming = true
+		return sink
 	}
 
-	// 取样本的中间部分进行分析，避免开头和结尾的静音部分
-	startIdx := len(samples) / 4
-	endIdx := startIdx + s.windowSize
-	if endIdx > len(samples) {
-		endIdx = len(samples)
-		startIdx = endIdx - s.windowSize
-		if startIdx < 0 {
-			startIdx = 0
+	n := (total-size)/hop + 1
+	if s.windows > 0 && n > int64(s.windows) {
+		n = int64(s.windows)
+		if n == 1 {
+			// 只用一个窗口时与旧版一样取1/4处，避开开头的静音
+			sink.next = min(total/4+size, total)
+		} else {
+			sink.step = (total - size) / (n - 1)
 		}
 	}
+	sink.planned = int(n)
+	return sink
+}
 
-	// 提取分析窗口
-	window := samples[startIdx:endIdx]
+func (w *welchSink) consume(block [][]float64) {
+	for _, v := range block[w.channel] {
+		if !w.streaming && w.captured >= w.planned && w.seen >= w.size {
+			return
+		}
+		w.ring[w.seen%w.size] = v
+		w.seen++
+		if w.seen != w.next || (!w.streaming && w.captured >= w.planned) {
+			continue
+		}
+		w.addWindow(w.recent())
+		// 长度未知时窗口数随文件长度按对数增长，后面的窗口间隔更大，每个窗口代表更长的时间
+		if w.streaming && w.s.windows > 0 && w.captured%w.s.windows == 0 {
+			w.step *= 2
+			w.weight *= 2
+		}
+		w.next += w.step
+	}
+}
 
-	// 应用汉明窗减少频谱泄漏
-	windowedSamples := s.applyHammingWindow(window)
+// addWindow 计算一个窗口的功率谱并计入平均
+func (w *welchSink) addWindow(window []float64) {
+	power := w.s.calculatePowerSpectrum(fft.FFTReal(w.s.applyHammingWindow(window)))
+	for k, p := range power {
+		w.sumPower[k] += w.weight * p
+	}
+	w.totalWeight += w.weight
+	w.captured++
 
-	// 进行FFT变换
-	spectrum := fft.FFTReal(windowedSamples)
+	// 静音窗口的噪声基底为0，最高有效频率没有意义
+	energy := 0.0
+	for _, v := range window {
+		energy += v * v
+	}
+	if energy/float64(len(window)) >= silentPower {
+		freqResolution := float64(w.s.sampleRate) / float64(len(power)*2)
+		w.windowMax = append(w.windowMax, w.s.findMaxEffectiveFrequency(power, freqResolution))
+	}
+}
 
-	// 计算功率谱密度
-	powerSpectrum := s.calculatePowerSpectrum(spectrum)
+// recent 按时间顺序返回最近的采样（不超过窗口长度）
+func (w *welchSink) recent() []float64 {
+	if w.seen < w.size {
+		return append([]float64(nil), w.ring[:w.seen]...)
+	}
+	start := w.seen % w.size
+	return append(append([]float64(nil), w.ring[start:]...), w.ring[:start]...)
+}
 
-	// 分析频谱特征
-	result := s.analyzeFrequencyContent(powerSpectrum)
+// result 汇总平均功率谱并分析频谱特征
+// 没有截取到窗口时（文件短于一个窗口，或时长预估偏长导致流提前结束）使用最后一段采样
+func (w *welchSink) result() (*SpectrumResult, error) {
+	if w.captured == 0 {
+		if w.seen == 0 {
+			return nil, fmt.Errorf("音频采样数据为空")
+		}
+		last := w.recent()
+		w.sumPower = make([]float64, len(last)/2)
+		w.addWindow(last)
+	}
 
+	avgPower := make([]float64, len(w.sumPower))
+	for i, p := range w.sumPower {
+		avgPower[i] = p / w.totalWeight
+	}
+	result := w.s.analyzeFrequencyContent(avgPower, w.windowMax)
+	result.Windows = w.captured
+	result.Silent = len(w.windowMax) == 0
 	return result, nil
 }
 
@@ -92,15 +264,36 @@ func (s *SpectrumAnalyzer) calculatePowerSpectrum(spectrum []complex128) []float
 
 // SpectrumResult 频谱分析结果
 type SpectrumResult struct {
-	MaxFrequency    float64   // 最高有效频率
-	CutoffFrequency float64   // 截断频率
-	IsFake          bool      // 是否为假无损
-	Details         string    // 详细说明
-	PowerSpectrum   []float64 // 功率谱（用于进一步分析）
+	MaxFrequency         float64             // 最高有效频率
+	CutoffFrequency      float64             // 截断频率
+	IsFake               bool                // 是否为假无损（置信度达到FAKE）
+	Confidence           float64             // 有损来源的置信度 (0-1)
+	Evidence             []types.Evidence    // 频谱分析得到的各条证据
+	EncoderMatch         *types.EncoderMatch // 与截断特征最吻合的编码器
+	Details              string              // 详细说明，取自支持结论的最强证据
+	PowerSpectrum        []float64           // Welch平均功率谱（用于进一步分析）
+	Windows              int                 // 参与平均的窗口数
+	WindowMaxFrequencies []float64           // 各个非静音窗口的最高有效频率
+	Silent               bool                // 所有窗口都是静音（如单声道录音中空着的声道）
+}
+
+// CutoffOccurrence 返回最高有效频率不超过 freq（含容差）的窗口比例，用于判断截断是否稳定存在
+// 没有非静音窗口时返回1
+func (r *SpectrumResult) CutoffOccurrence(freq float64) float64 {
+	if len(r.WindowMaxFrequencies) == 0 {
+		return 1
+	}
+	count := 0
+	for _, f := range r.WindowMaxFrequencies {
+		if f <= freq+cutoffTolerance {
+			count++
+		}
+	}
+	return float64(count) / float64(len(r.WindowMaxFrequencies))
 }
 
 // analyzeFrequencyContent 分析频率内容
-func (s *SpectrumAnalyzer) analyzeFrequencyContent(powerSpectrum []float64) *SpectrumResult {
+func (s *SpectrumAnalyzer) analyzeFrequencyContent(powerSpectrum, windowMax []float64) *SpectrumResult {
 	// 频率分辨率
 	freqResolution := float64(s.sampleRate) / float64(len(powerSpectrum)*2)
 
@@ -110,16 +303,22 @@ func (s *SpectrumAnalyzer) analyzeFrequencyContent(powerSpectrum []float64) *Spe
 	// 检测是否存在明显的频率截断
 	cutoffFreq := s.detectFrequencyCutoff(powerSpectrum, freqResolution)
 
-	// 判断是否为假无损
-	isFake, details := s.determineFakeStatus(maxFreq, cutoffFreq)
+	result := &SpectrumResult{
+		MaxFrequency:         maxFreq,
+		CutoffFrequency:      cutoffFreq,
+		PowerSpectrum:        powerSpectrum,
+		WindowMaxFrequencies: windowMax,
+	}
 
-	return &SpectrumResult{
-		MaxFrequency:    maxFreq,
-		CutoffFrequency: cutoffFreq,
-		IsFake:          isFake,
-		Details:         details,
-		PowerSpectrum:   powerSpectrum,
+	// 汇总各条证据给出判断
+	result.Evidence = s.spectrumEvidence(result)
+	result.Confidence = evidenceConfidence(result.Evidence)
+	result.IsFake = confidenceStatus(result.Confidence) == "FAKE"
+	if lead, ok := leadingEvidence(result.Evidence, result.Confidence >= suspectConfidence); ok {
+		result.Details = lead.Details
 	}
+
+	return result
 }
 
 // findMaxEffectiveFrequency 找到最高有效频率
@@ -190,35 +389,146 @@ func (s *SpectrumAnalyzer) calculateNoiseFloor(powerSpectrum []float64) float64
 	return sum / float64(count)
 }
 
-// determineFakeStatus 判断是否为假无损
-func (s *SpectrumAnalyzer) determineFakeStatus(maxFreq, cutoffFreq float64) (bool, string) {
-	// 常见的有损编码截断频率
-	commonCutoffs := map[float64]string{
-		16000: "MP3 128kbps",
-		17000: "MP3 160kbps",
-		19000: "MP3 192kbps",
-		20000: "MP3 256kbps",
-		21000: "MP3 320kbps",
+// BrickWall 砖墙式频率截断
+type BrickWall struct {
+	Frequency float64 // 截断频率 (Hz)
+	DropDB    float64 // 截断处两侧的电平差 (dB)
+}
+
+// brickWallSink 流式累积多个窗口的平均功率谱，用于寻找砖墙截断
+// 采样先混合为单声道，在整个文件范围内均匀选取窗口，以降低随机波动
+type brickWallSink struct {
+	s        *SpectrumAnalyzer
+	channels int
+	step     int64 // 相邻窗口起点的间隔（帧）
+	windows  int   // 计划截取的窗口数
+	ring     []float64
+	pos      int64
+	captured int
+	avgPower []float64
+}
+
+// newBrickWallSink 根据预计总帧数安排窗口位置，文件短于一个窗口时不截取
+func (s *SpectrumAnalyzer) newBrickWallSink(channels int, expectedFrames int64) *brickWallSink {
+	const maxWindows = 64
+	windowSize := int64(s.windowSize)
+
+	sink := &brickWallSink{
+		s:        s,
+		channels: channels,
+		ring:     make([]float64, windowSize),
+		avgPower: make([]float64, windowSize/2),
+	}
+	sink.windows, sink.step = spreadWindows(expectedFrames, windowSize, maxWindows)
+	return sink
+}
+
+// spreadWindows 在 frames 帧内均匀安排不超过 maxWindows 个互不重叠的窗口，返回窗口数和起点间隔
+// 不足一个窗口时返回0
+func spreadWindows(frames, windowSize, maxWindows int64) (int, int64) {
+	if frames < windowSize {
+		return 0, 0
 	}
+	n := frames / windowSize
+	if n > maxWindows {
+		n = maxWindows
+	}
+	return int(n), (frames - windowSize) / n
+}
 
-	// 检查是否接近已知的有损编码截断频率
-	for cutoff, format := range commonCutoffs {
-		if math.Abs(maxFreq-cutoff) < 500 { // 500Hz的容差
-			return true, fmt.Sprintf("检测到%s格式的典型截断频率 (%.0f Hz)", format, maxFreq)
+func (b *brickWallSink) consume(block [][]float64) {
+	windowSize := int64(len(b.ring))
+	for i := range block[0] {
+		if b.captured >= b.windows {
+			return
+		}
+
+		// 混合为单声道
+		sum := 0.0
+		for ch := 0; ch < b.channels; ch++ {
+			sum += block[ch][i]
+		}
+		b.ring[b.pos%windowSize] = sum / float64(b.channels)
+		b.pos++
+
+		// 到达下一个窗口的结束位置时计算功率谱
+		if b.pos == int64(b.captured)*b.step+windowSize {
+			start := b.pos % windowSize
+			mono := append(append(make([]float64, 0, windowSize), b.ring[start:]...), b.ring[:start]...)
+			power := b.s.calculatePowerSpectrum(fft.FFTReal(b.s.applyBlackmanHarrisWindow(mono)))
+			for k, p := range power {
+				b.avgPower[k] += p
+			}
+			b.captured++
 		}
 	}
+}
 
-	// 如果最高频率低于18kHz，很可能是假无损
-	if maxFreq < 18000 {
-		return true, fmt.Sprintf("最高有效频率过低 (%.0f Hz)，可能从有损格式转换而来", maxFreq)
+// detect 在 [minFreq, maxFreq] 范围内寻找最陡峭的频谱下降，没有截取到窗口时返回nil
+func (b *brickWallSink) detect(minFreq, maxFreq float64) *BrickWall {
+	if b.captured == 0 {
+		return nil
+	}
+	avgPower := make([]float64, len(b.avgPower))
+	for i, p := range b.avgPower {
+		avgPower[i] = p / float64(b.captured)
 	}
 
-	// 如果存在明显的频率截断
-	if cutoffFreq < float64(s.sampleRate)/2*0.9 { // 截断频率低于奈奎斯特频率的90%
-		return true, fmt.Sprintf("在 %.0f Hz 附近检测到明显的频率截断", cutoffFreq)
+	s := b.s
+	freqResolution := float64(s.sampleRate) / float64(s.windowSize)
+
+	// 比较候选频率两侧各1.2kHz频带（中间留0.3kHz过渡）的平均电平
+	const gap, width = 300.0, 1200.0
+	if maxFreq+gap+width > float64(s.sampleRate)/2 {
+		maxFreq = float64(s.sampleRate)/2 - gap - width
+	}
+
+	var best *BrickWall
+	for f := minFreq; f <= maxFreq; f += freqResolution {
+		drop := bandMeanDB(avgPower, freqResolution, f-gap-width, f-gap) -
+			bandMeanDB(avgPower, freqResolution, f+gap, f+gap+width)
+		if best == nil || drop > best.DropDB {
+			best = &BrickWall{Frequency: f, DropDB: drop}
+		}
 	}
 
-	return false, fmt.Sprintf("频谱正常，最高有效频率 %.0f Hz", maxFreq)
+	return best
+}
+
+// bandMeanDB 计算 [lo, hi) 频带的平均功率 (dB)，频带为空时返回0
+// 功率动态范围很大，直接求和以免前缀和相减损失精度
+func bandMeanDB(power []float64, freqResolution, lo, hi float64) float64 {
+	i := int(lo / freqResolution)
+	j := int(hi / freqResolution)
+	if i < 0 {
+		i = 0
+	}
+	if j > len(power) {
+		j = len(power)
+	}
+	if j <= i {
+		return 0
+	}
+	sum := 0.0
+	for _, p := range power[i:j] {
+		sum += p
+	}
+	return 10 * math.Log10(sum/float64(j-i)+1e-30)
+}
+
+// applyBlackmanHarrisWindow 应用4项布莱克曼-哈里斯窗
+// 旁瓣低于-92dB，适合测量深度截断后的残余电平
+func (s *SpectrumAnalyzer) applyBlackmanHarrisWindow(samples []float64) []float64 {
+	windowed := make([]float64, len(samples))
+	n := float64(len(samples) - 1)
+
+	for i, sample := range samples {
+		x := 2 * math.Pi * float64(i) / n
+		window := 0.35875 - 0.48829*math.Cos(x) + 0.14128*math.Cos(2*x) - 0.01168*math.Cos(3*x)
+		windowed[i] = sample * window
+	}
+
+	return windowed
 }
 
 // nearestPowerOf2 找到最接近的2的幂
@@ -0,0 +1,78 @@
+package analyzer
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// streamWelch 把采样分块送入长度未知（expectedFrames 为0）的Welch分析
+func streamWelch(t *testing.T, s *SpectrumAnalyzer, samples []float64) *SpectrumResult {
+	t.Helper()
+	sink := s.newWelchSink(0, 0)
+	for pos := 0; pos < len(samples); pos += 4096 {
+		sink.consume([][]float64{samples[pos:min(pos+4096, len(samples))]})
+	}
+	result, err := sink.result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+// 长度未知时平均整个流中的窗口，而不是只用最后一段：
+// 前5秒为1k/8k/17kHz的正弦波加微弱噪声，最后1秒为数字静音
+func TestWelchUnknownLength(t *testing.T) {
+	const sampleRate = 44100
+	rng := rand.New(rand.NewSource(1))
+	samples := make([]float64, 6*sampleRate)
+	for i := range 5 * sampleRate {
+		for _, freq := range []float64{1000, 8000, 17000} {
+			samples[i] += 0.1 * math.Sin(2*math.Pi*freq*float64(i)/sampleRate)
+		}
+		samples[i] += rng.NormFloat64() * 1e-4
+	}
+
+	s := NewSpectrumAnalyzer(sampleRate)
+	result := streamWelch(t, s, samples)
+	// 按50%重叠连续排列：(264600-8192)/4096+1 个窗口
+	if result.Windows != 63 {
+		t.Errorf("平均了 %d 个窗口，应为 63", result.Windows)
+	}
+	if result.Silent || math.Abs(result.MaxFrequency-17000) > cutoffTolerance {
+		t.Errorf("静音=%v，最高有效频率 %.0f Hz", result.Silent, result.MaxFrequency)
+	}
+
+	// 预先知道长度时结果相同
+	planned := s.newWelchSink(0, int64(len(samples)))
+	planned.consume([][]float64{samples})
+	want, err := planned.result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want.Windows != result.Windows || want.MaxFrequency != result.MaxFrequency {
+		t.Errorf("长度已知时为 %d 个窗口、%.0f Hz，未知时为 %d 个窗口、%.0f Hz",
+			want.Windows, want.MaxFrequency, result.Windows, result.MaxFrequency)
+	}
+}
+
+// 长度未知且窗口数有上限时，每截取一轮上限数量的窗口后间隔加倍
+func TestWelchUnknownLengthLimit(t *testing.T) {
+	s := NewSpectrumAnalyzer(44100)
+	s.SetWelchOptions(4, 0.5)
+	rng := rand.New(rand.NewSource(2))
+	samples := make([]float64, 8192+4096*20)
+	for i := range samples {
+		samples[i] = rng.NormFloat64() * 0.1
+	}
+
+	// 窗口结束位置：8192起每4096帧4个，之后每8192帧4个，再之后每16384帧2个
+	if result := streamWelch(t, s, samples); result.Windows != 10 {
+		t.Errorf("平均了 %d 个窗口，应为 10", result.Windows)
+	}
+
+	// 短于一个窗口时使用全部采样
+	if result := streamWelch(t, s, samples[:5000]); result.Windows != 1 {
+		t.Errorf("短于一个窗口时平均了 %d 个窗口", result.Windows)
+	}
+}
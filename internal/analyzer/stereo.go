@@ -0,0 +1,184 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/mjibson/go-dsp/fft"
+)
+
+const (
+	// 侧声道塌缩检测的频带划分
+	stereoBandWidth = 500.0   // 逐频带比较的带宽 (Hz)
+	stereoRefLow    = 4000.0  // 参考频段下限，联合立体声编码在参考频段内通常保留侧声道
+	stereoRefHigh   = 11000.0 // 参考频段上限
+	stereoScanLow   = 11000.0 // 塌缩起点的搜索下限
+	stereoScanHigh  = 20000.0 // 搜索上限，同时不超过奈奎斯特频率的95%
+
+	// stereoMinWidthDB 参考频段侧/中比低于此值时视为近似单声道，不做判断
+	stereoMinWidthDB = -30.0
+	// stereoMidContentDB 中声道频带电平不低于参考频段减去此值时才算有内容
+	stereoMidContentDB = 60.0
+	// stereoCollapseDropDB 侧/中比比参考频段低出这么多才算塌缩
+	stereoCollapseDropDB = 15.0
+	// stereoMinCollapseBands 塌缩区域至少要有这么多个有内容的频带（2kHz）
+	stereoMinCollapseBands = 4
+)
+
+// StereoCollapseResult 侧声道塌缩检测结果
+type StereoCollapseResult struct {
+	Detected   bool
+	Frequency  float64 // 侧声道开始塌缩的频率 (Hz)
+	DropDB     float64 // 塌缩区域侧/中比相对参考频段下降的平均幅度 (dB)
+	Confidence float64 // 0-1
+	Details    string
+}
+
+// stereoSink 流式累积中声道 (L+R)/2 和侧声道 (L-R)/2 的平均功率谱
+// 与砖墙检测一样在整个文件范围内均匀选取窗口
+type stereoSink struct {
+	s         *SpectrumAnalyzer
+	step      int64
+	windows   int
+	mid       []float64
+	side      []float64
+	pos       int64
+	captured  int
+	midPower  []float64
+	sidePower []float64
+}
+
+// newStereoSink 为双声道音频创建中/侧声道分析步骤
+func (s *SpectrumAnalyzer) newStereoSink(expectedFrames int64) *stereoSink {
+	const maxWindows = 64
+	windowSize := int64(s.windowSize)
+
+	sink := &stereoSink{
+		s:         s,
+		mid:       make([]float64, windowSize),
+		side:      make([]float64, windowSize),
+		midPower:  make([]float64, windowSize/2),
+		sidePower: make([]float64, windowSize/2),
+	}
+	sink.windows, sink.step = spreadWindows(expectedFrames, windowSize, maxWindows)
+	return sink
+}
+
+func (st *stereoSink) consume(block [][]float64) {
+	windowSize := int64(len(st.mid))
+	left, right := block[0], block[1]
+	for i := range left {
+		if st.captured >= st.windows {
+			return
+		}
+
+		st.mid[st.pos%windowSize] = (left[i] + right[i]) / 2
+		st.side[st.pos%windowSize] = (left[i] - right[i]) / 2
+		st.pos++
+
+		if st.pos == int64(st.captured)*st.step+windowSize {
+			start := st.pos % windowSize
+			st.accumulate(st.midPower, st.mid, start)
+			st.accumulate(st.sidePower, st.side, start)
+			st.captured++
+		}
+	}
+}
+
+// accumulate 按时间顺序取出环形缓冲中的窗口，把功率谱累加到 sum
+func (st *stereoSink) accumulate(sum, ring []float64, start int64) {
+	window := append(append(make([]float64, 0, len(ring)), ring[start:]...), ring[:start]...)
+	power := st.s.calculatePowerSpectrum(fft.FFTReal(st.s.applyBlackmanHarrisWindow(window)))
+	for k, p := range power {
+		sum[k] += p
+	}
+}
+
+// detect 比较各频带侧声道与中声道的电平差，寻找高频侧声道塌缩
+// MP3/AAC的联合立体声在约16kHz以上几乎丢弃侧声道，而中声道仍保留内容；
+// 近似单声道、中声道高频没有内容或没有截取到窗口时无法判断，返回nil
+func (st *stereoSink) detect() *StereoCollapseResult {
+	if st.captured == 0 {
+		return nil
+	}
+	s := st.s
+	freqResolution := float64(s.sampleRate) / float64(s.windowSize)
+	top := math.Min(stereoScanHigh, float64(s.sampleRate)/2*0.95)
+	if top-stereoScanLow < stereoMinCollapseBands*stereoBandWidth {
+		return nil
+	}
+
+	midDB := func(lo float64) float64 {
+		return bandMeanDB(st.midPower, freqResolution, lo, lo+stereoBandWidth)
+	}
+	ratioDB := func(lo float64) float64 {
+		return bandMeanDB(st.sidePower, freqResolution, lo, lo+stereoBandWidth) - midDB(lo)
+	}
+
+	// 参考频段的立体声宽度和中声道电平
+	var refRatio, refMid float64
+	refBands := 0
+	for f := stereoRefLow; f < stereoRefHigh; f += stereoBandWidth {
+		refRatio += ratioDB(f)
+		refMid += midDB(f)
+		refBands++
+	}
+	refRatio /= float64(refBands)
+	refMid /= float64(refBands)
+	if refRatio < stereoMinWidthDB {
+		return nil
+	}
+
+	// 中声道高频没有内容（如已被低通滤波）时，侧/中比没有意义
+	hasContent := false
+	for f := stereoScanLow; f+stereoBandWidth <= top; f += stereoBandWidth {
+		if midDB(f) >= refMid-stereoMidContentDB {
+			hasContent = true
+			break
+		}
+	}
+	if !hasContent {
+		return nil
+	}
+
+	// 从高往低找连续塌缩的频带，只统计中声道有内容的频带
+	var deficits []float64
+	collapseFrom := 0.0
+	for f := top - stereoBandWidth; f >= stereoScanLow; f -= stereoBandWidth {
+		if midDB(f) < refMid-stereoMidContentDB {
+			continue
+		}
+		deficit := refRatio - ratioDB(f)
+		if deficit < stereoCollapseDropDB {
+			break
+		}
+		deficits = append(deficits, deficit)
+		collapseFrom = f
+	}
+
+	if len(deficits) < stereoMinCollapseBands {
+		return &StereoCollapseResult{
+			Details: fmt.Sprintf("高频侧声道未见塌缩（参考频段侧/中比 %.0f dB）", refRatio),
+		}
+	}
+
+	drop := 0.0
+	for _, d := range deficits {
+		drop += d
+	}
+	drop /= float64(len(deficits))
+
+	// 下降幅度越大、立体声越宽，结论越可靠
+	confidence := 0.5 + (drop-stereoCollapseDropDB)/25
+	confidence *= math.Min(1, (refRatio-stereoMinWidthDB)/10)
+	confidence = math.Max(0, math.Min(1, confidence))
+
+	return &StereoCollapseResult{
+		Detected:   true,
+		Frequency:  collapseFrom,
+		DropDB:     drop,
+		Confidence: confidence,
+		Details: fmt.Sprintf("侧声道在 %.0f Hz 以上比参考频段低 %.0f dB，而中声道仍有内容，符合联合立体声有损编码特征",
+			collapseFrom, drop),
+	}
+}
@@ -0,0 +1,119 @@
+package analyzer
+
+import (
+	"math"
+	"math/cmplx"
+	"math/rand"
+	"testing"
+
+	"github.com/mjibson/go-dsp/fft"
+)
+
+// bandNoise 生成 n 帧（n 为2的幂）平坦频谱的噪声，只含 cutoff 以下的频率，有效值为0.1
+// 由随机相位的频谱逆变换得到，cutoff 处是理想的砖墙
+func bandNoise(rng *rand.Rand, n, sampleRate int, cutoff float64) []float64 {
+	spectrum := make([]complex128, n)
+	for k := 1; k < n/2; k++ {
+		if float64(k)*float64(sampleRate)/float64(n) >= cutoff {
+			break
+		}
+		spectrum[k] = cmplx.Rect(1, rng.Float64()*2*math.Pi)
+		spectrum[n-k] = cmplx.Conj(spectrum[k])
+	}
+	out := make([]float64, n)
+	sum := 0.0
+	for i, v := range fft.IFFT(spectrum) {
+		out[i] = real(v)
+		sum += out[i] * out[i]
+	}
+	scale := 0.1 / math.Sqrt(sum/float64(n))
+	for i := range out {
+		out[i] *= scale
+	}
+	return out
+}
+
+func TestStereoCollapse(t *testing.T) {
+	const sampleRate, frames = 44100, 1 << 16
+	rng := rand.New(rand.NewSource(1))
+	full := func() []float64 { return bandNoise(rng, frames, sampleRate, 21000) }
+	// fromMidSide 由中/侧声道合成左右声道
+	fromMidSide := func(mid, side []float64) [][]float64 {
+		left, right := make([]float64, len(mid)), make([]float64, len(mid))
+		for i := range mid {
+			left[i], right[i] = mid[i]+side[i], mid[i]-side[i]
+		}
+		return [][]float64{left, right}
+	}
+	mono := full()
+
+	tests := []struct {
+		name     string
+		block    [][]float64
+		result   bool // 能够判断（非nil）
+		detected bool
+		freq     float64
+	}{
+		{
+			// 联合立体声：侧声道在15kHz以上被丢弃，中声道仍是全频带
+			name:   "侧声道低通",
+			block:  fromMidSide(full(), scaled(bandNoise(rng, frames, sampleRate, 15000), 0.5)),
+			result: true, detected: true, freq: 15000,
+		},
+		{
+			name:   "真立体声",
+			block:  [][]float64{full(), full()},
+			result: true,
+		},
+		{
+			name:   "宽度较窄的立体声",
+			block:  fromMidSide(full(), scaled(full(), 0.2)),
+			result: true,
+		},
+		{
+			// 左右相同，侧声道为0
+			name:  "单声道",
+			block: [][]float64{mono, mono},
+		},
+		{
+			// 整个文件都在15kHz低通，中声道没有内容的频带不参与比较
+			name:   "中侧声道同时低通",
+			block:  fromMidSide(bandNoise(rng, frames, sampleRate, 15000), scaled(bandNoise(rng, frames, sampleRate, 15000), 0.5)),
+			result: true,
+		},
+		{
+			// 中声道在11kHz以上没有内容时无法判断
+			name:  "中声道低于扫描范围",
+			block: fromMidSide(bandNoise(rng, frames, sampleRate, 10000), scaled(bandNoise(rng, frames, sampleRate, 10000), 0.5)),
+		},
+	}
+
+	for _, tt := range tests {
+		sink := NewSpectrumAnalyzer(sampleRate).newStereoSink(frames)
+		sink.consume(tt.block)
+		result := sink.detect()
+		if (result != nil) != tt.result {
+			t.Errorf("%s: 结果为 %+v", tt.name, result)
+			continue
+		}
+		if result == nil {
+			continue
+		}
+		if result.Detected != tt.detected {
+			t.Errorf("%s: 检测结果为 %+v", tt.name, result)
+			continue
+		}
+		if tt.detected && (math.Abs(result.Frequency-tt.freq) > 2*stereoBandWidth || result.Confidence <= 0.5) {
+			t.Errorf("%s: 塌缩频率 %.0f Hz，置信度 %.2f，应为 %.0f Hz", tt.name, result.Frequency, result.Confidence, tt.freq)
+		}
+	}
+}
+
+// scaled 返回乘以 gain 后的采样
+func scaled(samples []float64, gain float64) []float64 {
+	out := make([]float64, len(samples))
+	for i, v := range samples {
+		out[i] = v * gain
+	}
+	return out
+}
@@ -0,0 +1,92 @@
+package analyzer
+
+import (
+	"io"
+	"math"
+
+	"audio-loss-checker/internal/types"
+)
+
+// streamBlockFrames 流式分析时每次读取的帧数
+const streamBlockFrames = 4096
+
+// sampleSink 在一次顺序读取中接收采样块的分析步骤
+// 各个 sink 只保留固定大小的状态，内存占用与文件长度无关
+type sampleSink interface {
+	// consume 接收一块按声道分开的采样，各声道长度相同，切片在返回后不再有效
+	consume(block [][]float64)
+}
+
+// runStream 顺序读完采样流，把每一块依次交给各个 sink，返回读取的总帧数
+func runStream(reader types.SampleReader, channels int, sinks []sampleSink) (int64, error) {
+	buf := make([][]float64, channels)
+	for ch := range buf {
+		buf[ch] = make([]float64, streamBlockFrames)
+	}
+	block := make([][]float64, channels)
+
+	var total int64
+	for {
+		n, err := reader.ReadBlock(buf)
+		if n > 0 {
+			for ch := range block {
+				block[ch] = buf[ch][:n]
+			}
+			for _, sink := range sinks {
+				sink.consume(block)
+			}
+			total += int64(n)
+		}
+		if err == io.EOF {
+			return total, nil
+		}
+		if err != nil {
+			return total, err
+		}
+	}
+}
+
+// expectedFrames 根据时长估算总帧数，用于预先确定分析窗口在流中的位置
+func expectedFrames(audioFile types.AudioFile) int64 {
+	return int64(math.Round(audioFile.GetDuration().Seconds() * float64(audioFile.GetSampleRate())))
+}
+
+// rangeSink 只把 [start, end) 范围内的帧转交给内部的 sink，用于按音轨分析整轨镜像
+type rangeSink struct {
+	start, end int64 // end 小于0表示直到流结束
+	pos        int64
+	sinks      []sampleSink
+	view       [][]float64
+}
+
+// newRangeSink 创建范围过滤器
+func newRangeSink(start, end int64, channels int, sinks []sampleSink) *rangeSink {
+	return &rangeSink{
+		start: start,
+		end:   end,
+		sinks: sinks,
+		view:  make([][]float64, channels),
+	}
+}
+
+func (r *rangeSink) consume(block [][]float64) {
+	n := int64(len(block[0]))
+	lo, hi := r.start-r.pos, n
+	if r.end >= 0 && r.end-r.pos < hi {
+		hi = r.end - r.pos
+	}
+	r.pos += n
+	if lo < 0 {
+		lo = 0
+	}
+	if lo >= hi {
+		return
+	}
+
+	for ch := range r.view {
+		r.view[ch] = block[ch][lo:hi]
+	}
+	for _, sink := range r.sinks {
+		sink.consume(r.view)
+	}
+}
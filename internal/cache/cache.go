@@ -0,0 +1,256 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"audio-loss-checker/internal/types"
+)
+
+// Stamp 文件在分析时的状态：大小、修改时间和可选的内容哈希
+type Stamp struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+	Missing bool      `json:"missing,omitempty"` // 文件不存在（如可选的 .wvc 校正文件、CUE引用的镜像）
+	SHA256  string    `json:"sha256,omitempty"`
+}
+
+// Settings 影响分析结果的分析器版本和配置，任一项不同时缓存失效
+type Settings struct {
+	AnalyzerVersion int     `json:"analyzerVersion"`
+	CutoffFreq      float64 `json:"cutoffFreq"`
+	Windows         int     `json:"windows"`
+	Overlap         float64 `json:"overlap"`
+	Segments        float64 `json:"segments,omitempty"`    // 分段分析每段的时长（秒）
+	Verify          bool    `json:"verify,omitempty"`      // 是否校验了PCM数据的MD5
+	AccurateRip     string  `json:"accurateRip,omitempty"` // 本地AccurateRip数据的SHA-256，未提供时为空
+	Fingerprints    string  `json:"fingerprints"`          // 编码器指纹表的SHA-256
+}
+
+// Entry 一个分析任务（音频文件或CUE表）的缓存
+type Entry struct {
+	Path         string                  `json:"path"`      // 绝对路径
+	Requested    string                  `json:"requested"` // 分析时给出的路径，结果中的文件路径以它为准
+	Settings     Settings                `json:"settings"`
+	Files        []Stamp                 `json:"files"` // 任务本身以及分析时读取的其他文件
+	Results      []*types.AnalysisResult `json:"results"`
+	Spectrograms [][]byte                `json:"spectrograms,omitempty"` // 与 Results 一一对应，仅在生成HTML报告时保存
+}
+
+// Cache 磁盘上的分析结果缓存，每个任务一个JSON文件，按路径哈希分散到子目录中
+type Cache struct {
+	dir string
+}
+
+// DefaultDir 返回默认缓存目录（用户缓存目录下的 audio-loss-checker）
+func DefaultDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("无法确定缓存目录: %w", err)
+	}
+	return filepath.Join(dir, "audio-loss-checker"), nil
+}
+
+// Open 打开缓存目录，不存在时创建
+func Open(dir string) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建缓存目录失败: %w", err)
+	}
+	return &Cache{dir: dir}, nil
+}
+
+// StatFiles 记录文件的当前状态，不存在的文件记为 Missing；hash 为true时同时计算内容的SHA-256
+// 路径记录为绝对路径，以便在其他工作目录下清理缓存
+func StatFiles(paths []string, hash bool) ([]Stamp, error) {
+	stamps := make([]Stamp, 0, len(paths))
+	for _, path := range paths {
+		path = absPath(path)
+		stamp := Stamp{Path: path}
+		info, err := os.Stat(path)
+		switch {
+		case os.IsNotExist(err):
+			stamp.Missing = true
+		case err != nil:
+			return nil, err
+		default:
+			stamp.Size = info.Size()
+			stamp.ModTime = info.ModTime()
+			if hash {
+				if stamp.SHA256, err = hashFile(path); err != nil {
+					return nil, err
+				}
+			}
+		}
+		stamps = append(stamps, stamp)
+	}
+	return stamps, nil
+}
+
+// hashFile 计算文件内容的SHA-256
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", fmt.Errorf("计算文件哈希失败: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Lookup 查找 path 的缓存，路径写法、分析器版本、配置和 stamps 中每个文件的状态都一致时返回，否则返回nil
+// stamps 带有哈希时，缓存中的哈希也必须一致
+func (c *Cache) Lookup(path string, settings Settings, stamps []Stamp) *Entry {
+	entry, err := c.read(c.entryPath(path))
+	if err != nil || entry.Requested != path || entry.Settings != settings || len(entry.Files) != len(stamps) {
+		return nil
+	}
+	for i, stamp := range stamps {
+		if !sameFile(entry.Files[i], stamp) {
+			return nil
+		}
+		if stamp.SHA256 != "" && entry.Files[i].SHA256 != stamp.SHA256 {
+			return nil
+		}
+	}
+	return entry
+}
+
+// sameFile 比较路径、是否存在、大小和修改时间
+func sameFile(cached, current Stamp) bool {
+	return cached.Path == current.Path &&
+		cached.Missing == current.Missing &&
+		cached.Size == current.Size &&
+		cached.ModTime.Equal(current.ModTime)
+}
+
+// Store 以 entry.Requested 为键写入缓存，先写临时文件再改名，中断时不会留下不完整的条目
+func (c *Cache) Store(entry *Entry) error {
+	entry.Path = absPath(entry.Requested)
+	path := c.entryPath(entry.Requested)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("创建缓存目录失败: %w", err)
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("编码缓存失败: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("写入缓存失败: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("写入缓存失败: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("写入缓存失败: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("写入缓存失败: %w", err)
+	}
+	return nil
+}
+
+// PruneStats 清理结果
+type PruneStats struct {
+	Removed int
+	Kept    int
+}
+
+// Prune 删除过期的缓存：分析器版本不同、文件已删除或已修改、条目损坏，以及中断留下的临时文件
+// 不重新计算哈希，只比较大小和修改时间
+func (c *Cache) Prune(analyzerVersion int) (PruneStats, error) {
+	var stats PruneStats
+	err := filepath.Walk(c.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		stale := strings.HasPrefix(info.Name(), ".tmp-")
+		if !stale {
+			if filepath.Ext(path) != ".json" {
+				return nil
+			}
+			entry, err := c.read(path)
+			stale = err != nil || entry.Settings.AnalyzerVersion != analyzerVersion || c.entryPath(entry.Path) != path || !entry.current()
+		}
+		if !stale {
+			stats.Kept++
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		stats.Removed++
+		return nil
+	})
+	if err != nil {
+		return stats, fmt.Errorf("清理缓存失败: %w", err)
+	}
+	return stats, nil
+}
+
+// current 检查条目记录的文件是否都没有变化
+func (e *Entry) current() bool {
+	paths := make([]string, len(e.Files))
+	for i, stamp := range e.Files {
+		paths[i] = stamp.Path
+	}
+	stamps, err := StatFiles(paths, false)
+	if err != nil {
+		return false
+	}
+	for i, stamp := range stamps {
+		if !sameFile(e.Files[i], stamp) {
+			return false
+		}
+	}
+	return true
+}
+
+// read 读取一个缓存条目
+func (c *Cache) read(path string) (*Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// entryPath 返回任务路径对应的缓存文件，以绝对路径的SHA-256命名，前两位作为子目录
+func (c *Cache) entryPath(path string) string {
+	sum := sha256.Sum256([]byte(absPath(path)))
+	name := hex.EncodeToString(sum[:])
+	return filepath.Join(c.dir, name[:2], name+".json")
+}
+
+// absPath 返回绝对路径，失败时原样返回
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
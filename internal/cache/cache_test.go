@@ -0,0 +1,263 @@
+package cache
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"audio-loss-checker/internal/types"
+)
+
+var testSettings = Settings{AnalyzerVersion: 3, CutoffFreq: 18000, Windows: 256, Overlap: 0.5, Fingerprints: "abc"}
+
+// storeFile 写入音频文件并为它存入一条缓存，返回文件路径
+func storeFile(t *testing.T, c *Cache, dir, name string, hash bool) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte("audio data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stamps, err := StatFiles([]string{path, path + ".wvc"}, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := &Entry{
+		Requested: path,
+		Settings:  testSettings,
+		Files:     stamps,
+		Results:   []*types.AnalysisResult{{FilePath: path, Status: "OK"}},
+	}
+	if err := c.Store(entry); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// lookup 按文件的当前状态查找缓存
+func lookup(t *testing.T, c *Cache, path string, settings Settings, hash bool) *Entry {
+	t.Helper()
+	stamps, err := StatFiles([]string{path, path + ".wvc"}, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c.Lookup(path, settings, stamps)
+}
+
+func TestLookup(t *testing.T) {
+	c, err := Open(filepath.Join(t.TempDir(), "cache"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	mtime := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		hash     bool
+		change   func(path string) // 存入缓存之后对文件或配置所做的修改
+		settings func(s *Settings)
+		hit      bool
+	}{
+		{name: "未修改", hit: true},
+		{name: "未修改（哈希）", hash: true, hit: true},
+		{name: "大小变化", change: func(path string) { os.WriteFile(path, []byte("audio data!"), 0644) }},
+		{name: "修改时间变化", change: func(path string) { os.Chtimes(path, mtime.Add(time.Second), mtime.Add(time.Second)) }},
+		{
+			// 大小和修改时间都不变，只有内容哈希能发现
+			name: "内容变化", hash: true,
+			change: func(path string) {
+				os.WriteFile(path, []byte("audio DATA"), 0644)
+				os.Chtimes(path, mtime, mtime)
+			},
+		},
+		{
+			// 不计算哈希时只比较大小和修改时间
+			name: "内容变化（不计算哈希）", hit: true,
+			change: func(path string) {
+				os.WriteFile(path, []byte("audio DATA"), 0644)
+				os.Chtimes(path, mtime, mtime)
+			},
+		},
+		{name: "可选文件出现", change: func(path string) { os.WriteFile(path+".wvc", nil, 0644) }},
+		{name: "文件删除", change: func(path string) { os.Remove(path) }},
+		{name: "分析器版本", settings: func(s *Settings) { s.AnalyzerVersion++ }},
+		{name: "截断阈值", settings: func(s *Settings) { s.CutoffFreq = 16000 }},
+		{name: "指纹表", settings: func(s *Settings) { s.Fingerprints = "def" }},
+		{name: "校验MD5", settings: func(s *Settings) { s.Verify = true }},
+	}
+
+	for i, tt := range tests {
+		path := filepath.Join(dir, fmt.Sprintf("%02d.flac", i))
+		if err := os.WriteFile(path, []byte("audio data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+		stamps, err := StatFiles([]string{path, path + ".wvc"}, tt.hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry := &Entry{Requested: path, Settings: testSettings, Files: stamps,
+			Results: []*types.AnalysisResult{{FilePath: path, Status: "OK"}}}
+		if err := c.Store(entry); err != nil {
+			t.Fatal(err)
+		}
+
+		settings := testSettings
+		if tt.change != nil {
+			tt.change(path)
+		}
+		if tt.settings != nil {
+			tt.settings(&settings)
+		}
+		got := lookup(t, c, path, settings, tt.hash)
+		if (got != nil) != tt.hit {
+			t.Errorf("%s: 命中缓存为 %v，应为 %v", tt.name, got != nil, tt.hit)
+		}
+		if got != nil && (len(got.Results) != 1 || got.Results[0].FilePath != path || got.Path != path) {
+			t.Errorf("%s: 缓存条目为 %+v", tt.name, got)
+		}
+	}
+
+	// 路径写法不同时不使用缓存，结果中的文件路径以分析时给出的写法为准
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "00.flac")
+	rel, err := filepath.Rel(wd, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := lookup(t, c, rel, testSettings, false); got != nil {
+		t.Errorf("相对路径 %s 命中了以绝对路径存入的缓存", rel)
+	}
+}
+
+// 清理文件已删除或修改、分析器版本不同、损坏的条目和中断留下的临时文件
+func TestPrune(t *testing.T) {
+	c, err := Open(filepath.Join(t.TempDir(), "cache"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+
+	kept := storeFile(t, c, dir, "kept.flac", false)
+	removed := storeFile(t, c, dir, "removed.flac", false)
+	modified := storeFile(t, c, dir, "modified.flac", false)
+	if err := os.Remove(removed); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(modified, []byte("re-encoded"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	old := filepath.Join(dir, "old.flac")
+	stamps, err := StatFiles([]string{old}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldSettings := testSettings
+	oldSettings.AnalyzerVersion--
+	if err := c.Store(&Entry{Requested: old, Settings: oldSettings, Files: stamps}); err != nil {
+		t.Fatal(err)
+	}
+
+	corrupt := filepath.Join(c.dir, "ab", strings.Repeat("ab", 32)+".json")
+	tmp := filepath.Join(c.dir, "ab", ".tmp-123")
+	other := filepath.Join(c.dir, "README")
+	for _, path := range []string{corrupt, tmp, other} {
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte("{"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats, err := c.Prune(testSettings.AnalyzerVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Removed != 5 || stats.Kept != 1 {
+		t.Errorf("删除 %d 条，保留 %d 条，应为删除5条、保留1条", stats.Removed, stats.Kept)
+	}
+	if got := lookup(t, c, kept, testSettings, false); got == nil {
+		t.Error("未修改文件的缓存被删除")
+	}
+	for _, path := range []string{c.entryPath(removed), c.entryPath(modified), c.entryPath(old), corrupt, tmp} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s 没有被删除", path)
+		}
+	}
+	// 不是缓存条目的文件不动
+	if _, err := os.Stat(other); err != nil {
+		t.Errorf("%s 被删除", other)
+	}
+}
+
+// 同时写入同一任务的缓存时，读到的条目总是完整的，最后留下其中一次写入的内容，不残留临时文件
+func TestStoreConcurrent(t *testing.T) {
+	c, err := Open(filepath.Join(t.TempDir(), "cache"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "album.flac")
+	entryPath := c.entryPath(path)
+	// 结果较多，使JSON写入不是一次完成
+	results := func(writer int) []*types.AnalysisResult {
+		out := make([]*types.AnalysisResult, 200)
+		for i := range out {
+			out[i] = &types.AnalysisResult{FilePath: path, Status: fmt.Sprintf("W%d", writer), Error: strings.Repeat("x", 100)}
+		}
+		return out
+	}
+
+	const writers, rounds = 8, 20
+	var wg sync.WaitGroup
+	errs := make(chan error, writers*rounds*2)
+	for w := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range rounds {
+				if err := c.Store(&Entry{Requested: path, Settings: testSettings, Results: results(w)}); err != nil {
+					errs <- err
+				}
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for range writers * rounds {
+			entry, err := c.read(entryPath)
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				errs <- fmt.Errorf("读到不完整的条目: %w", err)
+				continue
+			}
+			if status := entry.Results[0].Status; len(entry.Results) != 200 || entry.Results[199].Status != status {
+				errs <- fmt.Errorf("条目混合了多次写入的内容")
+			}
+		}
+	}()
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	if entry := c.Lookup(path, testSettings, nil); entry == nil || len(entry.Results) != 200 {
+		t.Fatal("最后的缓存条目不完整")
+	}
+	leftovers, _ := filepath.Glob(filepath.Join(filepath.Dir(entryPath), ".tmp-*"))
+	if len(leftovers) > 0 {
+		t.Errorf("残留临时文件: %v", leftovers)
+	}
+}
@@ -0,0 +1,238 @@
+package cue
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// FramesPerSecond CUE时间码中每秒的帧数（CD扇区）
+const FramesPerSecond = 75
+
+// Sheet CUE表
+type Sheet struct {
+	Path      string // CUE文件路径
+	Title     string // 专辑标题
+	Performer string // 专辑艺术家
+	Files     []File
+}
+
+// File CUE表中的一个音频文件（通常是整轨镜像）
+type File struct {
+	Name   string // FILE 行中的文件名（相对CUE所在目录）
+	Path   string // 解析后的实际文件路径
+	Tracks []Track
+}
+
+// Track 音轨
+type Track struct {
+	Number    int
+	Title     string
+	Performer string
+	Index01   int // INDEX 01 的位置（帧），音轨从这里开始
+
+	audio   bool // 音频轨（数据轨不参与分析）
+	indexed bool // 已读到 INDEX 01
+}
+
+// Parse 解析CUE文件，并把 FILE 行中的文件名解析为实际路径
+func Parse(path string) (*Sheet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取CUE文件失败: %w", err)
+	}
+
+	sheet := &Sheet{Path: path}
+	var file *File
+	var track *Track
+
+	scanner := bufio.NewScanner(bytes.NewReader(decodeText(data)))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		fields := splitFields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch strings.ToUpper(fields[0]) {
+		case "TITLE":
+			if len(fields) < 2 {
+				continue
+			}
+			if track != nil {
+				track.Title = fields[1]
+			} else {
+				sheet.Title = fields[1]
+			}
+		case "PERFORMER":
+			if len(fields) < 2 {
+				continue
+			}
+			if track != nil {
+				track.Performer = fields[1]
+			} else {
+				sheet.Performer = fields[1]
+			}
+		case "FILE":
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("CUE第%d行: FILE 缺少文件名", lineNo)
+			}
+			sheet.Files = append(sheet.Files, File{Name: fields[1]})
+			file = &sheet.Files[len(sheet.Files)-1]
+			track = nil
+		case "TRACK":
+			if file == nil {
+				return nil, fmt.Errorf("CUE第%d行: TRACK 出现在 FILE 之前", lineNo)
+			}
+			if len(fields) < 3 {
+				return nil, fmt.Errorf("CUE第%d行: TRACK 格式错误", lineNo)
+			}
+			number, err := strconv.Atoi(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("CUE第%d行: 音轨号无效: %s", lineNo, fields[1])
+			}
+			// 数据轨也要记录，以免其后的 TITLE 等字段误归到上一音轨
+			file.Tracks = append(file.Tracks, Track{
+				Number: number,
+				audio:  strings.EqualFold(fields[2], "AUDIO"),
+			})
+			track = &file.Tracks[len(file.Tracks)-1]
+		case "INDEX":
+			if track == nil || len(fields) < 3 || fields[1] != "01" {
+				continue
+			}
+			frames, err := parseTimestamp(fields[2])
+			if err != nil {
+				return nil, fmt.Errorf("CUE第%d行: %w", lineNo, err)
+			}
+			track.Index01 = frames
+			track.indexed = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取CUE文件失败: %w", err)
+	}
+
+	// 去掉数据轨，检查每条音轨都有 INDEX 01
+	dir := filepath.Dir(path)
+	for i := range sheet.Files {
+		f := &sheet.Files[i]
+		audio := f.Tracks[:0]
+		for _, t := range f.Tracks {
+			if !t.audio {
+				continue
+			}
+			if !t.indexed {
+				return nil, fmt.Errorf("音轨 %02d 缺少 INDEX 01", t.Number)
+			}
+			audio = append(audio, t)
+		}
+		f.Tracks = audio
+		f.Path = resolveFile(dir, f.Name)
+	}
+
+	return sheet, nil
+}
+
+// parseTimestamp 解析 MM:SS:FF 格式的时间码，返回帧数
+func parseTimestamp(s string) (int, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("时间码格式错误: %s", s)
+	}
+	var v [3]int
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("时间码格式错误: %s", s)
+		}
+		v[i] = n
+	}
+	if v[1] >= 60 || v[2] >= FramesPerSecond {
+		return 0, fmt.Errorf("时间码超出范围: %s", s)
+	}
+	return (v[0]*60+v[1])*FramesPerSecond + v[2], nil
+}
+
+// splitFields 按空白分割一行，双引号内的空白不分割
+func splitFields(line string) []string {
+	var fields []string
+	var cur strings.Builder
+	inQuote, hasField := false, false
+
+	for _, r := range line {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+			hasField = true
+		case !inQuote && (r == ' ' || r == '\t' || r == '\r'):
+			if hasField {
+				fields = append(fields, cur.String())
+				cur.Reset()
+				hasField = false
+			}
+		default:
+			cur.WriteRune(r)
+			hasField = true
+		}
+	}
+	if hasField {
+		fields = append(fields, cur.String())
+	}
+	return fields
+}
+
+// decodeText 去掉UTF-8 BOM，并把带BOM的UTF-16文本转换为UTF-8
+func decodeText(data []byte) []byte {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xef, 0xbb, 0xbf}):
+		return data[3:]
+	case bytes.HasPrefix(data, []byte{0xff, 0xfe}), bytes.HasPrefix(data, []byte{0xfe, 0xff}):
+		bigEndian := data[0] == 0xfe
+		units := make([]uint16, 0, len(data)/2)
+		for i := 2; i+1 < len(data); i += 2 {
+			if bigEndian {
+				units = append(units, uint16(data[i])<<8|uint16(data[i+1]))
+			} else {
+				units = append(units, uint16(data[i+1])<<8|uint16(data[i]))
+			}
+		}
+		return []byte(string(utf16.Decode(units)))
+	}
+	return data
+}
+
+// resolveFile 解析 FILE 行引用的文件
+// 抓轨软件常在转码后忘记修改CUE（如CUE写的是 .wav，实际为 .flac），
+// 找不到原文件时改为查找同目录下主文件名相同的其他文件
+func resolveFile(dir, name string) string {
+	// CUE可能来自Windows，统一路径分隔符
+	name = strings.ReplaceAll(name, "\\", "/")
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	if _, err := os.Stat(path); err == nil {
+		return path
+	}
+
+	stem := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		return path
+	}
+	for _, entry := range entries {
+		entryName := entry.Name()
+		ext := filepath.Ext(entryName)
+		if entry.IsDir() || strings.EqualFold(ext, ".cue") {
+			continue
+		}
+		if strings.EqualFold(strings.TrimSuffix(entryName, ext), stem) {
+			return filepath.Join(filepath.Dir(path), entryName)
+		}
+	}
+	return path
+}
@@ -0,0 +1,200 @@
+package cue
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeSheet 在临时目录中写入CUE文件和它引用的空音频文件，返回CUE路径
+func writeSheet(t *testing.T, text string, audio ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range audio {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := filepath.Join(dir, "album.cue")
+	if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParseTimestamp(t *testing.T) {
+	tests := []struct {
+		s       string
+		frames  int
+		samples int // 44.1kHz下的采样位置，每帧588个采样
+		wantErr bool
+	}{
+		{s: "00:00:00", frames: 0, samples: 0},
+		{s: "00:00:01", frames: 1, samples: 588},
+		{s: "00:02:00", frames: 150, samples: 88200},
+		{s: "01:02:03", frames: (62*75 + 3), samples: 2735964},
+		{s: "79:59:74", frames: (79*60+59)*75 + 74, samples: 211679412},
+		{s: "00:60:00", wantErr: true},
+		{s: "00:00:75", wantErr: true},
+		{s: "00:00", wantErr: true},
+		{s: "00:-1:00", wantErr: true},
+		{s: "aa:00:00", wantErr: true},
+	}
+	for _, tt := range tests {
+		frames, err := parseTimestamp(tt.s)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: 错误为 %v", tt.s, err)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		if frames != tt.frames || frames*44100/FramesPerSecond != tt.samples {
+			t.Errorf("%s: %d 帧（%d 个采样），应为 %d 帧（%d 个采样）", tt.s, frames,
+				frames*44100/FramesPerSecond, tt.frames, tt.samples)
+		}
+	}
+}
+
+func TestSplitFields(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{`FILE "My Image.flac" WAVE`, []string{"FILE", "My Image.flac", "WAVE"}},
+		{"  TRACK 01 AUDIO\r", []string{"TRACK", "01", "AUDIO"}},
+		{"\tTITLE \"\"", []string{"TITLE", ""}},
+		{`PERFORMER "A  B"`, []string{"PERFORMER", "A  B"}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		got := splitFields(tt.line)
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") || len(got) != len(tt.want) {
+			t.Errorf("%q: %q，应为 %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+// 单文件镜像：文件名带空格，第二轨有 INDEX 00 间隙，第三轨为数据轨
+func TestParseImage(t *testing.T) {
+	path := writeSheet(t, strings.Join([]string{
+		`REM GENRE Rock`,
+		`PERFORMER "The Band"`,
+		`TITLE "Album Title"`,
+		`FILE "My Image.flac" WAVE`,
+		`  TRACK 01 AUDIO`,
+		`    TITLE "First Song"`,
+		`    INDEX 01 00:00:00`,
+		`  TRACK 02 AUDIO`,
+		`    TITLE "Second Song"`,
+		`    PERFORMER "Guest"`,
+		`    INDEX 00 03:58:40`,
+		`    INDEX 01 04:00:00`,
+		`  TRACK 03 MODE1/2352`,
+		`    TITLE "Data"`,
+		`    INDEX 01 08:00:00`,
+	}, "\r\n"), "My Image.flac")
+
+	sheet, err := Parse(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sheet.Title != "Album Title" || sheet.Performer != "The Band" || len(sheet.Files) != 1 {
+		t.Fatalf("CUE表为 %+v", sheet)
+	}
+	file := sheet.Files[0]
+	if file.Name != "My Image.flac" || file.Path != filepath.Join(filepath.Dir(path), "My Image.flac") {
+		t.Errorf("文件为 %q（%s）", file.Name, file.Path)
+	}
+
+	want := []Track{
+		{Number: 1, Title: "First Song", Index01: 0},
+		// INDEX 00 只标记间隙，音轨从 INDEX 01 开始
+		{Number: 2, Title: "Second Song", Performer: "Guest", Index01: 240 * FramesPerSecond},
+	}
+	if len(file.Tracks) != len(want) {
+		t.Fatalf("音轨为 %+v", file.Tracks)
+	}
+	for i, track := range file.Tracks {
+		w := want[i]
+		if track.Number != w.Number || track.Title != w.Title || track.Performer != w.Performer || track.Index01 != w.Index01 {
+			t.Errorf("音轨 %d 为 %+v，应为 %+v", i+1, track, w)
+		}
+	}
+}
+
+// 每轨一个文件；CUE写的是 .wav，实际文件已转为 .flac
+func TestParseMultiFile(t *testing.T) {
+	path := writeSheet(t, strings.Join([]string{
+		`TITLE "Split"`,
+		`FILE "01 - One.wav" WAVE`,
+		`  TRACK 01 AUDIO`,
+		`    INDEX 01 00:00:00`,
+		`FILE "02 - Two.wav" WAVE`,
+		`  TRACK 02 AUDIO`,
+		`    INDEX 00 00:00:00`,
+		`    INDEX 01 00:02:00`,
+		`FILE "Sub\03 - Three.flac" WAVE`,
+		`  TRACK 03 AUDIO`,
+		`    INDEX 01 00:00:00`,
+	}, "\n"), "01 - One.wav", "02 - Two.flac")
+	dir := filepath.Dir(path)
+
+	sheet, err := Parse(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		path    string
+		number  int
+		index01 int
+	}{
+		{filepath.Join(dir, "01 - One.wav"), 1, 0},
+		{filepath.Join(dir, "02 - Two.flac"), 2, 150},
+		// 找不到的文件保留CUE中的路径，由分析时报告错误
+		{filepath.Join(dir, "Sub", "03 - Three.flac"), 3, 0},
+	}
+	if len(sheet.Files) != len(want) {
+		t.Fatalf("文件为 %+v", sheet.Files)
+	}
+	for i, w := range want {
+		file := sheet.Files[i]
+		if file.Path != w.path || len(file.Tracks) != 1 || file.Tracks[0].Number != w.number || file.Tracks[0].Index01 != w.index01 {
+			t.Errorf("文件 %d 为 %+v，应为 %s 音轨 %d INDEX 01 %d", i+1, file, w.path, w.number, w.index01)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		wantErr string
+	}{
+		{"缺少INDEX 01", "FILE \"a.wav\" WAVE\nTRACK 01 AUDIO\nINDEX 00 00:00:00\n", "缺少 INDEX 01"},
+		{"TRACK在FILE之前", "TRACK 01 AUDIO\nINDEX 01 00:00:00\n", "TRACK 出现在 FILE 之前"},
+		{"时间码超出范围", "FILE \"a.wav\" WAVE\nTRACK 01 AUDIO\nINDEX 01 00:00:75\n", "CUE第3行"},
+		{"音轨号无效", "FILE \"a.wav\" WAVE\nTRACK xx AUDIO\n", "音轨号无效"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(writeSheet(t, tt.text))
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: 错误为 %v，应包含 %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+// 带BOM的UTF-16LE和UTF-8文本
+func TestDecodeText(t *testing.T) {
+	text := "TITLE \"专辑\""
+	utf16le := []byte{0xff, 0xfe}
+	for _, r := range text {
+		utf16le = append(utf16le, byte(r), byte(r>>8))
+	}
+	for _, data := range [][]byte{utf16le, append([]byte{0xef, 0xbb, 0xbf}, text...), []byte(text)} {
+		if got := string(decodeText(data)); got != text {
+			t.Errorf("% x: %q", data[:3], got)
+		}
+	}
+}
@@ -0,0 +1,372 @@
+package decoder
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"audio-loss-checker/internal/types"
+)
+
+// AIFFDecoder AIFF/AIFF-C格式解码器
+type AIFFDecoder struct{}
+
+// AIFFFile AIFF文件实现
+type AIFFFile struct {
+	file        *Source
+	isAIFC      bool
+	compression string // AIFF-C压缩类型，普通AIFF为 "NONE"
+	dataOffset  int64
+	dataSize    int64
+	frames      int64
+	sampleRate  int
+	bitDepth    int
+	channels    int
+	duration    time.Duration
+	samples     [][]float64
+	metadata    types.AudioMetadata
+}
+
+// SupportedFormats 返回支持的格式
+func (d *AIFFDecoder) SupportedFormats() []string {
+	return []string{"aif", "aiff", "aifc"}
+}
+
+// Decode 解码AIFF文件
+func (d *AIFFDecoder) Decode(file *Source) (types.AudioFile, error) {
+	aiffFile := &AIFFFile{file: file, compression: "NONE"}
+	if err := aiffFile.parseChunks(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("解析AIFF文件失败: %w", err)
+	}
+
+	aiffFile.duration = time.Duration(float64(aiffFile.frames) / float64(aiffFile.sampleRate) * float64(time.Second))
+	aiffFile.metadata.Duration = aiffFile.duration.String()
+
+	return aiffFile, nil
+}
+
+// parseChunks 解析FORM容器中的各个块
+func (f *AIFFFile) parseChunks() error {
+	header := make([]byte, 12)
+	if _, err := io.ReadFull(f.file, header); err != nil {
+		return err
+	}
+	if string(header[0:4]) != "FORM" {
+		return fmt.Errorf("无效的AIFF文件标识")
+	}
+	switch string(header[8:12]) {
+	case "AIFF":
+	case "AIFC":
+		f.isAIFC = true
+	default:
+		return fmt.Errorf("不支持的FORM类型: %q", header[8:12])
+	}
+
+	gotComm, gotSound := false, false
+	chunkHeader := make([]byte, 8)
+	offset := int64(12)
+
+	for {
+		if _, err := f.file.Seek(offset, io.SeekStart); err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(f.file, chunkHeader); err != nil {
+			break
+		}
+		id := string(chunkHeader[0:4])
+		size := int64(binary.BigEndian.Uint32(chunkHeader[4:8]))
+		body := offset + 8
+
+		switch id {
+		case "COMM":
+			data, err := f.readChunk(body, size)
+			if err != nil {
+				return err
+			}
+			if err := f.parseComm(data); err != nil {
+				return err
+			}
+			gotComm = true
+
+		case "SSND":
+			data, err := f.readChunk(body, 8)
+			if err != nil {
+				return err
+			}
+			dataStart := int64(binary.BigEndian.Uint32(data[0:4]))
+			f.dataOffset = body + 8 + dataStart
+			f.dataSize = size - 8 - dataStart
+			gotSound = true
+
+		case "NAME", "AUTH":
+			data, err := f.readChunk(body, size)
+			if err != nil {
+				return err
+			}
+			text := strings.TrimSpace(strings.TrimRight(string(data), "\x00"))
+			if id == "NAME" && f.metadata.Title == "" {
+				f.metadata.Title = text
+			} else if id == "AUTH" && f.metadata.Artist == "" {
+				f.metadata.Artist = text
+			}
+
+		case "ID3 ", "id3 ":
+			data, err := f.readChunk(body, size)
+			if err != nil {
+				return err
+			}
+			// ID3标签优先于NAME/AUTH块
+			if meta, ok := parseID3v2(data); ok {
+				mergeMetadata(&f.metadata, meta)
+			}
+		}
+
+		// 块大小为奇数时有一个填充字节
+		offset = body + size + size&1
+	}
+
+	if !gotComm {
+		return fmt.Errorf("缺少COMM块")
+	}
+	if !gotSound {
+		return fmt.Errorf("缺少SSND块")
+	}
+
+	if f.dataSize < 0 {
+		return fmt.Errorf("SSND块大小无效")
+	}
+	if available := f.file.Size() - f.dataOffset; f.dataSize > available {
+		// 文件被截断时SSND块的声明大小超出文件末尾
+		f.dataSize = max(available, 0)
+	}
+
+	if maxFrames := f.dataSize / int64(f.sampleWidth()*f.channels); f.frames > maxFrames {
+		// 文件被截断时以实际数据为准
+		f.frames = maxFrames
+	}
+
+	return nil
+}
+
+// readChunk 读取块内容，块大小超出文件末尾时返回错误，不按损坏的大小分配内存
+func (f *AIFFFile) readChunk(offset, size int64) ([]byte, error) {
+	if size < 0 || size > f.file.Size()-offset {
+		return nil, fmt.Errorf("AIFF块大小超出文件末尾: %d", size)
+	}
+	data := make([]byte, size)
+	if _, err := f.file.ReadAt(data, offset); err != nil {
+		return nil, fmt.Errorf("读取AIFF块失败: %w", err)
+	}
+	return data, nil
+}
+
+// parseComm 解析COMM块
+func (f *AIFFFile) parseComm(data []byte) error {
+	if len(data) < 18 {
+		return fmt.Errorf("COMM块过短")
+	}
+
+	f.channels = int(binary.BigEndian.Uint16(data[0:2]))
+	f.frames = int64(binary.BigEndian.Uint32(data[2:6]))
+	f.bitDepth = int(binary.BigEndian.Uint16(data[6:8]))
+	f.sampleRate = int(math.Round(extendedToFloat64(data[8:18])))
+
+	if f.isAIFC {
+		if len(data) < 22 {
+			return fmt.Errorf("AIFF-C的COMM块缺少压缩类型")
+		}
+		f.compression = string(data[18:22])
+	}
+
+	switch f.compression {
+	case "NONE", "twos", "sowt":
+		if f.bitDepth < 1 || f.bitDepth > 32 {
+			return fmt.Errorf("不支持的位深度: %d", f.bitDepth)
+		}
+	case "fl32", "FL32":
+		f.bitDepth = 32
+	case "fl64", "FL64":
+		f.bitDepth = 64
+	default:
+		return fmt.Errorf("不支持的AIFF-C压缩类型: %q", f.compression)
+	}
+
+	if f.channels <= 0 {
+		return fmt.Errorf("无效的声道数: %d", f.channels)
+	}
+	if f.sampleRate <= 0 {
+		return fmt.Errorf("无效的采样率: %d", f.sampleRate)
+	}
+	return nil
+}
+
+// extendedToFloat64 将80位IEEE扩展精度浮点数转换为float64
+func extendedToFloat64(b []byte) float64 {
+	sign := 1.0
+	if b[0]&0x80 != 0 {
+		sign = -1.0
+	}
+	exponent := int(binary.BigEndian.Uint16(b[0:2]) & 0x7fff)
+	mantissa := binary.BigEndian.Uint64(b[2:10])
+	if exponent == 0 && mantissa == 0 {
+		return 0
+	}
+	return sign * math.Ldexp(float64(mantissa), exponent-16383-63)
+}
+
+// mergeMetadata 用非空字段覆盖已有元数据
+func mergeMetadata(dst *types.AudioMetadata, src types.AudioMetadata) {
+	if src.Title != "" {
+		dst.Title = src.Title
+	}
+	if src.Artist != "" {
+		dst.Artist = src.Artist
+	}
+	if src.Album != "" {
+		dst.Album = src.Album
+	}
+	if src.Year != "" {
+		dst.Year = src.Year
+	}
+	if src.Genre != "" {
+		dst.Genre = src.Genre
+	}
+	if src.TrackNumber != 0 {
+		dst.TrackNumber, dst.TrackTotal = src.TrackNumber, src.TrackTotal
+	}
+}
+
+// GetFormat 获取格式名称
+func (f *AIFFFile) GetFormat() string {
+	if f.isAIFC {
+		return "AIFF-C"
+	}
+	return "AIFF"
+}
+
+// GetSampleRate 获取采样率
+func (f *AIFFFile) GetSampleRate() int {
+	return f.sampleRate
+}
+
+// GetBitDepth 获取位深度
+func (f *AIFFFile) GetBitDepth() int {
+	return f.bitDepth
+}
+
+// GetChannels 获取声道数
+func (f *AIFFFile) GetChannels() int {
+	return f.channels
+}
+
+// GetDuration 获取时长
+func (f *AIFFFile) GetDuration() time.Duration {
+	return f.duration
+}
+
+// GetSamples 获取按声道分开的音频采样数据
+func (f *AIFFFile) GetSamples() ([][]float64, error) {
+	if f.samples != nil {
+		return f.samples, nil
+	}
+
+	reader, err := f.NewSampleReader()
+	if err != nil {
+		return nil, err
+	}
+	samples, err := readAllSamples(reader, f.channels)
+	if err != nil {
+		return nil, err
+	}
+
+	f.samples = samples
+	return samples, nil
+}
+
+// NewSampleReader 创建采样流，每次从SSND块中读取一段
+func (f *AIFFFile) NewSampleReader() (types.SampleReader, error) {
+	return newInterleavedReader(f.channels, f.frames, f.sampleWidth(), !f.floatSamples(), func(dst []float64, offset int64, data []byte, tap *intTap) error {
+		if _, err := f.file.ReadAt(data, f.dataOffset+offset); err != nil {
+			return fmt.Errorf("读取AIFF块失败: %w", err)
+		}
+		f.convertSamples(dst, data, tap)
+		return nil
+	}), nil
+}
+
+// sampleWidth 每个采样占用的字节数
+func (f *AIFFFile) sampleWidth() int {
+	switch f.compression {
+	case "fl32", "FL32":
+		return 4
+	case "fl64", "FL64":
+		return 8
+	}
+	return (f.bitDepth + 7) / 8
+}
+
+// floatSamples 采样是否为浮点数
+func (f *AIFFFile) floatSamples() bool {
+	switch f.compression {
+	case "fl32", "FL32", "fl64", "FL64":
+		return true
+	}
+	return false
+}
+
+// convertSamples 把SSND块中的采样转换为浮点数，整数采样在归一化之前交给 tap
+func (f *AIFFFile) convertSamples(dst []float64, data []byte, tap *intTap) {
+	switch f.compression {
+	case "fl32", "FL32":
+		for i := range dst {
+			dst[i] = float64(math.Float32frombits(binary.BigEndian.Uint32(data[i*4:])))
+		}
+
+	case "fl64", "FL64":
+		for i := range dst {
+			dst[i] = math.Float64frombits(binary.BigEndian.Uint64(data[i*8:]))
+		}
+
+	default:
+		// 整数PCM：采样左对齐存放在整数字节中，sowt 为小端序
+		width := (f.bitDepth + 7) / 8
+		littleEndian := f.compression == "sowt"
+		shift := uint(32 - f.bitDepth)
+		maxVal := float64(int64(1) << uint(f.bitDepth-1))
+		ints := tap.buffer(len(dst))
+
+		for i := range dst {
+			b := data[i*width : (i+1)*width]
+			var v uint32
+			for j := 0; j < width; j++ {
+				if littleEndian {
+					v |= uint32(b[j]) << uint(8*(4-width+j))
+				} else {
+					v |= uint32(b[j]) << uint(8*(3-j))
+				}
+			}
+			dst[i] = float64(int32(v)>>shift) / maxVal
+			if ints != nil {
+				ints[i] = int32(v) >> shift
+			}
+		}
+		tap.observe(ints)
+	}
+}
+
+// GetMetadata 获取元数据
+func (f *AIFFFile) GetMetadata() types.AudioMetadata {
+	return f.metadata
+}
+
+// Close 关闭文件
+func (f *AIFFFile) Close() error {
+	if f.file != nil {
+		return f.file.Close()
+	}
+	return nil
+}
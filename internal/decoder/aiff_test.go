@@ -0,0 +1,192 @@
+package decoder
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+// 80位扩展精度的常用采样率
+var (
+	extended44100 = []byte{0x40, 0x0e, 0xac, 0x44, 0, 0, 0, 0, 0, 0}
+	extended48000 = []byte{0x40, 0x0e, 0xbb, 0x80, 0, 0, 0, 0, 0, 0}
+)
+
+// aiffChunk 生成一个AIFF块，大小为奇数时补一个填充字节
+func aiffChunk(id string, data []byte) []byte {
+	chunk := append([]byte(id), binary.BigEndian.AppendUint32(nil, uint32(len(data)))...)
+	chunk = append(chunk, data...)
+	if len(data)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+	return chunk
+}
+
+// buildAIFF 生成AIFF文件：compression 为空时是普通AIFF，否则是带该压缩类型的AIFF-C
+// pcm 为已按文件字节序排列的采样数据
+func buildAIFF(channels, frames, bits int, rate []byte, compression string, pcm []byte, extra ...[]byte) []byte {
+	comm := binary.BigEndian.AppendUint16(nil, uint16(channels))
+	comm = binary.BigEndian.AppendUint32(comm, uint32(frames))
+	comm = binary.BigEndian.AppendUint16(comm, uint16(bits))
+	comm = append(comm, rate...)
+	form := "AIFF"
+	if compression != "" {
+		form = "AIFC"
+		comm = append(comm, compression...)
+		comm = append(comm, 0, 0) // 空的压缩名称（Pascal字符串加填充）
+	}
+
+	body := []byte(form)
+	body = append(body, aiffChunk("COMM", comm)...)
+	for _, chunk := range extra {
+		body = append(body, chunk...)
+	}
+	ssnd := append(make([]byte, 8), pcm...) // offset、blockSize 均为0
+	body = append(body, aiffChunk("SSND", ssnd)...)
+	return append(append([]byte("FORM"), binary.BigEndian.AppendUint32(nil, uint32(len(body)))...), body...)
+}
+
+// packPCM 把整数采样按声道交错，以 width 字节写出
+func packPCM(samples [][]int32, width int, littleEndian bool) []byte {
+	var out []byte
+	for i := range samples[0] {
+		for ch := range samples {
+			v := uint32(samples[ch][i])
+			for b := 0; b < width; b++ {
+				shift := 8 * (width - 1 - b)
+				if littleEndian {
+					shift = 8 * b
+				}
+				out = append(out, byte(v>>uint(shift)))
+			}
+		}
+	}
+	return out
+}
+
+func TestExtendedToFloat64(t *testing.T) {
+	tests := []struct {
+		b    []byte
+		want float64
+	}{
+		{extended44100, 44100},
+		{extended48000, 48000},
+		{[]byte{0x40, 0x0f, 0xbb, 0x80, 0, 0, 0, 0, 0, 0}, 96000},
+		{[]byte{0x40, 0x0b, 0xfa, 0, 0, 0, 0, 0, 0, 0}, 8000},
+		{[]byte{0x40, 0x0f, 0xac, 0x44, 0, 0, 0, 0, 0, 0}, 88200},
+		{make([]byte, 10), 0},
+	}
+	for _, tt := range tests {
+		if got := extendedToFloat64(tt.b); got != tt.want {
+			t.Errorf("% x: %g，应为 %g", tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestAIFF(t *testing.T) {
+	pcm16 := testSignal(2, 1000, 16)
+	pcm24 := testSignal(1, 1000, 24)
+	// ID3v2.3 标签，只有一个 TIT2 帧
+	title := append([]byte{0}, "ID3 Title"...)
+	frame := append([]byte("TIT2"), binary.BigEndian.AppendUint32(nil, uint32(len(title)))...)
+	frame = append(append(frame, 0, 0), title...)
+	id3 := append([]byte{'I', 'D', '3', 3, 0, 0, 0, 0, 0, byte(len(frame))}, frame...)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		format  string
+		rate    int
+		bits    int
+		want    [][]int32
+		title   string
+		artist  string
+		wantErr string
+	}{
+		{
+			name:   "16位大端序",
+			data:   buildAIFF(2, 1000, 16, extended44100, "", packPCM(pcm16, 2, false)),
+			format: "AIFF", rate: 44100, bits: 16, want: pcm16,
+		},
+		{
+			// NAME块长度为奇数，后面有填充字节
+			name: "24位带NAME/AUTH",
+			data: buildAIFF(1, 1000, 24, extended48000, "", packPCM(pcm24, 3, false),
+				aiffChunk("NAME", []byte("Odd")), aiffChunk("AUTH", []byte("Someone"))),
+			format: "AIFF", rate: 48000, bits: 24, want: pcm24, title: "Odd", artist: "Someone",
+		},
+		{
+			name:   "AIFF-C sowt小端序",
+			data:   buildAIFF(2, 1000, 16, extended44100, "sowt", packPCM(pcm16, 2, true)),
+			format: "AIFF-C", rate: 44100, bits: 16, want: pcm16,
+		},
+		{
+			name:   "AIFF-C NONE",
+			data:   buildAIFF(2, 1000, 16, extended44100, "NONE", packPCM(pcm16, 2, false)),
+			format: "AIFF-C", rate: 44100, bits: 16, want: pcm16,
+		},
+		{
+			// ID3块优先于NAME块
+			name: "ID3块",
+			data: buildAIFF(2, 1000, 16, extended44100, "", packPCM(pcm16, 2, false),
+				aiffChunk("NAME", []byte("Name")), aiffChunk("ID3 ", id3)),
+			format: "AIFF", rate: 44100, bits: 16, want: pcm16, title: "ID3 Title",
+		},
+		{
+			name:    "有损压缩类型",
+			data:    buildAIFF(2, 1000, 16, extended44100, "ima4", make([]byte, 100)),
+			wantErr: "不支持的AIFF-C压缩类型",
+		},
+		{
+			name:    "ulaw",
+			data:    buildAIFF(2, 1000, 16, extended44100, "ulaw", make([]byte, 100)),
+			wantErr: "不支持的AIFF-C压缩类型",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantErr != "" {
+				_, err := NewDecoderRegistry().DecodeReader(bytes.NewReader(tt.data), "test.aiff")
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("错误为 %v，应包含 %q", err, tt.wantErr)
+				}
+				return
+			}
+
+			audioFile, samples := decodeTestData(t, "test.aiff", tt.data)
+			if audioFile.GetFormat() != tt.format || audioFile.GetSampleRate() != tt.rate ||
+				audioFile.GetBitDepth() != tt.bits || audioFile.GetChannels() != len(tt.want) {
+				t.Fatalf("格式为 %s %d Hz %d位 %d声道", audioFile.GetFormat(), audioFile.GetSampleRate(),
+					audioFile.GetBitDepth(), audioFile.GetChannels())
+			}
+			if metadata := audioFile.GetMetadata(); metadata.Title != tt.title || metadata.Artist != tt.artist {
+				t.Errorf("元数据为 %+v", metadata)
+			}
+			checkSamples(t, samples, tt.want)
+		})
+	}
+}
+
+// 文件被截断时按实际数据长度解码
+func TestAIFFTruncated(t *testing.T) {
+	pcm := testSignal(2, 1000, 16)
+	data := buildAIFF(2, 1000, 16, extended44100, "", packPCM(pcm, 2, false))
+	_, samples := decodeTestData(t, "test.aiff", data[:len(data)-400])
+	for ch := range pcm {
+		pcm[ch] = pcm[ch][:900]
+	}
+	checkSamples(t, samples, pcm)
+}
+
+// 块大小损坏时报告错误，而不是按声明的大小分配内存
+func TestAIFFCorruptChunkSize(t *testing.T) {
+	pcm := testSignal(2, 100, 16)
+	name := append([]byte("NAME"), 0xff, 0xff, 0xff, 0xf0)
+	data := buildAIFF(2, 100, 16, extended44100, "", packPCM(pcm, 2, false), name)
+	_, err := NewDecoderRegistry().DecodeReader(bytes.NewReader(data), "test.aiff")
+	if err == nil || !strings.Contains(err.Error(), "超出文件末尾") {
+		t.Fatalf("错误为 %v", err)
+	}
+}
@@ -0,0 +1,767 @@
+package decoder
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/bits"
+	"time"
+
+	"audio-loss-checker/internal/types"
+)
+
+// ALACDecoder ALAC格式解码器（MP4/M4A容器）
+type ALACDecoder struct{}
+
+// ALACFile ALAC文件实现
+type ALACFile struct {
+	file          *Source
+	codec         *alacCodec
+	packetSizes   []uint32
+	packetOffsets []int64
+	sampleRate    int
+	bitDepth      int
+	channels      int
+	duration      time.Duration
+	samples       [][]float64
+	metadata      types.AudioMetadata
+}
+
+// SupportedFormats 返回支持的格式
+func (d *ALACDecoder) SupportedFormats() []string {
+	return []string{"m4a", "alac"}
+}
+
+// Decode 解码ALAC文件
+func (d *ALACDecoder) Decode(file *Source) (types.AudioFile, error) {
+	movie, err := parseMP4(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("解析MP4容器失败: %w", err)
+	}
+
+	track := movie.audioTrack()
+	if track == nil {
+		file.Close()
+		return nil, fmt.Errorf("未找到音频轨道: %s", file.Name())
+	}
+
+	switch track.codec {
+	case "alac":
+	case "mp4a":
+		file.Close()
+		return nil, fmt.Errorf("音频轨道为AAC有损编码，不是ALAC: %s", file.Name())
+	default:
+		file.Close()
+		return nil, fmt.Errorf("不支持的MP4音频编码: %s", track.codec)
+	}
+
+	config, err := parseALACConfig(track.entry)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	offsets, err := track.sampleOffsets()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	// 计算时长
+	var duration time.Duration
+	if track.timescale > 0 && track.duration > 0 {
+		duration = time.Duration(float64(track.duration) / float64(track.timescale) * float64(time.Second))
+	} else {
+		totalSamples := float64(len(track.sampleSizes)) * float64(config.frameLength)
+		duration = time.Duration(totalSamples / float64(config.sampleRate) * float64(time.Second))
+	}
+
+	alacFile := &ALACFile{
+		file:          file,
+		codec:         newALACCodec(config),
+		packetSizes:   track.sampleSizes,
+		packetOffsets: offsets,
+		sampleRate:    int(config.sampleRate),
+		bitDepth:      int(config.bitDepth),
+		channels:      int(config.numChannels),
+		duration:      duration,
+		metadata:      movie.metadata,
+	}
+	alacFile.metadata.Duration = duration.String()
+
+	return alacFile, nil
+}
+
+// GetFormat 获取格式名称
+func (f *ALACFile) GetFormat() string {
+	return "ALAC"
+}
+
+// GetSampleRate 获取采样率
+func (f *ALACFile) GetSampleRate() int {
+	return f.sampleRate
+}
+
+// GetBitDepth 获取位深度
+func (f *ALACFile) GetBitDepth() int {
+	return f.bitDepth
+}
+
+// GetChannels 获取声道数
+func (f *ALACFile) GetChannels() int {
+	return f.channels
+}
+
+// GetDuration 获取时长
+func (f *ALACFile) GetDuration() time.Duration {
+	return f.duration
+}
+
+// GetSamples 获取按声道分开的音频采样数据
+func (f *ALACFile) GetSamples() ([][]float64, error) {
+	if f.samples != nil {
+		return f.samples, nil
+	}
+
+	reader, err := f.NewSampleReader()
+	if err != nil {
+		return nil, err
+	}
+	samples, err := readAllSamples(reader, f.channels)
+	if err != nil {
+		return nil, err
+	}
+
+	f.samples = samples
+	return samples, nil
+}
+
+// NewSampleReader 创建采样流，逐个数据包解码
+func (f *ALACFile) NewSampleReader() (types.SampleReader, error) {
+	codec := newALACCodec(f.codec.config)
+	converter := newIntFrameConverter(f.channels, f.bitDepth)
+	packet := make([]byte, 0, codec.config.maxFrameBytes)
+	i := 0
+
+	return newIntFrameReader(converter, func() ([][]float64, error) {
+		if i >= len(f.packetSizes) {
+			return nil, io.EOF
+		}
+		size := f.packetSizes[i]
+		if cap(packet) < int(size) {
+			packet = make([]byte, size)
+		}
+		packet = packet[:size]
+
+		if _, err := f.file.ReadAt(packet, f.packetOffsets[i]); err != nil {
+			return nil, fmt.Errorf("读取ALAC数据包 %d 失败: %w", i, err)
+		}
+
+		channels, err := codec.decodeFrame(packet)
+		if err != nil {
+			return nil, fmt.Errorf("解码ALAC数据包 %d 失败: %w", i, err)
+		}
+		if len(channels) != f.channels {
+			return nil, fmt.Errorf("ALAC数据包 %d 声道数不匹配: %d", i, len(channels))
+		}
+
+		i++
+		return converter.convert(channels), nil
+	}), nil
+}
+
+// GetMetadata 获取元数据
+func (f *ALACFile) GetMetadata() types.AudioMetadata {
+	return f.metadata
+}
+
+// Close 关闭文件
+func (f *ALACFile) Close() error {
+	if f.file != nil {
+		return f.file.Close()
+	}
+	return nil
+}
+
+// alacConfig ALAC magic cookie (ALACSpecificConfig)
+type alacConfig struct {
+	frameLength   uint32
+	bitDepth      uint8
+	pb            uint8
+	mb            uint8
+	kb            uint8
+	numChannels   uint8
+	maxRun        uint16
+	maxFrameBytes uint32
+	sampleRate    uint32
+}
+
+// parseALACConfig 从stsd采样条目中查找并解析ALAC magic cookie
+func parseALACConfig(entry []byte) (*alacConfig, error) {
+	// 音频采样条目头部固定为28字节，cookie位于其后的 alac 子盒子中。
+	// QuickTime风格的文件会把它包在 wave 盒子里，因此这里直接扫描。
+	for i := 28; i+36 <= len(entry); i++ {
+		if string(entry[i+4:i+8]) != "alac" || binary.BigEndian.Uint32(entry[i:i+4]) < 36 {
+			continue
+		}
+
+		cookie := entry[i+12 : i+36]
+		config := &alacConfig{
+			frameLength:   binary.BigEndian.Uint32(cookie[0:4]),
+			bitDepth:      cookie[5],
+			pb:            cookie[6],
+			mb:            cookie[7],
+			kb:            cookie[8],
+			numChannels:   cookie[9],
+			maxRun:        binary.BigEndian.Uint16(cookie[10:12]),
+			maxFrameBytes: binary.BigEndian.Uint32(cookie[12:16]),
+			sampleRate:    binary.BigEndian.Uint32(cookie[20:24]),
+		}
+
+		if config.frameLength == 0 || config.numChannels == 0 || config.sampleRate == 0 {
+			return nil, fmt.Errorf("无效的ALAC配置")
+		}
+		if config.bitDepth == 0 || config.bitDepth > 32 {
+			return nil, fmt.Errorf("不支持的ALAC位深度: %d", config.bitDepth)
+		}
+
+		return config, nil
+	}
+
+	return nil, fmt.Errorf("未找到ALAC magic cookie")
+}
+
+// ALAC帧中的语法元素类型
+const (
+	alacElementSCE = 0 // 单声道
+	alacElementCPE = 1 // 声道对
+	alacElementCCE = 2
+	alacElementLFE = 3
+	alacElementDSE = 4 // 数据流
+	alacElementPCE = 5
+	alacElementFIL = 6 // 填充
+	alacElementEND = 7
+)
+
+// 自适应Golomb解码参数
+const (
+	alacQBShift     = 9
+	alacQB          = 1 << alacQBShift
+	alacMMulShift   = 2
+	alacMDenShift   = alacQBShift - alacMMulShift - 1
+	alacMOff        = 1 << (alacMDenShift - 2)
+	alacBitOff      = 24
+	alacMaxPrefix   = 9
+	alacMeanClamp   = 0xffff
+	alacMaxRunBits  = 16
+	alacMaxPredOrds = 32
+)
+
+// alacCodec ALAC帧解码器
+type alacCodec struct {
+	config    *alacConfig
+	predictor []int32
+	mixU      []int32
+	mixV      []int32
+	shift     []uint16
+}
+
+// newALACCodec 创建ALAC帧解码器
+func newALACCodec(config *alacConfig) *alacCodec {
+	n := int(config.frameLength)
+	return &alacCodec{
+		config:    config,
+		predictor: make([]int32, n),
+		mixU:      make([]int32, n),
+		mixV:      make([]int32, n),
+		shift:     make([]uint16, n*2),
+	}
+}
+
+// alacChannelParams 压缩声道的预测参数
+type alacChannelParams struct {
+	mode     uint32
+	denShift uint32
+	pbFactor uint32
+	numCoefs uint32
+	coefs    [alacMaxPredOrds]int16
+}
+
+// decodeFrame 解码一个ALAC数据包，返回每个声道的整数采样
+func (c *alacCodec) decodeFrame(data []byte) ([][]int32, error) {
+	br := &alacBitReader{data: data}
+	var channels [][]int32
+
+	for {
+		if br.pos+3 > len(data)*8 {
+			return nil, fmt.Errorf("帧数据不完整")
+		}
+
+		tag := br.read(3)
+		switch tag {
+		case alacElementSCE, alacElementLFE:
+			samples, err := c.decodeSingle(br)
+			if err != nil {
+				return nil, err
+			}
+			channels = append(channels, samples)
+
+		case alacElementCPE:
+			left, right, err := c.decodePair(br)
+			if err != nil {
+				return nil, err
+			}
+			channels = append(channels, left, right)
+
+		case alacElementDSE:
+			br.skip(4) // 元素实例标签
+			alignFlag := br.read(1)
+			count := int(br.read(8))
+			if count == 255 {
+				count += int(br.read(8))
+			}
+			if alignFlag != 0 {
+				br.align()
+			}
+			br.skip(count * 8)
+
+		case alacElementFIL:
+			count := int(br.read(4))
+			if count == 15 {
+				count += int(br.read(8)) - 1
+			}
+			br.skip(count * 8)
+
+		case alacElementEND:
+			br.align()
+			return channels, nil
+
+		default:
+			return nil, fmt.Errorf("不支持的ALAC元素类型: %d", tag)
+		}
+
+		if len(channels) > int(c.config.numChannels) {
+			return nil, fmt.Errorf("声道数超出配置: %d", len(channels))
+		}
+	}
+}
+
+// readHeader 读取元素头，返回采样数、移位字节数和是否为未压缩帧
+func (c *alacCodec) readHeader(br *alacBitReader) (int, uint32, bool, error) {
+	br.skip(4)  // 元素实例标签
+	br.skip(12) // 保留位
+	headerByte := br.read(4)
+	partialFrame := headerByte >> 3
+	bytesShifted := (headerByte >> 1) & 0x3
+	escapeFlag := headerByte & 0x1
+
+	numSamples := int(c.config.frameLength)
+	if partialFrame != 0 {
+		numSamples = int(br.read(32))
+	}
+	if numSamples > int(c.config.frameLength) {
+		return 0, 0, false, fmt.Errorf("帧采样数超出配置: %d", numSamples)
+	}
+	if bytesShifted == 3 {
+		return 0, 0, false, fmt.Errorf("无效的移位字节数")
+	}
+
+	return numSamples, bytesShifted, escapeFlag != 0, nil
+}
+
+// readChannelParams 读取一个声道的预测参数
+func (c *alacCodec) readChannelParams(br *alacBitReader) alacChannelParams {
+	var params alacChannelParams
+
+	headerByte := br.read(8)
+	params.mode = headerByte >> 4
+	params.denShift = headerByte & 0xf
+
+	headerByte = br.read(8)
+	params.pbFactor = headerByte >> 5
+	params.numCoefs = headerByte & 0x1f
+
+	for i := uint32(0); i < params.numCoefs; i++ {
+		params.coefs[i] = int16(br.read(16))
+	}
+
+	return params
+}
+
+// decompressChannel 熵解码并还原一个声道的预测残差
+func (c *alacCodec) decompressChannel(br *alacBitReader, params *alacChannelParams, out []int32, numSamples int, chanBits uint32) error {
+	pb := uint32(c.config.pb) * params.pbFactor / 4
+	if err := c.dynDecomp(br, c.predictor, numSamples, chanBits, pb); err != nil {
+		return err
+	}
+
+	if params.mode != 0 {
+		// 模式非零时先做一次一阶积分
+		alacUnpcBlock(c.predictor, c.predictor, numSamples, nil, 31, chanBits, 0)
+	}
+	alacUnpcBlock(c.predictor, out, numSamples, params.coefs[:params.numCoefs], int(params.numCoefs), chanBits, params.denShift)
+
+	return nil
+}
+
+// decodeSingle 解码单声道元素
+func (c *alacCodec) decodeSingle(br *alacBitReader) ([]int32, error) {
+	numSamples, bytesShifted, escape, err := c.readHeader(br)
+	if err != nil {
+		return nil, err
+	}
+
+	bitDepth := uint32(c.config.bitDepth)
+	chanBits := bitDepth - bytesShifted*8
+
+	if !escape {
+		br.skip(16) // mixBits / mixRes，单声道不使用
+		params := c.readChannelParams(br)
+
+		// 移位缓冲区位于熵编码数据之前，先记下位置
+		shiftPos := br.pos
+		br.skip(int(bytesShifted*8) * numSamples)
+
+		if err := c.decompressChannel(br, &params, c.mixU, numSamples, chanBits); err != nil {
+			return nil, err
+		}
+
+		if bytesShifted != 0 {
+			shiftReader := &alacBitReader{data: br.data, pos: shiftPos}
+			for i := 0; i < numSamples; i++ {
+				c.shift[i] = uint16(shiftReader.read(bytesShifted * 8))
+			}
+		}
+	} else {
+		chanBits = bitDepth
+		for i := 0; i < numSamples; i++ {
+			c.mixU[i] = br.readSigned(chanBits)
+		}
+		bytesShifted = 0
+	}
+
+	if br.pos > len(br.data)*8 {
+		return nil, fmt.Errorf("帧数据不完整")
+	}
+
+	out := make([]int32, numSamples)
+	shift := bytesShifted * 8
+	for i := 0; i < numSamples; i++ {
+		out[i] = c.mixU[i]
+		if shift != 0 {
+			out[i] = (out[i] << shift) | int32(c.shift[i])
+		}
+	}
+
+	return out, nil
+}
+
+// decodePair 解码立体声声道对元素
+func (c *alacCodec) decodePair(br *alacBitReader) ([]int32, []int32, error) {
+	numSamples, bytesShifted, escape, err := c.readHeader(br)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	bitDepth := uint32(c.config.bitDepth)
+	chanBits := bitDepth - bytesShifted*8 + 1
+	if chanBits > 32 {
+		return nil, nil, fmt.Errorf("不支持的声道位宽: %d", chanBits)
+	}
+
+	var mixBits uint32
+	var mixRes int32
+
+	if !escape {
+		mixBits = br.read(8)
+		mixRes = int32(int8(br.read(8)))
+
+		paramsU := c.readChannelParams(br)
+		paramsV := c.readChannelParams(br)
+
+		shiftPos := br.pos
+		br.skip(int(bytesShifted*8) * 2 * numSamples)
+
+		if err := c.decompressChannel(br, &paramsU, c.mixU, numSamples, chanBits); err != nil {
+			return nil, nil, err
+		}
+		if err := c.decompressChannel(br, &paramsV, c.mixV, numSamples, chanBits); err != nil {
+			return nil, nil, err
+		}
+
+		if bytesShifted != 0 {
+			shiftReader := &alacBitReader{data: br.data, pos: shiftPos}
+			for i := 0; i < numSamples*2; i += 2 {
+				c.shift[i] = uint16(shiftReader.read(bytesShifted * 8))
+				c.shift[i+1] = uint16(shiftReader.read(bytesShifted * 8))
+			}
+		}
+	} else {
+		chanBits = bitDepth
+		for i := 0; i < numSamples; i++ {
+			c.mixU[i] = br.readSigned(chanBits)
+			c.mixV[i] = br.readSigned(chanBits)
+		}
+		bytesShifted = 0
+	}
+
+	if br.pos > len(br.data)*8 {
+		return nil, nil, fmt.Errorf("帧数据不完整")
+	}
+
+	// 还原左右声道
+	left := make([]int32, numSamples)
+	right := make([]int32, numSamples)
+	shift := bytesShifted * 8
+	for i := 0; i < numSamples; i++ {
+		l, r := c.mixU[i], c.mixV[i]
+		if mixRes != 0 {
+			l = c.mixU[i] + c.mixV[i] - ((mixRes * c.mixV[i]) >> mixBits)
+			r = l - c.mixV[i]
+		}
+		if shift != 0 {
+			l = (l << shift) | int32(c.shift[i*2])
+			r = (r << shift) | int32(c.shift[i*2+1])
+		}
+		left[i] = l
+		right[i] = r
+	}
+
+	return left, right, nil
+}
+
+// dynDecomp 自适应Golomb熵解码
+func (c *alacCodec) dynDecomp(br *alacBitReader, out []int32, numSamples int, maxSize uint32, pb uint32) error {
+	kb := uint32(c.config.kb)
+	wb := uint32(1)<<kb - 1
+	mb := uint32(c.config.mb)
+	maxPos := len(br.data) * 8
+	zmode := uint32(0)
+
+	for i := 0; i < numSamples; {
+		if br.pos >= maxPos {
+			return fmt.Errorf("熵编码数据越界")
+		}
+
+		k := alacLg3a(mb >> alacQBShift)
+		if k > kb {
+			k = kb
+		}
+		m := uint32(1)<<k - 1
+
+		n := br.dynGet32(m, k, maxSize)
+
+		// 最低位为符号位
+		ndecode := n + zmode
+		multiplier := -int32(ndecode&1) | 1
+		out[i] = int32((ndecode+1)>>1) * multiplier
+		i++
+
+		mb = pb*(n+zmode) + mb - ((pb * mb) >> alacQBShift)
+		if n > alacMeanClamp {
+			mb = alacMeanClamp
+		}
+
+		zmode = 0
+
+		// 均值过低时进入零游程模式
+		if (mb<<alacMMulShift) < alacQB && i < numSamples {
+			zmode = 1
+			k := uint32(bits.LeadingZeros32(mb)) - alacBitOff + ((mb + alacMOff) >> alacMDenShift)
+			mz := (uint32(1)<<k - 1) & wb
+
+			run := int(br.dynGet(mz, k))
+			if i+run > numSamples {
+				return fmt.Errorf("零游程超出帧长度")
+			}
+			for j := 0; j < run; j++ {
+				out[i] = 0
+				i++
+			}
+
+			if run >= 65535 {
+				zmode = 0
+			}
+			mb = 0
+		}
+	}
+
+	return nil
+}
+
+// alacUnpcBlock 自适应线性预测还原
+func alacUnpcBlock(pc []int32, out []int32, num int, coefs []int16, numActive int, chanBits uint32, denShift uint32) {
+	chanShift := 32 - chanBits
+
+	if num == 0 {
+		return
+	}
+	out[0] = pc[0]
+
+	if numActive == 0 {
+		copy(out[1:num], pc[1:num])
+		return
+	}
+
+	if numActive == 31 {
+		prev := out[0]
+		for j := 1; j < num; j++ {
+			del := pc[j] + prev
+			prev = (del << chanShift) >> chanShift
+			out[j] = prev
+		}
+		return
+	}
+
+	for j := 1; j <= numActive && j < num; j++ {
+		del := pc[j] + out[j-1]
+		out[j] = (del << chanShift) >> chanShift
+	}
+
+	var denHalf int32
+	if denShift > 0 {
+		denHalf = 1 << (denShift - 1)
+	}
+
+	lim := numActive + 1
+	for j := lim; j < num; j++ {
+		top := out[j-lim]
+		var sum int32
+		for k := 0; k < numActive; k++ {
+			sum += int32(coefs[k]) * (out[j-1-k] - top)
+		}
+
+		del := pc[j]
+		del0 := del
+		sg := alacSign(del)
+		del += top + ((sum + denHalf) >> denShift)
+		out[j] = (del << chanShift) >> chanShift
+
+		// 根据残差符号调整预测系数
+		if sg > 0 {
+			for k := numActive - 1; k >= 0; k-- {
+				dd := top - out[j-1-k]
+				sgn := alacSign(dd)
+				coefs[k] -= int16(sgn)
+				del0 -= int32(numActive-k) * ((sgn * dd) >> denShift)
+				if del0 <= 0 {
+					break
+				}
+			}
+		} else if sg < 0 {
+			for k := numActive - 1; k >= 0; k-- {
+				dd := top - out[j-1-k]
+				sgn := alacSign(dd)
+				coefs[k] += int16(sgn)
+				del0 -= int32(numActive-k) * ((-sgn * dd) >> denShift)
+				if del0 >= 0 {
+					break
+				}
+			}
+		}
+	}
+}
+
+// alacSign 返回整数的符号 (-1, 0, 1)
+func alacSign(i int32) int32 {
+	return int32(uint32(-i)>>31) | (i >> 31)
+}
+
+// alacLg3a 计算 floor(log2(x+3))
+func alacLg3a(x uint32) uint32 {
+	return 31 - uint32(bits.LeadingZeros32(x+3))
+}
+
+// alacBitReader 高位优先的位读取器
+type alacBitReader struct {
+	data []byte
+	pos  int
+}
+
+// peek32 读取指定位置起的32位，超出数据末尾的部分补零
+func (b *alacBitReader) peek32(pos int) uint32 {
+	idx := pos >> 3
+	var v uint64
+	for i := 0; i < 5; i++ {
+		v <<= 8
+		if idx+i < len(b.data) {
+			v |= uint64(b.data[idx+i])
+		}
+	}
+	return uint32(v >> (8 - uint(pos&7)))
+}
+
+// read 读取n位无符号整数 (n <= 32)
+func (b *alacBitReader) read(n uint32) uint32 {
+	if n == 0 {
+		return 0
+	}
+	v := b.peek32(b.pos) >> (32 - n)
+	b.pos += int(n)
+	return v
+}
+
+// readSigned 读取n位有符号整数
+func (b *alacBitReader) readSigned(n uint32) int32 {
+	shift := 32 - n
+	return int32(b.read(n)<<shift) >> shift
+}
+
+// skip 跳过n位
+func (b *alacBitReader) skip(n int) {
+	b.pos += n
+}
+
+// align 对齐到字节边界
+func (b *alacBitReader) align() {
+	b.pos = (b.pos + 7) &^ 7
+}
+
+// dynGet32 读取一个自适应Golomb编码的采样值
+func (b *alacBitReader) dynGet32(m, k, maxBits uint32) uint32 {
+	stream := b.peek32(b.pos)
+	result := uint32(bits.LeadingZeros32(^stream))
+
+	if result >= alacMaxPrefix {
+		// 前缀过长时为转义编码，直接读取maxBits位
+		b.pos += alacMaxPrefix
+		return b.read(maxBits)
+	}
+
+	b.pos += int(result) + 1
+	if k != 1 {
+		v := (stream << (result + 1)) >> (32 - k)
+		b.pos += int(k) - 1
+		result *= m
+		if v >= 2 {
+			result += v - 1
+			b.pos++
+		}
+	}
+
+	return result
+}
+
+// dynGet 读取零游程长度
+func (b *alacBitReader) dynGet(m, k uint32) uint32 {
+	stream := b.peek32(b.pos)
+	pre := uint32(bits.LeadingZeros32(^stream))
+
+	if pre >= alacMaxPrefix {
+		b.pos += alacMaxPrefix
+		return b.read(alacMaxRunBits)
+	}
+
+	b.pos += int(pre) + 1
+	v := (stream << (pre + 1)) >> (32 - k)
+	b.pos += int(k)
+
+	result := pre*m + v - 1
+	if v < 2 {
+		result -= v - 1
+		b.pos--
+	}
+
+	return result
+}
@@ -0,0 +1,19 @@
+package decoder
+
+import "testing"
+
+// golden.m4a: 16位立体声，帧长1024，共4 帧（最后一帧不完整），moov位于mdat之后；
+// 依次为带声道混合的4阶预测、未压缩帧、带一阶差分的预测（mode 1）、不混合的8阶预测
+func TestALACGolden(t *testing.T) {
+	audioFile, samples := decodeTestFile(t, "golden.m4a")
+
+	if audioFile.GetFormat() != "ALAC" || audioFile.GetSampleRate() != 44100 ||
+		audioFile.GetBitDepth() != 16 || audioFile.GetChannels() != 2 {
+		t.Fatalf("格式为 %s %d Hz %d位 %d声道", audioFile.GetFormat(), audioFile.GetSampleRate(),
+			audioFile.GetBitDepth(), audioFile.GetChannels())
+	}
+	if metadata := audioFile.GetMetadata(); metadata.Title != "Golden" || metadata.TrackNumber != 3 || metadata.TrackTotal != 12 {
+		t.Errorf("元数据为 %+v", metadata)
+	}
+	checkSamples(t, samples, testSignal(2, 3*1024+517, 16))
+}
@@ -0,0 +1,1053 @@
+package decoder
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"time"
+
+	"audio-loss-checker/internal/types"
+)
+
+// APEDecoder Monkey's Audio (APE) 格式解码器
+type APEDecoder struct{}
+
+// APEFile APE文件实现
+type APEFile struct {
+	file       *Source
+	header     *apeHeader
+	frames     []apeFrame
+	sampleRate int
+	bitDepth   int
+	channels   int
+	duration   time.Duration
+	samples    [][]float64
+	metadata   types.AudioMetadata
+}
+
+// apeHeader APE文件头信息
+type apeHeader struct {
+	version          int
+	compressionLevel int
+	formatFlags      int
+	blocksPerFrame   int
+	finalFrameBlocks int
+	totalFrames      int
+	bitsPerSample    int
+	channels         int
+	sampleRate       int
+}
+
+// apeFrame APE帧在文件中的位置
+type apeFrame struct {
+	pos     int64
+	size    int64
+	nblocks int
+	skip    int
+}
+
+const (
+	apeMinVersion = 3950
+	apeMaxVersion = 4100
+
+	apeFormatFlag8Bit         = 1
+	apeFormatFlagPeakLevel    = 4
+	apeFormatFlag24Bit        = 8
+	apeFormatFlagSeekElements = 16
+	apeFormatFlagNoWAVHeader  = 32
+
+	apeFrameMonoSilence   = 1
+	apeFrameStereoSilence = 3
+	apeFramePseudoStereo  = 4
+
+	apeBlocksPerLoop = 4608
+)
+
+// SupportedFormats 返回支持的格式
+func (d *APEDecoder) SupportedFormats() []string {
+	return []string{"ape"}
+}
+
+// Decode 解码APE文件
+func (d *APEDecoder) Decode(file *Source) (types.AudioFile, error) {
+	header, frames, err := parseAPEHeader(file, file.Size())
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("解析APE文件失败: %w", err)
+	}
+
+	// 计算时长
+	totalBlocks := int64(header.totalFrames-1)*int64(header.blocksPerFrame) + int64(header.finalFrameBlocks)
+	duration := time.Duration(float64(totalBlocks) / float64(header.sampleRate) * float64(time.Second))
+
+	apeFile := &APEFile{
+		file:       file,
+		header:     header,
+		frames:     frames,
+		sampleRate: header.sampleRate,
+		bitDepth:   header.bitsPerSample,
+		channels:   header.channels,
+		duration:   duration,
+	}
+
+	// 解析APEv2标签
+	if tags, _ := readAPETag(file, file.Size()); tags != nil {
+		apeFile.metadata = apeTagMetadata(tags)
+	}
+	apeFile.metadata.Duration = duration.String()
+
+	return apeFile, nil
+}
+
+// parseAPEHeader 解析APE描述符、文件头和寻址表
+func parseAPEHeader(r io.ReadSeeker, fileSize int64) (*apeHeader, []apeFrame, error) {
+	// 文件开头可能带有ID3v2标签
+	junk := make([]byte, 10)
+	if _, err := io.ReadFull(r, junk); err != nil {
+		return nil, nil, err
+	}
+	junkLength := id3v2TagSize(junk)
+	if _, err := r.Seek(junkLength, io.SeekStart); err != nil {
+		return nil, nil, err
+	}
+
+	var fixed struct {
+		Magic   [4]byte
+		Version uint16
+	}
+	if err := binary.Read(r, binary.LittleEndian, &fixed); err != nil {
+		return nil, nil, err
+	}
+	if string(fixed.Magic[:]) != "MAC " {
+		return nil, nil, fmt.Errorf("无效的APE文件标识")
+	}
+
+	header := &apeHeader{version: int(fixed.Version)}
+	if header.version < apeMinVersion || header.version > apeMaxVersion {
+		return nil, nil, fmt.Errorf("不支持的APE版本: %d（仅支持 %d 至 %d）", header.version, apeMinVersion, apeMaxVersion)
+	}
+
+	var descriptorLength, headerLength, seekTableLength, wavHeaderLength, wavTailLength int64
+
+	if header.version >= 3980 {
+		var desc struct {
+			Padding          uint16
+			DescriptorLength uint32
+			HeaderLength     uint32
+			SeekTableLength  uint32
+			WAVHeaderLength  uint32
+			AudioDataLength  uint32
+			AudioDataHigh    uint32
+			WAVTailLength    uint32
+			MD5              [16]byte
+		}
+		if err := binary.Read(r, binary.LittleEndian, &desc); err != nil {
+			return nil, nil, err
+		}
+
+		descriptorLength = int64(desc.DescriptorLength)
+		headerLength = int64(desc.HeaderLength)
+		seekTableLength = int64(desc.SeekTableLength)
+		wavHeaderLength = int64(desc.WAVHeaderLength)
+		wavTailLength = int64(desc.WAVTailLength)
+
+		// 跳过描述符末尾的未知字段
+		if descriptorLength > 52 {
+			if _, err := r.Seek(descriptorLength-52, io.SeekCurrent); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		var h struct {
+			CompressionType  uint16
+			FormatFlags      uint16
+			BlocksPerFrame   uint32
+			FinalFrameBlocks uint32
+			TotalFrames      uint32
+			BitsPerSample    uint16
+			Channels         uint16
+			SampleRate       uint32
+		}
+		if err := binary.Read(r, binary.LittleEndian, &h); err != nil {
+			return nil, nil, err
+		}
+
+		header.compressionLevel = int(h.CompressionType)
+		header.formatFlags = int(h.FormatFlags)
+		header.blocksPerFrame = int(h.BlocksPerFrame)
+		header.finalFrameBlocks = int(h.FinalFrameBlocks)
+		header.totalFrames = int(h.TotalFrames)
+		header.bitsPerSample = int(h.BitsPerSample)
+		header.channels = int(h.Channels)
+		header.sampleRate = int(h.SampleRate)
+	} else {
+		var h struct {
+			CompressionType  uint16
+			FormatFlags      uint16
+			Channels         uint16
+			SampleRate       uint32
+			WAVHeaderLength  uint32
+			WAVTailLength    uint32
+			TotalFrames      uint32
+			FinalFrameBlocks uint32
+		}
+		if err := binary.Read(r, binary.LittleEndian, &h); err != nil {
+			return nil, nil, err
+		}
+
+		headerLength = 32
+		header.compressionLevel = int(h.CompressionType)
+		header.formatFlags = int(h.FormatFlags)
+		header.channels = int(h.Channels)
+		header.sampleRate = int(h.SampleRate)
+		header.totalFrames = int(h.TotalFrames)
+		header.finalFrameBlocks = int(h.FinalFrameBlocks)
+		wavHeaderLength = int64(h.WAVHeaderLength)
+		wavTailLength = int64(h.WAVTailLength)
+
+		if header.formatFlags&apeFormatFlagPeakLevel != 0 {
+			if _, err := r.Seek(4, io.SeekCurrent); err != nil {
+				return nil, nil, err
+			}
+			headerLength += 4
+		}
+
+		if header.formatFlags&apeFormatFlagSeekElements != 0 {
+			var elements uint32
+			if err := binary.Read(r, binary.LittleEndian, &elements); err != nil {
+				return nil, nil, err
+			}
+			headerLength += 4
+			seekTableLength = int64(elements) * 4
+		} else {
+			seekTableLength = int64(header.totalFrames) * 4
+		}
+
+		switch {
+		case header.formatFlags&apeFormatFlag8Bit != 0:
+			header.bitsPerSample = 8
+		case header.formatFlags&apeFormatFlag24Bit != 0:
+			header.bitsPerSample = 24
+		default:
+			header.bitsPerSample = 16
+		}
+
+		header.blocksPerFrame = 73728 * 4
+
+		if header.formatFlags&apeFormatFlagNoWAVHeader != 0 {
+			wavHeaderLength = 0
+		}
+	}
+
+	if header.totalFrames == 0 || header.blocksPerFrame == 0 {
+		return nil, nil, fmt.Errorf("APE文件不包含音频帧")
+	}
+	if header.channels < 1 || header.channels > 2 {
+		return nil, nil, fmt.Errorf("不支持的APE声道数: %d", header.channels)
+	}
+	if header.bitsPerSample != 8 && header.bitsPerSample != 16 && header.bitsPerSample != 24 {
+		return nil, nil, fmt.Errorf("不支持的APE位深度: %d", header.bitsPerSample)
+	}
+	if header.compressionLevel%1000 != 0 || header.compressionLevel < 1000 || header.compressionLevel > 5000 {
+		return nil, nil, fmt.Errorf("不支持的APE压缩级别: %d", header.compressionLevel)
+	}
+	if header.sampleRate <= 0 {
+		return nil, nil, fmt.Errorf("无效的APE采样率: %d", header.sampleRate)
+	}
+	if seekTableLength/4 < int64(header.totalFrames) {
+		return nil, nil, fmt.Errorf("APE寻址表不完整")
+	}
+
+	// 读取寻址表：新格式的寻址表紧跟文件头，旧格式的寻址表位于WAV头之后
+	seekTablePos := junkLength + descriptorLength + headerLength
+	if header.version < 3980 {
+		seekTablePos += wavHeaderLength
+	}
+	if _, err := r.Seek(seekTablePos, io.SeekStart); err != nil {
+		return nil, nil, err
+	}
+	seekTable := make([]uint32, header.totalFrames)
+	if err := binary.Read(r, binary.LittleEndian, seekTable); err != nil {
+		return nil, nil, fmt.Errorf("读取APE寻址表失败: %w", err)
+	}
+
+	// 计算每帧的位置和大小
+	frames := make([]apeFrame, header.totalFrames)
+	frames[0].pos = junkLength + descriptorLength + headerLength + seekTableLength + wavHeaderLength
+	frames[0].nblocks = header.blocksPerFrame
+	for i := 1; i < header.totalFrames; i++ {
+		frames[i].pos = int64(seekTable[i]) + junkLength
+		frames[i].nblocks = header.blocksPerFrame
+		frames[i-1].size = frames[i].pos - frames[i-1].pos
+		frames[i].skip = int((frames[i].pos - frames[0].pos) & 3)
+	}
+
+	last := &frames[header.totalFrames-1]
+	last.nblocks = header.finalFrameBlocks
+	last.size = fileSize - last.pos - wavTailLength
+	last.size -= last.size & 3
+	if last.size <= 0 {
+		last.size = int64(header.finalFrameBlocks) * 8
+	}
+
+	for i := range frames {
+		if frames[i].size < 0 {
+			return nil, nil, fmt.Errorf("APE寻址表无效")
+		}
+		frames[i].pos -= int64(frames[i].skip)
+		frames[i].size += int64(frames[i].skip)
+		frames[i].size = (frames[i].size + 3) &^ 3
+	}
+
+	return header, frames, nil
+}
+
+// GetFormat 获取格式名称
+func (f *APEFile) GetFormat() string {
+	return "APE"
+}
+
+// GetSampleRate 获取采样率
+func (f *APEFile) GetSampleRate() int {
+	return f.sampleRate
+}
+
+// GetBitDepth 获取位深度
+func (f *APEFile) GetBitDepth() int {
+	return f.bitDepth
+}
+
+// GetChannels 获取声道数
+func (f *APEFile) GetChannels() int {
+	return f.channels
+}
+
+// GetDuration 获取时长
+func (f *APEFile) GetDuration() time.Duration {
+	return f.duration
+}
+
+// GetSamples 获取按声道分开的音频采样数据
+func (f *APEFile) GetSamples() ([][]float64, error) {
+	if f.samples != nil {
+		return f.samples, nil
+	}
+
+	reader, err := f.NewSampleReader()
+	if err != nil {
+		return nil, err
+	}
+	samples, err := readAllSamples(reader, f.channels)
+	if err != nil {
+		return nil, err
+	}
+
+	f.samples = samples
+	return samples, nil
+}
+
+// NewSampleReader 创建采样流，逐帧解码
+func (f *APEFile) NewSampleReader() (types.SampleReader, error) {
+	codec := newAPECodec(f.header)
+	converter := newIntFrameConverter(f.channels, f.bitDepth)
+	i := 0
+
+	return newIntFrameReader(converter, func() ([][]float64, error) {
+		if i >= len(f.frames) {
+			return nil, io.EOF
+		}
+		frame := f.frames[i]
+		data := make([]byte, frame.size)
+		n, err := f.file.ReadAt(data, frame.pos)
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("读取APE帧 %d 失败: %w", i, err)
+		}
+		if n == 0 {
+			return nil, fmt.Errorf("APE帧 %d 数据缺失", i)
+		}
+
+		channels, err := codec.decodeFrame(data, frame.skip, frame.nblocks)
+		if err != nil {
+			return nil, fmt.Errorf("解码APE帧 %d 失败: %w", i, err)
+		}
+		for ch := range channels {
+			channels[ch] = channels[ch][:frame.nblocks]
+		}
+
+		i++
+		return converter.convert(channels), nil
+	}), nil
+}
+
+// GetMetadata 获取元数据
+func (f *APEFile) GetMetadata() types.AudioMetadata {
+	return f.metadata
+}
+
+// Close 关闭文件
+func (f *APEFile) Close() error {
+	if f.file != nil {
+		return f.file.Close()
+	}
+	return nil
+}
+
+// 各压缩级别（fast/normal/high/extra high/insane）使用的NN滤波器阶数和小数位数
+var (
+	apeFilterOrders = [5][3]int{
+		{0, 0, 0},
+		{16, 0, 0},
+		{64, 0, 0},
+		{32, 256, 0},
+		{16, 256, 1280},
+	}
+	apeFilterFracBits = [5][3]int{
+		{0, 0, 0},
+		{11, 0, 0},
+		{11, 0, 0},
+		{10, 13, 0},
+		{11, 13, 15},
+	}
+)
+
+// 区间编码符号频率表
+var (
+	apeCounts3970 = [22]uint32{
+		0, 14824, 28224, 39348, 47855, 53994, 58171, 60926,
+		62682, 63786, 64463, 64878, 65126, 65276, 65365, 65419,
+		65450, 65469, 65480, 65487, 65491, 65493,
+	}
+	apeCountsDiff3970 = [21]uint32{
+		14824, 13400, 11124, 8507, 6139, 4177, 2755, 1756,
+		1104, 677, 415, 248, 150, 89, 54, 31,
+		19, 11, 7, 4, 2,
+	}
+	apeCounts3980 = [22]uint32{
+		0, 19578, 36160, 48417, 56323, 60899, 63265, 64435,
+		64971, 65232, 65351, 65416, 65447, 65466, 65476, 65482,
+		65485, 65488, 65490, 65491, 65492, 65493,
+	}
+	apeCountsDiff3980 = [21]uint32{
+		19578, 16582, 12257, 7906, 4576, 2366, 1170, 536,
+		261, 119, 65, 31, 19, 10, 6, 3,
+		3, 2, 1, 1, 1,
+	}
+)
+
+// apeCodec APE帧解码器
+type apeCodec struct {
+	header    *apeHeader
+	fset      int
+	rc        apeRangeDecoder
+	riceX     apeRice
+	riceY     apeRice
+	predictor apePredictor
+	filters   [3][2]*apeFilter
+}
+
+// newAPECodec 创建APE帧解码器
+func newAPECodec(header *apeHeader) *apeCodec {
+	c := &apeCodec{
+		header: header,
+		fset:   header.compressionLevel/1000 - 1,
+	}
+
+	for i := 0; i < 3; i++ {
+		order := apeFilterOrders[c.fset][i]
+		if order == 0 {
+			break
+		}
+		for ch := 0; ch < 2; ch++ {
+			c.filters[i][ch] = newAPEFilter(order, apeFilterFracBits[c.fset][i], header.version)
+		}
+	}
+
+	return c
+}
+
+// decodeFrame 解码一个APE帧，返回每个声道的整数采样
+func (c *apeCodec) decodeFrame(raw []byte, skip int, nblocks int) ([][]int32, error) {
+	// APE帧数据以32位小端字为单位存储，先转换为字节流
+	data := make([]byte, len(raw)&^3)
+	for i := 0; i+4 <= len(raw); i += 4 {
+		binary.BigEndian.PutUint32(data[i:], binary.LittleEndian.Uint32(raw[i:]))
+	}
+	if skip > 3 || len(data) < skip+10 {
+		return nil, fmt.Errorf("帧数据不完整")
+	}
+	data = data[skip:]
+
+	// 帧头：CRC和可选的帧标志
+	storedCRC := binary.BigEndian.Uint32(data[0:4])
+	pos := 4
+	frameFlags := uint32(0)
+	if storedCRC&0x80000000 != 0 {
+		storedCRC &^= 0x80000000
+		frameFlags = binary.BigEndian.Uint32(data[4:8])
+		pos = 8
+	}
+
+	// 初始化熵解码器、预测器和滤波器
+	c.riceX = apeRice{k: 10, ksum: (1 << 10) * 16}
+	c.riceY = apeRice{k: 10, ksum: (1 << 10) * 16}
+	c.rc.start(data, pos+1) // 第一个字节不使用
+	c.predictor.reset()
+	for i := range c.filters {
+		for ch := range c.filters[i] {
+			if c.filters[i][ch] != nil {
+				c.filters[i][ch].reset()
+			}
+		}
+	}
+
+	decoded := [][]int32{make([]int32, nblocks), make([]int32, nblocks)}
+	mono := c.header.channels == 1 || frameFlags&apeFramePseudoStereo != 0
+
+	for start := 0; start < nblocks; start += apeBlocksPerLoop {
+		count := nblocks - start
+		if count > apeBlocksPerLoop {
+			count = apeBlocksPerLoop
+		}
+		d0 := decoded[0][start : start+count]
+		d1 := decoded[1][start : start+count]
+
+		if mono {
+			c.unpackMono(d0, d1, frameFlags)
+		} else {
+			c.unpackStereo(d0, d1, frameFlags)
+		}
+
+		if c.rc.err {
+			return nil, fmt.Errorf("熵编码数据越界")
+		}
+	}
+
+	// 校验帧CRC
+	if crc := c.outputCRC(decoded, nblocks); crc>>1 != storedCRC {
+		return nil, fmt.Errorf("帧CRC校验失败")
+	}
+
+	return decoded[:c.header.channels], nil
+}
+
+// unpackMono 解码单声道（或伪立体声）数据块
+func (c *apeCodec) unpackMono(d0, d1 []int32, frameFlags uint32) {
+	if frameFlags&apeFrameStereoSilence != 0 {
+		return
+	}
+
+	for i := range d0 {
+		d0[i] = c.decodeValue(&c.riceY)
+	}
+
+	c.applyFilters(d0, nil)
+	c.predictor.decodeMono(d0)
+
+	// 伪立体声：右声道复制左声道
+	if c.header.channels == 2 {
+		copy(d1, d0)
+	}
+}
+
+// unpackStereo 解码立体声数据块
+func (c *apeCodec) unpackStereo(d0, d1 []int32, frameFlags uint32) {
+	if frameFlags&apeFrameStereoSilence == apeFrameStereoSilence {
+		return
+	}
+
+	// 3930及以后的版本逐个采样交替存储两个声道的残差
+	for i := range d0 {
+		d0[i] = c.decodeValue(&c.riceY)
+		d1[i] = c.decodeValue(&c.riceX)
+	}
+
+	c.applyFilters(d0, d1)
+	c.predictor.decodeStereo(d0, d1)
+
+	// 由中间/差值声道还原左右声道
+	for i := range d0 {
+		left := d1[i] - d0[i]/2
+		right := left + d0[i]
+		d0[i] = left
+		d1[i] = right
+	}
+}
+
+// applyFilters 依次应用各级NN滤波器
+func (c *apeCodec) applyFilters(d0, d1 []int32) {
+	for i := range c.filters {
+		if c.filters[i][0] == nil {
+			break
+		}
+		c.filters[i][0].apply(d0)
+		if d1 != nil {
+			c.filters[i][1].apply(d1)
+		}
+	}
+}
+
+// decodeValue 解码一个残差值
+func (c *apeCodec) decodeValue(rice *apeRice) int32 {
+	var x uint32
+
+	if c.header.version >= 3990 {
+		pivot := rice.ksum >> 5
+		if pivot < 1 {
+			pivot = 1
+		}
+
+		overflow := c.rc.getSymbol(apeCounts3980[:], apeCountsDiff3980[:])
+		if overflow == 63 {
+			overflow = c.rc.decodeBits(16) << 16
+			overflow |= c.rc.decodeBits(16)
+		}
+
+		var base uint32
+		if pivot < 0x10000 {
+			base = c.rc.culFreq(pivot)
+			c.rc.update(1, base)
+		} else {
+			baseHi := pivot
+			bbits := uint32(0)
+			for baseHi&^0xffff != 0 {
+				baseHi >>= 1
+				bbits++
+			}
+			hi := c.rc.culFreq(baseHi + 1)
+			c.rc.update(1, hi)
+			lo := c.rc.culFreq(1 << bbits)
+			c.rc.update(1, lo)
+			base = hi<<bbits + lo
+		}
+
+		x = base + overflow*pivot
+	} else {
+		overflow := c.rc.getSymbol(apeCounts3970[:], apeCountsDiff3970[:])
+
+		var tmpk uint32
+		if overflow == 63 {
+			tmpk = c.rc.decodeBits(5)
+			overflow = 0
+		} else if rice.k >= 1 {
+			tmpk = rice.k - 1
+		}
+
+		switch {
+		case tmpk <= 16:
+			x = c.rc.decodeBits(tmpk)
+		case tmpk <= 31:
+			x = c.rc.decodeBits(16)
+			x |= c.rc.decodeBits(tmpk-16) << 16
+		default:
+			c.rc.err = true
+			return 0
+		}
+		x += overflow << tmpk
+	}
+
+	rice.update(x)
+
+	// 转换为有符号数
+	return int32((x>>1)^((x&1)-1)) + 1
+}
+
+// outputCRC 计算解码输出（小端PCM字节流）的CRC32
+func (c *apeCodec) outputCRC(decoded [][]int32, nblocks int) uint32 {
+	bytesPerSample := c.header.bitsPerSample / 8
+	buf := make([]byte, 0, nblocks*c.header.channels*bytesPerSample)
+
+	for i := 0; i < nblocks; i++ {
+		for ch := 0; ch < c.header.channels; ch++ {
+			v := decoded[ch][i]
+			switch bytesPerSample {
+			case 1:
+				buf = append(buf, byte(v+0x80))
+			case 2:
+				buf = append(buf, byte(v), byte(v>>8))
+			case 3:
+				buf = append(buf, byte(v), byte(v>>8), byte(v>>16))
+			}
+		}
+	}
+
+	return crc32.ChecksumIEEE(buf)
+}
+
+// apeRice 自适应Rice参数
+type apeRice struct {
+	k    uint32
+	ksum uint32
+}
+
+// update 根据解码值更新Rice参数
+func (r *apeRice) update(x uint32) {
+	lim := uint32(0)
+	if r.k != 0 {
+		lim = 1 << (r.k + 4)
+	}
+	r.ksum += (x+1)/2 - (r.ksum+16)>>5
+
+	if r.ksum < lim {
+		r.k--
+	} else if r.ksum >= 1<<(r.k+5) && r.k < 24 {
+		r.k++
+	}
+}
+
+// 区间解码器参数
+const (
+	apeCodeBits    = 32
+	apeTopValue    = uint32(1) << (apeCodeBits - 1)
+	apeExtraBits   = (apeCodeBits-2)%8 + 1
+	apeBottomValue = apeTopValue >> 8
+)
+
+// apeRangeDecoder APE区间解码器
+type apeRangeDecoder struct {
+	data   []byte
+	pos    int
+	low    uint32
+	rng    uint32
+	help   uint32
+	buffer uint32
+	err    bool
+}
+
+// start 从指定位置开始解码
+func (rc *apeRangeDecoder) start(data []byte, pos int) {
+	rc.data = data
+	rc.pos = pos
+	rc.err = false
+	rc.buffer = uint32(rc.nextByte())
+	rc.low = rc.buffer >> (8 - apeExtraBits)
+	rc.rng = 1 << apeExtraBits
+}
+
+// nextByte 读取下一个字节，越界时记录错误
+func (rc *apeRangeDecoder) nextByte() byte {
+	if rc.pos < len(rc.data) {
+		b := rc.data[rc.pos]
+		rc.pos++
+		return b
+	}
+	rc.err = true
+	return 0
+}
+
+// normalize 区间归一化
+func (rc *apeRangeDecoder) normalize() {
+	for rc.rng <= apeBottomValue {
+		rc.buffer = rc.buffer<<8 | uint32(rc.nextByte())
+		rc.low = rc.low<<8 | (rc.buffer>>1)&0xff
+		rc.rng <<= 8
+	}
+}
+
+// culFreq 按总频率解码累积频率
+func (rc *apeRangeDecoder) culFreq(totFreq uint32) uint32 {
+	rc.normalize()
+	rc.help = rc.rng / totFreq
+	if rc.help == 0 {
+		rc.err = true
+		return 0
+	}
+	return rc.low / rc.help
+}
+
+// culShift 按2的幂次总频率解码累积频率
+func (rc *apeRangeDecoder) culShift(shift uint32) uint32 {
+	rc.normalize()
+	rc.help = rc.rng >> shift
+	if rc.help == 0 {
+		rc.err = true
+		return 0
+	}
+	return rc.low / rc.help
+}
+
+// update 更新区间
+func (rc *apeRangeDecoder) update(symFreq, lowFreq uint32) {
+	rc.low -= rc.help * lowFreq
+	rc.rng = rc.help * symFreq
+}
+
+// decodeBits 直接解码n位
+func (rc *apeRangeDecoder) decodeBits(n uint32) uint32 {
+	sym := rc.culShift(n)
+	rc.update(1, sym)
+	return sym
+}
+
+// getSymbol 按频率表解码一个符号
+func (rc *apeRangeDecoder) getSymbol(counts []uint32, countsDiff []uint32) uint32 {
+	cf := rc.culShift(16)
+
+	if cf > 65492 {
+		symbol := cf - 65535 + 63
+		rc.update(1, cf)
+		if cf > 65535 {
+			rc.err = true
+		}
+		return symbol
+	}
+
+	symbol := 0
+	for counts[symbol+1] <= cf {
+		symbol++
+	}
+	rc.update(countsDiff[symbol], counts[symbol])
+
+	return uint32(symbol)
+}
+
+// 预测器参数
+const (
+	apeHistorySize    = 512
+	apePredictorOrder = 8
+	apePredictorSize  = 50
+
+	apeYDelayA = 18 + apePredictorOrder*4
+	apeYDelayB = 18 + apePredictorOrder*3
+	apeXDelayA = 18 + apePredictorOrder*2
+	apeXDelayB = 18 + apePredictorOrder
+
+	apeYAdaptCoeffsA = 18
+	apeXAdaptCoeffsA = 14
+	apeYAdaptCoeffsB = 10
+	apeXAdaptCoeffsB = 5
+)
+
+// apePredictor 3.95及以上版本的预测器
+type apePredictor struct {
+	history [apeHistorySize + apePredictorSize]int32
+	pos     int
+	lastA   [2]int32
+	filterA [2]int32
+	filterB [2]int32
+	coeffsA [2][4]int32
+	coeffsB [2][5]int32
+}
+
+// reset 在每帧开始时重置预测器状态
+func (p *apePredictor) reset() {
+	*p = apePredictor{}
+	initial := [4]int32{360, 317, -109, 98}
+	p.coeffsA[0] = initial
+	p.coeffsA[1] = initial
+}
+
+// advance 移动历史缓冲区位置
+func (p *apePredictor) advance() {
+	p.pos++
+	if p.pos == apeHistorySize {
+		copy(p.history[:apePredictorSize], p.history[p.pos:p.pos+apePredictorSize])
+		p.pos = 0
+	}
+}
+
+// updateFilter 对一个声道执行预测并更新自适应系数
+func (p *apePredictor) updateFilter(decoded int32, filter int, delayA, delayB, adaptA, adaptB int) int32 {
+	buf := p.history[p.pos:]
+
+	buf[delayA] = p.lastA[filter]
+	buf[adaptA] = apeSign(buf[delayA])
+	buf[delayA-1] = buf[delayA] - buf[delayA-1]
+	buf[adaptA-1] = apeSign(buf[delayA-1])
+
+	predictionA := buf[delayA]*p.coeffsA[filter][0] +
+		buf[delayA-1]*p.coeffsA[filter][1] +
+		buf[delayA-2]*p.coeffsA[filter][2] +
+		buf[delayA-3]*p.coeffsA[filter][3]
+
+	// 一阶滤波
+	buf[delayB] = p.filterA[filter^1] - int32(uint32(p.filterB[filter])*31)>>5
+	buf[adaptB] = apeSign(buf[delayB])
+	buf[delayB-1] = buf[delayB] - buf[delayB-1]
+	buf[adaptB-1] = apeSign(buf[delayB-1])
+	p.filterB[filter] = p.filterA[filter^1]
+
+	predictionB := buf[delayB]*p.coeffsB[filter][0] +
+		buf[delayB-1]*p.coeffsB[filter][1] +
+		buf[delayB-2]*p.coeffsB[filter][2] +
+		buf[delayB-3]*p.coeffsB[filter][3] +
+		buf[delayB-4]*p.coeffsB[filter][4]
+
+	p.lastA[filter] = decoded + (predictionA+predictionB>>1)>>10
+	p.filterA[filter] = p.lastA[filter] + int32(uint32(p.filterA[filter])*31)>>5
+
+	sign := apeSign(decoded)
+	for i := 0; i < 4; i++ {
+		p.coeffsA[filter][i] += buf[adaptA-i] * sign
+	}
+	for i := 0; i < 5; i++ {
+		p.coeffsB[filter][i] += buf[adaptB-i] * sign
+	}
+
+	return p.filterA[filter]
+}
+
+// decodeStereo 立体声预测还原
+func (p *apePredictor) decodeStereo(d0, d1 []int32) {
+	for i := range d0 {
+		d0[i] = p.updateFilter(d0[i], 0, apeYDelayA, apeYDelayB, apeYAdaptCoeffsA, apeYAdaptCoeffsB)
+		d1[i] = p.updateFilter(d1[i], 1, apeXDelayA, apeXDelayB, apeXAdaptCoeffsA, apeXAdaptCoeffsB)
+		p.advance()
+	}
+}
+
+// decodeMono 单声道预测还原
+func (p *apePredictor) decodeMono(d0 []int32) {
+	currentA := p.lastA[0]
+
+	for i := range d0 {
+		a := d0[i]
+		buf := p.history[p.pos:]
+
+		buf[apeYDelayA] = currentA
+		buf[apeYDelayA-1] = buf[apeYDelayA] - buf[apeYDelayA-1]
+
+		predictionA := buf[apeYDelayA]*p.coeffsA[0][0] +
+			buf[apeYDelayA-1]*p.coeffsA[0][1] +
+			buf[apeYDelayA-2]*p.coeffsA[0][2] +
+			buf[apeYDelayA-3]*p.coeffsA[0][3]
+
+		currentA = a + predictionA>>10
+
+		buf[apeYAdaptCoeffsA] = apeSign(buf[apeYDelayA])
+		buf[apeYAdaptCoeffsA-1] = apeSign(buf[apeYDelayA-1])
+
+		sign := apeSign(a)
+		for k := 0; k < 4; k++ {
+			p.coeffsA[0][k] += buf[apeYAdaptCoeffsA-k] * sign
+		}
+
+		p.advance()
+
+		p.filterA[0] = currentA + int32(uint32(p.filterA[0])*31)>>5
+		d0[i] = p.filterA[0]
+	}
+
+	p.lastA[0] = currentA
+}
+
+// apeSign 返回整数的反向符号（正数为-1，负数为1）
+func apeSign(x int32) int32 {
+	switch {
+	case x < 0:
+		return 1
+	case x > 0:
+		return -1
+	}
+	return 0
+}
+
+// apeFilter NN自适应滤波器
+type apeFilter struct {
+	order    int
+	fracBits uint
+	coeffs   []int16
+	delay    []int16 // 历史输出（已截断为16位）
+	adapt    []int16 // 自适应步长
+	pos      int
+	avg      int32
+	legacy   bool // 3980之前的版本使用固定步长的自适应规则
+}
+
+// newAPEFilter 创建NN滤波器，version 为APE文件版本
+func newAPEFilter(order, fracBits, version int) *apeFilter {
+	return &apeFilter{
+		order:    order,
+		fracBits: uint(fracBits),
+		legacy:   version < 3980,
+		coeffs:   make([]int16, order),
+		delay:    make([]int16, apeHistorySize+order),
+		adapt:    make([]int16, apeHistorySize+order),
+	}
+}
+
+// reset 清空滤波器状态
+func (f *apeFilter) reset() {
+	clear(f.coeffs)
+	clear(f.delay)
+	clear(f.adapt)
+	f.pos = f.order
+	f.avg = 0
+}
+
+// apply 对数据进行原地滤波还原
+func (f *apeFilter) apply(data []int32) {
+	for i, in := range data {
+		delay := f.delay[f.pos-f.order : f.pos]
+		adapt := f.adapt[f.pos-f.order : f.pos]
+		mul := int16(apeSign(in))
+
+		// 定点数内积，同时更新系数
+		var sum int32
+		for k := range f.coeffs {
+			sum += int32(f.coeffs[k]) * int32(delay[k])
+			f.coeffs[k] += mul * adapt[k]
+		}
+
+		res := int32((int64(sum) + 1<<(f.fracBits-1)) >> f.fracBits)
+		res += in
+		data[i] = res
+
+		f.delay[f.pos] = clampInt16(res)
+
+		if f.legacy {
+			// 旧版本：步长固定为±4，只衰减第4和第8个历史步长
+			f.adapt[f.pos] = 0
+			if res != 0 {
+				f.adapt[f.pos] = int16(((res >> 28) & 8) - 4)
+			}
+			f.adapt[f.pos-4] >>= 1
+			f.adapt[f.pos-8] >>= 1
+		} else {
+			f.updateAdapt(res)
+		}
+
+		f.pos++
+		if f.pos == len(f.delay) {
+			copy(f.delay[:f.order], f.delay[f.pos-f.order:])
+			copy(f.adapt[:f.order], f.adapt[f.pos-f.order:])
+			f.pos = f.order
+		}
+	}
+}
+
+// updateAdapt 按3980及以后版本的规则更新自适应步长，步长随输出幅度相对滑动平均值的大小变化
+func (f *apeFilter) updateAdapt(res int32) {
+	absres := uint32(res)
+	if res < 0 {
+		absres = uint32(-res)
+	}
+	if absres != 0 {
+		shift := 0
+		if int64(absres) > int64(f.avg)*3 {
+			shift++
+		}
+		if absres > uint32(f.avg+f.avg/3) {
+			shift++
+		}
+		f.adapt[f.pos] = int16(apeSign(res) * int32(8<<shift))
+	} else {
+		f.adapt[f.pos] = 0
+	}
+	f.avg += int32(absres-uint32(f.avg)) / 16
+
+	f.adapt[f.pos-1] >>= 1
+	f.adapt[f.pos-2] >>= 1
+	f.adapt[f.pos-8] >>= 1
+}
+
+// clampInt16 将整数截断到16位范围
+func clampInt16(v int32) int16 {
+	if v > 32767 {
+		return 32767
+	}
+	if v < -32768 {
+		return -32768
+	}
+	return int16(v)
+}
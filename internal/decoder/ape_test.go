@@ -0,0 +1,39 @@
+package decoder
+
+import "testing"
+
+// 每个版本区间一个金样文件，均为16位立体声、5000帧测试信号
+func TestAPEGolden(t *testing.T) {
+	tests := []struct {
+		name    string
+		silence int // 旧格式文件以一个固定长度的静音帧开头
+		title   string
+	}{
+		// 3.97 Extra High：旧文件头（带峰值和寻址表长度字段），旧熵编码，旧滤波器自适应规则
+		{name: "golden_3970.ape", silence: 73728 * 4},
+		// 3.98 High：新文件头，旧熵编码，新滤波器自适应规则，帧不按4字节对齐
+		{name: "golden_3980.ape"},
+		// 3.99 Insane：新熵编码，三级滤波器，带APEv2标签
+		{name: "golden_3990.ape", title: "Golden"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			audioFile, samples := decodeTestFile(t, tt.name)
+			if audioFile.GetFormat() != "APE" || audioFile.GetSampleRate() != 44100 ||
+				audioFile.GetBitDepth() != 16 || audioFile.GetChannels() != 2 {
+				t.Fatalf("格式为 %s %d Hz %d位 %d声道", audioFile.GetFormat(), audioFile.GetSampleRate(),
+					audioFile.GetBitDepth(), audioFile.GetChannels())
+			}
+			if metadata := audioFile.GetMetadata(); metadata.Title != tt.title {
+				t.Errorf("标题为 %q，应为 %q", metadata.Title, tt.title)
+			}
+
+			want := testSignal(2, 5000, 16)
+			for ch := range want {
+				want[ch] = append(make([]int32, tt.silence), want[ch]...)
+			}
+			checkSamples(t, samples, want)
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package decoder
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"strings"
+
+	"audio-loss-checker/internal/types"
+)
+
+const (
+	apeTagFooterSize = 32
+	id3v1TagSize     = 128
+)
+
+// readAPETag 从文件末尾读取APEv2标签（可能位于ID3v1标签之前）
+// 返回标签内容和标签（含页脚）在文件中的起始位置，未找到标签时返回nil
+func readAPETag(r io.ReaderAt, fileSize int64) (map[string]string, int64) {
+	end := fileSize
+
+	// 跳过文件末尾的ID3v1标签
+	if end >= id3v1TagSize {
+		marker := make([]byte, 3)
+		if _, err := r.ReadAt(marker, end-id3v1TagSize); err == nil && string(marker) == "TAG" {
+			end -= id3v1TagSize
+		}
+	}
+
+	if end < apeTagFooterSize {
+		return nil, fileSize
+	}
+
+	footer := make([]byte, apeTagFooterSize)
+	if _, err := r.ReadAt(footer, end-apeTagFooterSize); err != nil {
+		return nil, fileSize
+	}
+	if string(footer[0:8]) != "APETAGEX" {
+		return nil, fileSize
+	}
+
+	// size 包含所有条目和页脚，不包含可选的头部
+	size := int64(binary.LittleEndian.Uint32(footer[12:16]))
+	count := int(binary.LittleEndian.Uint32(footer[16:20]))
+	flags := binary.LittleEndian.Uint32(footer[20:24])
+	if size < apeTagFooterSize || size > end {
+		return nil, fileSize
+	}
+
+	start := end - size
+	data := make([]byte, size-apeTagFooterSize)
+	if _, err := r.ReadAt(data, start); err != nil {
+		return nil, fileSize
+	}
+
+	tagStart := start
+	if flags&(1<<31) != 0 {
+		// 存在32字节的标签头部
+		tagStart -= apeTagFooterSize
+	}
+
+	tags := make(map[string]string)
+	for i := 0; i < count && len(data) >= 8; i++ {
+		valueSize := int(binary.LittleEndian.Uint32(data[0:4]))
+		itemFlags := binary.LittleEndian.Uint32(data[4:8])
+		data = data[8:]
+
+		keyEnd := bytes.IndexByte(data, 0)
+		if keyEnd < 0 || keyEnd+1+valueSize > len(data) {
+			break
+		}
+		key := strings.ToUpper(string(data[:keyEnd]))
+		value := data[keyEnd+1 : keyEnd+1+valueSize]
+		data = data[keyEnd+1+valueSize:]
+
+		// 只保留UTF-8文本条目，跳过二进制数据（如封面）
+		if (itemFlags>>1)&0x3 == 0 {
+			tags[key] = strings.TrimRight(string(value), "\x00")
+		}
+	}
+
+	return tags, tagStart
+}
+
+// apeTagMetadata 将APEv2标签转换为音频元数据
+func apeTagMetadata(tags map[string]string) types.AudioMetadata {
+	metadata := types.AudioMetadata{
+		Title:  tags["TITLE"],
+		Artist: tags["ARTIST"],
+		Album:  tags["ALBUM"],
+		Year:   tags["YEAR"],
+		Genre:  tags["GENRE"],
+	}
+	metadata.TrackNumber, metadata.TrackTotal = parseTrackNumber(tags["TRACK"])
+	return metadata
+}
@@ -2,15 +2,17 @@ package decoder
 
 import (
 	"fmt"
+	"io"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"audio-loss-checker/internal/types"
 )
 
-// AudioDecoder 音频解码器接口
+// AudioDecoder 音频解码器接口，Decode 接管 src，解码失败时将其关闭
 type AudioDecoder interface {
-	Decode(filePath string) (types.AudioFile, error)
+	Decode(src *Source) (types.AudioFile, error)
 	SupportedFormats() []string
 }
 
@@ -28,12 +30,11 @@ func NewDecoderRegistry() *DecoderRegistry {
 	// 注册支持的解码器
 	registry.Register(&WAVDecoder{})
 	registry.Register(&FLACDecoder{})
-
-	// TODO: 添加 ALAC 和 APE 解码器
-	// ALAC: 可考虑使用 FFmpeg 绑定或 github.com/go-audio/m4a
-	// APE: 可考虑使用 FFmpeg 绑定或寻找专用的 APE 解码库
-	// registry.Register(&ALACDecoder{})
-	// registry.Register(&APEDecoder{})
+	registry.Register(&ALACDecoder{})
+	registry.Register(&APEDecoder{})
+	registry.Register(&WavPackDecoder{})
+	registry.Register(&AIFFDecoder{})
+	registry.Register(&DSDDecoder{})
 
 	return registry
 }
@@ -63,12 +64,54 @@ func (r *DecoderRegistry) GetDecoder(filePath string) (AudioDecoder, error) {
 	return decoder, nil
 }
 
-// DecodeFile 解码音频文件
+// DecodeFile 解码音频文件，按文件内容而不是扩展名选择解码器
 func (r *DecoderRegistry) DecodeFile(filePath string) (types.AudioFile, error) {
-	decoder, err := r.GetDecoder(filePath)
+	src, err := OpenSource(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return r.decode(src)
+}
+
+// DecodeReader 解码调用方提供的音频数据，从头开始读取；name 为文件名，内容无法识别时按其扩展名选择解码器
+// 返回的音频文件关闭时不关闭 rs，使用期间调用方不能读取或定位 rs
+func (r *DecoderRegistry) DecodeReader(rs io.ReadSeeker, name string) (types.AudioFile, error) {
+	src, err := NewSource(rs, name)
 	if err != nil {
 		return nil, err
 	}
+	return r.decode(src)
+}
+
+// decode 识别格式并交给对应的解码器，出错时关闭 src
+func (r *DecoderRegistry) decode(src *Source) (types.AudioFile, error) {
+	det, err := r.DetectSource(src)
+	if err != nil {
+		src.Close()
+		return nil, err
+	}
+	return r.DecodeSource(src, det)
+}
+
+// DecodeSource 按 DetectSource 的识别结果解码 src，不再重新识别格式；src 由返回的音频文件接管，出错时将其关闭
+func (r *DecoderRegistry) DecodeSource(src *Source, det *Detection) (types.AudioFile, error) {
+	decoder, err := r.decoderForDetection(src.Name(), det)
+	if err != nil {
+		src.Close()
+		return nil, err
+	}
+
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		src.Close()
+		return nil, fmt.Errorf("定位数据失败: %w", err)
+	}
+	return decoder.Decode(src)
+}
 
-	return decoder.Decode(filePath)
+// parseTrackNumber 解析 "3"、"03/12" 之类的音轨号标签，无法解析的部分为0
+func parseTrackNumber(s string) (number, total int) {
+	n, t, _ := strings.Cut(strings.TrimSpace(s), "/")
+	number, _ = strconv.Atoi(strings.TrimSpace(n))
+	total, _ = strconv.Atoi(strings.TrimSpace(t))
+	return number, total
 }
@@ -0,0 +1,101 @@
+package decoder
+
+import (
+	"bytes"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"audio-loss-checker/internal/types"
+)
+
+// testSignal 生成 testdata 中金样文件编码前的PCM：三角波加伪随机噪声，
+// 三分之一处有300帧数字静音，最后200帧放大一倍并削波到满幅
+// 只使用整数运算，在任何平台上结果都相同
+func testSignal(channels, frames, bits int) [][]int32 {
+	out := make([][]int32, channels)
+	seed := uint32(12345)
+	amp := int32(1) << uint(bits-2)
+	maxV := int32(1)<<uint(bits-1) - 1
+	minV := -maxV - 1
+	for ch := range out {
+		out[ch] = make([]int32, frames)
+		period := int32(97 + 31*ch)
+		for i := 0; i < frames; i++ {
+			seed = seed*1664525 + 1013904223
+			noise := (int32(seed>>24) - 128) << uint(bits-16)
+			phase := int32(i) % period
+			d := 2*phase - period
+			if d < 0 {
+				d = -d
+			}
+			v := int64(amp)*int64(2*d-period)/int64(period) + int64(noise)
+			switch {
+			case i >= frames/3 && i < frames/3+300:
+				v = 0
+			case i >= frames-200:
+				v *= 2
+			}
+			out[ch][i] = int32(max(int64(minV), min(int64(maxV), v)))
+		}
+	}
+	return out
+}
+
+// decodeTestFile 解码 testdata 中的文件，返回音频文件和还原为整数的采样
+func decodeTestFile(t *testing.T, name string) (types.AudioFile, [][]int32) {
+	t.Helper()
+	audioFile, err := NewDecoderRegistry().DecodeFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("解码 %s 失败: %v", name, err)
+	}
+	return audioFile, intSamples(t, name, audioFile)
+}
+
+// decodeTestData 解码测试中生成的数据，name 用于选择扩展名
+func decodeTestData(t *testing.T, name string, data []byte) (types.AudioFile, [][]int32) {
+	t.Helper()
+	audioFile, err := NewDecoderRegistry().DecodeReader(bytes.NewReader(data), name)
+	if err != nil {
+		t.Fatalf("解码 %s 失败: %v", name, err)
+	}
+	return audioFile, intSamples(t, name, audioFile)
+}
+
+// intSamples 读取全部采样并按声明的位深度还原为整数，测试结束时关闭音频文件
+func intSamples(t *testing.T, name string, audioFile types.AudioFile) [][]int32 {
+	t.Helper()
+	t.Cleanup(func() { audioFile.Close() })
+
+	samples, err := audioFile.GetSamples()
+	if err != nil {
+		t.Fatalf("读取 %s 的采样失败: %v", name, err)
+	}
+	scale := math.Ldexp(1, audioFile.GetBitDepth()-1)
+	ints := make([][]int32, len(samples))
+	for ch := range samples {
+		ints[ch] = make([]int32, len(samples[ch]))
+		for i, v := range samples[ch] {
+			ints[ch][i] = int32(math.Round(v * scale))
+		}
+	}
+	return ints
+}
+
+// checkSamples 逐个比较解码结果与编码前的PCM
+func checkSamples(t *testing.T, got, want [][]int32) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("声道数 %d，应为 %d", len(got), len(want))
+	}
+	for ch := range want {
+		if len(got[ch]) != len(want[ch]) {
+			t.Fatalf("声道%d 有 %d 帧，应为 %d", ch+1, len(got[ch]), len(want[ch]))
+		}
+		for i := range want[ch] {
+			if got[ch][i] != want[ch][i] {
+				t.Fatalf("声道%d 第 %d 帧为 %d，应为 %d", ch+1, i, got[ch][i], want[ch][i])
+			}
+		}
+	}
+}
@@ -0,0 +1,435 @@
+package decoder
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"audio-loss-checker/internal/types"
+)
+
+// DSDDecoder DSD格式解码器，支持DSF和DSDIFF(DFF)容器
+type DSDDecoder struct{}
+
+// DSDFile DSD文件实现，采样数据为抽取后的PCM
+type DSDFile struct {
+	file        *Source
+	container   string // "DSF" 或 "DFF"
+	dsdRate     int
+	pcmRate     int
+	channels    int
+	sampleCount int64 // 每声道的1位采样数
+	dataOffset  int64
+	dataSize    int64
+	blockSize   int  // DSF每声道数据块大小，DFF为0（按字节交错）
+	lsbFirst    bool // DSF的1位存储为低位在前
+	duration    time.Duration
+	samples     [][]float64
+	metadata    types.AudioMetadata
+}
+
+// SupportedFormats 返回支持的格式
+func (d *DSDDecoder) SupportedFormats() []string {
+	return []string{"dsf", "dff"}
+}
+
+// Decode 解码DSD文件
+func (d *DSDDecoder) Decode(file *Source) (types.AudioFile, error) {
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(file, magic); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("读取DSD文件头失败: %w", err)
+	}
+
+	dsdFile := &DSDFile{file: file}
+	var err error
+	switch string(magic) {
+	case "DSD ":
+		err = dsdFile.parseDSF()
+	case "FRM8":
+		err = dsdFile.parseDFF()
+	default:
+		err = fmt.Errorf("无效的DSD文件标识")
+	}
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("解析DSD文件失败: %w", err)
+	}
+
+	if dsdFile.channels <= 0 {
+		file.Close()
+		return nil, fmt.Errorf("无效的声道数: %d", dsdFile.channels)
+	}
+	if dsdFile.pcmRate, err = dsdPCMRate(dsdFile.dsdRate); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	dsdFile.duration = time.Duration(float64(dsdFile.sampleCount) / float64(dsdFile.dsdRate) * float64(time.Second))
+	dsdFile.metadata.Duration = dsdFile.duration.String()
+
+	return dsdFile, nil
+}
+
+// parseDSF 解析DSF文件（小端序）
+func (f *DSDFile) parseDSF() error {
+	f.container = "DSF"
+
+	header := make([]byte, 28)
+	if _, err := f.file.ReadAt(header, 0); err != nil {
+		return err
+	}
+	dsdChunkSize := int64(binary.LittleEndian.Uint64(header[4:12]))
+	metadataOffset := int64(binary.LittleEndian.Uint64(header[20:28]))
+
+	// fmt 块
+	fmtHeader := make([]byte, 52)
+	if _, err := f.file.ReadAt(fmtHeader, dsdChunkSize); err != nil {
+		return err
+	}
+	if string(fmtHeader[0:4]) != "fmt " {
+		return fmt.Errorf("缺少fmt块")
+	}
+	fmtSize := int64(binary.LittleEndian.Uint64(fmtHeader[4:12]))
+	if formatID := binary.LittleEndian.Uint32(fmtHeader[16:20]); formatID != 0 {
+		return fmt.Errorf("不支持的DSF格式ID: %d", formatID)
+	}
+	f.channels = int(binary.LittleEndian.Uint32(fmtHeader[24:28]))
+	f.dsdRate = int(binary.LittleEndian.Uint32(fmtHeader[28:32]))
+	bitsPerSample := binary.LittleEndian.Uint32(fmtHeader[32:36])
+	f.sampleCount = int64(binary.LittleEndian.Uint64(fmtHeader[36:44]))
+	f.blockSize = int(binary.LittleEndian.Uint32(fmtHeader[44:48]))
+
+	switch bitsPerSample {
+	case 1:
+		f.lsbFirst = true
+	case 8:
+		f.lsbFirst = false
+	default:
+		return fmt.Errorf("不支持的DSF位宽: %d", bitsPerSample)
+	}
+	if f.blockSize <= 0 {
+		return fmt.Errorf("无效的DSF块大小: %d", f.blockSize)
+	}
+
+	// data 块
+	dataHeader := make([]byte, 12)
+	dataChunk := dsdChunkSize + fmtSize
+	if _, err := f.file.ReadAt(dataHeader, dataChunk); err != nil {
+		return err
+	}
+	if string(dataHeader[0:4]) != "data" {
+		return fmt.Errorf("缺少data块")
+	}
+	f.dataOffset = dataChunk + 12
+	f.dataSize = int64(binary.LittleEndian.Uint64(dataHeader[4:12])) - 12
+
+	// 元数据为文件末尾的ID3v2标签
+	if metadataOffset > 0 {
+		if tag, err := f.readTag(metadataOffset); err == nil {
+			if meta, ok := parseID3v2(tag); ok {
+				f.metadata = meta
+			}
+		}
+	}
+
+	return nil
+}
+
+// readTag 读取指定位置的完整ID3v2标签
+func (f *DSDFile) readTag(offset int64) ([]byte, error) {
+	header := make([]byte, 10)
+	if _, err := f.file.ReadAt(header, offset); err != nil {
+		return nil, err
+	}
+	size := id3v2TagSize(header)
+	if size == 0 {
+		return nil, fmt.Errorf("无效的ID3标签")
+	}
+	tag := make([]byte, size)
+	if _, err := f.file.ReadAt(tag, offset); err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// parseDFF 解析DSDIFF文件（大端序，块大小为64位）
+func (f *DSDFile) parseDFF() error {
+	f.container = "DFF"
+
+	header := make([]byte, 12)
+	if _, err := f.file.ReadAt(header, 4); err != nil {
+		return err
+	}
+	if string(header[8:12]) != "DSD " {
+		return fmt.Errorf("不支持的DSDIFF表单类型: %q", header[8:12])
+	}
+	formEnd := 12 + int64(binary.BigEndian.Uint64(header[0:8]))
+
+	gotSound := false
+	err := f.walkDFFChunks(16, formEnd, func(id string, offset, size int64) error {
+		switch id {
+		case "PROP":
+			return f.parseDFFProp(offset, size)
+		case "DSD ":
+			f.dataOffset = offset
+			f.dataSize = size
+			gotSound = true
+		case "DST ":
+			return fmt.Errorf("暂不支持DST压缩的DFF文件")
+		case "DIIN":
+			return f.parseDFFInfo(offset, size)
+		case "ID3 ", "id3 ":
+			data := make([]byte, size)
+			if _, err := f.file.ReadAt(data, offset); err != nil {
+				return err
+			}
+			if meta, ok := parseID3v2(data); ok {
+				mergeMetadata(&f.metadata, meta)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if !gotSound {
+		return fmt.Errorf("缺少DSD音频数据块")
+	}
+	if f.channels > 0 {
+		f.sampleCount = f.dataSize * 8 / int64(f.channels)
+	}
+	return nil
+}
+
+// walkDFFChunks 遍历 [start, end) 范围内的DSDIFF块
+func (f *DSDFile) walkDFFChunks(start, end int64, fn func(id string, offset, size int64) error) error {
+	header := make([]byte, 12)
+	for offset := start; offset+12 <= end; {
+		if _, err := f.file.ReadAt(header, offset); err != nil {
+			return err
+		}
+		id := string(header[0:4])
+		size := int64(binary.BigEndian.Uint64(header[4:12]))
+		if size < 0 || offset+12+size > end {
+			return fmt.Errorf("DSDIFF块 %q 大小无效", id)
+		}
+		if err := fn(id, offset+12, size); err != nil {
+			return err
+		}
+		// 块大小为奇数时有一个填充字节
+		offset += 12 + size + size&1
+	}
+	return nil
+}
+
+// parseDFFProp 解析PROP块中的采样率、声道和压缩类型
+func (f *DSDFile) parseDFFProp(offset, size int64) error {
+	propType := make([]byte, 4)
+	if _, err := f.file.ReadAt(propType, offset); err != nil {
+		return err
+	}
+	if string(propType) != "SND " {
+		return nil
+	}
+
+	return f.walkDFFChunks(offset+4, offset+size, func(id string, offset, size int64) error {
+		data := make([]byte, size)
+		if _, err := f.file.ReadAt(data, offset); err != nil {
+			return err
+		}
+		switch id {
+		case "FS  ":
+			if len(data) >= 4 {
+				f.dsdRate = int(binary.BigEndian.Uint32(data))
+			}
+		case "CHNL":
+			if len(data) >= 2 {
+				f.channels = int(binary.BigEndian.Uint16(data))
+			}
+		case "CMPR":
+			if len(data) >= 4 && string(data[0:4]) != "DSD " {
+				return fmt.Errorf("暂不支持压缩类型为 %q 的DFF文件", data[0:4])
+			}
+		}
+		return nil
+	})
+}
+
+// parseDFFInfo 解析DIIN块中的标题(DITI)和艺术家(DIAR)
+func (f *DSDFile) parseDFFInfo(offset, size int64) error {
+	return f.walkDFFChunks(offset, offset+size, func(id string, offset, size int64) error {
+		if id != "DITI" && id != "DIAR" {
+			return nil
+		}
+		data := make([]byte, size)
+		if _, err := f.file.ReadAt(data, offset); err != nil {
+			return err
+		}
+		if len(data) < 4 {
+			return nil
+		}
+		count := int(binary.BigEndian.Uint32(data[0:4]))
+		if count > len(data)-4 {
+			count = len(data) - 4
+		}
+		text := strings.TrimSpace(string(data[4 : 4+count]))
+		if id == "DITI" && f.metadata.Title == "" {
+			f.metadata.Title = text
+		} else if id == "DIAR" && f.metadata.Artist == "" {
+			f.metadata.Artist = text
+		}
+		return nil
+	})
+}
+
+// GetFormat 获取格式名称
+func (f *DSDFile) GetFormat() string {
+	return "DSD (" + f.container + ")"
+}
+
+// GetSampleRate 获取采样率（抽取后的PCM采样率）
+func (f *DSDFile) GetSampleRate() int {
+	return f.pcmRate
+}
+
+// DSDSampleRate 获取原始DSD采样率
+func (f *DSDFile) DSDSampleRate() int {
+	return f.dsdRate
+}
+
+// GetBitDepth 获取位深度
+func (f *DSDFile) GetBitDepth() int {
+	return 1
+}
+
+// GetChannels 获取声道数
+func (f *DSDFile) GetChannels() int {
+	return f.channels
+}
+
+// GetDuration 获取时长
+func (f *DSDFile) GetDuration() time.Duration {
+	return f.duration
+}
+
+// GetSamples 获取音频采样数据（DSD经抽取滤波转换为PCM）
+func (f *DSDFile) GetSamples() ([][]float64, error) {
+	if f.samples != nil {
+		return f.samples, nil
+	}
+
+	reader, err := f.NewSampleReader()
+	if err != nil {
+		return nil, err
+	}
+	samples, err := readAllSamples(reader, f.channels)
+	if err != nil {
+		return nil, err
+	}
+
+	f.samples = samples
+	return samples, nil
+}
+
+// NewSampleReader 创建采样流，每次读取一段DSD数据并抽取为PCM
+func (f *DSDFile) NewSampleReader() (types.SampleReader, error) {
+	bank := newDSDFilterBank(f.dsdRate, f.pcmRate)
+	decimators := make([]*dsdDecimator, f.channels)
+	outputs := make([][]float64, f.channels)
+	for ch := range decimators {
+		decimators[ch] = bank.newDecimator()
+	}
+
+	if f.blockSize > 0 {
+		return newFrameReader(f.dsfBlocks(decimators, outputs)), nil
+	}
+	return newFrameReader(f.dffChunks(decimators, outputs)), nil
+}
+
+// dsfBlocks DSF：每个声道依次存放 blockSize 字节，每次处理一组
+func (f *DSDFile) dsfBlocks(decimators []*dsdDecimator, outputs [][]float64) func() ([][]float64, error) {
+	// 每声道有效字节数（DSF最后一个数据块会补零）
+	bytesPerChannel := (f.sampleCount + 7) / 8
+	group := make([]byte, f.blockSize*f.channels)
+	offset := f.dataOffset
+	var consumed int64
+
+	return func() ([][]float64, error) {
+		if offset >= f.dataOffset+f.dataSize || consumed >= bytesPerChannel {
+			return nil, io.EOF
+		}
+		n, err := f.file.ReadAt(group, offset)
+		if n < len(group) && err != nil && err != io.EOF {
+			return nil, fmt.Errorf("读取DSD数据失败: %w", err)
+		}
+		if n < len(group) {
+			return nil, io.EOF
+		}
+
+		valid := int64(f.blockSize)
+		if consumed+valid > bytesPerChannel {
+			valid = bytesPerChannel - consumed
+		}
+		for ch := 0; ch < f.channels; ch++ {
+			block := group[ch*f.blockSize : ch*f.blockSize+int(valid)]
+			outputs[ch] = decimators[ch].process(block, f.lsbFirst, outputs[ch][:0])
+		}
+		consumed += valid
+		offset += int64(len(group))
+		return outputs, nil
+	}
+}
+
+// dffChunks DFF：按字节交错，每次处理一段
+func (f *DSDFile) dffChunks(decimators []*dsdDecimator, outputs [][]float64) func() ([][]float64, error) {
+	const chunkFrames = 65536
+	buf := make([]byte, chunkFrames*f.channels)
+	perChannel := make([][]byte, f.channels)
+	for ch := range perChannel {
+		perChannel[ch] = make([]byte, chunkFrames)
+	}
+	offset := f.dataOffset
+
+	return func() ([][]float64, error) {
+		if offset >= f.dataOffset+f.dataSize {
+			return nil, io.EOF
+		}
+		size := int64(len(buf))
+		if remain := f.dataOffset + f.dataSize - offset; remain < size {
+			size = remain
+		}
+		n, err := f.file.ReadAt(buf[:size], offset)
+		if int64(n) < size && err != nil && err != io.EOF {
+			return nil, fmt.Errorf("读取DSD数据失败: %w", err)
+		}
+		frames := n / f.channels
+		if frames == 0 {
+			return nil, io.EOF
+		}
+		for ch := 0; ch < f.channels; ch++ {
+			for i := 0; i < frames; i++ {
+				perChannel[ch][i] = buf[i*f.channels+ch]
+			}
+			outputs[ch] = decimators[ch].process(perChannel[ch][:frames], false, outputs[ch][:0])
+		}
+		offset += int64(frames * f.channels)
+		return outputs, nil
+	}
+}
+
+// GetMetadata 获取元数据
+func (f *DSDFile) GetMetadata() types.AudioMetadata {
+	return f.metadata
+}
+
+// Close 关闭文件
+func (f *DSDFile) Close() error {
+	if f.file != nil {
+		return f.file.Close()
+	}
+	return nil
+}
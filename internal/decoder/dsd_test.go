@@ -0,0 +1,232 @@
+package decoder
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"math/bits"
+	"strings"
+	"testing"
+)
+
+const testDSDRate = 2822400 // DSD64
+
+// sigmaDelta 用二阶sigma-delta调制生成正弦波的1位数据，每字节高位在前（DFF的存储方式）
+func sigmaDelta(freq, amplitude float64, samples int) []byte {
+	out := make([]byte, samples/8)
+	var i1, i2, y float64
+	for n := 0; n < samples; n++ {
+		x := amplitude * math.Sin(2*math.Pi*freq*float64(n)/testDSDRate)
+		i1 += x - y
+		i2 += i1 - y
+		if i2 >= 0 {
+			y = 1
+			out[n/8] |= 0x80 >> uint(n%8)
+		} else {
+			y = -1
+		}
+	}
+	return out
+}
+
+// buildDSF 生成DSF文件：每声道依次存放 blockSize 字节，最后一组补零；1位数据低位在前，末尾为ID3v2标签
+func buildDSF(channels [][]byte, blockSize int, id3 []byte) []byte {
+	bytesPerChannel := len(channels[0])
+	groups := (bytesPerChannel + blockSize - 1) / blockSize
+	var data []byte
+	for g := 0; g < groups; g++ {
+		for _, ch := range channels {
+			block := make([]byte, blockSize)
+			for i := range block {
+				if pos := g*blockSize + i; pos < bytesPerChannel {
+					block[i] = bits.Reverse8(ch[pos])
+				}
+			}
+			data = append(data, block...)
+		}
+	}
+
+	le := binary.LittleEndian
+	dataChunk := 28 + 52
+	total := dataChunk + 12 + len(data) + len(id3)
+	metadataOffset := 0
+	if len(id3) > 0 {
+		metadataOffset = dataChunk + 12 + len(data)
+	}
+
+	out := []byte("DSD ")
+	out = le.AppendUint64(out, 28)
+	out = le.AppendUint64(out, uint64(total))
+	out = le.AppendUint64(out, uint64(metadataOffset))
+
+	out = append(out, "fmt "...)
+	out = le.AppendUint64(out, 52)
+	out = le.AppendUint32(out, 1) // 格式版本
+	out = le.AppendUint32(out, 0) // DSD原始数据
+	out = le.AppendUint32(out, 2) // 声道类型：立体声
+	out = le.AppendUint32(out, uint32(len(channels)))
+	out = le.AppendUint32(out, testDSDRate)
+	out = le.AppendUint32(out, 1) // 每采样1位，低位在前
+	out = le.AppendUint64(out, uint64(bytesPerChannel*8))
+	out = le.AppendUint32(out, uint32(blockSize))
+	out = le.AppendUint32(out, 0)
+
+	out = append(out, "data"...)
+	out = le.AppendUint64(out, uint64(12+len(data)))
+	out = append(out, data...)
+	return append(out, id3...)
+}
+
+// dffChunk 生成一个DSDIFF块（大端序，64位大小），大小为奇数时补一个填充字节
+func dffChunk(id string, data ...[]byte) []byte {
+	body := bytes.Join(data, nil)
+	chunk := append([]byte(id), binary.BigEndian.AppendUint64(nil, uint64(len(body)))...)
+	chunk = append(chunk, body...)
+	if len(body)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+	return chunk
+}
+
+// buildDFF 生成DFF文件：声道按字节交错，DIIN中带标题
+func buildDFF(channels [][]byte, compression, title string) []byte {
+	be := binary.BigEndian
+	var data []byte
+	for i := range channels[0] {
+		for _, ch := range channels {
+			data = append(data, ch[i])
+		}
+	}
+
+	chnl := be.AppendUint16(nil, uint16(len(channels)))
+	chnl = append(chnl, "SLFTSRGT"[:4*len(channels)]...)
+	cmpr := append([]byte(compression), 0)
+	diti := append(be.AppendUint32(nil, uint32(len(title))), title...)
+
+	soundID := "DSD "
+	if compression == "DST " {
+		soundID = "DST "
+	}
+	form := bytes.Join([][]byte{
+		[]byte("DSD "),
+		dffChunk("FVER", be.AppendUint32(nil, 0x01050000)),
+		dffChunk("PROP", []byte("SND "),
+			dffChunk("FS  ", be.AppendUint32(nil, testDSDRate)),
+			dffChunk("CHNL", chnl),
+			dffChunk("CMPR", cmpr)),
+		dffChunk("DIIN", dffChunk("DITI", diti)),
+		dffChunk(soundID, data),
+	}, nil)
+	return dffChunk("FRM8", form)
+}
+
+// toneLevel 返回去掉开头滤波器暂态后的信号频率（按过零点计算）和有效值
+func toneLevel(samples []float64, sampleRate int) (freq, rms float64) {
+	samples = samples[500 : len(samples)-500]
+	crossings := 0
+	sum := 0.0
+	for i, v := range samples {
+		sum += v * v
+		if i > 0 && (samples[i-1] < 0) != (v < 0) {
+			crossings++
+		}
+	}
+	seconds := float64(len(samples)) / float64(sampleRate)
+	return float64(crossings) / 2 / seconds, math.Sqrt(sum / float64(len(samples)))
+}
+
+// 0.1秒的DSD64立体声：左声道1kHz/-6dB，右声道3kHz/-12dB
+// DSF和DFF中存放相同的1位数据，抽取后的PCM必须一致，频率和电平与调制前的正弦波相符
+func TestDSD(t *testing.T) {
+	const bitsPerChannel = testDSDRate / 10
+	left := sigmaDelta(1000, 0.5, bitsPerChannel)
+	right := sigmaDelta(3000, 0.25, bitsPerChannel)
+	channels := [][]byte{left, right}
+
+	title := append([]byte{0}, "DSF Title"...)
+	frame := append([]byte("TIT2"), binary.BigEndian.AppendUint32(nil, uint32(len(title)))...)
+	frame = append(append(frame, 0, 0), title...)
+	id3 := append([]byte{'I', 'D', '3', 3, 0, 0, 0, 0, 0, byte(len(frame))}, frame...)
+
+	// 块大小不整除每声道字节数，最后一组只有一部分有效
+	dsf, _ := decodeDSDTest(t, "test.dsf", buildDSF(channels, 4096, id3))
+	dff, dffFile := decodeDSDTest(t, "test.dff", buildDFF(channels, "DSD ", "DFF Title"))
+
+	if dsf[0] == nil || len(dsf[0]) != len(dff[0]) {
+		t.Fatalf("DSF解码出 %d 帧，DFF解码出 %d 帧", len(dsf[0]), len(dff[0]))
+	}
+	if want := bitsPerChannel / 32; len(dsf[0]) != want {
+		t.Errorf("解码出 %d 帧，应为 %d", len(dsf[0]), want)
+	}
+	for ch := range dsf {
+		for i := range dsf[ch] {
+			if dsf[ch][i] != dff[ch][i] {
+				t.Fatalf("声道%d 第 %d 帧DSF为 %g，DFF为 %g", ch+1, i, dsf[ch][i], dff[ch][i])
+			}
+		}
+	}
+	if title := dffFile.GetMetadata().Title; title != "DFF Title" {
+		t.Errorf("DFF标题为 %q", title)
+	}
+
+	tests := []struct {
+		freq, amplitude float64
+	}{{1000, 0.5}, {3000, 0.25}}
+	for ch, tt := range tests {
+		freq, rms := toneLevel(dsf[ch], 88200)
+		if math.Abs(freq-tt.freq) > tt.freq*0.01 {
+			t.Errorf("声道%d 频率为 %.1f Hz，应为 %.0f Hz", ch+1, freq, tt.freq)
+		}
+		if db := 20 * math.Log10(rms/(tt.amplitude/math.Sqrt2)); math.Abs(db) > 0.5 {
+			t.Errorf("声道%d 电平与调制前相差 %.2f dB", ch+1, db)
+		}
+	}
+}
+
+// decodeDSDTest 解码DSD数据，检查格式信息，返回抽取后的PCM
+func decodeDSDTest(t *testing.T, name string, data []byte) ([][]float64, *DSDFile) {
+	t.Helper()
+	audioFile, err := NewDecoderRegistry().DecodeReader(bytes.NewReader(data), name)
+	if err != nil {
+		t.Fatalf("解码 %s 失败: %v", name, err)
+	}
+	t.Cleanup(func() { audioFile.Close() })
+	dsd := audioFile.(*DSDFile)
+	if dsd.DSDSampleRate() != testDSDRate || dsd.GetSampleRate() != 88200 || dsd.GetChannels() != 2 {
+		t.Fatalf("%s: %s %d Hz（PCM %d Hz）%d声道", name, dsd.GetFormat(), dsd.DSDSampleRate(),
+			dsd.GetSampleRate(), dsd.GetChannels())
+	}
+	if name == "test.dsf" && dsd.GetMetadata().Title != "DSF Title" {
+		t.Errorf("DSF标题为 %q", dsd.GetMetadata().Title)
+	}
+	samples, err := dsd.GetSamples()
+	if err != nil {
+		t.Fatalf("读取 %s 的采样失败: %v", name, err)
+	}
+	return samples, dsd
+}
+
+func TestDSDUnsupported(t *testing.T) {
+	channels := [][]byte{make([]byte, 1024), make([]byte, 1024)}
+	_, err := NewDecoderRegistry().DecodeReader(bytes.NewReader(buildDFF(channels, "DST ", "")), "test.dff")
+	if err == nil || !strings.Contains(err.Error(), "DST") {
+		t.Errorf("DST压缩的错误为 %v", err)
+	}
+
+	tests := []struct {
+		rate int
+		want int
+	}{
+		{2822400, 88200}, {5644800, 88200}, {11289600, 88200},
+		{3072000, 96000}, {6144000, 96000},
+		{1411200, 88200}, // DSD32，第二级只做2倍抽取
+		{705600, 0},      // 第一级8倍抽取后已经是88.2kHz，无法再滤除30kHz以上的噪声
+		{44100, 0},
+	}
+	for _, tt := range tests {
+		got, err := dsdPCMRate(tt.rate)
+		if got != tt.want || (err != nil) != (tt.want == 0) {
+			t.Errorf("%d Hz: PCM采样率 %d（%v），应为 %d", tt.rate, got, err, tt.want)
+		}
+	}
+}
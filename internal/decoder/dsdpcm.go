@@ -0,0 +1,165 @@
+package decoder
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+// DSD转PCM的两级抽取滤波器：
+// 第一级以字节为单位（8倍抽取），用64阶FIR和查找表直接处理1位数据；
+// 第二级用普通FIR把中间采样率降到分析采样率（88.2kHz或96kHz）。
+
+const (
+	dsdStage1Taps   = 64
+	dsdStage1Factor = 8
+	dsdSilence      = 0x69 // DSD静音图样，0和1各占一半
+)
+
+// dsdDecimator 单个声道的DSD抽取器
+type dsdDecimator struct {
+	table1  *[dsdStage1Taps / 8][256]float64
+	history [dsdStage1Taps / 8]byte // 最近8个字节（高位在前）
+	pos1    int
+
+	taps2   []float64
+	buf2    []float64 // 第二级环形缓冲
+	pos2    int
+	factor2 int
+	phase2  int
+}
+
+// dsdFilterBank 同一文件所有声道共享的滤波器系数
+type dsdFilterBank struct {
+	table1  [dsdStage1Taps / 8][256]float64
+	taps2   []float64
+	factor2 int
+}
+
+// dsdPCMRate 根据DSD采样率选择分析用的PCM采样率
+func dsdPCMRate(dsdRate int) (int, error) {
+	pcmRate := 88200
+	if dsdRate%44100 != 0 {
+		pcmRate = 96000
+	}
+	if dsdRate%(pcmRate*dsdStage1Factor) != 0 || dsdRate/pcmRate < 2*dsdStage1Factor {
+		return 0, fmt.Errorf("不支持的DSD采样率: %d Hz", dsdRate)
+	}
+	return pcmRate, nil
+}
+
+// newDSDFilterBank 为指定的DSD采样率设计抽取滤波器
+func newDSDFilterBank(dsdRate, pcmRate int) *dsdFilterBank {
+	bank := &dsdFilterBank{}
+	midRate := float64(dsdRate / dsdStage1Factor)
+
+	// 第一级：截止频率取中间采样率的0.425倍，混叠成分会被第二级滤除
+	h1 := designLowpass(dsdStage1Taps, 0.425*midRate/float64(dsdRate))
+	for k := 0; k < dsdStage1Taps/8; k++ {
+		for b := 0; b < 256; b++ {
+			sum := 0.0
+			for j := 0; j < 8; j++ {
+				// 高位在前：第j位对应第 8k+j 个系数
+				if b&(0x80>>uint(j)) != 0 {
+					sum += h1[8*k+j]
+				} else {
+					sum -= h1[8*k+j]
+				}
+			}
+			bank.table1[k][b] = sum
+		}
+	}
+
+	// 第二级：通带到30kHz，阻带从 pcmRate-30kHz 开始，保证30kHz以下不受混叠影响
+	passEdge := 30000.0
+	stopEdge := float64(pcmRate) - passEdge
+	numTaps := int(math.Ceil(5.5*midRate/(stopEdge-passEdge))) | 1
+	cutoff := (passEdge + stopEdge) / 2 / midRate
+	bank.taps2 = designLowpass(numTaps, cutoff)
+	bank.factor2 = int(midRate) / pcmRate
+
+	return bank
+}
+
+// designLowpass 设计布莱克曼窗加权的sinc低通滤波器，cutoff为归一化频率（相对采样率）
+func designLowpass(numTaps int, cutoff float64) []float64 {
+	taps := make([]float64, numTaps)
+	center := float64(numTaps-1) / 2
+	sum := 0.0
+	for i := range taps {
+		x := float64(i) - center
+		var sinc float64
+		if x == 0 {
+			sinc = 2 * cutoff
+		} else {
+			sinc = math.Sin(2*math.Pi*cutoff*x) / (math.Pi * x)
+		}
+		w := 0.42 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(numTaps-1)) +
+			0.08*math.Cos(4*math.Pi*float64(i)/float64(numTaps-1))
+		taps[i] = sinc * w
+		sum += taps[i]
+	}
+	// 直流增益归一化为1
+	for i := range taps {
+		taps[i] /= sum
+	}
+	return taps
+}
+
+// newDecimator 创建单声道抽取器
+func (bank *dsdFilterBank) newDecimator() *dsdDecimator {
+	d := &dsdDecimator{
+		table1:  &bank.table1,
+		taps2:   bank.taps2,
+		buf2:    make([]float64, len(bank.taps2)),
+		factor2: bank.factor2,
+	}
+	// 以DSD静音图样初始化，避免开头出现直流跳变
+	for i := range d.history {
+		d.history[i] = dsdSilence
+	}
+	return d
+}
+
+// process 处理一段DSD字节，lsbFirst 表示每字节中较早的采样位于低位；返回追加后的PCM
+func (d *dsdDecimator) process(data []byte, lsbFirst bool, out []float64) []float64 {
+	n1 := len(d.history)
+	n2 := len(d.buf2)
+
+	for _, b := range data {
+		if lsbFirst {
+			b = bits.Reverse8(b)
+		}
+		d.history[d.pos1] = b
+		d.pos1 = (d.pos1 + 1) % n1
+
+		// 第一级输出：history 中从 pos1 开始为时间顺序
+		mid := 0.0
+		for k := 0; k < n1; k++ {
+			mid += d.table1[k][d.history[(d.pos1+k)%n1]]
+		}
+
+		d.buf2[d.pos2] = mid
+		d.pos2 = (d.pos2 + 1) % n2
+
+		d.phase2++
+		if d.phase2 < d.factor2 {
+			continue
+		}
+		d.phase2 = 0
+
+		// 第二级输出
+		sum := 0.0
+		idx := d.pos2
+		for _, tap := range d.taps2 {
+			sum += tap * d.buf2[idx]
+			idx++
+			if idx == n2 {
+				idx = 0
+			}
+		}
+		out = append(out, sum)
+	}
+
+	return out
+}
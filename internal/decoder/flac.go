@@ -2,12 +2,13 @@ package decoder
 
 import (
 	"fmt"
-	"os"
+	"io"
 	"time"
 
 	"audio-loss-checker/internal/types"
 
 	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/frame"
 	"github.com/mewkiz/flac/meta"
 )
 
@@ -17,12 +18,14 @@ type FLACDecoder struct{}
 // FLACFile FLAC文件实现
 type FLACFile struct {
 	stream     *flac.Stream
-	file       *os.File
+	file       *Source
 	sampleRate int
 	bitDepth   int
 	channels   int
 	duration   time.Duration
-	samples    []float64
+	nSamples   int64    // STREAMINFO 记录的每声道采样数，0表示未知
+	md5sum     [16]byte // STREAMINFO 记录的PCM数据MD5，全为0表示未记录
+	samples    [][]float64
 	metadata   types.AudioMetadata
 }
 
@@ -32,12 +35,7 @@ func (d *FLACDecoder) SupportedFormats() []string {
 }
 
 // Decode 解码FLAC文件
-func (d *FLACDecoder) Decode(filePath string) (types.AudioFile, error) {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("打开FLAC文件失败: %w", err)
-	}
-
+func (d *FLACDecoder) Decode(file *Source) (types.AudioFile, error) {
 	stream, err := flac.New(file)
 	if err != nil {
 		file.Close()
@@ -47,7 +45,7 @@ func (d *FLACDecoder) Decode(filePath string) (types.AudioFile, error) {
 	info := stream.Info
 	if info == nil {
 		file.Close()
-		return nil, fmt.Errorf("无法读取FLAC信息: %s", filePath)
+		return nil, fmt.Errorf("无法读取FLAC信息: %s", file.Name())
 	}
 
 	// 计算时长
@@ -60,6 +58,8 @@ func (d *FLACDecoder) Decode(filePath string) (types.AudioFile, error) {
 		bitDepth:   int(info.BitsPerSample),
 		channels:   int(info.NChannels),
 		duration:   duration,
+		nSamples:   int64(info.NSamples),
+		md5sum:     info.MD5sum,
 	}
 
 	// 解析元数据
@@ -81,6 +81,15 @@ func (f *FLACFile) parseMetadata() {
 					Genre:    getVorbisTag(comment, "GENRE"),
 					Duration: f.duration.String(),
 				}
+				f.metadata.TrackNumber, f.metadata.TrackTotal = parseTrackNumber(getVorbisTag(comment, "TRACKNUMBER"))
+				if f.metadata.TrackTotal == 0 {
+					// 音轨总数常单独写在 TRACKTOTAL 或 TOTALTRACKS 中
+					total := getVorbisTag(comment, "TRACKTOTAL")
+					if total == "" {
+						total = getVorbisTag(comment, "TOTALTRACKS")
+					}
+					f.metadata.TrackTotal, _ = parseTrackNumber(total)
+				}
 			}
 		}
 	}
@@ -121,33 +130,75 @@ func (f *FLACFile) GetDuration() time.Duration {
 	return f.duration
 }
 
-// GetSamples 获取音频采样数据
-func (f *FLACFile) GetSamples() ([]float64, error) {
+// GetSamples 获取按声道分开的音频采样数据
+func (f *FLACFile) GetSamples() ([][]float64, error) {
 	if f.samples != nil {
 		return f.samples, nil
 	}
 
-	var allSamples []float64
-	maxVal := float64(int(1) << uint(f.bitDepth-1))
+	reader, err := f.NewSampleReader()
+	if err != nil {
+		return nil, err
+	}
+	samples, err := readAllSamples(reader, f.channels)
+	if err != nil {
+		return nil, err
+	}
+
+	f.samples = samples
+	return samples, nil
+}
+
+// flacReader FLAC采样流，同时检查解码完整性
+type flacReader struct {
+	*frameReader
+	*integrityTracker
+}
 
-	// 读取所有音频帧
-	for {
-		frame, err := f.stream.ParseNext()
+// NewSampleReader 创建采样流，逐帧解码
+// 每个采样流在文件上独立解析，互不影响读取位置
+// CRC-16校验失败的帧已完整读出，记录错误后继续解码；帧头损坏时无法确定下一帧的位置，记录错误后停止
+func (f *FLACFile) NewSampleReader() (types.SampleReader, error) {
+	stream, err := flac.New(io.NewSectionReader(f.file, 0, f.file.Size()))
+	if err != nil {
+		return nil, fmt.Errorf("解析FLAC文件失败: %w", err)
+	}
+
+	tracker := newIntegrityTracker(f.nSamples, f.md5sum, f.bitDepth)
+	converter := newIntFrameConverter(f.channels, f.bitDepth)
+	frame := make([][]int32, f.channels)
+	reader := newIntFrameReader(converter, func() ([][]float64, error) {
+		next, err := stream.ParseNext()
+		if err == io.EOF {
+			return nil, io.EOF
+		}
 		if err != nil {
-			break
+			if !completeFrame(next, f.channels) {
+				tracker.stop(err)
+				return nil, io.EOF
+			}
+			tracker.addError(err)
 		}
+		for ch := range frame {
+			frame[ch] = next.Subframes[ch].Samples
+		}
+		tracker.addFrame(frame)
+		return converter.convert(frame), nil
+	})
+	return &flacReader{frameReader: reader, integrityTracker: tracker}, nil
+}
 
-		// 将所有声道的数据合并（简化处理，实际应用中可能需要更复杂的处理）
-		for i := 0; i < len(frame.Subframes[0].Samples); i++ {
-			for ch := 0; ch < f.channels; ch++ {
-				sample := float64(frame.Subframes[ch].Samples[i]) / maxVal
-				allSamples = append(allSamples, sample)
-			}
+// completeFrame 判断出错的帧是否已完整解析出所有声道的采样（只有末尾的CRC-16校验失败）
+func completeFrame(f *frame.Frame, channels int) bool {
+	if f == nil || len(f.Subframes) < channels {
+		return false
+	}
+	for _, sub := range f.Subframes[:channels] {
+		if sub == nil || len(sub.Samples) != int(f.BlockSize) {
+			return false
 		}
 	}
-
-	f.samples = allSamples
-	return allSamples, nil
+	return true
 }
 
 // GetMetadata 获取元数据
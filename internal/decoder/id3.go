@@ -0,0 +1,191 @@
+package decoder
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"unicode/utf16"
+
+	"audio-loss-checker/internal/types"
+)
+
+// id3v2TagSize 返回文件开头ID3v2标签的总长度，不存在时返回0
+func id3v2TagSize(header []byte) int64 {
+	if len(header) < 10 || string(header[0:3]) != "ID3" {
+		return 0
+	}
+
+	size := int64(header[6]&0x7f)<<21 | int64(header[7]&0x7f)<<14 |
+		int64(header[8]&0x7f)<<7 | int64(header[9]&0x7f)
+	size += 10
+	if header[5]&0x10 != 0 {
+		// 带有页脚
+		size += 10
+	}
+	return size
+}
+
+// id3v2FrameFields ID3v2帧ID与元数据字段的对应关系（v2.2使用三字符ID）
+var id3v2FrameFields = map[string]string{
+	"TIT2": "title", "TT2": "title",
+	"TPE1": "artist", "TP1": "artist",
+	"TALB": "album", "TAL": "album",
+	"TYER": "year", "TYE": "year", "TDRC": "year",
+	"TCON": "genre", "TCO": "genre",
+	"TRCK": "track", "TRK": "track",
+}
+
+// parseID3v2 解析完整的ID3v2标签（含10字节头部），只读取常用文本帧
+func parseID3v2(tag []byte) (types.AudioMetadata, bool) {
+	var meta types.AudioMetadata
+	if len(tag) < 10 || string(tag[0:3]) != "ID3" {
+		return meta, false
+	}
+
+	version := tag[3]
+	flags := tag[5]
+	size := int(id3v2TagSize(tag[:10]) - 10)
+	if flags&0x10 != 0 {
+		size -= 10
+	}
+	if version < 2 || version > 4 || size > len(tag)-10 {
+		return meta, false
+	}
+	data := tag[10 : 10+size]
+
+	// v2.3及以下的整体反同步
+	if flags&0x80 != 0 && version < 4 {
+		data = removeUnsync(data)
+	}
+
+	// 跳过扩展头部
+	if flags&0x40 != 0 && version >= 3 && len(data) >= 4 {
+		extSize := int(binary.BigEndian.Uint32(data[0:4]))
+		if version == 4 {
+			extSize = syncsafeInt(data[0:4])
+		} else {
+			extSize += 4
+		}
+		if extSize > len(data) {
+			return meta, false
+		}
+		data = data[extSize:]
+	}
+
+	idLen, headerLen := 4, 10
+	if version == 2 {
+		idLen, headerLen = 3, 6
+	}
+
+	for len(data) >= headerLen && data[0] != 0 {
+		id := string(data[:idLen])
+		var frameSize int
+		var frameFlags uint16
+		switch version {
+		case 2:
+			frameSize = int(data[3])<<16 | int(data[4])<<8 | int(data[5])
+		case 3:
+			frameSize = int(binary.BigEndian.Uint32(data[4:8]))
+			frameFlags = binary.BigEndian.Uint16(data[8:10])
+		default:
+			frameSize = syncsafeInt(data[4:8])
+			frameFlags = binary.BigEndian.Uint16(data[8:10])
+		}
+		if frameSize < 0 || headerLen+frameSize > len(data) {
+			break
+		}
+		body := data[headerLen : headerLen+frameSize]
+		data = data[headerLen+frameSize:]
+
+		field, ok := id3v2FrameFields[id]
+		if !ok || len(body) == 0 {
+			continue
+		}
+		// 跳过压缩或加密的帧
+		if (version == 3 && frameFlags&0x00c0 != 0) || (version == 4 && frameFlags&0x000c != 0) {
+			continue
+		}
+		if version == 4 && frameFlags&0x0002 != 0 {
+			body = removeUnsync(body)
+		}
+
+		text := decodeID3Text(body[0], body[1:])
+		switch field {
+		case "title":
+			meta.Title = text
+		case "artist":
+			meta.Artist = text
+		case "album":
+			meta.Album = text
+		case "year":
+			if meta.Year == "" || id == "TDRC" {
+				meta.Year = text
+			}
+		case "genre":
+			meta.Genre = text
+		case "track":
+			meta.TrackNumber, meta.TrackTotal = parseTrackNumber(text)
+		}
+	}
+
+	return meta, true
+}
+
+// syncsafeInt 解析每字节7位有效的同步安全整数
+func syncsafeInt(b []byte) int {
+	return int(b[0]&0x7f)<<21 | int(b[1]&0x7f)<<14 | int(b[2]&0x7f)<<7 | int(b[3]&0x7f)
+}
+
+// removeUnsync 还原反同步处理（0xFF 0x00 → 0xFF）
+func removeUnsync(data []byte) []byte {
+	return bytes.ReplaceAll(data, []byte{0xff, 0x00}, []byte{0xff})
+}
+
+// decodeID3Text 按ID3文本编码解码字符串，多值时只取第一个
+func decodeID3Text(encoding byte, data []byte) string {
+	var text string
+	switch encoding {
+	case 1, 2:
+		// UTF-16（带BOM）或 UTF-16BE
+		bigEndian := encoding == 2
+		if len(data) >= 2 {
+			if data[0] == 0xff && data[1] == 0xfe {
+				bigEndian = false
+				data = data[2:]
+			} else if data[0] == 0xfe && data[1] == 0xff {
+				bigEndian = true
+				data = data[2:]
+			}
+		}
+		units := make([]uint16, 0, len(data)/2)
+		for i := 0; i+1 < len(data); i += 2 {
+			var u uint16
+			if bigEndian {
+				u = binary.BigEndian.Uint16(data[i:])
+			} else {
+				u = binary.LittleEndian.Uint16(data[i:])
+			}
+			if u == 0 {
+				break
+			}
+			units = append(units, u)
+		}
+		text = string(utf16.Decode(units))
+	case 3:
+		if i := bytes.IndexByte(data, 0); i >= 0 {
+			data = data[:i]
+		}
+		text = string(data)
+	default:
+		// ISO-8859-1
+		if i := bytes.IndexByte(data, 0); i >= 0 {
+			data = data[:i]
+		}
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		text = string(runes)
+	}
+	return strings.TrimSpace(text)
+}
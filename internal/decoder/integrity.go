@@ -0,0 +1,141 @@
+package decoder
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+
+	"audio-loss-checker/internal/types"
+)
+
+// maxRecordedErrors 完整性检查中最多记录的帧错误数，其余只计数
+const maxRecordedErrors = 10
+
+// integrityTracker 在解码过程中记录采样数、帧错误和PCM数据的MD5
+type integrityTracker struct {
+	expectedSamples int64
+	expectedMD5     [16]byte // 全为0表示文件未记录MD5
+	bitDepth        int
+
+	decoded     int64
+	frameErrors int
+	errors      []types.DecodeError
+	stopped     bool
+	truncated   bool
+
+	md5 hash.Hash // 未启用MD5校验时为nil
+	buf []byte
+}
+
+// newIntegrityTracker 按文件头记录的采样数和MD5创建检查器
+func newIntegrityTracker(expectedSamples int64, expectedMD5 [16]byte, bitDepth int) *integrityTracker {
+	return &integrityTracker{expectedSamples: expectedSamples, expectedMD5: expectedMD5, bitDepth: bitDepth}
+}
+
+// EnableMD5 实现 types.IntegrityChecker
+func (t *integrityTracker) EnableMD5() {
+	t.md5 = md5.New()
+}
+
+// addFrame 记录一帧成功解码的整数采样
+// MD5按FLAC的约定计算：采样按声道交错，每个采样为 (bitDepth+7)/8 字节的小端序有符号整数
+func (t *integrityTracker) addFrame(frame [][]int32) {
+	n := len(frame[0])
+	t.decoded += int64(n)
+	if t.md5 == nil {
+		return
+	}
+
+	width := (t.bitDepth + 7) / 8
+	size := n * len(frame) * width
+	if cap(t.buf) < size {
+		t.buf = make([]byte, size)
+	}
+	buf := t.buf[:size]
+	pos := 0
+	for i := 0; i < n; i++ {
+		for _, samples := range frame {
+			v := uint32(samples[i])
+			for b := 0; b < width; b++ {
+				buf[pos] = byte(v >> (8 * uint(b)))
+				pos++
+			}
+		}
+	}
+	t.md5.Write(buf)
+}
+
+// addError 记录一个帧错误；数据提前结束时只标记为截断，不计为帧错误
+func (t *integrityTracker) addError(err error) {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		t.truncated = true
+		return
+	}
+	t.frameErrors++
+	if len(t.errors) < maxRecordedErrors {
+		t.errors = append(t.errors, types.DecodeError{Position: t.decoded, Message: err.Error()})
+	}
+}
+
+// stop 遇到无法跳过的错误，停止解码
+func (t *integrityTracker) stop(err error) {
+	t.addError(err)
+	if !t.truncated {
+		t.stopped = true
+	}
+}
+
+// Integrity 实现 types.IntegrityChecker
+func (t *integrityTracker) Integrity() *types.Integrity {
+	result := &types.Integrity{
+		ExpectedSamples: t.expectedSamples,
+		DecodedSamples:  t.decoded,
+		FrameErrors:     t.frameErrors,
+		Errors:          t.errors,
+		Stopped:         t.stopped,
+		// 中途停止时缺少的采样是错误造成的，不算截断
+		Truncated: t.truncated || !t.stopped && t.expectedSamples > 0 && t.decoded < t.expectedSamples,
+	}
+
+	var details []string
+	if result.FrameErrors > 0 {
+		details = append(details, fmt.Sprintf("%d 个帧校验失败或无法解析", result.FrameErrors))
+	}
+	if result.Stopped {
+		details = append(details, fmt.Sprintf("在第 %d 个采样处遇到无法跳过的错误，其后的数据没有解码", t.decoded))
+	}
+	if result.Truncated {
+		if t.expectedSamples > 0 {
+			details = append(details, fmt.Sprintf("文件被截断：只解码出 %d / %d 个采样", t.decoded, t.expectedSamples))
+		} else {
+			details = append(details, "文件被截断：最后一帧不完整")
+		}
+	}
+
+	// 没有完整解码时MD5必然不符，不再比较
+	if t.md5 != nil && !result.Stopped && !result.Truncated {
+		if t.expectedMD5 == [16]byte{} {
+			result.MD5 = "missing"
+			details = append(details, "文件未记录MD5，无法校验")
+		} else {
+			result.ExpectedMD5 = hex.EncodeToString(t.expectedMD5[:])
+			result.ActualMD5 = hex.EncodeToString(t.md5.Sum(nil))
+			if result.ExpectedMD5 == result.ActualMD5 {
+				result.MD5 = "match"
+				details = append(details, "MD5校验通过")
+			} else {
+				result.MD5 = "mismatch"
+				details = append(details, "解码后的PCM数据与文件头记录的MD5不符")
+			}
+		}
+	}
+	if len(details) == 0 {
+		details = append(details, "没有发现帧错误")
+	}
+	result.Details = strings.Join(details, "；")
+	return result
+}
@@ -0,0 +1,79 @@
+package decoder
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"audio-loss-checker/internal/types"
+)
+
+// 243749.flac 来自 github.com/mewkiz/flac 的测试数据（公有领域，freesound.org/s/243749），
+// 8kHz 24位单声道共402个采样，STREAMINFO 中的MD5由参考编码器写入
+func TestFLACMD5(t *testing.T) {
+	const (
+		streamInfoMD5 = 26 // "fLaC" + 元数据块头 + STREAMINFO 的前18字节
+		knownMD5      = "dfc196fd415953b679d92ceb1a59ccf1"
+	)
+	data, err := os.ReadFile(filepath.Join("testdata", "243749.flac"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	modify := func(f func(b []byte) []byte) []byte {
+		return f(bytes.Clone(data))
+	}
+	tests := []struct {
+		name      string
+		data      []byte
+		md5       string
+		truncated bool
+	}{
+		{name: "原始文件", data: data, md5: "match"},
+		{name: "MD5被改动", data: modify(func(b []byte) []byte {
+			b[streamInfoMD5] ^= 0x01
+			return b
+		}), md5: "mismatch"},
+		{name: "未记录MD5", data: modify(func(b []byte) []byte {
+			clear(b[streamInfoMD5 : streamInfoMD5+16])
+			return b
+		}), md5: "missing"},
+		// 截断后不再比较MD5
+		{name: "截断", data: data[:len(data)-200], truncated: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			audioFile, err := NewDecoderRegistry().DecodeReader(bytes.NewReader(tt.data), "243749.flac")
+			if err != nil {
+				t.Fatalf("解码失败: %v", err)
+			}
+			defer audioFile.Close()
+			reader, err := audioFile.NewSampleReader()
+			if err != nil {
+				t.Fatal(err)
+			}
+			checker := reader.(types.IntegrityChecker)
+			checker.EnableMD5()
+			block := [][]float64{make([]float64, 100)}
+			for {
+				if _, err := reader.ReadBlock(block); err == io.EOF {
+					break
+				} else if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			integrity := checker.Integrity()
+			if integrity.MD5 != tt.md5 || integrity.Truncated != tt.truncated {
+				t.Errorf("MD5=%q Truncated=%v: %s", integrity.MD5, integrity.Truncated, integrity.Details)
+			}
+			// 改动文件头不影响按PCM数据算出的MD5
+			if (tt.md5 == "match" || tt.md5 == "mismatch") && integrity.ActualMD5 != knownMD5 {
+				t.Errorf("MD5为 %s，应为 %s", integrity.ActualMD5, knownMD5)
+			}
+		})
+	}
+}
@@ -0,0 +1,360 @@
+package decoder
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+
+	"audio-loss-checker/internal/types"
+)
+
+// mp4Track MP4音轨的采样表信息
+type mp4Track struct {
+	handler      string   // 处理器类型，音频为 "soun"
+	timescale    uint32   // 时间刻度
+	duration     uint64   // 以时间刻度为单位的时长
+	codec        string   // stsd中的编码格式，如 "alac", "mp4a"
+	entry        []byte   // stsd采样条目内容（不含8字节盒子头）
+	sampleSizes  []uint32 // 每个数据包的大小
+	chunkOffsets []int64  // 每个块在文件中的偏移
+	stsc         []mp4SampleToChunk
+}
+
+// mp4SampleToChunk stsc表项
+type mp4SampleToChunk struct {
+	firstChunk      uint32
+	samplesPerChunk uint32
+}
+
+// mp4Movie MP4容器解析结果
+type mp4Movie struct {
+	tracks   []*mp4Track
+	metadata types.AudioMetadata
+}
+
+// parseMP4 读取并解析MP4文件的moov盒子
+func parseMP4(r io.ReadSeeker) (*mp4Movie, error) {
+	moov, err := readMP4TopLevelBox(r, "moov")
+	if err != nil {
+		return nil, err
+	}
+
+	movie := &mp4Movie{}
+	for _, box := range mp4Children(moov) {
+		switch box.boxType {
+		case "trak":
+			track, err := parseMP4Track(box.data)
+			if err != nil {
+				return nil, err
+			}
+			movie.tracks = append(movie.tracks, track)
+		case "udta":
+			if meta := mp4FindBox(box.data, "meta"); len(meta) > 4 {
+				// meta是FullBox，跳过版本号和标志
+				if ilst := mp4FindBox(meta[4:], "ilst"); ilst != nil {
+					movie.metadata = parseMP4Tags(ilst)
+				}
+			}
+		}
+	}
+
+	return movie, nil
+}
+
+// audioTrack 返回第一个音频轨道
+func (m *mp4Movie) audioTrack() *mp4Track {
+	for _, track := range m.tracks {
+		if track.handler == "soun" {
+			return track
+		}
+	}
+	return nil
+}
+
+// readMP4TopLevelBox 在文件顶层查找指定盒子并读取其内容
+func readMP4TopLevelBox(r io.ReadSeeker, boxType string) ([]byte, error) {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 16)
+	for {
+		if _, err := io.ReadFull(r, header[:8]); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return nil, fmt.Errorf("未找到 %s 盒子", boxType)
+			}
+			return nil, err
+		}
+
+		size := int64(binary.BigEndian.Uint32(header[0:4]))
+		name := string(header[4:8])
+		headerSize := int64(8)
+
+		switch size {
+		case 1:
+			// 64位扩展大小
+			if _, err := io.ReadFull(r, header[8:16]); err != nil {
+				return nil, err
+			}
+			size = int64(binary.BigEndian.Uint64(header[8:16]))
+			headerSize = 16
+		case 0:
+			// 盒子延伸到文件末尾
+			if name != boxType {
+				return nil, fmt.Errorf("未找到 %s 盒子", boxType)
+			}
+			return io.ReadAll(r)
+		}
+
+		if size < headerSize {
+			return nil, fmt.Errorf("无效的MP4盒子大小: %s", name)
+		}
+
+		if name == boxType {
+			data := make([]byte, size-headerSize)
+			if _, err := io.ReadFull(r, data); err != nil {
+				return nil, fmt.Errorf("读取 %s 盒子失败: %w", boxType, err)
+			}
+			return data, nil
+		}
+
+		if _, err := r.Seek(size-headerSize, io.SeekCurrent); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// mp4BoxData 内存中的MP4盒子
+type mp4BoxData struct {
+	boxType string
+	data    []byte
+}
+
+// mp4Children 解析内存中的子盒子列表
+func mp4Children(data []byte) []mp4BoxData {
+	var boxes []mp4BoxData
+	for len(data) >= 8 {
+		size := uint64(binary.BigEndian.Uint32(data[0:4]))
+		name := string(data[4:8])
+		headerSize := uint64(8)
+
+		switch size {
+		case 1:
+			if len(data) < 16 {
+				return boxes
+			}
+			size = binary.BigEndian.Uint64(data[8:16])
+			headerSize = 16
+		case 0:
+			size = uint64(len(data))
+		}
+
+		if size < headerSize || size > uint64(len(data)) {
+			return boxes
+		}
+
+		boxes = append(boxes, mp4BoxData{boxType: name, data: data[headerSize:size]})
+		data = data[size:]
+	}
+	return boxes
+}
+
+// mp4FindBox 按路径查找子盒子
+func mp4FindBox(data []byte, path ...string) []byte {
+	for _, name := range path {
+		found := false
+		for _, box := range mp4Children(data) {
+			if box.boxType == name {
+				data = box.data
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil
+		}
+	}
+	return data
+}
+
+// parseMP4Track 解析trak盒子
+func parseMP4Track(trak []byte) (*mp4Track, error) {
+	track := &mp4Track{}
+
+	mdia := mp4FindBox(trak, "mdia")
+	if mdia == nil {
+		return nil, fmt.Errorf("音轨缺少 mdia 盒子")
+	}
+
+	if hdlr := mp4FindBox(mdia, "hdlr"); len(hdlr) >= 12 {
+		track.handler = string(hdlr[8:12])
+	}
+
+	if mdhd := mp4FindBox(mdia, "mdhd"); len(mdhd) >= 24 {
+		if mdhd[0] == 1 {
+			if len(mdhd) >= 36 {
+				track.timescale = binary.BigEndian.Uint32(mdhd[20:24])
+				track.duration = binary.BigEndian.Uint64(mdhd[24:32])
+			}
+		} else {
+			track.timescale = binary.BigEndian.Uint32(mdhd[12:16])
+			track.duration = uint64(binary.BigEndian.Uint32(mdhd[16:20]))
+		}
+	}
+
+	stbl := mp4FindBox(mdia, "minf", "stbl")
+	if stbl == nil {
+		return track, nil
+	}
+
+	// 采样描述
+	if stsd := mp4FindBox(stbl, "stsd"); len(stsd) >= 8 {
+		entries := mp4Children(stsd[8:])
+		if len(entries) > 0 {
+			track.codec = entries[0].boxType
+			track.entry = entries[0].data
+		}
+	}
+
+	// 采样大小
+	if stsz := mp4FindBox(stbl, "stsz"); len(stsz) >= 12 {
+		uniformSize := binary.BigEndian.Uint32(stsz[4:8])
+		count := int(binary.BigEndian.Uint32(stsz[8:12]))
+		if uniformSize != 0 {
+			track.sampleSizes = make([]uint32, count)
+			for i := range track.sampleSizes {
+				track.sampleSizes[i] = uniformSize
+			}
+		} else {
+			if len(stsz) < 12+count*4 {
+				return nil, fmt.Errorf("stsz 盒子已截断")
+			}
+			track.sampleSizes = make([]uint32, count)
+			for i := range track.sampleSizes {
+				track.sampleSizes[i] = binary.BigEndian.Uint32(stsz[12+i*4:])
+			}
+		}
+	}
+
+	// 块偏移（32位或64位）
+	if stco := mp4FindBox(stbl, "stco"); len(stco) >= 8 {
+		count := int(binary.BigEndian.Uint32(stco[4:8]))
+		if len(stco) < 8+count*4 {
+			return nil, fmt.Errorf("stco 盒子已截断")
+		}
+		for i := 0; i < count; i++ {
+			track.chunkOffsets = append(track.chunkOffsets, int64(binary.BigEndian.Uint32(stco[8+i*4:])))
+		}
+	} else if co64 := mp4FindBox(stbl, "co64"); len(co64) >= 8 {
+		count := int(binary.BigEndian.Uint32(co64[4:8]))
+		if len(co64) < 8+count*8 {
+			return nil, fmt.Errorf("co64 盒子已截断")
+		}
+		for i := 0; i < count; i++ {
+			track.chunkOffsets = append(track.chunkOffsets, int64(binary.BigEndian.Uint64(co64[8+i*8:])))
+		}
+	}
+
+	// 采样到块的映射
+	if stsc := mp4FindBox(stbl, "stsc"); len(stsc) >= 8 {
+		count := int(binary.BigEndian.Uint32(stsc[4:8]))
+		if len(stsc) < 8+count*12 {
+			return nil, fmt.Errorf("stsc 盒子已截断")
+		}
+		for i := 0; i < count; i++ {
+			entry := stsc[8+i*12:]
+			track.stsc = append(track.stsc, mp4SampleToChunk{
+				firstChunk:      binary.BigEndian.Uint32(entry[0:4]),
+				samplesPerChunk: binary.BigEndian.Uint32(entry[4:8]),
+			})
+		}
+	}
+
+	return track, nil
+}
+
+// sampleOffsets 根据采样表计算每个数据包在文件中的偏移
+func (t *mp4Track) sampleOffsets() ([]int64, error) {
+	if len(t.stsc) == 0 || len(t.chunkOffsets) == 0 {
+		return nil, fmt.Errorf("MP4采样表不完整")
+	}
+
+	offsets := make([]int64, 0, len(t.sampleSizes))
+	sample := 0
+	for i, entry := range t.stsc {
+		lastChunk := uint32(len(t.chunkOffsets))
+		if i+1 < len(t.stsc) {
+			lastChunk = t.stsc[i+1].firstChunk - 1
+		}
+
+		for chunk := entry.firstChunk; chunk <= lastChunk; chunk++ {
+			if chunk == 0 || int(chunk) > len(t.chunkOffsets) {
+				return nil, fmt.Errorf("无效的MP4块索引: %d", chunk)
+			}
+			offset := t.chunkOffsets[chunk-1]
+			for j := uint32(0); j < entry.samplesPerChunk && sample < len(t.sampleSizes); j++ {
+				offsets = append(offsets, offset)
+				offset += int64(t.sampleSizes[sample])
+				sample++
+			}
+		}
+	}
+
+	if sample < len(t.sampleSizes) {
+		return nil, fmt.Errorf("MP4采样表不一致: %d/%d", sample, len(t.sampleSizes))
+	}
+
+	return offsets, nil
+}
+
+// parseMP4Tags 解析iTunes风格的ilst元数据
+func parseMP4Tags(ilst []byte) types.AudioMetadata {
+	var metadata types.AudioMetadata
+
+	for _, item := range mp4Children(ilst) {
+		// 音轨号为二进制数据: 2字节填充、音轨号、音轨总数（大端序16位）
+		if item.boxType == "trkn" {
+			if data := mp4FindBox(item.data, "data"); len(data) >= 14 {
+				metadata.TrackNumber = int(binary.BigEndian.Uint16(data[10:12]))
+				metadata.TrackTotal = int(binary.BigEndian.Uint16(data[12:14]))
+			}
+			continue
+		}
+
+		value := mp4TagText(item.data)
+		if value == "" {
+			continue
+		}
+
+		switch item.boxType {
+		case "\xa9nam":
+			metadata.Title = value
+		case "\xa9ART":
+			metadata.Artist = value
+		case "\xa9alb":
+			metadata.Album = value
+		case "\xa9day":
+			metadata.Year = value
+		case "\xa9gen":
+			metadata.Genre = value
+		}
+	}
+
+	return metadata
+}
+
+// mp4TagText 读取ilst条目中data盒子的UTF-8文本
+func mp4TagText(item []byte) string {
+	data := mp4FindBox(item, "data")
+	if len(data) < 8 {
+		return ""
+	}
+
+	// 类型标识1表示UTF-8文本
+	if binary.BigEndian.Uint32(data[0:4])&0xffffff != 1 {
+		return ""
+	}
+
+	return strings.TrimRight(string(data[8:]), "\x00")
+}
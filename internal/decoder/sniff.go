@@ -0,0 +1,307 @@
+package decoder
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// sniffSize 识别格式时读取的文件头长度
+const sniffSize = 4096
+
+// Detection 按文件内容识别格式的结果
+type Detection struct {
+	Format    string // 识别出的格式（解码器注册表中的键），无法识别时为空
+	Name      string // 可读的格式名称，如 "FLAC"、"MP3"
+	Lossy     bool   // 内容本身为有损编码
+	Extension string // 文件扩展名（小写，不含点）
+	Mismatch  bool   // 扩展名与内容不一致
+}
+
+// formatNames 格式键对应的可读名称
+var formatNames = map[string]string{
+	"wav":     "WAV",
+	"flac":    "FLAC",
+	"ape":     "APE",
+	"m4a":     "MP4/M4A",
+	"m4a-aac": "AAC (MP4)",
+	"aiff":    "AIFF",
+	"wv":      "WavPack",
+	"dsf":     "DSF",
+	"dff":     "DFF",
+	"mp3":     "MP3",
+	"aac":     "AAC (ADTS)",
+	"vorbis":  "Ogg Vorbis",
+	"opus":    "Opus",
+	"speex":   "Speex",
+	"oggflac": "Ogg FLAC",
+}
+
+// lossyExtensions 有损格式及其常用扩展名
+var lossyExtensions = map[string][]string{
+	"mp3":     {"mp3"},
+	"aac":     {"aac"},
+	"m4a-aac": {"m4a", "mp4", "m4b"},
+	"vorbis":  {"ogg", "oga"},
+	"opus":    {"opus", "ogg"},
+	"speex":   {"spx", "ogg"},
+}
+
+// Detect 读取文件头识别真实格式，并与扩展名比对
+func (r *DecoderRegistry) Detect(filePath string) (*Detection, error) {
+	src, err := OpenSource(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+	return r.DetectSource(src)
+}
+
+// DetectReader 识别调用方提供的音频数据的真实格式，并与 name 的扩展名比对
+func (r *DecoderRegistry) DetectReader(rs io.ReadSeeker, name string) (*Detection, error) {
+	src, err := NewSource(rs, name)
+	if err != nil {
+		return nil, err
+	}
+	return r.DetectSource(src)
+}
+
+// DetectSource 从 src 的开头读取文件头识别格式，不关闭 src
+func (r *DecoderRegistry) DetectSource(src *Source) (*Detection, error) {
+	header := make([]byte, sniffSize)
+	n, err := src.ReadAt(header, 0)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("读取文件头失败: %w", err)
+	}
+	header = header[:n]
+
+	// 跳过ID3v2标签后再识别（MP3、部分FLAC/APE文件开头带有ID3v2）
+	if tagSize := id3v2TagSize(header); tagSize > 0 {
+		header = make([]byte, sniffSize)
+		n, err = src.ReadAt(header, tagSize)
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("读取文件头失败: %w", err)
+		}
+		header = header[:n]
+	}
+
+	det := &Detection{
+		Format:    sniffFormat(header),
+		Extension: strings.TrimPrefix(strings.ToLower(filepath.Ext(src.Name())), "."),
+	}
+	if det.Format == "" {
+		return det, nil
+	}
+	// MP4容器可以装ALAC也可以装AAC，需要看音频轨道的编码
+	if det.Format == "m4a" {
+		det.Format = sniffMP4Codec(src)
+	}
+
+	det.Name = formatNames[det.Format]
+	exts, lossy := lossyExtensions[det.Format]
+	det.Lossy = lossy
+	if lossy {
+		det.Mismatch = true
+		for _, ext := range exts {
+			if ext == det.Extension {
+				det.Mismatch = false
+			}
+		}
+	} else {
+		// 由同一个解码器处理的扩展名视为一致（如 aif/aiff/aifc）
+		contentDecoder := r.decoders[det.Format]
+		extDecoder, ok := r.decoders[det.Extension]
+		det.Mismatch = !ok || contentDecoder == nil || extDecoder != contentDecoder
+	}
+
+	return det, nil
+}
+
+// sniffFormat 根据魔数识别格式，无法识别时返回空字符串
+func sniffFormat(h []byte) string {
+	if len(h) < 12 {
+		return ""
+	}
+
+	switch {
+	case (string(h[0:4]) == "RIFF" || string(h[0:4]) == "RF64" || string(h[0:4]) == "BW64") && string(h[8:12]) == "WAVE":
+		return "wav"
+	case len(h) >= 40 && bytes.Equal(h[0:16], w64RIFF) && bytes.Equal(h[24:40], w64WAVE):
+		return "wav"
+	case string(h[0:4]) == "fLaC":
+		return "flac"
+	case string(h[0:4]) == "MAC ":
+		return "ape"
+	case string(h[4:8]) == "ftyp":
+		return "m4a"
+	case string(h[0:4]) == "FORM" && (string(h[8:12]) == "AIFF" || string(h[8:12]) == "AIFC"):
+		return "aiff"
+	case string(h[0:4]) == "wvpk":
+		return "wv"
+	case string(h[0:4]) == "DSD ":
+		return "dsf"
+	case string(h[0:4]) == "FRM8":
+		return "dff"
+	case string(h[0:4]) == "OggS":
+		return sniffOgg(h)
+	}
+
+	if isADTSFrame(h) {
+		return "aac"
+	}
+	if isMPEGAudioFrame(h) {
+		return "mp3"
+	}
+	return ""
+}
+
+// mp4Codecs MP4采样条目中的有损编码对应的格式键
+var mp4Codecs = map[string]string{
+	"mp4a": "m4a-aac",
+}
+
+// sniffMP4Codec 根据moov中第一个音频轨道的编码细分MP4格式
+// 读不到moov或编码未知时仍返回 "m4a"，交给解码器报告具体错误
+func sniffMP4Codec(src *Source) string {
+	moov, err := readMP4TopLevelBox(src, "moov")
+	if err != nil {
+		return "m4a"
+	}
+	for _, box := range mp4Children(moov) {
+		if box.boxType != "trak" {
+			continue
+		}
+		track, err := parseMP4Track(box.data)
+		if err != nil || track.handler != "soun" {
+			continue
+		}
+		if format, ok := mp4Codecs[track.codec]; ok {
+			return format
+		}
+		return "m4a"
+	}
+	return "m4a"
+}
+
+// sniffOgg 根据Ogg第一个数据包识别编码
+func sniffOgg(h []byte) string {
+	if len(h) < 27 {
+		return ""
+	}
+	start := 27 + int(h[26])
+	if start >= len(h) {
+		return ""
+	}
+	packet := h[start:]
+	switch {
+	case bytes.HasPrefix(packet, []byte("\x01vorbis")):
+		return "vorbis"
+	case bytes.HasPrefix(packet, []byte("OpusHead")):
+		return "opus"
+	case bytes.HasPrefix(packet, []byte("Speex   ")):
+		return "speex"
+	case bytes.HasPrefix(packet, []byte("\x7fFLAC")):
+		return "oggflac"
+	}
+	return ""
+}
+
+// MPEG音频帧头参数表
+var (
+	mpegBitrates = [2][3][15]int{
+		{ // MPEG-1: Layer I, II, III
+			{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448},
+			{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384},
+			{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},
+		},
+		{ // MPEG-2/2.5: Layer I, II, III
+			{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256},
+			{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+			{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+		},
+	}
+	mpegSampleRates = map[int][3]int{
+		3: {44100, 48000, 32000}, // MPEG-1
+		2: {22050, 24000, 16000}, // MPEG-2
+		0: {11025, 12000, 8000},  // MPEG-2.5
+	}
+)
+
+// mpegFrameLength 解析MPEG音频帧头，返回帧长度
+func mpegFrameLength(h []byte) (int, bool) {
+	if len(h) < 4 || h[0] != 0xff || h[1]&0xe0 != 0xe0 {
+		return 0, false
+	}
+	version := int(h[1]>>3) & 3
+	layer := int(h[1]>>1) & 3
+	bitrateIndex := int(h[2] >> 4)
+	rateIndex := int(h[2]>>2) & 3
+	padding := int(h[2]>>1) & 1
+
+	rates, ok := mpegSampleRates[version]
+	if !ok || layer == 0 || bitrateIndex == 0 || bitrateIndex == 15 || rateIndex == 3 {
+		return 0, false
+	}
+
+	table := 0
+	if version != 3 {
+		table = 1
+	}
+	layerIndex := 3 - layer // Layer I=0, II=1, III=2
+	bitrate := mpegBitrates[table][layerIndex][bitrateIndex] * 1000
+	sampleRate := rates[rateIndex]
+
+	switch {
+	case layerIndex == 0:
+		return (12*bitrate/sampleRate + padding) * 4, true
+	case layerIndex == 2 && version != 3:
+		return 72*bitrate/sampleRate + padding, true
+	default:
+		return 144*bitrate/sampleRate + padding, true
+	}
+}
+
+// isMPEGAudioFrame 判断数据是否以MPEG音频帧开头（如有可能再校验下一帧的同步字）
+func isMPEGAudioFrame(h []byte) bool {
+	length, ok := mpegFrameLength(h)
+	if !ok || length <= 0 {
+		return false
+	}
+	if length+4 > len(h) {
+		return true
+	}
+	_, ok = mpegFrameLength(h[length:])
+	return ok
+}
+
+// isADTSFrame 判断数据是否以AAC ADTS帧开头
+func isADTSFrame(h []byte) bool {
+	if len(h) < 7 || h[0] != 0xff || h[1]&0xf6 != 0xf0 {
+		return false
+	}
+	length := int(h[3]&0x03)<<11 | int(h[4])<<3 | int(h[5]>>5)
+	if length < 7 {
+		return false
+	}
+	if length+2 > len(h) {
+		return true
+	}
+	next := h[length:]
+	return next[0] == 0xff && next[1]&0xf6 == 0xf0
+}
+
+// decoderForDetection 根据内容识别结果选择解码器，无法识别时退回按扩展名选择
+func (r *DecoderRegistry) decoderForDetection(filePath string, det *Detection) (AudioDecoder, error) {
+	if det.Lossy {
+		return nil, fmt.Errorf("文件内容为%s有损编码", det.Name)
+	}
+	if det.Format != "" {
+		if decoder, ok := r.decoders[det.Format]; ok {
+			return decoder, nil
+		}
+		return nil, fmt.Errorf("不支持的音频格式: %s", det.Name)
+	}
+	return r.GetDecoder(filePath)
+}
@@ -0,0 +1,136 @@
+package decoder
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// mp3Frames 生成两个MPEG-1 Layer III帧（128kbps、44.1kHz，每帧417字节），帧体为0
+func mp3Frames() []byte {
+	frame := make([]byte, 417)
+	copy(frame, []byte{0xff, 0xfb, 0x90, 0x00})
+	return append(bytes.Clone(frame), frame...)
+}
+
+// adtsFrames 生成两个AAC-LC的ADTS帧（44.1kHz立体声，每帧100字节）
+func adtsFrames() []byte {
+	const length = 100
+	frame := make([]byte, length)
+	copy(frame, []byte{0xff, 0xf1, 0x50, 0x80 | length>>11, byte(length >> 3), byte(length&7)<<5 | 0x1f, 0xfc})
+	return append(bytes.Clone(frame), frame...)
+}
+
+// oggPage 生成只含一个数据包的Ogg首页
+func oggPage(packet []byte) []byte {
+	page := []byte("OggS")
+	page = append(page, 0, 2)               // 版本、首页标志
+	page = append(page, make([]byte, 8)...) // granule position
+	page = binary.LittleEndian.AppendUint32(page, 1)
+	page = binary.LittleEndian.AppendUint32(page, 0)
+	page = append(page, make([]byte, 4)...) // CRC（识别格式时不校验）
+	page = append(page, 1, byte(len(packet)))
+	return append(page, packet...)
+}
+
+// id3Tag 生成一个内容为填充字节的ID3v2.4标签
+func id3Tag(size int) []byte {
+	tag := []byte{'I', 'D', '3', 4, 0, 0, byte(size >> 21 & 0x7f), byte(size >> 14 & 0x7f), byte(size >> 7 & 0x7f), byte(size & 0x7f)}
+	return append(tag, make([]byte, size)...)
+}
+
+// 按内容识别格式，不看扩展名
+func TestDetect(t *testing.T) {
+	pcm := testSignal(2, 1000, 16)
+	wav := buildWAV(pcmFormat(1, 2, 44100, 16), packPCM(pcm, 2, true))
+
+	tests := []struct {
+		name     string
+		data     []byte
+		format   string
+		lossy    bool
+		mismatch bool
+	}{
+		{name: "fake.flac", data: mp3Frames(), format: "mp3", lossy: true, mismatch: true},
+		{name: "fake.flac", data: append(id3Tag(300), mp3Frames()...), format: "mp3", lossy: true, mismatch: true},
+		{name: "song.mp3", data: append(id3Tag(300), mp3Frames()...), format: "mp3", lossy: true},
+		{name: "fake.wav", data: adtsFrames(), format: "aac", lossy: true, mismatch: true},
+		{name: "song.aac", data: adtsFrames(), format: "aac", lossy: true},
+		{name: "fake.flac", data: oggPage(append([]byte("\x01vorbis"), make([]byte, 22)...)), format: "vorbis", lossy: true, mismatch: true},
+		{name: "song.ogg", data: oggPage(append([]byte("\x01vorbis"), make([]byte, 22)...)), format: "vorbis", lossy: true},
+		{name: "fake.ape", data: oggPage(append([]byte("OpusHead"), make([]byte, 11)...)), format: "opus", lossy: true, mismatch: true},
+		{name: "song.opus", data: oggPage(append([]byte("OpusHead"), make([]byte, 11)...)), format: "opus", lossy: true},
+		{name: "song.oga", data: oggPage(append([]byte("\x7fFLAC"), make([]byte, 40)...)), format: "oggflac", mismatch: true},
+		{name: "track.flac", data: wav, format: "wav", mismatch: true},
+		{name: "track.wav", data: wav, format: "wav"},
+		{name: "track.flac", data: []byte("not audio at all"), format: ""},
+	}
+
+	registry := NewDecoderRegistry()
+	for _, tt := range tests {
+		det, err := registry.DetectReader(bytes.NewReader(tt.data), tt.name)
+		if err != nil {
+			t.Fatalf("%s: 识别失败: %v", tt.name, err)
+		}
+		if det.Format != tt.format || det.Lossy != tt.lossy || det.Mismatch != tt.mismatch {
+			t.Errorf("%s (%s): 识别结果为 %+v", tt.name, tt.format, det)
+		}
+	}
+
+	// 有损内容不交给解码器
+	if _, err := registry.DecodeReader(bytes.NewReader(mp3Frames()), "fake.flac"); err == nil {
+		t.Error("扩展名为 .flac 的MP3不应被解码")
+	}
+
+	// 改了扩展名的WAV仍按WAV解码
+	audioFile, samples := decodeTestData(t, "track.flac", wav)
+	if audioFile.GetFormat() != "WAV" {
+		t.Errorf("格式为 %s", audioFile.GetFormat())
+	}
+	checkSamples(t, samples, pcm)
+}
+
+// MP4容器按音频轨道的编码区分ALAC和AAC；golden.m4a 的moov在mdat之后，超出文件头的读取范围
+func TestDetectMP4Codec(t *testing.T) {
+	alac, err := os.ReadFile(filepath.Join("testdata", "golden.m4a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 把stsd中第一个采样条目的类型改为mp4a，得到一个装着AAC的同结构文件
+	aac := bytes.Clone(alac)
+	stsd := bytes.Index(aac, []byte("stsd"))
+	if stsd < 0 || string(aac[stsd+16:stsd+20]) != "alac" {
+		t.Fatal("golden.m4a 中找不到alac采样条目")
+	}
+	copy(aac[stsd+16:], "mp4a")
+
+	tests := []struct {
+		name     string
+		data     []byte
+		format   string
+		lossy    bool
+		mismatch bool
+	}{
+		{name: "track.m4a", data: alac, format: "m4a"},
+		{name: "track.m4a", data: aac, format: "m4a-aac", lossy: true},
+		{name: "track.mp4", data: aac, format: "m4a-aac", lossy: true},
+		{name: "track.flac", data: aac, format: "m4a-aac", lossy: true, mismatch: true},
+	}
+
+	registry := NewDecoderRegistry()
+	for _, tt := range tests {
+		det, err := registry.DetectReader(bytes.NewReader(tt.data), tt.name)
+		if err != nil {
+			t.Fatalf("%s: 识别失败: %v", tt.name, err)
+		}
+		if det.Format != tt.format || det.Lossy != tt.lossy || det.Mismatch != tt.mismatch {
+			t.Errorf("%s (%s): 识别结果为 %+v", tt.name, tt.format, det)
+		}
+	}
+
+	if _, err := registry.DecodeReader(bytes.NewReader(aac), "track.m4a"); err == nil {
+		t.Error("装着AAC的M4A不应被解码")
+	}
+}
@@ -0,0 +1,104 @@
+package decoder
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// Source 解码器读取的数据：打开的文件，或调用方提供的 io.ReadSeeker
+// 解码器混用顺序读取和按位置读取，多个采样流还可能同时按位置读取；
+// 数据本身不支持 io.ReaderAt 时，按位置读取先定位再读取，读完恢复原来的位置
+type Source struct {
+	name   string // 文件路径或调用方给出的名称，用于扩展名和错误信息
+	path   string // 文件路径，调用方提供的数据为空（无法查找同目录下的校正文件等）
+	r      io.ReadSeeker
+	at     io.ReaderAt // r 本身支持按位置读取时直接使用
+	size   int64
+	closer io.Closer // 自行打开的文件，调用方提供的数据由调用方关闭
+	mu     sync.Mutex
+}
+
+// OpenSource 打开文件
+func OpenSource(filePath string) (*Source, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("打开文件失败: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("读取文件信息失败: %w", err)
+	}
+	return &Source{name: filePath, path: filePath, r: file, at: file, size: info.Size(), closer: file}, nil
+}
+
+// NewSource 包装调用方提供的数据，从头开始读取；name 为空时无法按扩展名识别格式
+func NewSource(r io.ReadSeeker, name string) (*Source, error) {
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, fmt.Errorf("读取数据长度失败: %w", err)
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("定位数据失败: %w", err)
+	}
+	src := &Source{name: name, r: r, size: size}
+	src.at, _ = r.(io.ReaderAt)
+	return src, nil
+}
+
+// Name 返回文件路径或调用方给出的名称
+func (s *Source) Name() string {
+	return s.name
+}
+
+// Size 返回数据长度
+func (s *Source) Size() int64 {
+	return s.size
+}
+
+// Read 从当前位置顺序读取
+func (s *Source) Read(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.Read(p)
+}
+
+// Seek 设置顺序读取的位置
+func (s *Source) Seek(offset int64, whence int) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.r.Seek(offset, whence)
+}
+
+// ReadAt 按位置读取，不影响顺序读取的位置；读到末尾时与 os.File 一样返回 io.EOF
+func (s *Source) ReadAt(p []byte, off int64) (int, error) {
+	if s.at != nil {
+		return s.at.ReadAt(p, off)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pos, err := s.r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	defer s.r.Seek(pos, io.SeekStart)
+	if _, err := s.r.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	n, err := io.ReadFull(s.r, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// Close 关闭自行打开的文件，调用方提供的数据不关闭
+func (s *Source) Close() error {
+	if s.closer != nil {
+		return s.closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package decoder
+
+import (
+	"io"
+
+	"audio-loss-checker/internal/types"
+)
+
+// frameReader 把按自然单位（帧、数据包、块）解码的数据适配为 types.SampleReader
+type frameReader struct {
+	next    func() ([][]float64, error) // 解码下一帧并按声道返回，没有更多帧时返回 io.EOF
+	pending [][]float64                 // 当前帧中尚未读取的部分
+	pos     int
+	err     error
+	tap     *intTap // 整数采样的观察点，采样不是整数时为nil
+}
+
+// newFrameReader 创建帧适配器，next 返回的切片在下一次调用前有效
+func newFrameReader(next func() ([][]float64, error)) *frameReader {
+	return &frameReader{next: next}
+}
+
+// newIntFrameReader 创建整数采样的帧适配器，next 应通过 converter 归一化，以便观察者看到整数采样
+func newIntFrameReader(converter *intFrameConverter, next func() ([][]float64, error)) *frameReader {
+	return &frameReader{next: next, tap: &converter.tap}
+}
+
+// ObserveIntSamples 实现 types.IntSampleSource
+func (r *frameReader) ObserveIntSamples(observer types.IntSampleObserver) bool {
+	if r.tap == nil {
+		return false
+	}
+	r.tap.observer = observer
+	return true
+}
+
+// ReadBlock 实现 types.SampleReader
+func (r *frameReader) ReadBlock(dst [][]float64) (int, error) {
+	want := len(dst[0])
+	n := 0
+	for n < want {
+		if len(r.pending) == 0 || r.pos >= len(r.pending[0]) {
+			if r.err != nil {
+				break
+			}
+			frame, err := r.next()
+			if err != nil {
+				r.err = err
+				break
+			}
+			r.pending, r.pos = frame, 0
+			continue
+		}
+
+		var k int
+		for ch := range dst {
+			k = copy(dst[ch][n:want], r.pending[ch][r.pos:])
+		}
+		n += k
+		r.pos += k
+	}
+
+	if n == 0 && r.err != nil {
+		return 0, r.err
+	}
+	return n, nil
+}
+
+// intTap 在归一化之前把整数采样转交给观察者
+type intTap struct {
+	observer types.IntSampleObserver
+	buf      []int32
+}
+
+// buffer 有观察者时返回长度为 n 的复用缓冲，否则返回nil
+func (t *intTap) buffer(n int) []int32 {
+	if t == nil || t.observer == nil {
+		return nil
+	}
+	if cap(t.buf) < n {
+		t.buf = make([]int32, n)
+	}
+	return t.buf[:n]
+}
+
+// observe 把整数采样交给观察者，没有观察者时不做任何事
+func (t *intTap) observe(samples []int32) {
+	if t != nil && t.observer != nil {
+		t.observer.ObserveInt(samples)
+	}
+}
+
+// intFrameConverter 把整数采样帧归一化为浮点数，复用输出缓冲
+type intFrameConverter struct {
+	scale float64
+	out   [][]float64
+	tap   intTap
+}
+
+// newIntFrameConverter 按位深度创建转换器，采样值除以 1<<(bitDepth-1)
+func newIntFrameConverter(channels, bitDepth int) *intFrameConverter {
+	return &intFrameConverter{
+		scale: 1 / float64(int64(1)<<uint(bitDepth-1)),
+		out:   make([][]float64, channels),
+	}
+}
+
+// convert 转换一帧的前 channels 个声道，返回的切片在下一次调用前有效
+func (c *intFrameConverter) convert(frame [][]int32) [][]float64 {
+	for ch := range c.out {
+		samples := frame[ch]
+		c.tap.observe(samples)
+		if cap(c.out[ch]) < len(samples) {
+			c.out[ch] = make([]float64, len(samples))
+		}
+		c.out[ch] = c.out[ch][:len(samples)]
+		for i, v := range samples {
+			c.out[ch][i] = float64(v) * c.scale
+		}
+	}
+	return c.out
+}
+
+// readAllSamples 读完整个采样流，返回按声道分开的采样
+func readAllSamples(reader types.SampleReader, channels int) ([][]float64, error) {
+	const blockFrames = 4096
+	block := make([][]float64, channels)
+	for ch := range block {
+		block[ch] = make([]float64, blockFrames)
+	}
+
+	samples := make([][]float64, channels)
+	for {
+		n, err := reader.ReadBlock(block)
+		for ch := range samples {
+			samples[ch] = append(samples[ch], block[ch][:n]...)
+		}
+		if err == io.EOF {
+			return samples, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// newInterleavedReader 为交错存放的未压缩PCM创建采样流
+// read 从data区偏移 offset 处读取 len(data) 字节并转换为交错采样写入 dst；
+// integer 为true时 read 还应在归一化之前把整数采样交给 tap
+func newInterleavedReader(channels int, frames int64, sampleWidth int, integer bool, read func(dst []float64, offset int64, data []byte, tap *intTap) error) types.SampleReader {
+	const chunkFrames = 4096
+	interleaved := make([]float64, chunkFrames*channels)
+	data := make([]byte, chunkFrames*channels*sampleWidth)
+	out := make([][]float64, channels)
+	for ch := range out {
+		out[ch] = make([]float64, chunkFrames)
+	}
+
+	var tap *intTap
+	if integer {
+		tap = &intTap{}
+	}
+
+	var pos int64
+	reader := newFrameReader(func() ([][]float64, error) {
+		if pos >= frames {
+			return nil, io.EOF
+		}
+		n := int64(chunkFrames)
+		if n > frames-pos {
+			n = frames - pos
+		}
+		count := int(n) * channels
+		if err := read(interleaved[:count], pos*int64(channels*sampleWidth), data[:count*sampleWidth], tap); err != nil {
+			return nil, err
+		}
+		for ch := range out {
+			out[ch] = out[ch][:n]
+			for i := range out[ch] {
+				out[ch][i] = interleaved[i*channels+ch]
+			}
+		}
+		pos += n
+		return out, nil
+	})
+	reader.tap = tap
+	return reader
+}
@@ -1,74 +1,300 @@
 package decoder
 
 import (
+	"bytes"
+	"encoding/binary"
 	"fmt"
-	"os"
+	"io"
+	"math"
+	"math/bits"
 	"time"
 
 	"audio-loss-checker/internal/types"
-
-	"github.com/go-audio/audio"
-	"github.com/go-audio/wav"
 )
 
-// WAVDecoder WAV格式解码器
+// WAVDecoder WAV格式解码器，支持RIFF、RF64/BW64和Sony Wave64容器
 type WAVDecoder struct{}
 
 // WAVFile WAV文件实现
 type WAVFile struct {
-	decoder    *wav.Decoder
-	file       *os.File
-	format     *audio.Format
-	sampleRate int
-	bitDepth   int
-	channels   int
-	duration   time.Duration
-	samples    []float64
+	file        *Source
+	container   string // "RIFF", "RF64", "BW64", "W64"
+	float       bool   // IEEE浮点采样
+	channelMask uint32 // WAVE_FORMAT_EXTENSIBLE 的声道掩码，其他格式为0
+	dataOffset  int64
+	dataSize    int64
+	frames      int64
+	sampleWidth int // 每个采样占用的字节数
+	sampleRate  int
+	bitDepth    int // 有效位数
+	channels    int
+	duration    time.Duration
+	samples     [][]float64
 }
 
+// WAV编码格式标识
+const (
+	wavFormatPCM        = 0x0001
+	wavFormatIEEEFloat  = 0x0003
+	wavFormatExtensible = 0xfffe
+)
+
+var (
+	// wavSubFormatSuffix WAVE_FORMAT_EXTENSIBLE 子格式GUID中编码格式标识之后的固定部分
+	wavSubFormatSuffix = []byte{0x00, 0x00, 0x00, 0x00, 0x10, 0x00, 0x80, 0x00, 0x00, 0xaa, 0x00, 0x38, 0x9b, 0x71}
+
+	// Wave64的块以GUID标识，其中 riff/wave/fmt/data 的GUID以对应的四字符码开头
+	w64RIFF = []byte{0x72, 0x69, 0x66, 0x66, 0x2e, 0x91, 0xcf, 0x11, 0xa5, 0xd6, 0x28, 0xdb, 0x04, 0xc1, 0x00, 0x00}
+	w64WAVE = []byte{0x77, 0x61, 0x76, 0x65, 0xf3, 0xac, 0xd3, 0x11, 0x8c, 0xd1, 0x00, 0xc0, 0x4f, 0x8e, 0xdb, 0x8a}
+	w64FMT  = []byte{0x66, 0x6d, 0x74, 0x20, 0xf3, 0xac, 0xd3, 0x11, 0x8c, 0xd1, 0x00, 0xc0, 0x4f, 0x8e, 0xdb, 0x8a}
+	w64DATA = []byte{0x64, 0x61, 0x74, 0x61, 0xf3, 0xac, 0xd3, 0x11, 0x8c, 0xd1, 0x00, 0xc0, 0x4f, 0x8e, 0xdb, 0x8a}
+)
+
 // SupportedFormats 返回支持的格式
 func (d *WAVDecoder) SupportedFormats() []string {
-	return []string{"wav"}
+	return []string{"wav", "w64"}
 }
 
 // Decode 解码WAV文件
-func (d *WAVDecoder) Decode(filePath string) (types.AudioFile, error) {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("打开WAV文件失败: %w", err)
+func (d *WAVDecoder) Decode(file *Source) (types.AudioFile, error) {
+	wavFile := &WAVFile{file: file}
+	if err := wavFile.parseHeader(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("解析WAV文件失败: %w", err)
 	}
 
-	decoder := wav.NewDecoder(file)
-	if !decoder.IsValidFile() {
-		file.Close()
-		return nil, fmt.Errorf("无效的WAV文件: %s", filePath)
+	wavFile.duration = time.Duration(float64(wavFile.frames) / float64(wavFile.sampleRate) * float64(time.Second))
+
+	return wavFile, nil
+}
+
+// parseHeader 识别容器类型并解析各个块
+func (w *WAVFile) parseHeader() error {
+	header := make([]byte, 40)
+	n, err := io.ReadFull(w.file, header)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	header = header[:n]
+
+	switch {
+	case len(header) >= 40 && bytes.Equal(header[0:16], w64RIFF) && bytes.Equal(header[24:40], w64WAVE):
+		w.container = "W64"
+		return w.parseWave64()
+	case len(header) >= 12 && string(header[8:12]) == "WAVE":
+		switch string(header[0:4]) {
+		case "RIFF", "RF64", "BW64":
+			w.container = string(header[0:4])
+			return w.parseRIFF()
+		}
+	}
+	return fmt.Errorf("无效的WAV文件标识")
+}
+
+// parseRIFF 解析RIFF/RF64/BW64容器
+// RF64/BW64中超过4GB的块大小记为0xFFFFFFFF，实际大小放在紧跟文件头的 ds64 块中
+func (w *WAVFile) parseRIFF() error {
+	var ds64DataSize int64 = -1
+	gotFmt, gotData := false, false
+	chunkHeader := make([]byte, 8)
+	offset := int64(12)
+
+	for {
+		if _, err := w.file.ReadAt(chunkHeader, offset); err != nil {
+			break
+		}
+		id := string(chunkHeader[0:4])
+		size := int64(binary.LittleEndian.Uint32(chunkHeader[4:8]))
+		body := offset + 8
+
+		switch id {
+		case "ds64":
+			data, err := w.readChunk(body, size)
+			if err != nil {
+				return err
+			}
+			if len(data) < 24 {
+				return fmt.Errorf("ds64块过短")
+			}
+			ds64DataSize = int64(binary.LittleEndian.Uint64(data[8:16]))
+			if ds64DataSize < 0 {
+				return fmt.Errorf("ds64块中的data大小无效")
+			}
+
+		case "fmt ":
+			data, err := w.readChunk(body, size)
+			if err != nil {
+				return err
+			}
+			if err := w.parseFmt(data); err != nil {
+				return err
+			}
+			gotFmt = true
+
+		case "data":
+			if size == 0xffffffff && w.container != "RIFF" {
+				if ds64DataSize < 0 {
+					return fmt.Errorf("%s文件缺少ds64块", w.container)
+				}
+				size = ds64DataSize
+			}
+			w.dataOffset = body
+			w.dataSize = size
+			gotData = true
+		}
+
+		if gotFmt && gotData {
+			break
+		}
+		// 块大小为奇数时有一个填充字节
+		offset = body + size + size&1
 	}
 
-	// 获取音频格式信息
-	format := &audio.Format{
-		NumChannels: int(decoder.NumChans),
-		SampleRate:  int(decoder.SampleRate),
+	return w.finish(gotFmt, gotData)
+}
+
+// parseWave64 解析Sony Wave64容器：块以16字节GUID标识，块大小为64位且包含24字节的块头，按8字节对齐
+func (w *WAVFile) parseWave64() error {
+	gotFmt, gotData := false, false
+	chunkHeader := make([]byte, 24)
+	offset := int64(40)
+
+	for {
+		if _, err := w.file.ReadAt(chunkHeader, offset); err != nil {
+			break
+		}
+		guid := chunkHeader[0:16]
+		size := int64(binary.LittleEndian.Uint64(chunkHeader[16:24]))
+		if size < 24 || size > math.MaxInt64-7 {
+			return fmt.Errorf("Wave64块大小无效")
+		}
+		body := offset + 24
+
+		switch {
+		case bytes.Equal(guid, w64FMT):
+			data, err := w.readChunk(body, size-24)
+			if err != nil {
+				return err
+			}
+			if err := w.parseFmt(data); err != nil {
+				return err
+			}
+			gotFmt = true
+
+		case bytes.Equal(guid, w64DATA):
+			w.dataOffset = body
+			w.dataSize = size - 24
+			gotData = true
+		}
+
+		if gotFmt && gotData {
+			break
+		}
+		offset += (size + 7) &^ 7
 	}
-	bitDepth := int(decoder.BitDepth)
 
-	// 计算时长
-	duration := time.Duration(float64(decoder.PCMLen()) / float64(format.SampleRate) * float64(time.Second))
+	return w.finish(gotFmt, gotData)
+}
 
-	wavFile := &WAVFile{
-		decoder:    decoder,
-		file:       file,
-		format:     format,
-		sampleRate: format.SampleRate,
-		bitDepth:   bitDepth,
-		channels:   format.NumChannels,
-		duration:   duration,
+// finish 检查必需的块并计算采样帧数
+func (w *WAVFile) finish(gotFmt, gotData bool) error {
+	if !gotFmt {
+		return fmt.Errorf("缺少fmt块")
+	}
+	if !gotData {
+		return fmt.Errorf("缺少data块")
 	}
 
-	return wavFile, nil
+	// 文件被截断时以实际数据为准
+	if size := w.file.Size(); w.dataOffset+w.dataSize > size {
+		w.dataSize = size - w.dataOffset
+	}
+	w.frames = w.dataSize / int64(w.sampleWidth*w.channels)
+	return nil
+}
+
+// readChunk 读取块内容，块大小超出文件末尾时返回错误，不按损坏的大小分配内存
+func (w *WAVFile) readChunk(offset, size int64) ([]byte, error) {
+	if size < 0 || size > w.file.Size()-offset {
+		return nil, fmt.Errorf("WAV块大小超出文件末尾: %d", size)
+	}
+	data := make([]byte, size)
+	if _, err := w.file.ReadAt(data, offset); err != nil {
+		return nil, fmt.Errorf("读取WAV块失败: %w", err)
+	}
+	return data, nil
+}
+
+// parseFmt 解析fmt块，包括 WAVE_FORMAT_EXTENSIBLE 的扩展字段
+func (w *WAVFile) parseFmt(data []byte) error {
+	if len(data) < 16 {
+		return fmt.Errorf("fmt块过短")
+	}
+
+	formatTag := binary.LittleEndian.Uint16(data[0:2])
+	w.channels = int(binary.LittleEndian.Uint16(data[2:4]))
+	w.sampleRate = int(binary.LittleEndian.Uint32(data[4:8]))
+	blockAlign := int(binary.LittleEndian.Uint16(data[12:14]))
+	w.bitDepth = int(binary.LittleEndian.Uint16(data[14:16]))
+
+	if w.channels <= 0 {
+		return fmt.Errorf("无效的声道数: %d", w.channels)
+	}
+	if w.sampleRate <= 0 {
+		return fmt.Errorf("无效的采样率: %d", w.sampleRate)
+	}
+
+	if formatTag == wavFormatExtensible {
+		if len(data) < 40 {
+			return fmt.Errorf("WAVE_FORMAT_EXTENSIBLE的fmt块过短")
+		}
+		if validBits := int(binary.LittleEndian.Uint16(data[18:20])); validBits > 0 && validBits <= w.bitDepth {
+			w.bitDepth = validBits
+		}
+		w.channelMask = binary.LittleEndian.Uint32(data[20:24])
+		if !bytes.Equal(data[26:40], wavSubFormatSuffix) {
+			return fmt.Errorf("不支持的WAVE_FORMAT_EXTENSIBLE子格式")
+		}
+		formatTag = binary.LittleEndian.Uint16(data[24:26])
+
+		// 声道掩码中的声道数不能多于实际声道数（掩码为0表示未指定声道位置）
+		if bits.OnesCount32(w.channelMask) > w.channels {
+			return fmt.Errorf("声道掩码 0x%x 与声道数 %d 不符", w.channelMask, w.channels)
+		}
+	}
+
+	// 每个采样的字节数以 blockAlign 为准，有效位数可能小于存储位数（如24位存放在32位中）
+	w.sampleWidth = blockAlign / w.channels
+	if w.sampleWidth <= 0 || blockAlign%w.channels != 0 {
+		return fmt.Errorf("无效的块对齐: %d", blockAlign)
+	}
+
+	switch formatTag {
+	case wavFormatPCM:
+		if w.sampleWidth > 4 || w.bitDepth < 1 || w.bitDepth > w.sampleWidth*8 {
+			return fmt.Errorf("不支持的位深度: %d", w.bitDepth)
+		}
+	case wavFormatIEEEFloat:
+		if w.sampleWidth != 4 && w.sampleWidth != 8 {
+			return fmt.Errorf("不支持的浮点位深度: %d", w.sampleWidth*8)
+		}
+		w.float = true
+		w.bitDepth = w.sampleWidth * 8
+	default:
+		return fmt.Errorf("不支持的WAV编码格式: 0x%04x", formatTag)
+	}
+
+	return nil
 }
 
 // GetFormat 获取格式名称
 func (w *WAVFile) GetFormat() string {
+	switch w.container {
+	case "RF64", "BW64":
+		return "WAV (" + w.container + ")"
+	case "W64":
+		return "Wave64"
+	}
 	return "WAV"
 }
 
@@ -92,40 +318,76 @@ func (w *WAVFile) GetDuration() time.Duration {
 	return w.duration
 }
 
-// GetSamples 获取音频采样数据
-func (w *WAVFile) GetSamples() ([]float64, error) {
+// GetSamples 获取按声道分开的音频采样数据
+func (w *WAVFile) GetSamples() ([][]float64, error) {
 	if w.samples != nil {
 		return w.samples, nil
 	}
 
-	// 读取所有音频数据
-	buf := &audio.IntBuffer{
-		Format: &audio.Format{
-			NumChannels: w.channels,
-			SampleRate:  w.sampleRate,
-		},
+	reader, err := w.NewSampleReader()
+	if err != nil {
+		return nil, err
+	}
+	samples, err := readAllSamples(reader, w.channels)
+	if err != nil {
+		return nil, err
 	}
 
-	for {
-		n, err := w.decoder.PCMBuffer(buf)
-		if err != nil {
-			break
-		}
-		if n == 0 {
-			break
+	w.samples = samples
+	return samples, nil
+}
+
+// NewSampleReader 创建采样流，每次从data块中读取一段
+func (w *WAVFile) NewSampleReader() (types.SampleReader, error) {
+	return newInterleavedReader(w.channels, w.frames, w.sampleWidth, !w.float, func(dst []float64, offset int64, data []byte, tap *intTap) error {
+		if _, err := w.file.ReadAt(data, w.dataOffset+offset); err != nil {
+			return fmt.Errorf("读取WAV音频数据失败: %w", err)
 		}
-	}
+		w.convertSamples(dst, data, tap)
+		return nil
+	}), nil
+}
 
-	// 转换为float64格式
-	samples := make([]float64, len(buf.Data))
-	maxVal := float64(int(1) << uint(w.bitDepth-1))
+// convertSamples 把小端序采样转换为 [-1, 1) 范围的浮点数
+// 整数采样左对齐存放，按存储位数归一化；8位PCM为无符号数；浮点采样原样使用
+// 整数采样在归一化之前按声明的位深度右对齐后交给 tap
+func (w *WAVFile) convertSamples(dst []float64, data []byte, tap *intTap) {
+	width := w.sampleWidth
+	ints := tap.buffer(len(dst))
 
-	for i, sample := range buf.Data {
-		samples[i] = float64(sample) / maxVal
+	switch {
+	case w.float && width == 4:
+		for i := range dst {
+			dst[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:])))
+		}
+	case w.float:
+		for i := range dst {
+			dst[i] = math.Float64frombits(binary.LittleEndian.Uint64(data[i*8:]))
+		}
+	case width == 1:
+		for i := range dst {
+			dst[i] = (float64(data[i]) - 128) / 128
+			if ints != nil {
+				ints[i] = int32(data[i]) - 128
+			}
+		}
+		tap.observe(ints)
+	default:
+		scale := 1 / float64(uint32(1)<<31)
+		shift := uint(32 - w.bitDepth)
+		for i := range dst {
+			b := data[i*width : (i+1)*width]
+			var v uint32
+			for j := 0; j < width; j++ {
+				v |= uint32(b[j]) << uint(8*(4-width+j))
+			}
+			dst[i] = float64(int32(v)) * scale
+			if ints != nil {
+				ints[i] = int32(v) >> shift
+			}
+		}
+		tap.observe(ints)
 	}
-
-	w.samples = samples
-	return samples, nil
 }
 
 // GetMetadata 获取元数据
@@ -0,0 +1,294 @@
+package decoder
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+)
+
+// buildWAV 生成带fmt和data块的RIFF WAVE文件，fmtChunk 为fmt块内容
+func buildWAV(fmtChunk, data []byte) []byte {
+	le := binary.LittleEndian
+	body := []byte("WAVE")
+	body = append(body, "fmt "...)
+	body = le.AppendUint32(body, uint32(len(fmtChunk)))
+	body = append(body, fmtChunk...)
+	body = append(body, "data"...)
+	body = le.AppendUint32(body, uint32(len(data)))
+	body = append(body, data...)
+	if len(data)%2 == 1 {
+		body = append(body, 0)
+	}
+	return append(le.AppendUint32([]byte("RIFF"), uint32(len(body))), body...)
+}
+
+// pcmFormat 生成WAVE_FORMAT_PCM（或 IEEE float）的fmt块内容
+func pcmFormat(formatTag uint16, channels, rate, bits int) []byte {
+	le := binary.LittleEndian
+	blockAlign := channels * bits / 8
+	f := le.AppendUint16(nil, formatTag)
+	f = le.AppendUint16(f, uint16(channels))
+	f = le.AppendUint32(f, uint32(rate))
+	f = le.AppendUint32(f, uint32(rate*blockAlign))
+	f = le.AppendUint16(f, uint16(blockAlign))
+	return le.AppendUint16(f, uint16(bits))
+}
+
+// extensibleFormat 生成 WAVE_FORMAT_EXTENSIBLE 的fmt块内容：采样以 containerBits 位存放，有效位数为 validBits
+func extensibleFormat(subFormat uint16, channels, rate, containerBits, validBits int, channelMask uint32) []byte {
+	le := binary.LittleEndian
+	f := pcmFormat(wavFormatExtensible, channels, rate, containerBits)
+	f = le.AppendUint16(f, 22)
+	f = le.AppendUint16(f, uint16(validBits))
+	f = le.AppendUint32(f, channelMask)
+	f = le.AppendUint16(f, subFormat)
+	return append(f, wavSubFormatSuffix...)
+}
+
+// buildRF64 生成RF64文件：RIFF和data块的大小记为0xFFFFFFFF，实际大小放在ds64块中，data块之后还有一个LIST块
+func buildRF64(fmtChunk, data []byte) []byte {
+	le := binary.LittleEndian
+	list := append([]byte("LIST"), le.AppendUint32(nil, 4)...)
+	list = append(list, "INFO"...)
+
+	out := append([]byte("RF64"), 0xff, 0xff, 0xff, 0xff)
+	out = append(out, "WAVE"...)
+	out = append(out, "ds64"...)
+	out = le.AppendUint32(out, 28)
+	out = le.AppendUint64(out, uint64(4+36+8+len(fmtChunk)+8+len(data)+len(list)))
+	out = le.AppendUint64(out, uint64(len(data)))
+	out = le.AppendUint64(out, 0) // 采样数（不使用）
+	out = le.AppendUint32(out, 0) // 没有其他块的大小表
+	out = append(out, "fmt "...)
+	out = le.AppendUint32(out, uint32(len(fmtChunk)))
+	out = append(out, fmtChunk...)
+	out = append(out, "data"...)
+	out = append(out, 0xff, 0xff, 0xff, 0xff)
+	out = append(out, data...)
+	return append(out, list...)
+}
+
+// w64Chunk 生成一个Wave64块：块大小包含24字节的块头，块按8字节对齐
+func w64Chunk(guid, body []byte) []byte {
+	chunk := append(bytes.Clone(guid), binary.LittleEndian.AppendUint64(nil, uint64(24+len(body)))...)
+	chunk = append(chunk, body...)
+	if pad := len(chunk) % 8; pad != 0 {
+		chunk = append(chunk, make([]byte, 8-pad)...)
+	}
+	return chunk
+}
+
+// buildW64 生成Wave64文件：fmt块之前有一个需要对齐的未知块，data块之后还有一个未知块
+func buildW64(fmtChunk, data []byte) []byte {
+	junk := append([]byte("junk"), w64FMT[4:]...)
+	body := bytes.Join([][]byte{
+		w64Chunk(junk, []byte("12345")),
+		w64Chunk(w64FMT, fmtChunk),
+		w64Chunk(w64DATA, data),
+		w64Chunk(junk, []byte("trailer")),
+	}, nil)
+	out := append(bytes.Clone(w64RIFF), binary.LittleEndian.AppendUint64(nil, uint64(40+len(body)))...)
+	out = append(out, w64WAVE...)
+	return append(out, body...)
+}
+
+func TestWAV(t *testing.T) {
+	pcm16 := testSignal(2, 1001, 16)
+	pcm20 := testSignal(2, 1000, 20)
+	pcm24 := testSignal(1, 1000, 24)
+	// 8位PCM为无符号数
+	pcm8 := testSignal(1, 1000, 8)
+	var unsigned8 []byte
+	for _, v := range pcm8[0] {
+		unsigned8 = append(unsigned8, byte(v+128))
+	}
+	// 有效位数小于存储位数时采样左对齐，低位补0
+	shifted := func(samples [][]int32, shift uint) [][]int32 {
+		out := make([][]int32, len(samples))
+		for ch := range samples {
+			for _, v := range samples[ch] {
+				out[ch] = append(out[ch], v<<shift)
+			}
+		}
+		return out
+	}
+	// 奇数长度的data块之后有一个填充字节
+	odd8 := testSignal(1, 999, 8)
+	var oddUnsigned []byte
+	for _, v := range odd8[0] {
+		oddUnsigned = append(oddUnsigned, byte(v+128))
+	}
+
+	tests := []struct {
+		name   string
+		file   string
+		data   []byte
+		format string
+		rate   int
+		bits   int
+		want   [][]int32
+	}{
+		{
+			name: "16位PCM", file: "test.wav",
+			data:   buildWAV(pcmFormat(wavFormatPCM, 2, 44100, 16), packPCM(pcm16, 2, true)),
+			format: "WAV", rate: 44100, bits: 16, want: pcm16,
+		},
+		{
+			name: "8位无符号PCM", file: "test.wav",
+			data:   buildWAV(pcmFormat(wavFormatPCM, 1, 22050, 8), unsigned8),
+			format: "WAV", rate: 22050, bits: 8, want: pcm8,
+		},
+		{
+			name: "8位奇数长度", file: "test.wav",
+			data:   buildWAV(pcmFormat(wavFormatPCM, 1, 8000, 8), oddUnsigned),
+			format: "WAV", rate: 8000, bits: 8, want: odd8,
+		},
+		{
+			name: "24位PCM", file: "test.wav",
+			data:   buildWAV(pcmFormat(wavFormatPCM, 1, 96000, 24), packPCM(pcm24, 3, true)),
+			format: "WAV", rate: 96000, bits: 24, want: pcm24,
+		},
+		{
+			// 20位有效数据存放在24位中，按20位归一化
+			name: "扩展格式20位存放在24位中", file: "test.wav",
+			data:   buildWAV(extensibleFormat(wavFormatPCM, 2, 48000, 24, 20, 0x3), packPCM(shifted(pcm20, 4), 3, true)),
+			format: "WAV", rate: 48000, bits: 20, want: pcm20,
+		},
+		{
+			name: "扩展格式24位存放在32位中", file: "test.wav",
+			data:   buildWAV(extensibleFormat(wavFormatPCM, 1, 192000, 32, 24, 0x4), packPCM(shifted(pcm24, 8), 4, true)),
+			format: "WAV", rate: 192000, bits: 24, want: pcm24,
+		},
+		{
+			// ds64中的大小代替0xFFFFFFFF，data块之后的LIST块不算作采样
+			name: "RF64", file: "test.wav",
+			data:   buildRF64(pcmFormat(wavFormatPCM, 2, 44100, 16), packPCM(pcm16, 2, true)),
+			format: "WAV (RF64)", rate: 44100, bits: 16, want: pcm16,
+		},
+		{
+			name: "Wave64", file: "test.w64",
+			data:   buildW64(extensibleFormat(wavFormatPCM, 1, 48000, 24, 24, 0), packPCM(pcm24, 3, true)),
+			format: "Wave64", rate: 48000, bits: 24, want: pcm24,
+		},
+		{
+			// 每帧2字节、1001帧的单声道数据之后需要6字节填充
+			name: "Wave64对齐", file: "test.w64",
+			data:   buildW64(pcmFormat(wavFormatPCM, 1, 44100, 16), packPCM(pcm16[:1], 2, true)),
+			format: "Wave64", rate: 44100, bits: 16, want: pcm16[:1],
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			audioFile, samples := decodeTestData(t, tt.file, tt.data)
+			if audioFile.GetFormat() != tt.format || audioFile.GetSampleRate() != tt.rate ||
+				audioFile.GetBitDepth() != tt.bits || audioFile.GetChannels() != len(tt.want) {
+				t.Fatalf("格式为 %s %d Hz %d位 %d声道", audioFile.GetFormat(), audioFile.GetSampleRate(),
+					audioFile.GetBitDepth(), audioFile.GetChannels())
+			}
+			checkSamples(t, samples, tt.want)
+		})
+	}
+}
+
+// 浮点采样原样使用：超出±1.0的值不削波也不折返，保留原有的频谱
+func TestWAVFloat(t *testing.T) {
+	values := []float64{0, 0.5, -0.25, 1, -1, 1.5, -2, 3.0517578125e-05, -1e-3}
+	le := binary.LittleEndian
+
+	var data32, data64 []byte
+	for _, v := range values {
+		data32 = le.AppendUint32(data32, math.Float32bits(float32(v)))
+		data64 = le.AppendUint64(data64, math.Float64bits(v))
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		bits int
+	}{
+		{"float32", buildWAV(pcmFormat(wavFormatIEEEFloat, 1, 44100, 32), data32), 32},
+		{"float64", buildWAV(pcmFormat(wavFormatIEEEFloat, 1, 44100, 64), data64), 64},
+		{"扩展格式float32", buildWAV(extensibleFormat(wavFormatIEEEFloat, 1, 44100, 32, 32, 0x4), data32), 32},
+	}
+	for _, tt := range tests {
+		audioFile, err := NewDecoderRegistry().DecodeReader(bytes.NewReader(tt.data), "test.wav")
+		if err != nil {
+			t.Fatalf("%s: 解码失败: %v", tt.name, err)
+		}
+		defer audioFile.Close()
+		if audioFile.GetBitDepth() != tt.bits {
+			t.Errorf("%s: 位深度为 %d", tt.name, audioFile.GetBitDepth())
+		}
+		samples, err := audioFile.GetSamples()
+		if err != nil {
+			t.Fatalf("%s: 读取采样失败: %v", tt.name, err)
+		}
+		if len(samples) != 1 || len(samples[0]) != len(values) {
+			t.Fatalf("%s: 解码出 %d 帧", tt.name, len(samples[0]))
+		}
+		for i, want := range values {
+			if tt.bits == 32 {
+				want = float64(float32(want))
+			}
+			if samples[0][i] != want {
+				t.Errorf("%s: 第 %d 个采样为 %g，应为 %g", tt.name, i, samples[0][i], want)
+			}
+		}
+	}
+}
+
+// 块大小损坏时报告错误，而不是按声明的大小分配内存
+func TestWAVCorruptChunkSize(t *testing.T) {
+	le := binary.LittleEndian
+	pcm := packPCM(testSignal(2, 100, 16), 2, true)
+
+	riff := buildWAV(pcmFormat(wavFormatPCM, 2, 44100, 16), pcm)
+	le.PutUint32(riff[16:], 0xfffffff0) // fmt块大小
+
+	rf64 := buildRF64(pcmFormat(wavFormatPCM, 2, 44100, 16), pcm)
+	le.PutUint32(rf64[16:], 0x7ffffff0) // ds64块大小
+
+	w64 := buildW64(pcmFormat(wavFormatPCM, 2, 44100, 16), pcm)
+	fmtHeader := 40 + 32 + 16
+	le.PutUint64(w64[fmtHeader:], 1<<40) // fmt块大小
+
+	overflow := buildW64(pcmFormat(wavFormatPCM, 2, 44100, 16), pcm)
+	le.PutUint64(overflow[40+16:], math.MaxInt64) // fmt之前的未知块，跳过时会溢出
+
+	tests := []struct {
+		name    string
+		file    string
+		data    []byte
+		wantErr string
+	}{
+		{"RIFF fmt", "test.wav", riff, "超出文件末尾"},
+		{"RF64 ds64", "test.wav", rf64, "超出文件末尾"},
+		{"Wave64 fmt", "test.w64", w64, "超出文件末尾"},
+		{"Wave64 溢出", "test.w64", overflow, "Wave64块大小无效"},
+	}
+	for _, tt := range tests {
+		_, err := NewDecoderRegistry().DecodeReader(bytes.NewReader(tt.data), tt.file)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: 错误为 %v，应包含 %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+// data块被截断时按实际数据长度解码；RF64缺少ds64时无法确定data块大小
+func TestWAVTruncated(t *testing.T) {
+	pcm := testSignal(2, 1000, 16)
+	data := buildWAV(pcmFormat(wavFormatPCM, 2, 44100, 16), packPCM(pcm, 2, true))
+	_, samples := decodeTestData(t, "test.wav", data[:len(data)-400])
+	want := [][]int32{pcm[0][:900], pcm[1][:900]}
+	checkSamples(t, samples, want)
+
+	rf64 := buildRF64(pcmFormat(wavFormatPCM, 2, 44100, 16), packPCM(pcm, 2, true))
+	copy(rf64[12:16], "JUNK")
+	_, err := NewDecoderRegistry().DecodeReader(bytes.NewReader(rf64), "test.wav")
+	if err == nil || !strings.Contains(err.Error(), "缺少ds64块") {
+		t.Errorf("缺少ds64块时错误为 %v", err)
+	}
+}
@@ -0,0 +1,1108 @@
+package decoder
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"audio-loss-checker/internal/types"
+)
+
+// WavPackDecoder WavPack格式解码器
+type WavPackDecoder struct{}
+
+// WavPackFile WavPack文件实现
+type WavPackFile struct {
+	file           *Source
+	blocks         []wvBlockHeader
+	sampleRate     int
+	bitDepth       int
+	channels       int
+	duration       time.Duration
+	hybrid         bool   // 是否为混合模式
+	correctionFile string // 混合模式的 .wvc 校正文件路径
+	samples        [][]float64
+	metadata       types.AudioMetadata
+}
+
+// wvBlockHeader WavPack块头
+type wvBlockHeader struct {
+	offset       int64
+	size         int64 // 含32字节块头
+	version      uint16
+	totalSamples int64
+	blockIndex   int64
+	blockSamples uint32
+	flags        uint32
+	crc          uint32
+}
+
+const (
+	wvHeaderSize = 32
+
+	wvFlagBytesStored  = 0x3
+	wvFlagMono         = 0x4
+	wvFlagHybrid       = 0x8
+	wvFlagJointStereo  = 0x10
+	wvFlagFloat        = 0x80
+	wvFlagHybridRate   = 0x200
+	wvFlagInitialBlock = 0x800
+	wvFlagFinalBlock   = 0x1000
+	wvFlagShiftLSB     = 13
+	wvFlagSRateLSB     = 23
+	wvFlagFalseStereo  = 0x40000000
+	wvFlagDSD          = 0x80000000
+
+	wvIDLarge          = 0x80
+	wvIDOddSize        = 0x40
+	wvIDDecorrTerms    = 0x2
+	wvIDDecorrWeights  = 0x3
+	wvIDDecorrSamples  = 0x4
+	wvIDEntropyVars    = 0x5
+	wvIDHybridProfile  = 0x6
+	wvIDInt32Info      = 0x9
+	wvIDWVBitstream    = 0xa
+	wvIDChannelInfo    = 0xd
+	wvIDSampleRate     = 0x27
+	wvMaxTerms         = 16
+	wvMinStreamVersion = 0x402
+	wvMaxStreamVersion = 0x410
+)
+
+// wvSampleRates 块头中采样率索引对应的采样率
+var wvSampleRates = [15]int{
+	6000, 8000, 9600, 11025, 12000, 16000, 22050, 24000,
+	32000, 44100, 48000, 64000, 88200, 96000, 192000,
+}
+
+// SupportedFormats 返回支持的格式
+func (d *WavPackDecoder) SupportedFormats() []string {
+	return []string{"wv"}
+}
+
+// Decode 解码WavPack文件
+func (d *WavPackDecoder) Decode(file *Source) (types.AudioFile, error) {
+	blocks, err := scanWavPackBlocks(file, file.Size())
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("解析WavPack文件失败: %w", err)
+	}
+
+	wvFile := &WavPackFile{
+		file:   file,
+		blocks: blocks,
+	}
+
+	if err := wvFile.parseStreamInfo(); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	// 混合模式需要 .wvc 校正文件才能还原为无损；调用方提供的数据没有所在目录，视为缺少校正文件
+	if wvFile.hybrid && file.path != "" {
+		wvFile.correctionFile = FindCorrectionFile(file.path)
+	}
+
+	// 解析APEv2标签
+	if tags, _ := readAPETag(file, file.Size()); tags != nil {
+		wvFile.metadata = apeTagMetadata(tags)
+	}
+	wvFile.metadata.Duration = wvFile.duration.String()
+
+	return wvFile, nil
+}
+
+// scanWavPackBlocks 扫描文件中所有WavPack块的位置
+func scanWavPackBlocks(r io.ReadSeeker, fileSize int64) ([]wvBlockHeader, error) {
+	head := make([]byte, wvHeaderSize)
+	if _, err := io.ReadFull(r, head[:10]); err != nil {
+		return nil, err
+	}
+
+	var blocks []wvBlockHeader
+	offset := id3v2TagSize(head[:10])
+
+	for offset+wvHeaderSize <= fileSize {
+		if _, err := r.Seek(offset, io.SeekStart); err != nil {
+			return nil, err
+		}
+		if _, err := io.ReadFull(r, head); err != nil {
+			return nil, err
+		}
+		if string(head[0:4]) != "wvpk" {
+			// 文件末尾为APE/ID3标签
+			break
+		}
+
+		hdr := wvBlockHeader{
+			offset:       offset,
+			size:         int64(binary.LittleEndian.Uint32(head[4:8])) + 8,
+			version:      binary.LittleEndian.Uint16(head[8:10]),
+			totalSamples: int64(binary.LittleEndian.Uint32(head[12:16])) | int64(head[11])<<32,
+			blockIndex:   int64(binary.LittleEndian.Uint32(head[16:20])) | int64(head[10])<<32,
+			blockSamples: binary.LittleEndian.Uint32(head[20:24]),
+			flags:        binary.LittleEndian.Uint32(head[24:28]),
+			crc:          binary.LittleEndian.Uint32(head[28:32]),
+		}
+		if binary.LittleEndian.Uint32(head[12:16]) == 0xffffffff {
+			hdr.totalSamples = -1
+		}
+
+		if hdr.size < wvHeaderSize || offset+hdr.size > fileSize {
+			return nil, fmt.Errorf("WavPack块大小无效: 偏移 %d", offset)
+		}
+		if hdr.version < wvMinStreamVersion || hdr.version > wvMaxStreamVersion {
+			return nil, fmt.Errorf("不支持的WavPack版本: 0x%x", hdr.version)
+		}
+
+		blocks = append(blocks, hdr)
+		offset += hdr.size
+	}
+
+	if len(blocks) == 0 {
+		return nil, fmt.Errorf("无效的WavPack文件标识")
+	}
+
+	return blocks, nil
+}
+
+// parseStreamInfo 从第一组音频块中读取流参数
+func (f *WavPackFile) parseStreamInfo() error {
+	firstIdx := -1
+	for i := range f.blocks {
+		if f.blocks[i].blockSamples > 0 {
+			firstIdx = i
+			break
+		}
+	}
+	if firstIdx < 0 {
+		return fmt.Errorf("WavPack文件不包含音频数据")
+	}
+	first := &f.blocks[firstIdx]
+
+	if first.flags&wvFlagDSD != 0 {
+		return fmt.Errorf("暂不支持DSD格式的WavPack文件")
+	}
+	if first.flags&wvFlagFloat != 0 {
+		return fmt.Errorf("暂不支持浮点格式的WavPack文件")
+	}
+
+	bytesPerSample := int(first.flags&wvFlagBytesStored) + 1
+	shift := int(first.flags>>wvFlagShiftLSB) & 0x1f
+	f.bitDepth = bytesPerSample*8 - shift
+
+	// 统计一组多声道块中的声道数
+	for _, block := range f.blocks[firstIdx:] {
+		if block.flags&wvFlagMono != 0 {
+			f.channels++
+		} else {
+			f.channels += 2
+		}
+		if block.flags&wvFlagFinalBlock != 0 {
+			break
+		}
+	}
+
+	for _, block := range f.blocks {
+		if block.flags&wvFlagHybrid != 0 {
+			f.hybrid = true
+			break
+		}
+	}
+
+	// 采样率索引15表示自定义采样率，存储在元数据子块中
+	rateIndex := int(first.flags>>wvFlagSRateLSB) & 0xf
+	if rateIndex < len(wvSampleRates) {
+		f.sampleRate = wvSampleRates[rateIndex]
+	} else {
+		data, err := f.readBlock(first)
+		if err != nil {
+			return err
+		}
+		for _, sub := range wvSubBlocks(data) {
+			if sub.id == wvIDSampleRate && len(sub.data) >= 3 {
+				f.sampleRate = int(sub.data[0]) | int(sub.data[1])<<8 | int(sub.data[2])<<16
+			}
+		}
+	}
+	if f.sampleRate <= 0 {
+		return fmt.Errorf("无法确定WavPack采样率")
+	}
+
+	totalSamples := first.totalSamples
+	if totalSamples < 0 {
+		totalSamples = 0
+		for _, block := range f.blocks {
+			if block.flags&wvFlagInitialBlock != 0 {
+				totalSamples += int64(block.blockSamples)
+			}
+		}
+	}
+	f.duration = time.Duration(float64(totalSamples) / float64(f.sampleRate) * float64(time.Second))
+
+	return nil
+}
+
+// FindCorrectionFile 查找与 .wv 文件同名的 .wvc 校正文件
+func FindCorrectionFile(filePath string) string {
+	base := strings.TrimSuffix(filePath, filepath.Ext(filePath))
+	for _, ext := range []string{".wvc", ".WVC", ".Wvc"} {
+		if _, err := os.Stat(base + ext); err == nil {
+			return base + ext
+		}
+	}
+	return ""
+}
+
+// readBlock 读取整个块（不含块头）
+func (f *WavPackFile) readBlock(block *wvBlockHeader) ([]byte, error) {
+	data := make([]byte, block.size-wvHeaderSize)
+	if _, err := f.file.ReadAt(data, block.offset+wvHeaderSize); err != nil {
+		return nil, fmt.Errorf("读取WavPack块失败: %w", err)
+	}
+	return data, nil
+}
+
+// GetFormat 获取格式名称
+func (f *WavPackFile) GetFormat() string {
+	return "WavPack"
+}
+
+// GetSampleRate 获取采样率
+func (f *WavPackFile) GetSampleRate() int {
+	return f.sampleRate
+}
+
+// GetBitDepth 获取位深度
+func (f *WavPackFile) GetBitDepth() int {
+	return f.bitDepth
+}
+
+// GetChannels 获取声道数
+func (f *WavPackFile) GetChannels() int {
+	return f.channels
+}
+
+// GetDuration 获取时长
+func (f *WavPackFile) GetDuration() time.Duration {
+	return f.duration
+}
+
+// LossyReason 混合模式且缺少校正文件时，音频本身就是有损编码
+func (f *WavPackFile) LossyReason() string {
+	if f.hybrid && f.correctionFile == "" {
+		return "WavPack混合模式文件缺少 .wvc 校正文件，音频为有损编码"
+	}
+	return ""
+}
+
+// PartialReason 混合模式且有校正文件时，校正数据没有参与解码，得到的只是有损层
+func (f *WavPackFile) PartialReason() string {
+	if f.hybrid && f.correctionFile != "" {
+		return "WavPack混合模式文件的 .wvc 校正文件未参与解码，结论只基于有损层"
+	}
+	return ""
+}
+
+// GetSamples 获取按声道分开的音频采样数据
+func (f *WavPackFile) GetSamples() ([][]float64, error) {
+	if f.samples != nil {
+		return f.samples, nil
+	}
+
+	reader, err := f.NewSampleReader()
+	if err != nil {
+		return nil, err
+	}
+	samples, err := readAllSamples(reader, f.channels)
+	if err != nil {
+		return nil, err
+	}
+
+	f.samples = samples
+	return samples, nil
+}
+
+// NewSampleReader 创建采样流，逐组解码（多声道文件的一组块共同组成一帧）
+func (f *WavPackFile) NewSampleReader() (types.SampleReader, error) {
+	converter := newIntFrameConverter(f.channels, f.bitDepth)
+	var frame [][]int32
+	i := 0
+
+	return newIntFrameReader(converter, func() ([][]float64, error) {
+		for ; i < len(f.blocks); i++ {
+			block := &f.blocks[i]
+			if block.blockSamples == 0 {
+				continue
+			}
+
+			data, err := f.readBlock(block)
+			if err != nil {
+				return nil, err
+			}
+
+			channels, err := decodeWavPackBlock(data, block)
+			if err != nil {
+				return nil, fmt.Errorf("解码WavPack块 %d 失败: %w", i, err)
+			}
+
+			if block.flags&wvFlagInitialBlock != 0 {
+				frame = frame[:0]
+			}
+			frame = append(frame, channels...)
+
+			if block.flags&wvFlagFinalBlock == 0 {
+				continue
+			}
+			if len(frame) != f.channels {
+				return nil, fmt.Errorf("WavPack块 %d 声道数不匹配: %d", i, len(frame))
+			}
+
+			i++
+			return converter.convert(frame), nil
+		}
+		return nil, io.EOF
+	}), nil
+}
+
+// GetMetadata 获取元数据
+func (f *WavPackFile) GetMetadata() types.AudioMetadata {
+	return f.metadata
+}
+
+// Close 关闭文件
+func (f *WavPackFile) Close() error {
+	if f.file != nil {
+		return f.file.Close()
+	}
+	return nil
+}
+
+// wvSubBlock WavPack元数据子块
+type wvSubBlock struct {
+	id   byte
+	data []byte
+}
+
+// wvSubBlocks 解析块中的元数据子块
+func wvSubBlocks(data []byte) []wvSubBlock {
+	var subs []wvSubBlock
+	for len(data) >= 2 {
+		id := data[0]
+		size := int(data[1])
+		p := 2
+		if id&wvIDLarge != 0 {
+			if len(data) < 4 {
+				break
+			}
+			size |= int(data[2])<<8 | int(data[3])<<16
+			p = 4
+		}
+
+		// 大小以16位字为单位
+		size <<= 1
+		dataSize := size
+		if id&wvIDOddSize != 0 && dataSize > 0 {
+			dataSize--
+		}
+		if p+size > len(data) {
+			break
+		}
+
+		subs = append(subs, wvSubBlock{id: id &^ (wvIDLarge | wvIDOddSize), data: data[p : p+dataSize]})
+		data = data[p+size:]
+	}
+	return subs
+}
+
+// wvDecorr 去相关滤波器参数
+type wvDecorr struct {
+	value    int32
+	delta    int32
+	weightA  int32
+	weightB  int32
+	samplesA [8]int32
+	samplesB [8]int32
+}
+
+// wvChannel 每个声道的熵解码状态
+type wvChannel struct {
+	median       [3]uint32
+	slowLevel    int32
+	errorLimit   int32
+	bitrateAcc   uint32
+	bitrateDelta uint32
+}
+
+// wvBlockDecoder 单个WavPack块的解码状态
+type wvBlockDecoder struct {
+	flags         uint32
+	stereoIn      bool
+	hybrid        bool
+	hybridBitrate bool
+	terms         int
+	decorr        [wvMaxTerms]wvDecorr
+	ch            [2]wvChannel
+	zero          bool
+	one           bool
+	zeroes        int32
+	and           int32
+	or            int32
+	shift         uint32
+	maxClip       int32
+	minClip       int32
+	br            wvBitReader
+}
+
+// decodeWavPackBlock 解码一个WavPack块，返回1或2个声道的整数采样
+func decodeWavPackBlock(data []byte, hdr *wvBlockHeader) ([][]int32, error) {
+	origBits := uint(hdr.flags&wvFlagBytesStored+1) * 8
+	s := &wvBlockDecoder{
+		flags:         hdr.flags,
+		stereoIn:      hdr.flags&wvFlagMono == 0 && hdr.flags&wvFlagFalseStereo == 0,
+		hybrid:        hdr.flags&wvFlagHybrid != 0,
+		hybridBitrate: hdr.flags&wvFlagHybridRate != 0,
+		maxClip:       int32(int64(1)<<(origBits-1) - 1),
+		minClip:       int32(-(int64(1) << (origBits - 1))),
+	}
+
+	gotTerms, gotEntropy, gotHybrid, gotBitstream := false, false, false, false
+	stereoIn := 0
+	if s.stereoIn {
+		stereoIn = 1
+	}
+
+	for _, sub := range wvSubBlocks(data) {
+		buf := sub.data
+		switch sub.id {
+		case wvIDDecorrTerms:
+			if len(buf) > wvMaxTerms {
+				return nil, fmt.Errorf("去相关项过多: %d", len(buf))
+			}
+			s.terms = len(buf)
+			for i, b := range buf {
+				dp := &s.decorr[s.terms-i-1]
+				dp.value = int32(b&0x1f) - 5
+				dp.delta = int32(b>>5) & 7
+				if dp.value == 0 || dp.value < -3 || (dp.value > 8 && dp.value < 17) || dp.value > 18 {
+					return nil, fmt.Errorf("无效的去相关项: %d", dp.value)
+				}
+			}
+			gotTerms = true
+
+		case wvIDDecorrWeights:
+			weights := len(buf) >> uint(stereoIn)
+			if weights > s.terms {
+				return nil, fmt.Errorf("去相关权重数量无效")
+			}
+			for i := 0; i < weights; i++ {
+				dp := &s.decorr[s.terms-i-1]
+				dp.weightA = wvRestoreWeight(int8(buf[0]))
+				buf = buf[1:]
+				if s.stereoIn {
+					dp.weightB = wvRestoreWeight(int8(buf[0]))
+					buf = buf[1:]
+				}
+			}
+
+		case wvIDDecorrSamples:
+			t := 0
+			next := func() int32 {
+				if len(buf) < 2 {
+					return 0
+				}
+				v := wpExp2(int16(binary.LittleEndian.Uint16(buf)))
+				buf = buf[2:]
+				return v
+			}
+			for i := s.terms - 1; i >= 0 && t < len(sub.data); i-- {
+				dp := &s.decorr[i]
+				switch {
+				case dp.value > 8:
+					dp.samplesA[0] = next()
+					dp.samplesA[1] = next()
+					if s.stereoIn {
+						dp.samplesB[0] = next()
+						dp.samplesB[1] = next()
+						t += 4
+					}
+					t += 4
+				case dp.value < 0:
+					dp.samplesA[0] = next()
+					dp.samplesB[0] = next()
+					t += 4
+				default:
+					for j := 0; j < int(dp.value); j++ {
+						dp.samplesA[j] = next()
+						if s.stereoIn {
+							dp.samplesB[j] = next()
+						}
+					}
+					t += int(dp.value) * 2 * (stereoIn + 1)
+				}
+			}
+
+		case wvIDEntropyVars:
+			if len(buf) != 6*(stereoIn+1) {
+				return nil, fmt.Errorf("熵编码参数大小无效")
+			}
+			for j := 0; j <= stereoIn; j++ {
+				for i := 0; i < 3; i++ {
+					s.ch[j].median[i] = uint32(wpExp2(int16(binary.LittleEndian.Uint16(buf))))
+					buf = buf[2:]
+				}
+			}
+			gotEntropy = true
+
+		case wvIDHybridProfile:
+			if s.hybridBitrate {
+				for i := 0; i <= stereoIn && len(buf) >= 2; i++ {
+					s.ch[i].slowLevel = wpExp2(int16(binary.LittleEndian.Uint16(buf)))
+					buf = buf[2:]
+				}
+			}
+			for i := 0; i <= stereoIn && len(buf) >= 2; i++ {
+				s.ch[i].bitrateAcc = uint32(binary.LittleEndian.Uint16(buf)) << 16
+				buf = buf[2:]
+			}
+			for i := 0; i <= stereoIn && len(buf) >= 2; i++ {
+				s.ch[i].bitrateDelta = uint32(wpExp2(int16(binary.LittleEndian.Uint16(buf))))
+				buf = buf[2:]
+			}
+			gotHybrid = true
+
+		case wvIDInt32Info:
+			if len(buf) != 4 {
+				return nil, fmt.Errorf("INT32信息大小无效")
+			}
+			switch {
+			case buf[0] != 0:
+				return nil, fmt.Errorf("暂不支持扩展精度的WavPack文件")
+			case buf[1] != 0:
+				s.shift = uint32(buf[1])
+			case buf[2] != 0:
+				s.and, s.or = 1, 1
+				s.shift = uint32(buf[2])
+			case buf[3] != 0:
+				s.and = 1
+				s.shift = uint32(buf[3])
+			}
+			if s.shift > 31 {
+				return nil, fmt.Errorf("INT32移位无效: %d", s.shift)
+			}
+
+		case wvIDWVBitstream:
+			s.br = wvBitReader{data: buf}
+			gotBitstream = true
+		}
+	}
+
+	if !gotTerms || !gotEntropy || !gotBitstream {
+		return nil, fmt.Errorf("块缺少必要的元数据")
+	}
+	if s.hybrid && !gotHybrid {
+		return nil, fmt.Errorf("混合模式块缺少码率参数")
+	}
+
+	n := int(hdr.blockSamples)
+	if s.stereoIn {
+		left, right, crc := s.unpackStereo(n)
+		if crc != hdr.crc {
+			return nil, fmt.Errorf("块CRC校验失败")
+		}
+		return [][]int32{left, right}, nil
+	}
+
+	mono, crc := s.unpackMono(n)
+	if crc != hdr.crc {
+		return nil, fmt.Errorf("块CRC校验失败")
+	}
+	if hdr.flags&wvFlagMono == 0 {
+		// 伪立体声：两个声道内容相同
+		right := make([]int32, n)
+		copy(right, mono)
+		return [][]int32{mono, right}, nil
+	}
+	return [][]int32{mono}, nil
+}
+
+// wvRestoreWeight 还原存储为8位的去相关权重
+func wvRestoreWeight(v int8) int32 {
+	w := int32(v) * 8
+	if w > 0 {
+		w += (w + 64) >> 7
+	}
+	return w
+}
+
+// unpackMono 解码单声道采样
+func (s *wvBlockDecoder) unpackMono(n int) ([]int32, uint32) {
+	out := make([]int32, n)
+	crc := uint32(0xffffffff)
+	pos := 0
+
+	for count := 0; count < n; count++ {
+		t, ok := s.getValue(0)
+		if !ok {
+			break
+		}
+
+		for i := 0; i < s.terms; i++ {
+			dp := &s.decorr[i]
+			var a int32
+			var j int
+			if dp.value > 8 {
+				if dp.value&1 != 0 {
+					a = 2*dp.samplesA[0] - dp.samplesA[1]
+				} else {
+					a = (3*dp.samplesA[0] - dp.samplesA[1]) >> 1
+				}
+				dp.samplesA[1] = dp.samplesA[0]
+				j = 0
+			} else {
+				a = dp.samplesA[pos]
+				j = (pos + int(dp.value)) & 7
+			}
+
+			sample := t + int32((int64(dp.weightA)*int64(a)+512)>>10)
+			if a != 0 && t != 0 {
+				dp.weightA -= (((t^a)>>30)&2 - 1) * dp.delta
+			}
+			t = sample
+			dp.samplesA[j] = t
+		}
+
+		pos = (pos + 1) & 7
+		crc = crc*3 + uint32(t)
+		out[count] = s.integerValue(t)
+	}
+
+	return out, crc
+}
+
+// unpackStereo 解码立体声采样
+func (s *wvBlockDecoder) unpackStereo(n int) ([]int32, []int32, uint32) {
+	left := make([]int32, n)
+	right := make([]int32, n)
+	crc := uint32(0xffffffff)
+	pos := 0
+
+	for count := 0; count < n; count++ {
+		l, ok := s.getValue(0)
+		if !ok {
+			break
+		}
+		r, ok := s.getValue(1)
+		if !ok {
+			break
+		}
+
+		for i := 0; i < s.terms; i++ {
+			dp := &s.decorr[i]
+			switch {
+			case dp.value > 0:
+				var a, b int32
+				var j int
+				if dp.value > 8 {
+					if dp.value&1 != 0 {
+						a = 2*dp.samplesA[0] - dp.samplesA[1]
+						b = 2*dp.samplesB[0] - dp.samplesB[1]
+					} else {
+						a = (3*dp.samplesA[0] - dp.samplesA[1]) >> 1
+						b = (3*dp.samplesB[0] - dp.samplesB[1]) >> 1
+					}
+					dp.samplesA[1] = dp.samplesA[0]
+					dp.samplesB[1] = dp.samplesB[0]
+					j = 0
+				} else {
+					a = dp.samplesA[pos]
+					b = dp.samplesB[pos]
+					j = (pos + int(dp.value)) & 7
+				}
+
+				l2 := l + wvApplyWeight(dp.weightA, a)
+				r2 := r + wvApplyWeight(dp.weightB, b)
+				if a != 0 && l != 0 {
+					dp.weightA -= (((l^a)>>30)&2 - 1) * dp.delta
+				}
+				if b != 0 && r != 0 {
+					dp.weightB -= (((r^b)>>30)&2 - 1) * dp.delta
+				}
+				l, r = l2, r2
+				dp.samplesA[j] = l
+				dp.samplesB[j] = r
+
+			case dp.value == -1:
+				l2 := l + wvApplyWeight(dp.weightA, dp.samplesA[0])
+				wvUpdateWeightClip(&dp.weightA, dp.delta, dp.samplesA[0], l)
+				l = l2
+				r2 := r + wvApplyWeight(dp.weightB, l2)
+				wvUpdateWeightClip(&dp.weightB, dp.delta, l2, r)
+				r = r2
+				dp.samplesA[0] = r
+
+			default:
+				r2 := r + wvApplyWeight(dp.weightB, dp.samplesB[0])
+				wvUpdateWeightClip(&dp.weightB, dp.delta, dp.samplesB[0], r)
+				r = r2
+
+				if dp.value == -3 {
+					r2 = dp.samplesA[0]
+					dp.samplesA[0] = r
+				}
+
+				l2 := l + wvApplyWeight(dp.weightA, r2)
+				wvUpdateWeightClip(&dp.weightA, dp.delta, r2, l)
+				l = l2
+				dp.samplesB[0] = l
+			}
+		}
+
+		pos = (pos + 1) & 7
+		if s.flags&wvFlagJointStereo != 0 {
+			r -= l >> 1
+			l += r
+		}
+		crc = (crc*3+uint32(l))*3 + uint32(r)
+
+		left[count] = s.integerValue(l)
+		right[count] = s.integerValue(r)
+	}
+
+	return left, right, crc
+}
+
+// wvApplyWeight 计算加权预测值
+func wvApplyWeight(weight, sample int32) int32 {
+	return int32((int64(weight)*int64(sample) + 512) >> 10)
+}
+
+// wvUpdateWeightClip 更新交叉去相关权重并限制范围
+func wvUpdateWeightClip(weight *int32, delta, samples, in int32) {
+	if samples == 0 || in == 0 {
+		return
+	}
+	if samples^in < 0 {
+		*weight -= delta
+		if *weight < -1024 {
+			*weight = -1024
+		}
+	} else {
+		*weight += delta
+		if *weight > 1024 {
+			*weight = 1024
+		}
+	}
+}
+
+// integerValue 还原INT32信息中的移位，并在混合模式下限幅
+func (s *wvBlockDecoder) integerValue(v int32) int32 {
+	bit := (v & s.and) | s.or
+	v = ((v + bit) << s.shift) - bit
+	if s.hybrid {
+		if v > s.maxClip {
+			v = s.maxClip
+		} else if v < s.minClip {
+			v = s.minClip
+		}
+	}
+	return v
+}
+
+// wvLevelDecay 慢速电平衰减
+func wvLevelDecay(a int32) int32 {
+	return (a + 0x80) >> 8
+}
+
+// getMedian 返回第n个中位数对应的步长
+func (c *wvChannel) getMedian(n int) uint32 {
+	return c.median[n]>>4 + 1
+}
+
+// decMedian 减小第n个中位数
+func (c *wvChannel) decMedian(n int) {
+	div := uint32(128) >> uint(n)
+	c.median[n] -= (c.median[n] + div - 2) / div * 2
+}
+
+// incMedian 增大第n个中位数
+func (c *wvChannel) incMedian(n int) {
+	div := uint32(128) >> uint(n)
+	c.median[n] += (c.median[n] + div) / div * 5
+}
+
+// updateErrorLimit 混合模式下根据码率更新误差上限
+func (s *wvBlockDecoder) updateErrorLimit() {
+	var br, sl [2]int32
+	channels := 1
+	if s.stereoIn {
+		channels = 2
+	}
+
+	for i := 0; i < channels; i++ {
+		s.ch[i].bitrateAcc += s.ch[i].bitrateDelta
+		br[i] = int32(s.ch[i].bitrateAcc >> 16)
+		sl[i] = wvLevelDecay(s.ch[i].slowLevel)
+	}
+
+	if s.stereoIn && s.hybridBitrate {
+		balance := (sl[1] - sl[0] + br[1] + 1) >> 1
+		switch {
+		case balance > br[0]:
+			br[1] = br[0] * 2
+			br[0] = 0
+		case -balance > br[0]:
+			br[0] *= 2
+			br[1] = 0
+		default:
+			br[1] = br[0] + balance
+			br[0] = br[0] - balance
+		}
+	}
+
+	for i := 0; i < channels; i++ {
+		if s.hybridBitrate {
+			if sl[i]-br[i] > -0x100 {
+				s.ch[i].errorLimit = wpExp2(int16(sl[i] - br[i] + 0x100))
+			} else {
+				s.ch[i].errorLimit = 0
+			}
+		} else {
+			s.ch[i].errorLimit = wpExp2(int16(br[i]))
+		}
+	}
+}
+
+// getValue 熵解码一个残差值，数据耗尽时返回false
+func (s *wvBlockDecoder) getValue(channel int) (int32, bool) {
+	c := &s.ch[channel]
+	br := &s.br
+
+	// 两个声道的中位数都很小时，使用零游程编码
+	if s.ch[0].median[0] < 2 && s.ch[1].median[0] < 2 && !s.zero && !s.one {
+		if s.zeroes > 0 {
+			s.zeroes--
+			if s.zeroes > 0 {
+				c.slowLevel -= wvLevelDecay(c.slowLevel)
+				return 0, true
+			}
+		} else {
+			t := br.unary()
+			if t >= 2 {
+				if t >= 32 || br.bitsLeft() < t-1 {
+					return 0, false
+				}
+				t = int(br.read(uint(t-1))) | 1<<(t-1)
+			} else if br.bitsLeft() < 0 {
+				return 0, false
+			}
+			s.zeroes = int32(t)
+			if s.zeroes > 0 {
+				s.ch[0].median = [3]uint32{}
+				s.ch[1].median = [3]uint32{}
+				c.slowLevel -= wvLevelDecay(c.slowLevel)
+				return 0, true
+			}
+		}
+	}
+
+	var t int
+	if s.zero {
+		t = 0
+		s.zero = false
+	} else {
+		t = br.unary()
+		if br.bitsLeft() < 0 {
+			return 0, false
+		}
+		if t == 16 {
+			t2 := br.unary()
+			if t2 < 2 {
+				if br.bitsLeft() < 0 {
+					return 0, false
+				}
+				t += t2
+			} else {
+				if t2 >= 32 || br.bitsLeft() < t2-1 {
+					return 0, false
+				}
+				t += int(br.read(uint(t2-1))) | 1<<(t2-1)
+			}
+		}
+
+		if s.one {
+			s.one = t&1 != 0
+			t = t>>1 + 1
+		} else {
+			s.one = t&1 != 0
+			t >>= 1
+		}
+		s.zero = !s.one
+	}
+
+	if s.hybrid && channel == 0 {
+		s.updateErrorLimit()
+	}
+
+	var base, add uint32
+	switch t {
+	case 0:
+		base = 0
+		add = c.getMedian(0) - 1
+		c.decMedian(0)
+	case 1:
+		base = c.getMedian(0)
+		add = c.getMedian(1) - 1
+		c.incMedian(0)
+		c.decMedian(1)
+	default:
+		base = c.getMedian(0) + c.getMedian(1) + c.getMedian(2)*uint32(t-2)
+		add = c.getMedian(2) - 1
+		c.incMedian(0)
+		c.incMedian(1)
+		// 落在第三个区间时减小第三个中位数，更大时增大
+		if t == 2 {
+			c.decMedian(2)
+		} else {
+			c.incMedian(2)
+		}
+	}
+
+	var ret uint32
+	if c.errorLimit == 0 {
+		ret = base + br.tail(add)
+		if br.bitsLeft() <= 0 {
+			return 0, false
+		}
+	} else {
+		// 混合模式：在误差范围内二分逼近
+		mid := (base*2 + add + 1) >> 1
+		for add > uint32(c.errorLimit) {
+			if br.bitsLeft() <= 0 {
+				return 0, false
+			}
+			if br.read(1) != 0 {
+				add -= mid - base
+				base = mid
+			} else {
+				add = mid - base - 1
+			}
+			mid = (base*2 + add + 1) >> 1
+		}
+		ret = mid
+	}
+
+	sign := br.read(1)
+	if s.hybridBitrate {
+		c.slowLevel += wpLog2(ret) - wvLevelDecay(c.slowLevel)
+	}
+
+	if sign != 0 {
+		return ^int32(ret), true
+	}
+	return int32(ret), true
+}
+
+// wvBitReader 低位优先的位读取器，越界部分按零读取
+type wvBitReader struct {
+	data []byte
+	pos  int
+}
+
+// bitsLeft 剩余位数（越界时为负数）
+func (b *wvBitReader) bitsLeft() int {
+	return len(b.data)*8 - b.pos
+}
+
+// read 读取n位 (n <= 32)，先读到的位为低位
+func (b *wvBitReader) read(n uint) uint32 {
+	var v uint32
+	for i := uint(0); i < n; i++ {
+		idx := b.pos >> 3
+		if idx < len(b.data) && b.data[idx]>>(uint(b.pos)&7)&1 != 0 {
+			v |= 1 << i
+		}
+		b.pos++
+	}
+	return v
+}
+
+// unary 读取一元编码（连续的1，最多33个）
+func (b *wvBitReader) unary() int {
+	n := 0
+	for n < 33 && b.read(1) != 0 {
+		n++
+	}
+	return n
+}
+
+// tail 读取截断二进制编码的余数
+func (b *wvBitReader) tail(k uint32) uint32 {
+	if k < 1 {
+		return 0
+	}
+	p := uint(bits.Len32(k) - 1)
+	e := uint32(uint64(1)<<(p+1) - uint64(k) - 1)
+	res := b.read(p)
+	if res >= e {
+		res = res<<1 - e + b.read(1)
+	}
+	return res
+}
+
+// WavPack对数/指数查找表
+var wpExp2Table, wpLog2Table = func() ([256]uint8, [256]uint8) {
+	var exp2, log2 [256]uint8
+	for i := 0; i < 256; i++ {
+		exp2[i] = uint8(math.Round(256 * (math.Exp2(float64(i)/256) - 1)))
+		log2[i] = uint8(math.Round(256 * math.Log2(1+float64(i)/256)))
+	}
+	return exp2, log2
+}()
+
+// wpExp2 将WavPack的对数表示还原为整数
+func wpExp2(val int16) int32 {
+	neg := val < 0
+	v := int32(val)
+	if neg {
+		v = -v
+	}
+
+	res := int32(wpExp2Table[v&0xff]) | 0x100
+	v >>= 8
+	if v > 31 {
+		return math.MinInt32
+	}
+	if v > 9 {
+		res <<= uint(v - 9)
+	} else {
+		res >>= uint(9 - v)
+	}
+
+	if neg {
+		return -res
+	}
+	return res
+}
+
+// wpLog2 计算整数的WavPack对数表示
+func wpLog2(val uint32) int32 {
+	if val == 0 {
+		return 0
+	}
+	if val == 1 {
+		return 256
+	}
+	val += val >> 9
+	n := bits.Len32(val)
+	if n < 9 {
+		return int32(n<<8) + int32(wpLog2Table[(val<<uint(9-n))&0xff])
+	}
+	return int32(n<<8) + int32(wpLog2Table[(val>>uint(n-9))&0xff])
+}
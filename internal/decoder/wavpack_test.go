@@ -0,0 +1,87 @@
+package decoder
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// golden.wv: 16位立体声，每块2048帧共3块，联合立体声，
+// 去相关项覆盖 17/18、2/3/8 以及交叉声道的 -1/-2/-3，块之间携带量化后的权重和历史采样
+func TestWavPackGolden(t *testing.T) {
+	audioFile, samples := decodeTestFile(t, "golden.wv")
+
+	if audioFile.GetFormat() != "WavPack" || audioFile.GetSampleRate() != 44100 ||
+		audioFile.GetBitDepth() != 16 || audioFile.GetChannels() != 2 {
+		t.Fatalf("格式为 %s %d Hz %d位 %d声道", audioFile.GetFormat(), audioFile.GetSampleRate(),
+			audioFile.GetBitDepth(), audioFile.GetChannels())
+	}
+	if metadata := audioFile.GetMetadata(); metadata.Title != "Golden" || metadata.TrackNumber != 7 {
+		t.Errorf("元数据为 %+v", metadata)
+	}
+	checkSamples(t, samples, testSignal(2, 5000, 16))
+}
+
+// golden_hybrid.wv: 混合模式，误差上限固定为32，解码结果只是有损层
+func TestWavPackHybridGolden(t *testing.T) {
+	_, samples := decodeTestFile(t, "golden_hybrid.wv")
+
+	want := testSignal(2, 5000, 16)
+	if len(samples) != 2 || len(samples[0]) != len(want[0]) {
+		t.Fatalf("解码得到 %d 声道", len(samples))
+	}
+	differs := false
+	for ch := range want {
+		for i := range want[ch] {
+			diff := samples[ch][i] - want[ch][i]
+			if diff < -32 || diff > 32 {
+				t.Fatalf("声道%d 第 %d 帧为 %d，与原始值 %d 相差超过误差上限", ch+1, i, samples[ch][i], want[ch][i])
+			}
+			differs = differs || diff != 0
+		}
+	}
+	if !differs {
+		t.Error("有损层与原始PCM完全相同")
+	}
+}
+
+// 混合模式缺少校正文件时整个文件是有损的，有校正文件时只解码了有损层
+func TestWavPackHybridReasons(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "golden_hybrid.wv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "track.wv")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reasons := func() (string, string) {
+		t.Helper()
+		audioFile, err := NewDecoderRegistry().DecodeFile(path)
+		if err != nil {
+			t.Fatalf("解码失败: %v", err)
+		}
+		defer audioFile.Close()
+		wv := audioFile.(*WavPackFile)
+		return wv.LossyReason(), wv.PartialReason()
+	}
+
+	if lossy, partial := reasons(); lossy == "" || partial != "" {
+		t.Errorf("缺少校正文件时 LossyReason=%q PartialReason=%q", lossy, partial)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "track.wvc"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if lossy, partial := reasons(); lossy != "" || partial == "" {
+		t.Errorf("有校正文件时 LossyReason=%q PartialReason=%q", lossy, partial)
+	}
+
+	// 无损文件两者都为空
+	audioFile, _ := decodeTestFile(t, "golden.wv")
+	if wv := audioFile.(*WavPackFile); wv.LossyReason() != "" || wv.PartialReason() != "" {
+		t.Errorf("无损文件 LossyReason=%q PartialReason=%q", wv.LossyReason(), wv.PartialReason())
+	}
+}
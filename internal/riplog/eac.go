@@ -0,0 +1,90 @@
+package riplog
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// eacVersion 日志第一行，如 "Exact Audio Copy V1.6 from 23. October 2020"、"Exact Audio Copy V1.0 beta 3 from 29. August 2011"
+	eacVersion = regexp.MustCompile(`^Exact Audio Copy (V.+?) from`)
+	// eacTrack 逐轨抓取时每条音轨的开头，如 "Track  1"
+	eacTrack = regexp.MustCompile(`^Track\s+(\d+)$`)
+	// eacSummary 整轨范围抓取时 AccurateRip summary 中的一行，如 "Track  1  accurately ripped (confidence 12)  [ABCDEF01]  (AR v2)"
+	eacSummary = regexp.MustCompile(`^Track\s+(\d+)\s+(.+)$`)
+)
+
+// parseEAC 解析EAC日志
+// 逐轨抓取时每条音轨有 Filename、Test/Copy CRC 和AccurateRip结果；
+// 整轨范围抓取时只有整个范围的CRC，各音轨的AccurateRip结果在 AccurateRip summary 中
+func parseEAC(log *Log, lines []string) {
+	var current *Track
+	summary := false
+	for _, line := range lines {
+		if m := eacVersion.FindStringSubmatch(line); m != nil {
+			log.Version = m[1]
+			continue
+		}
+		if log.parseTOC(line) {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "Used drive"):
+			if v, ok := valueAfter(line); ok {
+				// 去掉 "Adapter: 1  ID: 0" 之类的附加信息
+				if i := strings.Index(v, "Adapter:"); i >= 0 {
+					v = strings.TrimSpace(v[:i])
+				}
+				log.Drive = v
+			}
+			continue
+		case strings.HasPrefix(line, "Read mode"):
+			if v, ok := valueAfter(line); ok && log.ReadMode == "" {
+				log.ReadMode = v
+			}
+			continue
+		case line == "Range status and errors":
+			log.Range = &Track{}
+			current, summary = log.Range, false
+			continue
+		case strings.HasPrefix(line, "AccurateRip summary"):
+			current, summary = nil, true
+			continue
+		}
+
+		if n, ok := trackNumber(line, eacTrack); ok {
+			log.Tracks = append(log.Tracks, Track{Number: n})
+			current, summary = &log.Tracks[len(log.Tracks)-1], false
+			continue
+		}
+		if summary {
+			if n, ok := trackNumber(line, eacSummary); ok {
+				track := log.TrackByNumber(n)
+				if track == nil {
+					log.Tracks = append(log.Tracks, Track{Number: n})
+					track = &log.Tracks[len(log.Tracks)-1]
+				}
+				if track.AccurateRip == "" {
+					track.setAccurateRip(eacSummary.FindStringSubmatch(line)[2])
+				}
+			}
+			continue
+		}
+		if current == nil {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "Filename "):
+			current.Filename = strings.TrimSpace(strings.TrimPrefix(line, "Filename "))
+		case strings.HasPrefix(line, "Test CRC"):
+			current.TestCRC = parseCRC(line)
+		case strings.HasPrefix(line, "Copy CRC"):
+			current.CopyCRC = parseCRC(line)
+		case strings.HasPrefix(line, "Accurately ripped"), strings.HasPrefix(line, "Cannot be verified"),
+			strings.HasPrefix(line, "Track not present"):
+			// 只认音轨内的结果行，日志末尾的 "All tracks accurately ripped" 等总结不归入最后一轨
+			current.setAccurateRip(line)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package riplog
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// AccurateRip 结果的归一化取值
+const (
+	AccurateRipAccurate   = "accurate"   // 与数据库一致
+	AccurateRipInaccurate = "inaccurate" // 数据库中有记录但不一致
+	AccurateRipNotFound   = "notFound"   // 数据库中没有这张光盘或这条音轨
+)
+
+// Log 一份EAC或XLD抓轨日志
+type Log struct {
+	Path     string
+	Ripper   string // "EAC" 或 "XLD"
+	Version  string // 抓轨软件版本，日志中没有时为空
+	Drive    string // 使用的光驱
+	ReadMode string // 读取模式，如 "Secure"、"XLD Secure Ripper"
+	Tracks   []Track
+	Range    *Track     // 整轨范围抓取（EAC的 Range status、XLD的 All Tracks）的结果，没有时为nil
+	TOC      []TOCEntry // 光盘目录（TOC of the extracted CD），包括没有抓取的音轨，日志中没有时为空
+}
+
+// TOCEntry 光盘目录中的一条音轨，位置以扇区（1/75秒）为单位
+type TOCEntry struct {
+	Number int
+	Start  int // 起始扇区
+	End    int // 结束扇区（含）
+}
+
+// Track 日志中一条音轨（或整轨范围）的抓取结果
+type Track struct {
+	Number        int    // 音轨号，整轨范围为0
+	Filename      string // 日志中记录的输出文件名
+	TestCRC       string // 测试抓取的CRC32，8位大写十六进制
+	CopyCRC       string // 复制抓取的CRC32
+	CopyCRCNoNull string // 跳过值为0的采样计算的CRC32（XLD的 skip zero），日志中没有时为空
+	AccurateRip   string // AccurateRipAccurate 等，日志中没有时为空
+	AccurateLine  string // 日志中AccurateRip结果的原文
+}
+
+// Name 抓轨软件及版本，如 "EAC V1.6"
+func (l *Log) Name() string {
+	if l.Version == "" {
+		return l.Ripper
+	}
+	return l.Ripper + " " + l.Version
+}
+
+// Find 返回目录中的 .log 文件，按文件名排序
+func Find(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var logs []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.EqualFold(filepath.Ext(entry.Name()), ".log") {
+			logs = append(logs, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return logs
+}
+
+// ParseFile 解析抓轨日志，按开头的标识区分EAC和XLD；不是这两种日志时返回错误
+// 只支持英文界面生成的日志
+func ParseFile(path string) (*Log, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取抓轨日志失败: %w", err)
+	}
+	text := string(decodeText(data))
+
+	var lines []string
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, strings.TrimSpace(scanner.Text()))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取抓轨日志失败: %w", err)
+	}
+
+	log := &Log{Path: path}
+	switch {
+	case strings.Contains(text, "EAC extraction logfile"):
+		log.Ripper = "EAC"
+		parseEAC(log, lines)
+	case strings.Contains(text, "XLD extraction logfile"):
+		log.Ripper = "XLD"
+		parseXLD(log, lines)
+	default:
+		return nil, fmt.Errorf("%s 不是EAC或XLD抓轨日志", filepath.Base(path))
+	}
+	return log, nil
+}
+
+// TrackByNumber 返回指定音轨号的记录，没有时返回nil
+func (l *Log) TrackByNumber(number int) *Track {
+	for i := range l.Tracks {
+		if l.Tracks[i].Number == number {
+			return &l.Tracks[i]
+		}
+	}
+	return nil
+}
+
+// TrackByFile 按主文件名找到音频文件对应的音轨记录，没有时返回nil
+// 抓轨后常会转码（日志记录的是 .wav，实际为 .flac），因此忽略扩展名
+func (l *Log) TrackByFile(path string) *Track {
+	stem := fileStem(path)
+	for i := range l.Tracks {
+		if l.Tracks[i].Filename != "" && strings.EqualFold(fileStem(l.Tracks[i].Filename), stem) {
+			return &l.Tracks[i]
+		}
+	}
+	return nil
+}
+
+// RangeForFile 整轨范围抓取的输出文件与 path 的主文件名相同时返回其记录，日志没有记录文件名时也返回
+func (l *Log) RangeForFile(path string) *Track {
+	if l.Range == nil {
+		return nil
+	}
+	if l.Range.Filename != "" && !strings.EqualFold(fileStem(l.Range.Filename), fileStem(path)) {
+		return nil
+	}
+	return l.Range
+}
+
+// fileStem 返回不含目录和扩展名的文件名，同时识别Windows和Unix路径分隔符
+func fileStem(path string) string {
+	if i := strings.LastIndexAny(path, `/\`); i >= 0 {
+		path = path[i+1:]
+	}
+	return strings.TrimSuffix(path, filepath.Ext(path))
+}
+
+// tocLine 光盘目录中的一行，EAC如 "1  |  0:00.00 |  3:39.45 |         0    |    16469"，XLD的时间为 "00:00:00"
+var tocLine = regexp.MustCompile(`^(\d+)\s*\|\s*[\d:.]+\s*\|\s*[\d:.]+\s*\|\s*(\d+)\s*\|\s*(\d+)$`)
+
+// parseTOC 解析光盘目录中的一行，不是目录行时返回false
+func (l *Log) parseTOC(line string) bool {
+	m := tocLine.FindStringSubmatch(line)
+	if m == nil {
+		return false
+	}
+	number, _ := strconv.Atoi(m[1])
+	start, _ := strconv.Atoi(m[2])
+	end, _ := strconv.Atoi(m[3])
+	l.TOC = append(l.TOC, TOCEntry{Number: number, Start: start, End: end})
+	return true
+}
+
+// valueAfter 返回行中第一个冒号之后的内容，没有冒号时返回false
+func valueAfter(line string) (string, bool) {
+	i := strings.Index(line, ":")
+	if i < 0 {
+		return "", false
+	}
+	return strings.TrimSpace(line[i+1:]), true
+}
+
+// crcPattern 8位十六进制的CRC32
+var crcPattern = regexp.MustCompile(`\b[0-9A-Fa-f]{8}\b`)
+
+// parseCRC 从行尾取出CRC32并转为大写，没有时返回空字符串
+func parseCRC(s string) string {
+	return strings.ToUpper(crcPattern.FindString(s))
+}
+
+// classifyAccurateRip 按日志原文归一化AccurateRip结果，无法识别时返回空字符串
+func classifyAccurateRip(line string) string {
+	lower := strings.ToLower(line)
+	switch {
+	case strings.Contains(lower, "accurately ripped"):
+		return AccurateRipAccurate
+	case strings.Contains(lower, "cannot be verified"), strings.Contains(lower, "may not be accurate"):
+		return AccurateRipInaccurate
+	case strings.Contains(lower, "not present"), strings.Contains(lower, "not found"):
+		return AccurateRipNotFound
+	}
+	return ""
+}
+
+// setAccurateRip 记录音轨的AccurateRip结果，line 无法识别时不做修改
+func (t *Track) setAccurateRip(line string) {
+	if status := classifyAccurateRip(line); status != "" {
+		t.AccurateRip = status
+		t.AccurateLine = line
+	}
+}
+
+// trackNumber 解析 "Track  1"、"Track 01" 之类只有音轨号的行
+func trackNumber(line string, pattern *regexp.Regexp) (int, bool) {
+	m := pattern.FindStringSubmatch(line)
+	if m == nil {
+		return 0, false
+	}
+	n, err := strconv.Atoi(m[1])
+	return n, err == nil
+}
+
+// decodeText 去掉UTF-8 BOM，并把带BOM的UTF-16文本转换为UTF-8（EAC的日志为UTF-16LE）
+func decodeText(data []byte) []byte {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xef, 0xbb, 0xbf}):
+		return data[3:]
+	case bytes.HasPrefix(data, []byte{0xff, 0xfe}), bytes.HasPrefix(data, []byte{0xfe, 0xff}):
+		bigEndian := data[0] == 0xfe
+		units := make([]uint16, 0, len(data)/2)
+		for i := 2; i+1 < len(data); i += 2 {
+			if bigEndian {
+				units = append(units, uint16(data[i])<<8|uint16(data[i+1]))
+			} else {
+				units = append(units, uint16(data[i+1])<<8|uint16(data[i]))
+			}
+		}
+		return []byte(string(utf16.Decode(units)))
+	}
+	return data
+}
@@ -0,0 +1,99 @@
+package riplog
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// xldVersion 日志第一行，如 "X Lossless Decoder version 20230416 (155.2)"
+	xldVersion = regexp.MustCompile(`^X Lossless Decoder version (\S+)`)
+	// xldTrack 每条音轨的开头，如 "Track 01"
+	xldTrack = regexp.MustCompile(`^Track (\d+)$`)
+	// xldSummary AccurateRip Summary 中的一行，如 "Track 01 : OK (A1+A2, confidence 5+7/12)"
+	xldSummary = regexp.MustCompile(`^Track (\d+) : (.+)$`)
+)
+
+// parseXLD 解析XLD日志
+// 每条音轨都有 Filename 和 CRC32 hash；整轨抓取时 All Tracks 部分给出整个镜像的CRC
+func parseXLD(log *Log, lines []string) {
+	var current *Track
+	summary := false
+	for _, line := range lines {
+		if m := xldVersion.FindStringSubmatch(line); m != nil {
+			log.Version = m[1]
+			continue
+		}
+		if log.parseTOC(line) {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "Used drive"):
+			if v, ok := valueAfter(line); ok {
+				log.Drive = v
+			}
+			continue
+		case strings.HasPrefix(line, "Ripper mode"), strings.HasPrefix(line, "Use cdparanoia mode"):
+			if v, ok := valueAfter(line); ok && log.ReadMode == "" {
+				log.ReadMode = v
+			}
+			continue
+		case strings.EqualFold(line, "All Tracks"):
+			log.Range = &Track{}
+			current, summary = log.Range, false
+			continue
+		case strings.HasPrefix(line, "AccurateRip Summary"):
+			current, summary = nil, true
+			continue
+		}
+
+		if n, ok := trackNumber(line, xldTrack); ok {
+			track := log.TrackByNumber(n)
+			if track == nil {
+				log.Tracks = append(log.Tracks, Track{Number: n})
+				track = &log.Tracks[len(log.Tracks)-1]
+			}
+			current, summary = track, false
+			continue
+		}
+		if summary {
+			if n, ok := trackNumber(line, xldSummary); ok {
+				result := xldSummary.FindStringSubmatch(line)[2]
+				track := log.TrackByNumber(n)
+				if track == nil {
+					log.Tracks = append(log.Tracks, Track{Number: n})
+					track = &log.Tracks[len(log.Tracks)-1]
+				}
+				switch {
+				case strings.HasPrefix(result, "OK"):
+					track.AccurateRip, track.AccurateLine = AccurateRipAccurate, result
+				case strings.HasPrefix(result, "NG"):
+					track.AccurateRip, track.AccurateLine = AccurateRipInaccurate, result
+				default:
+					track.setAccurateRip(result)
+				}
+			}
+			continue
+		}
+		if current == nil {
+			continue
+		}
+
+		key, _, _ := strings.Cut(line, ":")
+		switch strings.TrimSpace(key) {
+		case "Filename":
+			current.Filename, _ = valueAfter(line)
+		case "CRC32 hash (test run)":
+			current.TestCRC = parseCRC(line)
+		case "CRC32 hash":
+			current.CopyCRC = parseCRC(line)
+		case "CRC32 hash (skip zero)":
+			current.CopyCRCNoNull = parseCRC(line)
+		default:
+			// 逐轨的结果以 "->" 开头，如 "->Accurately ripped (v1+v2, confidence 5+7/12)"
+			if strings.HasPrefix(line, "->") {
+				current.setAccurateRip(strings.TrimPrefix(line, "->"))
+			}
+		}
+	}
+}
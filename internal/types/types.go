@@ -1,14 +1,36 @@
 package types
 
-import "time"
+import (
+	"time"
+
+	"audio-loss-checker/internal/accuraterip"
+)
 
 // AnalyzerConfig 分析器配置
 type AnalyzerConfig struct {
-	CutoffFreq  float64 // 频率截断阈值 (Hz)
-	Concurrency int     // 并发数
-	Quiet       bool    // 静默模式
-	OnlyFake    bool    // 只显示假无损
-	JSONOutput  bool    // JSON输出格式
+	CutoffFreq   float64 // 频率截断阈值 (Hz)
+	Concurrency  int     // 并发数
+	Windows      int     // Welch平均的最大窗口数，0表示使用全部窗口
+	Overlap      float64 // 相邻分析窗口的重叠比例 [0, 1)
+	Spectrograms bool    // 在结果中附带PNG频谱图（HTML报告使用）
+	Segments     float64 // 分段分析每段的时长（秒），0表示不分段
+	Verify       bool    // 校验解码后PCM数据的MD5（目前支持FLAC）
+	AlbumBy      string  // 专辑汇总的分组方式: "auto"（CUE表、专辑标签、目录依次优先）、"directory"、"none"；为空时同 "auto"
+
+	Fingerprints []EncoderFingerprint  // 有损编码器低通指纹表，为空时使用内置表
+	AccurateRip  *accuraterip.Database // 本地AccurateRip数据，为nil时只计算校验和，不核对
+
+	CacheDir     string // 结果缓存目录，为空时不使用缓存
+	RebuildCache bool   // 忽略已有缓存，重新分析并写入
+	CacheHash    bool   // 除大小和修改时间外还校验文件内容的SHA-256
+}
+
+// OutputConfig 命令行的输出方式
+type OutputConfig struct {
+	Quiet      bool   // 静默模式
+	OnlyFake   bool   // 只显示假无损
+	JSONOutput bool   // JSON输出格式
+	HTMLReport string // HTML报告输出路径，为空时不生成
 }
 
 // AudioMetadata 音频元数据
@@ -19,11 +41,15 @@ type AudioMetadata struct {
 	Year     string `json:"year,omitempty"`
 	Genre    string `json:"genre,omitempty"`
 	Duration string `json:"duration,omitempty"`
+
+	TrackNumber int `json:"trackNumber,omitempty"` // 标签中的音轨号
+	TrackTotal  int `json:"trackTotal,omitempty"`  // 标签中的音轨总数
 }
 
 // AnalysisDetails 详细分析结果
 type AnalysisDetails struct {
-	IsFake       bool    `json:"isFake"`
+	IsFake       bool    `json:"isFake"`     // 判定为有损来源（FAKE），FAKE_HIRES 不计入；CORRUPT/TRUNCATED 时保留频谱的判定
+	Confidence   float64 `json:"confidence"` // 有损来源的置信度 0-1，由各条证据合成
 	CutoffHz     float64 `json:"cutoffHz,omitempty"`
 	Details      string  `json:"details"`
 	SampleRate   int     `json:"sampleRate"`
@@ -31,16 +57,210 @@ type AnalysisDetails struct {
 	Channels     int     `json:"channels"`
 	Duration     float64 `json:"duration"`
 	MaxFrequency float64 `json:"maxFrequency"`
+	DSDRate      int     `json:"dsdRate,omitempty"`   // 原始DSD采样率
+	DSDOrigin    string  `json:"dsdOrigin,omitempty"` // "native", "pcm44.1k", "pcm48k", "lossy"
+
+	Windows              int       `json:"windows"`                        // 参与Welch平均的窗口数
+	CutoffOccurrence     float64   `json:"cutoffOccurrence"`               // 最高有效频率不超过 maxFrequency 的窗口比例
+	WindowMaxFrequencies []float64 `json:"windowMaxFrequencies,omitempty"` // 各个非静音窗口的最高有效频率
+
+	PerChannel []ChannelAnalysis `json:"perChannel,omitempty"` // 各声道分别分析的结果，整体结果取自最高有效频率最高的声道
+
+	StereoCollapse *StereoCollapse `json:"stereoCollapse,omitempty"` // 侧声道塌缩检测，独立于频谱截断的发现；无法判断时为空
+
+	EncoderMatch *EncoderMatch `json:"encoderMatch,omitempty"` // 与截断特征最吻合的有损编码器，没有吻合时为空
+
+	Upsampling *Upsampling `json:"upsampling,omitempty"` // 高采样率文件由较低采样率升频而来，未检测到时为空
+
+	EffectiveBitDepth *EffectiveBitDepth `json:"effectiveBitDepth,omitempty"` // 按整数采样值估计的有效位深度，浮点、DSD或无法判断时为空
+
+	PartialDecode string `json:"partialDecode,omitempty"` // 只解码了部分数据的原因，此时结论只基于已解码的部分，不做CRC、AccurateRip和位深度检测
+
+	Segments []Segment `json:"segments,omitempty"` // 分段分析的结果，仅在启用分段分析时输出
+
+	Evidence []Evidence `json:"evidence,omitempty"` // 参与判定的各条证据
+}
+
+// EncoderFingerprint 一种有损编码器设置的低通特征
+type EncoderFingerprint struct {
+	Codec      string  `json:"codec"`
+	Profile    string  `json:"profile,omitempty"`
+	Bitrate    string  `json:"bitrate,omitempty"`
+	Lowpass    float64 `json:"lowpass"`    // 低通滤波的通带上限 (Hz)
+	Transition float64 `json:"transition"` // 过渡带宽度 (Hz)，内容在 lowpass 到 lowpass+transition 之间消失
+	Shelf      string  `json:"shelf"`      // 截断形状: "brick" 陡峭截断, "soft" 较缓的滚降
+}
+
+// EncoderMatch 最可能的来源编码器
+type EncoderMatch struct {
+	Codec   string  `json:"codec"`
+	Profile string  `json:"profile,omitempty"`
+	Bitrate string  `json:"bitrate,omitempty"`
+	Lowpass float64 `json:"lowpass"`
+	Score   float64 `json:"score"` // 匹配度 0-1
+}
+
+// Evidence 一条判定证据，权重以对数几率为单位，正值支持有损来源，负值支持真无损
+type Evidence struct {
+	Detector string  `json:"detector"` // "cutoff", "encoder", "shelf", "bandlimit", "stereo", "segments", "dsd", "container"
+	Weight   float64 `json:"weight"`
+	Details  string  `json:"details"`
+}
+
+// Upsampling 升频检测：频谱终止于较低标准采样率的奈奎斯特频率
+type Upsampling struct {
+	OriginalSampleRate int     `json:"originalSampleRate"` // 推断的原始采样率
+	Frequency          float64 `json:"frequency"`          // 砖墙截断频率 (Hz)
+	DropDB             float64 `json:"dropDB"`             // 截断两侧的电平差 (dB)
+	Details            string  `json:"details"`
+}
+
+// Segment 分段分析中的一段
+type Segment struct {
+	Start        float64 `json:"start"` // 起始位置（秒），按CUE分轨时相对音轨开头
+	End          float64 `json:"end"`   // 结束位置（秒）
+	MaxFrequency float64 `json:"maxFrequency"`
+	DropDB       float64 `json:"dropDB,omitempty"`  // 最高有效频率两侧的电平差 (dB)
+	Silent       bool    `json:"silent,omitempty"`  // 整段都是静音
+	Deviant      bool    `json:"deviant,omitempty"` // 最高有效频率与其余部分明显不同
+}
+
+// EffectiveBitDepth 按解码后、归一化之前的整数采样值估计的有效位深度
+type EffectiveBitDepth struct {
+	Bits     int    `json:"bits"`     // 有效位深度
+	Method   string `json:"method"`   // "lsb" 低位恒定（如补零）, "dither" 低位只有微弱的抖动噪声
+	Mismatch bool   `json:"mismatch"` // 有效位深度低于声明的位深度
+	Details  string `json:"details"`
+}
+
+// StereoCollapse 高频侧声道 (L-R) 塌缩，是MP3/AAC联合立体声编码的特征
+type StereoCollapse struct {
+	Detected   bool    `json:"detected"`
+	Frequency  float64 `json:"frequency,omitempty"` // 侧声道开始塌缩的频率 (Hz)
+	DropDB     float64 `json:"dropDB,omitempty"`    // 塌缩区域侧/中比相对参考频段的下降 (dB)
+	Confidence float64 `json:"confidence"`          // 置信度 0-1
+	Details    string  `json:"details"`
+}
+
+// ChannelAnalysis 单个声道的频谱分析结果
+type ChannelAnalysis struct {
+	Channel          int     `json:"channel"` // 声道序号，从1开始
+	MaxFrequency     float64 `json:"maxFrequency"`
+	CutoffHz         float64 `json:"cutoffHz"`
+	CutoffOccurrence float64 `json:"cutoffOccurrence"`
+	Silent           bool    `json:"silent,omitempty"` // 整个声道都是静音
 }
 
 // AnalysisResult 分析结果
 type AnalysisResult struct {
-	FilePath string          `json:"filePath"`
-	Format   string          `json:"format"`
-	Metadata AudioMetadata   `json:"metadata"`
-	Status   string          `json:"status"` // "OK", "FAKE", "ERROR"
-	Analysis AnalysisDetails `json:"analysis"`
-	Error    string          `json:"error,omitempty"`
+	FilePath       string          `json:"filePath"`
+	Format         string          `json:"format"`
+	Metadata       AudioMetadata   `json:"metadata"`
+	Status         string          `json:"status"` // "OK", "SUSPECT", "FAKE", "FAKE_HIRES", "CORRUPT", "TRUNCATED", "ERROR"
+	Analysis       AnalysisDetails `json:"analysis"`
+	Error          string          `json:"error,omitempty"`
+	VerdictSource  string          `json:"verdictSource,omitempty"`  // 判定依据: "spectrum", "container", "stereo", "hires", "integrity", "accuraterip"
+	FormatMismatch *FormatMismatch `json:"formatMismatch,omitempty"` // 扩展名与内容不一致，独立于音质判定
+	Integrity      *Integrity      `json:"integrity,omitempty"`      // 解码完整性检查，启用 --verify 或发现问题时填充
+	RipLog         *RipLog         `json:"ripLog,omitempty"`         // 同目录下对应的EAC/XLD抓轨日志，没有时为空
+	AccurateRip    *AccurateRip    `json:"accurateRip,omitempty"`    // AccurateRip校验和，仅CD规格的音频
+	Track          *TrackInfo      `json:"track,omitempty"`          // 按CUE分轨分析时的音轨信息
+	Spectrogram    []byte          `json:"-"`                        // PNG格式的频谱图，仅在配置了 Spectrograms 时填充
+}
+
+// AlbumResult 按专辑汇总的分析结果，单条音轨的判定容易受个别曲目影响，整张专辑一起看更可靠
+type AlbumResult struct {
+	Album        string  `json:"album,omitempty"`
+	Artist       string  `json:"artist,omitempty"`
+	Directory    string  `json:"directory"`          // 第一条音轨所在目录
+	CueSheet     string  `json:"cueSheet,omitempty"` // 按CUE表分组时的CUE文件路径
+	GroupBy      string  `json:"groupBy"`            // 分组依据: "cue", "tags", "directory"
+	Tracks       int     `json:"tracks"`             // 参与汇总的音轨数，不含出错的文件
+	Fake         int     `json:"fake"`               // 有损来源置信度达到 FAKE 的音轨数（含损坏、截断但频谱判定为有损的音轨）
+	Suspect      int     `json:"suspect"`            // 有损来源置信度达到 SUSPECT 的音轨数
+	FlaggedShare float64 `json:"flaggedShare"`       // 判定为假无损或疑似的音轨比例 (0-1)
+	MedianCutoff float64 `json:"medianCutoff"`       // 各音轨最高有效频率的中位数 (Hz)
+	CutoffSpread float64 `json:"cutoffSpread"`       // 各音轨最高有效频率与中位数之差的中位数 (Hz)
+	Consistency  float64 `json:"consistency"`        // 最高有效频率与中位数相差不超过500 Hz的音轨比例 (0-1)
+	Status       string  `json:"status"`             // "OK", "SUSPECT", "FAKE"
+	Details      string  `json:"details"`
+}
+
+// AnalysisReport 一批文件的分析结果
+type AnalysisReport struct {
+	Results   []*AnalysisResult `json:"results"`          // 按完成的顺序排列，CUE表每条音轨一个结果
+	Albums    []*AlbumResult    `json:"albums,omitempty"` // 至少有2条音轨的专辑的汇总
+	Paths     int               `json:"paths"`            // 分析的文件数，CUE表及其镜像算一个
+	CacheHits int               `json:"cacheHits"`        // 直接使用缓存结果的文件数
+	Cached    bool              `json:"cached"`           // 是否使用了结果缓存
+}
+
+// TrackInfo 整轨镜像中按CUE划分的音轨
+type TrackInfo struct {
+	CueSheet  string  `json:"cueSheet"` // CUE文件路径
+	Number    int     `json:"number"`
+	Title     string  `json:"title,omitempty"`
+	Performer string  `json:"performer,omitempty"`
+	Start     float64 `json:"start"` // 在镜像中的起始位置（秒）
+	End       float64 `json:"end"`   // 在镜像中的结束位置（秒）
+}
+
+// FormatMismatch 扩展名与文件内容不一致
+type FormatMismatch struct {
+	Extension string `json:"extension"` // 文件扩展名
+	Content   string `json:"content"`   // 按文件内容识别的格式
+}
+
+// Integrity 解码完整性检查结果
+type Integrity struct {
+	ExpectedSamples int64         `json:"expectedSamples,omitempty"` // 文件头记录的每声道采样数，未记录时为0
+	DecodedSamples  int64         `json:"decodedSamples"`            // 实际解码出的每声道采样数
+	FrameErrors     int           `json:"frameErrors,omitempty"`     // CRC校验失败或无法解析的帧数
+	Errors          []DecodeError `json:"errors,omitempty"`          // 最先遇到的几个帧错误
+	Stopped         bool          `json:"stopped,omitempty"`         // 遇到无法跳过的错误，其后的数据没有解码
+	Truncated       bool          `json:"truncated,omitempty"`       // 数据在文件头记录的长度之前结束
+	MD5             string        `json:"md5,omitempty"`             // "match", "mismatch", "missing"（文件未记录MD5）；未校验或未能完整解码时为空
+	ExpectedMD5     string        `json:"expectedMD5,omitempty"`
+	ActualMD5       string        `json:"actualMD5,omitempty"`
+	Details         string        `json:"details,omitempty"`
+}
+
+// DecodeError 解码过程中遇到的帧错误
+type DecodeError struct {
+	Position int64  `json:"position"` // 出错帧的起始位置（每声道采样数）
+	Message  string `json:"message"`
+}
+
+// RipLog 抓轨日志中与本结果对应的记录，以及按解码后的PCM重新计算的CRC
+type RipLog struct {
+	Path               string `json:"path"`
+	Ripper             string `json:"ripper"` // 抓轨软件及版本，如 "EAC V1.6"、"XLD 20230416"
+	Drive              string `json:"drive,omitempty"`
+	ReadMode           string `json:"readMode,omitempty"`
+	Track              int    `json:"track,omitempty"`              // 日志中对应的音轨号，整轨镜像本身为0
+	Range              bool   `json:"range,omitempty"`              // CRC来自整轨范围抓取，针对整个镜像
+	TestCRC            string `json:"testCRC,omitempty"`            // 日志中测试抓取的CRC32
+	CopyCRC            string `json:"copyCRC,omitempty"`            // 日志中复制抓取的CRC32
+	AccurateRip        string `json:"accurateRip,omitempty"`        // 日志中的AccurateRip结果: "accurate", "inaccurate", "notFound"
+	AccurateRipDetails string `json:"accurateRipDetails,omitempty"` // 日志中AccurateRip结果的原文
+	AudioCRC           string `json:"audioCRC,omitempty"`           // 按解码后的PCM计算的CRC32，无法计算时为空
+	CRCMatch           string `json:"crcMatch,omitempty"`           // "match", "mismatch"；日志没有CRC或无法计算时为空
+	Details            string `json:"details"`
+}
+
+// AccurateRip 按AccurateRip算法计算的音轨校验和，以及与本地AccurateRip数据的核对结果
+type AccurateRip struct {
+	Track      int    `json:"track,omitempty"`      // 音轨号，无法确定时为0
+	TrackCount int    `json:"trackCount,omitempty"` // 光盘的音轨数，无法确定时为0
+	Position   string `json:"position"`             // "first", "middle", "last", "only"（单音轨光盘）；无法确定时为 "unknown"
+	V1         string `json:"v1"`                   // 按 Position 计算的v1校验和，8位大写十六进制；位置无法确定时按中间音轨计算
+	V2         string `json:"v2"`
+	DiscID     string `json:"discId,omitempty"`     // 按CUE或抓轨日志的光盘目录计算的光盘标识，如 "012-0012a5b1-00a1c2d3-9e0b1c0c"
+	Match      string `json:"match,omitempty"`      // "match", "mismatch"（数据中有这张光盘但没有吻合的记录）, "notFound"；未提供数据时为空
+	Version    int    `json:"version,omitempty"`    // 吻合的校验和版本: 1 或 2
+	Confidence int    `json:"confidence,omitempty"` // 吻合记录的置信度（提交相同结果的次数）
+	Source     string `json:"source,omitempty"`     // 吻合记录所在的数据文件
+	Details    string `json:"details,omitempty"`
 }
 
 // AudioFile 音频文件接口
@@ -50,7 +270,59 @@ type AudioFile interface {
 	GetBitDepth() int
 	GetChannels() int
 	GetDuration() time.Duration
-	GetSamples() ([]float64, error)
+	// GetSamples 读取全部采样，按声道分开返回（samples[ch][i]），各声道长度相同
+	GetSamples() ([][]float64, error)
+	// NewSampleReader 创建从头开始的采样流，内存占用与文件长度无关
+	NewSampleReader() (SampleReader, error)
 	GetMetadata() AudioMetadata
 	Close() error
 }
+
+// SampleReader 按块顺序读取音频采样
+type SampleReader interface {
+	// ReadBlock 读取下一块采样，按声道分别写入 dst[ch]，每个声道最多读取 len(dst[0]) 帧
+	// 返回实际读取的帧数；没有更多数据时返回 0 和 io.EOF
+	ReadBlock(dst [][]float64) (int, error)
+}
+
+// IntSampleObserver 接收归一化为浮点数之前的整数采样
+type IntSampleObserver interface {
+	// ObserveInt 接收一段整数采样，数值右对齐到声明的位深度，声道顺序不定；切片在返回后不再有效
+	ObserveInt(samples []int32)
+}
+
+// IntSampleSource 可以在归一化之前提供整数采样的采样流
+type IntSampleSource interface {
+	// ObserveIntSamples 注册观察者，此后每次解码都先把整数采样交给它
+	// 采样为浮点或DSD时不注册，返回false
+	ObserveIntSamples(observer IntSampleObserver) bool
+}
+
+// IntegrityChecker 能够在解码时检查数据完整性的采样流
+// 帧校验失败或数据提前结束时继续提供已解码的采样，问题记录在检查结果中，而不是当作文件结束
+type IntegrityChecker interface {
+	// EnableMD5 在开始读取前调用，解码时同时计算PCM数据的MD5
+	EnableMD5()
+	// Integrity 在采样流读完后返回检查结果
+	Integrity() *Integrity
+}
+
+// LossyContainer 可在容器层面判定为有损的音频文件
+// 例如缺少 .wvc 校正文件的WavPack混合模式文件，无论频谱如何都是有损的
+type LossyContainer interface {
+	// LossyReason 返回有损原因，容器本身无损时返回空字符串
+	LossyReason() string
+}
+
+// PartialSource 只能解码出部分数据的音频文件
+// 例如带 .wvc 校正文件的WavPack混合模式文件：只解码有损层，得到的PCM与原始数据不是逐位相同的
+type PartialSource interface {
+	// PartialReason 返回只解码了部分数据的原因，完整解码时返回空字符串
+	PartialReason() string
+}
+
+// DSDSource 由1位DSD抽取为PCM的音频文件
+type DSDSource interface {
+	// DSDSampleRate 返回原始DSD采样率（如 2822400）
+	DSDSampleRate() int
+}
@@ -0,0 +1,95 @@
+// Package checker 检测无损音频文件是否由有损格式转换而来（"假无损"），是命令行工具使用的分析接口
+//
+// 基本用法:
+//
+//	c, err := checker.New(checker.WithVerify(true))
+//	if err != nil {
+//		return err
+//	}
+//	report, err := c.AnalyzePath("/mnt/music")
+//	if err != nil {
+//		return err
+//	}
+//	for _, result := range report.Results {
+//		if result.Status == checker.StatusFake {
+//			fmt.Println(result.FilePath, result.Analysis.Details)
+//		}
+//	}
+package checker
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+
+	"audio-loss-checker/internal/analyzer"
+	"audio-loss-checker/internal/types"
+)
+
+// Checker 按给定参数分析音频，可在多个goroutine中同时使用
+type Checker struct {
+	analyzer *analyzer.Analyzer
+}
+
+// New 按默认参数加上 opts 创建分析器，参数无效或读取指纹表、AccurateRip数据失败时返回错误
+func New(opts ...Option) (*Checker, error) {
+	config := &types.AnalyzerConfig{
+		CutoffFreq:  analyzer.DefaultCutoffFreq,
+		Concurrency: runtime.NumCPU(),
+		Windows:     analyzer.DefaultWelchWindows,
+		Overlap:     analyzer.DefaultWelchOverlap,
+		AlbumBy:     "auto",
+	}
+	for _, opt := range opts {
+		if err := opt(config); err != nil {
+			return nil, err
+		}
+	}
+	a, err := analyzer.NewAnalyzer(config)
+	if err != nil {
+		return nil, err
+	}
+	return &Checker{analyzer: a}, nil
+}
+
+// AnalyzeReader 分析调用方提供的音频数据，从头开始读取，分析期间调用方不能读取或定位 r
+// name 为文件名，内容无法识别时按其扩展名选择解码器，并作为结果中的 FilePath；
+// 数据不在文件系统中，不核对抓轨日志，WavPack混合模式视为缺少校正文件
+// 无法分析时返回错误，同时返回 Status 为 ERROR 的结果
+func (c *Checker) AnalyzeReader(r io.ReadSeeker, name string) (*Result, error) {
+	result := c.analyzer.AnalyzeReader(r, name)
+	if result.Status == StatusError {
+		return result, errors.New(result.Error)
+	}
+	return result, nil
+}
+
+// AnalyzeFile 分析一个音频文件，CUE表按音轨各返回一个结果
+// 路径不存在或是目录时返回错误；单个文件或音轨无法分析时不返回错误，其结果的 Status 为 ERROR
+func (c *Checker) AnalyzeFile(path string) ([]*Result, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取文件失败: %w", err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s 是目录，请使用 AnalyzePath", path)
+	}
+	return c.analyzer.AnalyzePath(path), nil
+}
+
+// AnalyzeFiles 并发分析多个文件（通常来自 CollectFiles），并按专辑汇总
+// 每个文件分析完成后以其结果调用 done（可为nil），done 在调用 AnalyzeFiles 的goroutine中依次调用，可用于显示进度
+func (c *Checker) AnalyzeFiles(paths []string, done func(path string, results []*Result)) *Report {
+	return c.analyzer.Analyze(paths, done)
+}
+
+// AnalyzePath 分析一个文件，或目录中所有支持的音频文件（CUE表引用的整轨镜像按音轨分析）
+func (c *Checker) AnalyzePath(path string) (*Report, error) {
+	files, err := CollectFiles(path)
+	if err != nil {
+		return nil, fmt.Errorf("收集音频文件失败: %w", err)
+	}
+	return c.AnalyzeFiles(files, nil), nil
+}
@@ -0,0 +1,196 @@
+package checker
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// 243749.flac 来自 github.com/mewkiz/flac 的测试数据（公有领域），8kHz 24位单声道共402个采样
+var testFLAC = filepath.Join("..", "..", "internal", "decoder", "testdata", "243749.flac")
+
+func TestNewOptions(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing")
+	tests := []struct {
+		name    string
+		opts    []Option
+		wantErr string // 为空表示应创建成功
+	}{
+		{"默认参数", nil, ""},
+		{"全部有效", []Option{WithCutoff(17000), WithConcurrency(2), WithWindows(0), WithOverlap(0.75),
+			WithSegments(5), WithVerify(true), WithAlbumBy("directory")}, ""},
+		{"并发数为0", []Option{WithConcurrency(0)}, "并发数至少为1"},
+		{"阈值为负数", []Option{WithCutoff(-1)}, "频率截断阈值不能为负数"},
+		{"窗口数为负数", []Option{WithWindows(-1)}, "窗口数不能为负数"},
+		{"重叠比例为1", []Option{WithOverlap(1)}, "重叠比例必须在0到1之间"},
+		{"分段过短", []Option{WithSegments(0.01)}, "分段时长不能小于"},
+		{"分组方式无效", []Option{WithAlbumBy("artist")}, "不支持的专辑分组方式"},
+		{"指纹表不存在", []Option{WithFingerprintFile(missing)}, "读取指纹表失败"},
+		{"AccurateRip数据不存在", []Option{WithAccurateRip(missing)}, "读取AccurateRip数据失败"},
+	}
+
+	for _, tt := range tests {
+		c, err := New(tt.opts...)
+		if tt.wantErr == "" {
+			if err != nil || c == nil {
+				t.Errorf("%s: %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: 错误为 %v，应包含 %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+// 同一份数据按文件和按调用方提供的数据分析，结果相同
+func TestAnalyzeFileAndReader(t *testing.T) {
+	c, err := New(WithConcurrency(1), WithVerify(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := c.AnalyzeFile(testFLAC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("得到 %d 个结果", len(results))
+	}
+	file := results[0]
+	if file.Status == StatusError || file.FilePath != testFLAC || file.Analysis.SampleRate != 8000 ||
+		file.Integrity == nil || file.Integrity.MD5 != "match" {
+		t.Fatalf("AnalyzeFile: %+v", file)
+	}
+
+	data, err := os.ReadFile(testFLAC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, err := c.AnalyzeReader(bytes.NewReader(data), "243749.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reader.Status != file.Status || reader.FilePath != "243749.flac" ||
+		!reflect.DeepEqual(reader.Analysis, file.Analysis) {
+		t.Errorf("AnalyzeReader 的结果为 %s %+v，AnalyzeFile 为 %s %+v", reader.Status, reader.Analysis,
+			file.Status, file.Analysis)
+	}
+
+	// 无法识别的数据返回错误，同时返回 ERROR 结果
+	if result, err := c.AnalyzeReader(bytes.NewReader([]byte("not audio")), "notes.txt"); err == nil ||
+		result == nil || result.Status != StatusError {
+		t.Errorf("无法识别的数据: %v %+v", err, result)
+	}
+	// 路径不存在或是目录时返回错误
+	if _, err := c.AnalyzeFile(filepath.Join(t.TempDir(), "missing.flac")); err == nil {
+		t.Error("文件不存在时没有返回错误")
+	}
+	if _, err := c.AnalyzeFile(t.TempDir()); err == nil || !strings.Contains(err.Error(), "AnalyzePath") {
+		t.Errorf("目录: %v", err)
+	}
+}
+
+func TestCollectFiles(t *testing.T) {
+	dir := t.TempDir()
+	sheet := "FILE \"image.wav\" WAVE\n  TRACK 01 AUDIO\n    INDEX 01 00:00:00\n"
+	files := map[string]string{
+		"album.cue":        sheet,
+		"image.wav":        "",
+		"Other.FLAC":       "",
+		"notes.txt":        "",
+		"sub/track.ape":    "",
+		"sub/broken.cue":   "TRACK 01 AUDIO\n",
+		"sub/unlisted.wav": "",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := CollectFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// CUE表引用的 image.wav 改为按音轨分析；无法解析的CUE表保留，由分析器报告错误
+	var want []string
+	for _, name := range []string{"Other.FLAC", "album.cue", "sub/broken.cue", "sub/track.ape", "sub/unlisted.wav"} {
+		want = append(want, filepath.Join(dir, filepath.FromSlash(name)))
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("得到 %q，应为 %q", got, want)
+	}
+
+	if _, err := CollectFiles(filepath.Join(dir, "missing")); err == nil {
+		t.Error("路径不存在时没有返回错误")
+	}
+}
+
+func TestRenderSpectrograms(t *testing.T) {
+	c, err := New(WithConcurrency(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.RenderSpectrograms([]string{testFLAC}, SpectrogramOptions{Width: 8}); err == nil ||
+		!strings.Contains(err.Error(), "频谱图尺寸过小") {
+		t.Errorf("尺寸过小: %v", err)
+	}
+
+	// 默认尺寸，输出目录中保留相对于 Root 的子目录；CUE表替换为其引用的整轨镜像
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, "album"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	image := filepath.Join(root, "album", "image.wav")
+	if err := os.WriteFile(image, sineWAV(44100, 44100), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sheet := filepath.Join(root, "album", "album.cue")
+	if err := os.WriteFile(sheet, []byte("FILE \"image.wav\" WAVE\n  TRACK 01 AUDIO\n    INDEX 01 00:00:00\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := t.TempDir()
+	if err := c.RenderSpectrograms([]string{sheet, image}, SpectrogramOptions{OutputDir: out, Root: root}); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(filepath.Join(out, "album"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "image.wav.png" {
+		t.Errorf("输出目录中有 %v", entries)
+	}
+}
+
+// sineWAV 生成 frames 帧、1kHz正弦波的16位单声道WAV
+func sineWAV(sampleRate, frames int) []byte {
+	var buf bytes.Buffer
+	le := binary.LittleEndian
+	buf.WriteString("RIFF")
+	buf.Write(le.AppendUint32(nil, uint32(36+2*frames)))
+	buf.WriteString("WAVEfmt ")
+	buf.Write(le.AppendUint32(nil, 16))
+	buf.Write(le.AppendUint16(nil, 1)) // PCM
+	buf.Write(le.AppendUint16(nil, 1))
+	buf.Write(le.AppendUint32(nil, uint32(sampleRate)))
+	buf.Write(le.AppendUint32(nil, uint32(2*sampleRate)))
+	buf.Write(le.AppendUint16(nil, 2))
+	buf.Write(le.AppendUint16(nil, 16))
+	buf.WriteString("data")
+	buf.Write(le.AppendUint32(nil, uint32(2*frames)))
+	for i := range frames {
+		v := int16(10000 * math.Sin(2*math.Pi*1000*float64(i)/float64(sampleRate)))
+		buf.Write(le.AppendUint16(nil, uint16(v)))
+	}
+	return buf.Bytes()
+}
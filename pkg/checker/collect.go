@@ -0,0 +1,78 @@
+package checker
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"audio-loss-checker/internal/cue"
+)
+
+// CollectFiles 列出 path（文件或目录）中扩展名受支持的音频文件和CUE表，
+// 已被CUE表引用的整轨镜像不单独列出，改为按CUE分轨分析
+func CollectFiles(path string) ([]string, error) {
+	var files []string
+	supportedExts := map[string]bool{
+		".wav":  true,
+		".w64":  true, // Sony Wave64
+		".flac": true,
+		".alac": true,
+		".m4a":  true, // ALAC 文件通常使用 .m4a 扩展名
+		".ape":  true,
+		".wv":   true, // WavPack 混合模式的 .wvc 校正文件随 .wv 一起读取
+		".aif":  true,
+		".aiff": true,
+		".aifc": true,
+		".dsf":  true,
+		".dff":  true,
+		".cue":  true, // 整轨镜像按CUE分轨分析
+	}
+
+	err := filepath.Walk(path, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+
+		ext := filepath.Ext(strings.ToLower(filePath))
+		if supportedExts[ext] {
+			files = append(files, filePath)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return excludeCueImages(files), nil
+}
+
+// excludeCueImages 去掉已被CUE表引用的整轨镜像，这些文件改为按音轨分析
+// 无法解析的CUE表保留在列表中，由分析器报告错误，其引用的文件仍按整个文件分析
+func excludeCueImages(files []string) []string {
+	referenced := make(map[string]bool)
+	for _, file := range files {
+		if !strings.EqualFold(filepath.Ext(file), ".cue") {
+			continue
+		}
+		sheet, err := cue.Parse(file)
+		if err != nil {
+			continue
+		}
+		for _, f := range sheet.Files {
+			referenced[filepath.Clean(f.Path)] = true
+		}
+	}
+
+	kept := files[:0]
+	for _, file := range files {
+		if !referenced[filepath.Clean(file)] {
+			kept = append(kept, file)
+		}
+	}
+	return kept
+}
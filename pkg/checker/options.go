@@ -0,0 +1,130 @@
+package checker
+
+import (
+	"audio-loss-checker/internal/accuraterip"
+	"audio-loss-checker/internal/analyzer"
+	"audio-loss-checker/internal/cache"
+	"audio-loss-checker/internal/types"
+)
+
+// Option 分析参数，在 New 中依次应用；读取文件失败等错误由 New 返回
+type Option func(*types.AnalyzerConfig) error
+
+// WithCutoff 设置判定为假无损的最高有效频率阈值 (Hz)，默认 18000
+func WithCutoff(hz float64) Option {
+	return func(c *types.AnalyzerConfig) error {
+		c.CutoffFreq = hz
+		return nil
+	}
+}
+
+// WithConcurrency 设置批量分析时同时分析的文件数，默认为CPU核数
+func WithConcurrency(n int) Option {
+	return func(c *types.AnalyzerConfig) error {
+		c.Concurrency = n
+		return nil
+	}
+}
+
+// WithWindows 设置频谱平均的最大窗口数，0表示使用全部窗口，默认 256
+func WithWindows(n int) Option {
+	return func(c *types.AnalyzerConfig) error {
+		c.Windows = n
+		return nil
+	}
+}
+
+// WithOverlap 设置相邻分析窗口的重叠比例 [0, 1)，默认 0.5
+func WithOverlap(ratio float64) Option {
+	return func(c *types.AnalyzerConfig) error {
+		c.Overlap = ratio
+		return nil
+	}
+}
+
+// WithSegments 按 seconds 秒分段分析，结果中给出各段的最高有效频率；0表示不分段（默认）
+func WithSegments(seconds float64) Option {
+	return func(c *types.AnalyzerConfig) error {
+		c.Segments = seconds
+		return nil
+	}
+}
+
+// WithVerify 校验解码后PCM数据的MD5（FLAC），损坏或被截断的文件判定为 CORRUPT/TRUNCATED
+func WithVerify(enabled bool) Option {
+	return func(c *types.AnalyzerConfig) error {
+		c.Verify = enabled
+		return nil
+	}
+}
+
+// WithSpectrograms 在结果的 Spectrogram 中附带480x200的PNG频谱图
+func WithSpectrograms(enabled bool) Option {
+	return func(c *types.AnalyzerConfig) error {
+		c.Spectrograms = enabled
+		return nil
+	}
+}
+
+// WithAlbumBy 设置批量分析时专辑汇总的分组方式: "auto"（默认，CUE表、专辑标签、目录依次优先）、"directory"、"none"
+func WithAlbumBy(mode string) Option {
+	return func(c *types.AnalyzerConfig) error {
+		c.AlbumBy = mode
+		return nil
+	}
+}
+
+// WithFingerprintFile 从JSON文件读取编码器低通指纹表，代替内置表
+func WithFingerprintFile(path string) Option {
+	return func(c *types.AnalyzerConfig) error {
+		fingerprints, err := analyzer.LoadFingerprints(path)
+		if err != nil {
+			return err
+		}
+		c.Fingerprints = fingerprints
+		return nil
+	}
+}
+
+// WithAccurateRip 与本地AccurateRip数据核对CD规格音轨的校验和，path 为 dBAR-*.bin 文件或包含这些文件的目录
+func WithAccurateRip(path string) Option {
+	return func(c *types.AnalyzerConfig) error {
+		db, err := accuraterip.Load(path)
+		if err != nil {
+			return err
+		}
+		c.AccurateRip = db
+		return nil
+	}
+}
+
+// WithCache 把分析结果缓存到 dir，文件没有变化时直接使用缓存；dir 为空时使用用户缓存目录下的 audio-loss-checker
+// 调用方提供的数据（AnalyzeReader）不使用缓存
+func WithCache(dir string) Option {
+	return func(c *types.AnalyzerConfig) error {
+		if dir == "" {
+			var err error
+			if dir, err = cache.DefaultDir(); err != nil {
+				return err
+			}
+		}
+		c.CacheDir = dir
+		return nil
+	}
+}
+
+// WithCacheRebuild 忽略已有缓存，重新分析并更新缓存
+func WithCacheRebuild(enabled bool) Option {
+	return func(c *types.AnalyzerConfig) error {
+		c.RebuildCache = enabled
+		return nil
+	}
+}
+
+// WithCacheHash 命中缓存前还校验文件内容的SHA-256
+func WithCacheHash(enabled bool) Option {
+	return func(c *types.AnalyzerConfig) error {
+		c.CacheHash = enabled
+		return nil
+	}
+}
@@ -0,0 +1,66 @@
+package checker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"audio-loss-checker/internal/analyzer"
+	"audio-loss-checker/internal/cue"
+)
+
+// 频谱图默认尺寸（绘图区域，不含坐标轴和色标）
+const (
+	DefaultSpectrogramWidth  = analyzer.DefaultSpectrogramWidth
+	DefaultSpectrogramHeight = analyzer.DefaultSpectrogramHeight
+)
+
+// minSpectrogramSize 频谱图每个方向至少的像素数
+const minSpectrogramSize = 16
+
+// SpectrogramOptions 频谱图渲染选项，Width 和 Height 为0时使用默认尺寸
+type SpectrogramOptions = analyzer.SpectrogramOptions
+
+// RenderSpectrograms 为每个文件（通常来自 CollectFiles）生成频谱图PNG，并在图上标出分析得到的截断频率
+// CUE表替换为其引用的整轨镜像，按整个文件生成一张图；进度和各文件的错误输出到标准输出和标准错误，
+// 有文件失败时返回错误
+func (c *Checker) RenderSpectrograms(paths []string, opts SpectrogramOptions) error {
+	if opts.Width == 0 {
+		opts.Width = DefaultSpectrogramWidth
+	}
+	if opts.Height == 0 {
+		opts.Height = DefaultSpectrogramHeight
+	}
+	if opts.Width < minSpectrogramSize || opts.Height < minSpectrogramSize {
+		return fmt.Errorf("频谱图尺寸过小: %dx%d", opts.Width, opts.Height)
+	}
+	return c.analyzer.RenderSpectrograms(expandCueSheets(paths), opts)
+}
+
+// expandCueSheets 把CUE表替换为其引用的整轨镜像，无法解析的CUE表直接跳过
+func expandCueSheets(files []string) []string {
+	var expanded []string
+	seen := make(map[string]bool)
+	for _, file := range files {
+		paths := []string{file}
+		if strings.EqualFold(filepath.Ext(file), ".cue") {
+			sheet, err := cue.Parse(file)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "跳过无法解析的CUE表 %s: %v\n", file, err)
+				continue
+			}
+			paths = paths[:0]
+			for _, f := range sheet.Files {
+				paths = append(paths, f.Path)
+			}
+		}
+		for _, path := range paths {
+			if !seen[filepath.Clean(path)] {
+				seen[filepath.Clean(path)] = true
+				expanded = append(expanded, path)
+			}
+		}
+	}
+	return expanded
+}
@@ -0,0 +1,54 @@
+package checker
+
+import "audio-loss-checker/internal/types"
+
+// 分析结果的类型，与命令行 --json 输出的结构相同
+type (
+	// Result 一个文件（或CUE表中的一条音轨）的分析结果
+	Result = types.AnalysisResult
+	// Details 频谱分析的详细结果和判定证据
+	Details = types.AnalysisDetails
+	// Metadata 文件标签中的元数据
+	Metadata = types.AudioMetadata
+	// Evidence 一条判定证据，权重以对数几率为单位，正值支持有损来源，负值支持真无损
+	Evidence = types.Evidence
+	// ChannelAnalysis 单个声道的频谱分析结果
+	ChannelAnalysis = types.ChannelAnalysis
+	// EncoderMatch 截断特征最吻合的有损编码器设置
+	EncoderMatch = types.EncoderMatch
+	// StereoCollapse 高频侧声道塌缩检测
+	StereoCollapse = types.StereoCollapse
+	// Upsampling 升频检测
+	Upsampling = types.Upsampling
+	// EffectiveBitDepth 有效位深度
+	EffectiveBitDepth = types.EffectiveBitDepth
+	// Segment 分段分析中的一段
+	Segment = types.Segment
+	// FormatMismatch 扩展名与文件内容不一致
+	FormatMismatch = types.FormatMismatch
+	// Integrity 解码完整性检查结果
+	Integrity = types.Integrity
+	// DecodeError 解码过程中遇到的帧错误
+	DecodeError = types.DecodeError
+	// RipLog 对应的抓轨日志记录及CRC核对结果
+	RipLog = types.RipLog
+	// AccurateRip AccurateRip校验和及核对结果
+	AccurateRip = types.AccurateRip
+	// TrackInfo 按CUE分轨分析时的音轨信息
+	TrackInfo = types.TrackInfo
+	// Album 按专辑汇总的结果
+	Album = types.AlbumResult
+	// Report 一批文件的分析结果和专辑汇总
+	Report = types.AnalysisReport
+)
+
+// Result.Status 和 Album.Status 的取值
+const (
+	StatusOK        = "OK"
+	StatusSuspect   = "SUSPECT"    // 证据不足以定论，建议人工确认
+	StatusFake      = "FAKE"       // 有损来源
+	StatusFakeHiRes = "FAKE_HIRES" // 由较低采样率升频或填充位深度而来（仅文件结果）
+	StatusCorrupt   = "CORRUPT"    // 数据损坏（仅文件结果）
+	StatusTruncated = "TRUNCATED"  // 数据不完整（仅文件结果）
+	StatusError     = "ERROR"      // 无法分析，原因见 Result.Error（仅文件结果）
+)